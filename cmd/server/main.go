@@ -12,6 +12,7 @@ import (
 	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
 	"github.com/zhuangbiaowei/LocalAIStack/internal/control"
 	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/runtime"
 	"github.com/zhuangbiaowei/LocalAIStack/pkg/logging"
 )
 
@@ -47,6 +48,7 @@ func main() {
 
 	// Initialize API server
 	apiServer := api.NewServer(cfg, controlLayer)
+	apiServer.AttachRuntime(runtime.NewManager(cfg.Runtime))
 	go func() {
 		if err := apiServer.Start(); err != nil {
 			log.Error().Err(err).Msg(i18n.T("API server error"))
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/auth"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
+)
+
+func TestRequireScope(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Control.DataDir = t.TempDir()
+	server := NewServer(cfg, nil)
+
+	store := auth.NewStore(filepath.Join(cfg.Control.DataDir, "auth", "tokens.json"))
+	_, reader, err := store.Create("reader", []auth.Scope{auth.ScopeRead})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	_, admin, err := store.Create("admin", []auth.Scope{auth.ScopeAdmin})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{"health is public", http.MethodGet, "/health", "", http.StatusOK},
+		{"missing token", http.MethodGet, "/api/v1/providers", "", http.StatusUnauthorized},
+		{"invalid token", http.MethodGet, "/api/v1/providers", "las_nope", http.StatusUnauthorized},
+		{"read scope", http.MethodGet, "/api/v1/providers", reader, http.StatusOK},
+		{"read cannot install", http.MethodPost, "/api/v1/modules/demo/install", reader, http.StatusForbidden},
+		{"read cannot delete models", http.MethodDelete, "/api/v1/models/ollama/llama3", reader, http.StatusForbidden},
+		{"admin passes the scope check", http.MethodPost, "/api/v1/modules/missing/install", admin, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.token != "" {
+				request.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			recorder := httptest.NewRecorder()
+			server.server.Handler.ServeHTTP(recorder, request)
+			if recorder.Code != tc.status {
+				t.Fatalf("expected %d, got %d: %s", tc.status, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
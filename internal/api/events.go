@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/events"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/jobs"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/runtime"
+)
+
+const (
+	eventsHeartbeat        = 15 * time.Second
+	downloadReportInterval = 500 * time.Millisecond
+)
+
+// stepEventPayload is the payload of events.TypeStep events.
+type stepEventPayload struct {
+	JobID string `json:"job_id"`
+	module.StepEvent
+}
+
+// jobPublisher forwards job changes to the bus, skipping updates that only
+// append output or byte progress; downloads are streamed as
+// events.TypeDownloadProgress instead.
+type jobPublisher struct {
+	bus  *events.Bus
+	mu   sync.Mutex
+	last map[string]jobs.Job
+}
+
+func newJobPublisher(bus *events.Bus) *jobPublisher {
+	return &jobPublisher{bus: bus, last: make(map[string]jobs.Job)}
+}
+
+func (p *jobPublisher) publish(job jobs.Job) {
+	job.Output = ""
+	p.mu.Lock()
+	previous, seen := p.last[job.ID]
+	if seen && previous.Status == job.Status && previous.Completed == job.Completed &&
+		previous.Total == job.Total && len(previous.Steps) == len(job.Steps) {
+		p.mu.Unlock()
+		return
+	}
+	if job.Done() {
+		delete(p.last, job.ID)
+	} else {
+		p.last[job.ID] = job
+	}
+	p.mu.Unlock()
+	p.bus.Publish(events.TypeJob, job)
+}
+
+// AttachRuntime publishes process state and health transitions of manager
+// on the event stream.
+func (s *Server) AttachRuntime(manager *runtime.Manager) {
+	var (
+		mu   sync.Mutex
+		last = make(map[string]runtime.Status)
+	)
+	manager.Watch(func(status runtime.Status) {
+		mu.Lock()
+		previous, seen := last[status.Name]
+		last[status.Name] = status
+		mu.Unlock()
+		if !seen || previous.State != status.State {
+			s.events.Publish(events.TypeProcessState, status)
+		}
+		if seen && previous.Health != status.Health {
+			s.events.Publish(events.TypeHealth, status)
+		}
+	})
+	s.runtime = manager
+}
+
+// moduleRunOptions wires a module action's progress, output and downloads
+// into the job reporter and the event bus.
+func (s *Server) moduleRunOptions(name string, reporter *jobs.Reporter) module.RunOptions {
+	jobID := reporter.JobID()
+	return module.RunOptions{
+		Output: reporter,
+		Progress: func(event module.StepEvent) {
+			reporter.Step(event.StepID, event.Intent, event.Status, event.Error, event.Total)
+			s.events.Publish(events.TypeStep, stepEventPayload{JobID: jobID, StepEvent: event})
+		},
+		Download: s.downloadProgress("module/"+name, reporter),
+	}
+}
+
+// downloadProgress returns a download callback that records byte progress on
+// the job and publishes throttled events.TypeDownloadProgress events.
+func (s *Server) downloadProgress(target string, reporter *jobs.Reporter) func(downloaded, total int64) {
+	publish := s.events.DownloadReporter(events.DownloadProgress{Target: target, JobID: reporter.JobID()}, downloadReportInterval)
+	return func(downloaded, total int64) {
+		reporter.Transfer(downloaded, total)
+		publish(downloaded, total)
+	}
+}
+
+// eventsHandler streams bus events as server-sent events. Clients may filter
+// with ?types=job,step and resume with the Last-Event-ID header.
+func (s *Server) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, i18n.T("streaming not supported"), http.StatusInternalServerError)
+		return
+	}
+
+	var types []string
+	for _, value := range strings.Split(r.URL.Query().Get("types"), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			types = append(types, value)
+		}
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	afterID, _ := strconv.ParseUint(lastID, 10, 64)
+
+	// The stream outlives the server's write timeout.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	ch, unsubscribe := s.events.Subscribe(afterID, types...)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			payload, err := json.Marshal(event.Data)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, payload)
+			flusher.Flush()
+		}
+	}
+}
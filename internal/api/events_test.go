@@ -0,0 +1,74 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+)
+
+func TestEventsHandler_StreamsInstallSteps(t *testing.T) {
+	setupTestModule(t, "#!/usr/bin/env bash\ntouch \"$(dirname \"$0\")/../installed\"\n")
+	server := NewServer(testConfig(), nil)
+	httpServer := httptest.NewServer(server.server.Handler)
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL+"/api/v1/events?types=step", nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer response.Body.Close()
+	if got := response.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", got)
+	}
+
+	if _, err := http.Post(httpServer.URL+"/api/v1/modules/demo/install", "application/json", nil); err != nil {
+		t.Fatalf("failed to start install: %v", err)
+	}
+
+	var (
+		eventType string
+		finished  []string
+	)
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if eventType != "step" {
+				t.Fatalf("unexpected event type %q", eventType)
+			}
+			var payload stepEventPayload
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &payload); err != nil {
+				t.Fatalf("failed to decode event: %v", err)
+			}
+			if payload.JobID == "" || payload.Module != "demo" {
+				t.Fatalf("unexpected payload %+v", payload)
+			}
+			if payload.Status == module.StepSucceeded {
+				finished = append(finished, payload.StepID)
+			}
+			if len(finished) == 2 {
+				if finished[0] != "S10" || finished[1] != "S20" {
+					t.Fatalf("unexpected step order %v", finished)
+				}
+				return
+			}
+		}
+	}
+	t.Fatalf("stream ended before both steps finished: %v", scanner.Err())
+}
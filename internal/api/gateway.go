@@ -0,0 +1,275 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/metrics"
+)
+
+const (
+	gatewayDiscoveryTimeout = 2 * time.Second
+	gatewayDiscoveryTTL     = 15 * time.Second
+	maxChatRequestBytes     = 8 << 20
+	maxBufferedChatResponse = 16 << 20
+)
+
+// chatModel is a model served by one of the configured gateway endpoints.
+type chatModel struct {
+	ID       string `json:"id"`
+	Endpoint string `json:"endpoint"`
+}
+
+type chatModelsResponse struct {
+	OK     bool              `json:"ok"`
+	Error  string            `json:"error,omitempty"`
+	Models []chatModel       `json:"models"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// gateway proxies OpenAI-compatible chat requests to whichever configured
+// endpoint serves the requested model.
+type gateway struct {
+	endpoints []string
+	client    *http.Client
+	duration  *metrics.HistogramVec
+	tokens    *metrics.CounterVec
+
+	mu           sync.Mutex
+	models       []chatModel
+	errors       map[string]string
+	discoveredAt time.Time
+}
+
+// newGateway creates a gateway for endpoints and registers its latency and
+// token series in registry.
+func newGateway(endpoints []string, timeout time.Duration, registry *metrics.Registry) *gateway {
+	cleaned := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/"); endpoint != "" {
+			cleaned = append(cleaned, endpoint)
+		}
+	}
+	return &gateway{
+		endpoints: cleaned,
+		client:    &http.Client{Timeout: timeout},
+		duration:  registry.Histogram("las_gateway_request_duration_seconds", "Latency of requests proxied to model runtimes.", []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}, "model", "status"),
+		tokens:    registry.Counter("las_gateway_tokens_total", "Tokens processed by proxied requests, by type (prompt or completion).", "model", "type"),
+	}
+}
+
+// observe records a request proxied to a model runtime.
+func (g *gateway) observe(model string, status int, elapsed time.Duration, promptTokens, completionTokens int) {
+	g.duration.Observe(elapsed.Seconds(), model, strconv.Itoa(status))
+	g.tokens.Add(float64(promptTokens), model, "prompt")
+	g.tokens.Add(float64(completionTokens), model, "completion")
+}
+
+// discover lists the models of every endpoint, reusing the previous result
+// for gatewayDiscoveryTTL unless refresh is set.
+func (g *gateway) discover(ctx context.Context, refresh bool) ([]chatModel, map[string]string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !refresh && !g.discoveredAt.IsZero() && time.Since(g.discoveredAt) < gatewayDiscoveryTTL {
+		return g.models, g.errors
+	}
+
+	type result struct {
+		endpoint string
+		models   []chatModel
+		err      error
+	}
+	results := make(chan result, len(g.endpoints))
+	for _, endpoint := range g.endpoints {
+		go func(endpoint string) {
+			models, err := g.listModels(ctx, endpoint)
+			results <- result{endpoint: endpoint, models: models, err: err}
+		}(endpoint)
+	}
+	models := []chatModel{}
+	errors := map[string]string{}
+	for range g.endpoints {
+		res := <-results
+		if res.err != nil {
+			errors[res.endpoint] = res.err.Error()
+			continue
+		}
+		models = append(models, res.models...)
+	}
+	g.models, g.errors, g.discoveredAt = models, errors, time.Now()
+	return models, errors
+}
+
+func (g *gateway) listModels(ctx context.Context, endpoint string) ([]chatModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, gatewayDiscoveryTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/v1/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := g.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, i18n.Errorf("unexpected status %s", response.Status)
+	}
+	var payload struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&payload); err != nil {
+		return nil, i18n.Errorf("invalid model list: %w", err)
+	}
+	models := make([]chatModel, 0, len(payload.Data))
+	for _, entry := range payload.Data {
+		models = append(models, chatModel{ID: entry.ID, Endpoint: endpoint})
+	}
+	return models, nil
+}
+
+func (g *gateway) endpointFor(ctx context.Context, model string) string {
+	for _, refresh := range []bool{false, true} {
+		models, _ := g.discover(ctx, refresh)
+		for _, candidate := range models {
+			if candidate.ID == model {
+				return candidate.Endpoint
+			}
+		}
+	}
+	return ""
+}
+
+type chatUsage struct {
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (s *Server) chatModelsHandler(w http.ResponseWriter, r *http.Request) {
+	models, errors := s.gateway.discover(r.Context(), r.URL.Query().Get("refresh") != "")
+	writeJSON(w, http.StatusOK, chatModelsResponse{OK: true, Models: models, Errors: errors})
+}
+
+// chatCompletionsHandler forwards an OpenAI chat completion request to the
+// endpoint serving its model, streaming the response back unchanged.
+func (s *Server) chatCompletionsHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxChatRequestBytes+1))
+	if err != nil || len(body) > maxChatRequestBytes {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: i18n.T("invalid or oversized request body")})
+		return
+	}
+	var request struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil || strings.TrimSpace(request.Model) == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: i18n.T("request must be JSON with a model")})
+		return
+	}
+	endpoint := s.gateway.endpointFor(r.Context(), request.Model)
+	if endpoint == "" {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: i18n.T("no running endpoint serves model %q", request.Model)})
+		return
+	}
+
+	upstream, err := http.NewRequestWithContext(r.Context(), http.MethodPost, endpoint+"/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+	upstream.Header.Set("Content-Type", "application/json")
+	started := time.Now()
+	response, err := s.gateway.client.Do(upstream)
+	if err != nil {
+		s.gateway.observe(request.Model, http.StatusBadGateway, time.Since(started), 0, 0)
+		writeJSON(w, http.StatusBadGateway, errorResponse{Error: i18n.T("model endpoint failed: %v", err)})
+		return
+	}
+	defer response.Body.Close()
+
+	// Generation can outlast the server's write timeout.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	for _, header := range []string{"Content-Type", "Cache-Control"} {
+		if value := response.Header.Get(header); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+	w.WriteHeader(response.StatusCode)
+
+	var usage chatUsage
+	if request.Stream {
+		usage = relayStream(w, response.Body)
+	} else {
+		var buffered bytes.Buffer
+		_, _ = io.Copy(w, io.TeeReader(response.Body, &limitedBuffer{buf: &buffered, limit: maxBufferedChatResponse}))
+		_ = json.Unmarshal(buffered.Bytes(), &usage)
+	}
+	promptTokens, completionTokens := 0, 0
+	if usage.Usage != nil {
+		promptTokens, completionTokens = usage.Usage.PromptTokens, usage.Usage.CompletionTokens
+	}
+	s.gateway.observe(request.Model, response.StatusCode, time.Since(started), promptTokens, completionTokens)
+	if response.StatusCode < http.StatusBadRequest {
+		// Recorded for `las model prune`; names matching no downloaded
+		// model are ignored.
+		s.models.TouchModelByName(request.Model)
+	}
+}
+
+// relayStream copies server-sent events line by line, flushing each event and
+// picking up the usage block that servers send with the final chunk.
+func relayStream(w http.ResponseWriter, body io.Reader) chatUsage {
+	flusher, _ := w.(http.Flusher)
+	var usage chatUsage
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if _, writeErr := w.Write(line); writeErr != nil {
+				return usage
+			}
+			if data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:")); ok {
+				var chunk chatUsage
+				if json.Unmarshal(bytes.TrimSpace(data), &chunk) == nil && chunk.Usage != nil {
+					usage = chunk
+				}
+			}
+			if flusher != nil && len(bytes.TrimSpace(line)) == 0 {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return usage
+		}
+	}
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the
+// rest without failing the copy.
+type limitedBuffer struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining > 0 {
+		b.buf.Write(p[:min(len(p), remaining)])
+	}
+	return len(p), nil
+}
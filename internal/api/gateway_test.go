@@ -0,0 +1,85 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newFakeChatEndpoint(t *testing.T, model string) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			io.WriteString(w, `{"data":[{"id":"`+model+`"}]}`)
+		case "/v1/chat/completions":
+			body, _ := io.ReadAll(r.Body)
+			if strings.Contains(string(body), `"stream":true`) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\n")
+				io.WriteString(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":1}}\n\n")
+				io.WriteString(w, "data: [DONE]\n\n")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"hello"}}],"usage":{"prompt_tokens":5,"completion_tokens":2}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func TestChatGateway(t *testing.T) {
+	upstream := newFakeChatEndpoint(t, "demo-model")
+	cfg := testConfig()
+	cfg.Gateway.Endpoints = []string{upstream.URL, "http://127.0.0.1:1"}
+	server := NewServer(cfg, nil)
+
+	recorder := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/chat/models", nil))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"id":"demo-model"`) {
+		t.Fatalf("unexpected model list %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if !strings.Contains(recorder.Body.String(), "127.0.0.1:1") {
+		t.Fatalf("expected unreachable endpoint to be reported, got %s", recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/chat/completions",
+		strings.NewReader(`{"model":"demo-model","messages":[{"role":"user","content":"hi"}]}`)))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "hello") {
+		t.Fatalf("unexpected completion %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/chat/completions",
+		strings.NewReader(`{"model":"demo-model","stream":true,"messages":[{"role":"user","content":"hi"}]}`)))
+	if got := recorder.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("expected event stream, got %q", got)
+	}
+	if !strings.Contains(recorder.Body.String(), `"content":"hi"`) || !strings.Contains(recorder.Body.String(), "[DONE]") {
+		t.Fatalf("stream was not relayed: %s", recorder.Body.String())
+	}
+
+	metrics := scrapeMetrics(t, server)
+	for _, want := range []string{
+		`las_gateway_tokens_total{model="demo-model",type="prompt"} 8`,
+		`las_gateway_tokens_total{model="demo-model",type="completion"} 3`,
+		`las_gateway_request_duration_seconds_count{model="demo-model",status="200"} 2`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Fatalf("expected %q in metrics:\n%s", want, metrics)
+		}
+	}
+
+	recorder = httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/chat/completions",
+		strings.NewReader(`{"model":"missing"}`)))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown model, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/jobs"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/metrics"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/runtime"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/system/info"
+)
+
+// hardwareRefreshInterval bounds how often a scrape shells out to
+// nvidia-smi.
+const hardwareRefreshInterval = 10 * time.Second
+
+var (
+	runtimeStates = []runtime.ProcessState{runtime.StateStarting, runtime.StateRunning, runtime.StateStopped, runtime.StateFailed}
+	healthStates  = []runtime.HealthState{runtime.HealthUnknown, runtime.HealthHealthy, runtime.HealthUnhealthy}
+)
+
+// serverMetrics holds the metric families exposed on /metrics.
+type serverMetrics struct {
+	registry *metrics.Registry
+
+	runtimeState    *metrics.GaugeVec
+	runtimeHealth   *metrics.GaugeVec
+	runtimeRestarts *metrics.GaugeVec
+	runtimeUptime   *metrics.GaugeVec
+
+	moduleActionDuration *metrics.HistogramVec
+	downloadBytes        *metrics.CounterVec
+	downloadDuration     *metrics.HistogramVec
+
+	memoryTotal     *metrics.GaugeVec
+	memoryAvailable *metrics.GaugeVec
+	gpuUtilization  *metrics.GaugeVec
+	gpuMemoryUsed   *metrics.GaugeVec
+	gpuMemoryTotal  *metrics.GaugeVec
+
+	mu          sync.Mutex
+	running     map[string]bool
+	hardware    info.Utilization
+	hardwareAt  time.Time
+	utilization func(context.Context) info.Utilization
+}
+
+func newServerMetrics() *serverMetrics {
+	r := metrics.NewRegistry()
+	return &serverMetrics{
+		registry: r,
+
+		runtimeState:    r.Gauge("las_runtime_module_state", "Current process state of a runtime module (1 for the active state).", "module", "mode", "state"),
+		runtimeHealth:   r.Gauge("las_runtime_module_health", "Current health of a runtime module (1 for the active health state).", "module", "health"),
+		runtimeRestarts: r.Gauge("las_runtime_module_restarts", "Number of times a runtime module was started again after stopping.", "module"),
+		runtimeUptime:   r.Gauge("las_runtime_module_uptime_seconds", "Seconds since a running runtime module started.", "module"),
+
+		moduleActionDuration: r.Histogram("las_module_action_duration_seconds", "Duration of module install, uninstall and purge jobs by outcome.", nil, "module", "action", "outcome"),
+		downloadBytes:        r.Counter("las_model_download_bytes_total", "Bytes downloaded by model download jobs.", "source"),
+		downloadDuration:     r.Histogram("las_model_download_duration_seconds", "Duration of model download jobs by outcome.", nil, "source", "outcome"),
+
+		memoryTotal:     r.Gauge("las_memory_total_bytes", "Total system memory."),
+		memoryAvailable: r.Gauge("las_memory_available_bytes", "Memory available for new allocations."),
+		gpuUtilization:  r.Gauge("las_gpu_utilization_ratio", "GPU compute utilization between 0 and 1.", "gpu", "name"),
+		gpuMemoryUsed:   r.Gauge("las_gpu_memory_used_bytes", "GPU memory in use.", "gpu", "name"),
+		gpuMemoryTotal:  r.Gauge("las_gpu_memory_total_bytes", "Total GPU memory.", "gpu", "name"),
+
+		running:     make(map[string]bool),
+		utilization: info.CollectUtilization,
+	}
+}
+
+// observeJob records the duration and outcome of finished module and model
+// download jobs. It is a jobs.Manager watcher.
+func (m *serverMetrics) observeJob(job jobs.Job) {
+	m.mu.Lock()
+	if !job.Done() {
+		m.running[job.ID] = true
+		m.mu.Unlock()
+		return
+	}
+	started := m.running[job.ID]
+	delete(m.running, job.ID)
+	m.mu.Unlock()
+	if !started || job.StartedAt == nil || job.FinishedAt == nil {
+		return
+	}
+
+	elapsed := job.FinishedAt.Sub(*job.StartedAt).Seconds()
+	outcome := string(job.Status)
+	switch {
+	case strings.HasPrefix(job.Kind, "module."):
+		m.moduleActionDuration.Observe(elapsed, strings.TrimPrefix(job.Target, "module/"), strings.TrimPrefix(job.Kind, "module."), outcome)
+	case job.Kind == "model.download":
+		source, _, _ := strings.Cut(strings.TrimPrefix(job.Target, "model/"), ":")
+		m.downloadDuration.Observe(elapsed, source, outcome)
+	}
+}
+
+// countDownload wraps a download progress callback so that byte progress is
+// added to las_model_download_bytes_total. Progress restarts at 0 for every
+// file of a model.
+func (m *serverMetrics) countDownload(source string, progress func(downloaded, total int64)) func(downloaded, total int64) {
+	var last int64
+	return func(downloaded, total int64) {
+		if downloaded < last {
+			last = 0
+		}
+		if downloaded > last {
+			m.downloadBytes.Add(float64(downloaded-last), source)
+			last = downloaded
+		}
+		progress(downloaded, total)
+	}
+}
+
+// collectRuntime mirrors the runtime manager's process table.
+func (m *serverMetrics) collectRuntime(manager *runtime.Manager) {
+	m.runtimeState.Reset()
+	m.runtimeHealth.Reset()
+	m.runtimeRestarts.Reset()
+	m.runtimeUptime.Reset()
+	if manager == nil {
+		return
+	}
+	now := time.Now()
+	for _, status := range manager.List() {
+		for _, state := range runtimeStates {
+			m.runtimeState.Set(boolValue(status.State == state), status.Name, string(status.Mode), string(state))
+		}
+		for _, health := range healthStates {
+			m.runtimeHealth.Set(boolValue(status.Health == health), status.Name, string(health))
+		}
+		m.runtimeRestarts.Set(float64(status.Restarts), status.Name)
+		if status.State == runtime.StateRunning {
+			m.runtimeUptime.Set(now.Sub(status.StartedAt).Seconds(), status.Name)
+		}
+	}
+}
+
+// hardwareUsage returns memory and GPU utilization, sampled at most once per
+// hardwareRefreshInterval.
+func (m *serverMetrics) hardwareUsage() info.Utilization {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if time.Since(m.hardwareAt) >= hardwareRefreshInterval {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		m.hardware = m.utilization(ctx)
+		cancel()
+		m.hardwareAt = time.Now()
+	}
+	return m.hardware
+}
+
+// collectHardware refreshes the memory and GPU gauges.
+func (m *serverMetrics) collectHardware() {
+	usage := m.hardwareUsage()
+	m.memoryTotal.Set(float64(usage.MemoryTotalBytes))
+	m.memoryAvailable.Set(float64(usage.MemoryAvailableBytes))
+	m.gpuUtilization.Reset()
+	m.gpuMemoryUsed.Reset()
+	m.gpuMemoryTotal.Reset()
+	for _, gpu := range usage.GPUs {
+		index := strconv.Itoa(gpu.Index)
+		m.gpuUtilization.Set(gpu.UtilizationRatio, index, gpu.Name)
+		m.gpuMemoryUsed.Set(float64(gpu.MemoryUsedBytes), index, gpu.Name)
+		m.gpuMemoryTotal.Set(float64(gpu.MemoryTotalBytes), index, gpu.Name)
+	}
+}
+
+func boolValue(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := s.metrics.registry.WriteText(w); err != nil {
+		http.Error(w, i18n.T("failed to write metrics: %v", err), http.StatusInternalServerError)
+	}
+}
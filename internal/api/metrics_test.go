@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/system/info"
+)
+
+func scrapeMetrics(t *testing.T, server *Server) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if got := recorder.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", got)
+	}
+	return recorder.Body.String()
+}
+
+func TestMetricsHandler(t *testing.T) {
+	setupTestModule(t, "#!/usr/bin/env bash\ntouch \"$(dirname \"$0\")/../installed\"\n")
+	server := newModelTestServer(t)
+	server.metrics.utilization = func(context.Context) info.Utilization {
+		return info.Utilization{
+			MemoryTotalBytes:     64 << 30,
+			MemoryAvailableBytes: 32 << 30,
+			GPUs:                 []info.GPUUtilization{{Index: 0, Name: "Test GPU", UtilizationRatio: 0.5, MemoryUsedBytes: 1 << 30, MemoryTotalBytes: 24 << 30}},
+		}
+	}
+
+	_, installed := serve(t, server, http.MethodPost, "/api/v1/modules/demo/install")
+	pollJob(t, server, installed.Job.ID)
+	var downloaded jobResponse
+	serveModels(t, server, http.MethodPost, "/api/v1/models/download", `{"id":"hf:org/demo"}`, &downloaded)
+	pollJob(t, server, downloaded.Job.ID)
+
+	body := scrapeMetrics(t, server)
+	for _, expected := range []string{
+		`las_module_action_duration_seconds_count{module="demo",action="install",outcome="succeeded"} 1`,
+		`las_model_download_duration_seconds_count{source="huggingface",outcome="succeeded"} 1`,
+		`las_model_download_bytes_total{source="huggingface"} 12`,
+		`las_memory_available_bytes 3.4359738368e+10`,
+		`las_gpu_utilization_ratio{gpu="0",name="Test GPU"} 0.5`,
+		"# TYPE las_runtime_module_state gauge",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("metrics output is missing %q:\n%s", expected, body)
+		}
+	}
+}
+
+func TestCountDownload_AddsEveryFile(t *testing.T) {
+	m := newServerMetrics()
+	progress := m.countDownload("huggingface", func(int64, int64) {})
+	for _, call := range [][2]int64{{0, 5}, {5, 5}, {0, 300}, {64, 300}, {300, 300}} {
+		progress(call[0], call[1])
+	}
+	var out strings.Builder
+	if err := m.registry.WriteText(&out); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	if want := `las_model_download_bytes_total{source="huggingface"} 305`; !strings.Contains(out.String(), want) {
+		t.Fatalf("metrics output is missing %q:\n%s", want, out.String())
+	}
+}
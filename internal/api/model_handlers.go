@@ -0,0 +1,423 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/jobs"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/modelmanager"
+)
+
+const defaultSearchLimit = 10
+
+type localModel struct {
+	ID           string                   `json:"id"`
+	Name         string                   `json:"name"`
+	Source       modelmanager.ModelSource `json:"source"`
+	Format       modelmanager.ModelFormat `json:"format"`
+	Size         int64                    `json:"size"`
+	LocalPath    string                   `json:"local_path"`
+	DownloadedAt int64                    `json:"downloaded_at"`
+}
+
+type modelsResponse struct {
+	OK     bool         `json:"ok"`
+	Error  string       `json:"error,omitempty"`
+	Models []localModel `json:"models,omitempty"`
+}
+
+type modelSearchResponse struct {
+	OK      bool                                                  `json:"ok"`
+	Error   string                                                `json:"error,omitempty"`
+	Results map[modelmanager.ModelSource][]modelmanager.ModelInfo `json:"results,omitempty"`
+}
+
+type modelInfoResponse struct {
+	OK    bool                    `json:"ok"`
+	Error string                  `json:"error,omitempty"`
+	Model *modelmanager.ModelInfo `json:"model,omitempty"`
+}
+
+type modelActionResponse struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type modelDownloadRequest struct {
+	ID     string `json:"id"`
+	Source string `json:"source"`
+	File   string `json:"file"`
+	// IgnoreQuota downloads even when the model quota would be exceeded.
+	IgnoreQuota bool `json:"ignore_quota"`
+	// Queue adds the download to the download queue instead of starting
+	// it right away.
+	Queue    bool `json:"queue"`
+	Priority int  `json:"priority"`
+}
+
+type queueResponse struct {
+	OK      bool                     `json:"ok"`
+	Error   string                   `json:"error,omitempty"`
+	Items   []modelmanager.QueueItem `json:"items"`
+	Removed int                      `json:"removed,omitempty"`
+}
+
+type queueItemResponse struct {
+	OK    bool                    `json:"ok"`
+	Error string                  `json:"error,omitempty"`
+	Item  *modelmanager.QueueItem `json:"item,omitempty"`
+	Job   *jobs.Job               `json:"job,omitempty"`
+}
+
+type queuePriorityRequest struct {
+	Priority *int `json:"priority"`
+}
+
+// resolveModelRef splits a model reference into its source and ID. An
+// explicit source wins over a prefix in id.
+func resolveModelRef(source, id string) (modelmanager.ModelSource, string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", "", i18n.Errorf("model id is required")
+	}
+	if strings.TrimSpace(source) != "" {
+		src, err := modelmanager.ParseSource(source)
+		return src, id, err
+	}
+	return modelmanager.ParseModelID(id)
+}
+
+func (s *Server) modelsListHandler(w http.ResponseWriter, r *http.Request) {
+	downloaded, err := s.models.ListDownloadedModels()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, modelsResponse{Error: err.Error()})
+		return
+	}
+	models := make([]localModel, 0, len(downloaded))
+	for _, model := range downloaded {
+		size, _ := modelmanager.PathSize(model.LocalPath)
+		models = append(models, localModel{
+			ID:           model.ID,
+			Name:         model.Name,
+			Source:       model.Source,
+			Format:       localModelFormat(model),
+			Size:         size,
+			LocalPath:    model.LocalPath,
+			DownloadedAt: model.DownloadedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, modelsResponse{OK: true, Models: models})
+}
+
+// localModelFormat prefers the recorded format and falls back to the files
+// present on disk.
+func localModelFormat(model modelmanager.DownloadedModel) modelmanager.ModelFormat {
+	if model.Format != "" && model.Format != modelmanager.FormatUnknown {
+		return model.Format
+	}
+	if files, err := modelmanager.FindGGUFFiles(model.LocalPath); err == nil && len(files) > 0 {
+		return modelmanager.FormatGGUF
+	}
+	if files, err := modelmanager.FindSafetensorsFiles(model.LocalPath); err == nil && len(files) > 0 {
+		return modelmanager.FormatSafetensors
+	}
+	return modelmanager.FormatUnknown
+}
+
+// modelSearchHandler searches one source, or all of them when source is
+// empty or "all".
+func (s *Server) modelSearchHandler(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeJSON(w, http.StatusBadRequest, modelSearchResponse{Error: i18n.T("query parameter q is required")})
+		return
+	}
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, modelSearchResponse{Error: i18n.T("invalid limit %q", raw)})
+			return
+		}
+		limit = parsed
+	}
+
+	source := strings.TrimSpace(r.URL.Query().Get("source"))
+	if source == "" || strings.EqualFold(source, "all") {
+		results, err := s.models.SearchAll(query, limit)
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, modelSearchResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, modelSearchResponse{OK: true, Results: results})
+		return
+	}
+
+	src, err := modelmanager.ParseSource(source)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, modelSearchResponse{Error: err.Error()})
+		return
+	}
+	provider, err := s.models.GetProvider(src)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, modelSearchResponse{Error: err.Error()})
+		return
+	}
+	models, err := provider.Search(r.Context(), query, limit)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, modelSearchResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, modelSearchResponse{OK: true, Results: map[modelmanager.ModelSource][]modelmanager.ModelInfo{src: models}})
+}
+
+func (s *Server) modelInfoHandler(w http.ResponseWriter, r *http.Request) {
+	src, modelID, err := resolveModelRef(r.PathValue("source"), r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, modelInfoResponse{Error: err.Error()})
+		return
+	}
+	info, err := s.models.GetModelInfo(src, modelID)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, modelInfoResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, modelInfoResponse{OK: true, Model: info})
+}
+
+// modelDownloadHandler starts a model download as a background job.
+func (s *Server) modelDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	var req modelDownloadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, jobResponse{Error: i18n.T("invalid request body: %v", err)})
+		return
+	}
+	src, modelID, err := resolveModelRef(req.Source, req.ID)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, jobResponse{Error: err.Error()})
+		return
+	}
+	if _, err := s.models.GetProvider(src); err != nil {
+		writeJSON(w, http.StatusBadRequest, jobResponse{Error: err.Error()})
+		return
+	}
+
+	target := "model/" + string(src) + ":" + modelID
+	opts := modelmanager.DownloadOptions{FileHint: strings.TrimSpace(req.File), IgnoreQuota: req.IgnoreQuota}
+	if req.Queue {
+		s.queueDownload(w, src, modelID, opts, req.Priority)
+		return
+	}
+	job, err := s.jobs.Submit("model.download", target, func(ctx context.Context, reporter *jobs.Reporter) error {
+		const stepID = "download"
+		intent := i18n.T("Download %s from %s", modelID, src)
+		reporter.Step(stepID, intent, "running", "", 1)
+		progress := s.metrics.countDownload(string(src), s.downloadProgress(target, reporter))
+		if err := s.models.DownloadModelWithContext(ctx, src, modelID, progress, opts); err != nil {
+			reporter.Step(stepID, intent, "failed", err.Error(), 0)
+			return err
+		}
+		reporter.Step(stepID, intent, "succeeded", "", 0)
+		return nil
+	})
+	if err != nil {
+		writeJSON(w, http.StatusConflict, jobResponse{Error: err.Error()})
+		return
+	}
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, jobResponse{OK: true, Job: &job})
+}
+
+// queueDownload adds a download to the queue and tracks it with a job.
+func (s *Server) queueDownload(w http.ResponseWriter, src modelmanager.ModelSource, modelID string, opts modelmanager.DownloadOptions, priority int) {
+	if s.downloads == nil {
+		writeJSON(w, http.StatusServiceUnavailable, queueItemResponse{Error: i18n.T("the download queue is unavailable")})
+		return
+	}
+	item, err := s.downloads.Add(src, modelID, opts, priority)
+	if err != nil {
+		writeJSON(w, http.StatusConflict, queueItemResponse{Error: err.Error()})
+		return
+	}
+	job, err := s.trackDownload(item)
+	if err != nil {
+		// Another job is downloading the model already.
+		_, _ = s.downloads.Cancel(item.ID)
+		writeJSON(w, http.StatusConflict, queueItemResponse{Error: err.Error()})
+		return
+	}
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, queueItemResponse{OK: true, Item: &item, Job: &job})
+}
+
+// startDownloads runs the download queue until the server stops and tracks
+// the downloads left over from the last run.
+func (s *Server) startDownloads() {
+	if s.downloads == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopDownloads = cancel
+	go s.downloads.Run(ctx)
+	for _, item := range s.downloads.List() {
+		if !item.Done() {
+			_, _ = s.trackDownload(item)
+		}
+	}
+}
+
+// trackDownload submits a job that follows a queued download until it
+// finishes, mirroring its state in the job's step and its progress in the
+// job's transfer. Canceling the job cancels the download.
+func (s *Server) trackDownload(item modelmanager.QueueItem) (jobs.Job, error) {
+	target := "model/" + item.Ref()
+	return s.jobs.Submit("model.download", target, func(ctx context.Context, reporter *jobs.Reporter) error {
+		const stepID = "download"
+		intent := i18n.T("Download %s from %s", item.ModelID, item.Source)
+		progress := s.metrics.countDownload(string(item.Source), s.downloadProgress(target, reporter))
+		var state modelmanager.QueueState
+		final, err := s.downloads.Wait(ctx, item.ID, func(current modelmanager.QueueItem) {
+			if current.State != state && !current.Done() {
+				reporter.Step(stepID, intent, string(current.State), "", 1)
+			}
+			state = current.State
+			if current.Downloaded > 0 {
+				progress(current.Downloaded, current.Total)
+			}
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				_, _ = s.downloads.Cancel(item.ID)
+			}
+			reporter.Step(stepID, intent, "failed", err.Error(), 0)
+			return err
+		}
+		switch final.State {
+		case modelmanager.QueueDone:
+			reporter.Step(stepID, intent, "succeeded", "", 0)
+			return nil
+		case modelmanager.QueueCanceled:
+			reporter.Step(stepID, intent, "canceled", "", 0)
+			return i18n.Errorf("download %s was canceled", item.ID)
+		default:
+			reporter.Step(stepID, intent, "failed", final.Error, 0)
+			return errors.New(final.Error)
+		}
+	})
+}
+
+func (s *Server) queueListHandler(w http.ResponseWriter, r *http.Request) {
+	if s.downloads == nil {
+		writeJSON(w, http.StatusServiceUnavailable, queueResponse{Error: i18n.T("the download queue is unavailable")})
+		return
+	}
+	writeJSON(w, http.StatusOK, queueResponse{OK: true, Items: s.downloads.List()})
+}
+
+// queueClearHandler removes finished downloads from the queue.
+func (s *Server) queueClearHandler(w http.ResponseWriter, r *http.Request) {
+	if s.downloads == nil {
+		writeJSON(w, http.StatusServiceUnavailable, queueResponse{Error: i18n.T("the download queue is unavailable")})
+		return
+	}
+	removed, err := s.downloads.Clear()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, queueResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, queueResponse{OK: true, Items: s.downloads.List(), Removed: removed})
+}
+
+// queueActionHandler pauses, resumes, cancels or reprioritizes a queued
+// download.
+func (s *Server) queueActionHandler(w http.ResponseWriter, r *http.Request) {
+	if s.downloads == nil {
+		writeJSON(w, http.StatusServiceUnavailable, queueItemResponse{Error: i18n.T("the download queue is unavailable")})
+		return
+	}
+	id := r.PathValue("id")
+	var item modelmanager.QueueItem
+	var err error
+	switch action := r.PathValue("action"); action {
+	case "pause":
+		item, err = s.downloads.Pause(id)
+	case "resume":
+		// The job tracking a failed or canceled download has finished.
+		if item, err = s.downloads.Resume(id); err == nil {
+			_, _ = s.trackDownload(item)
+		}
+	case "cancel":
+		item, err = s.downloads.Cancel(id)
+	case "priority":
+		var req queuePriorityRequest
+		if decodeErr := json.NewDecoder(r.Body).Decode(&req); decodeErr != nil || req.Priority == nil {
+			writeJSON(w, http.StatusBadRequest, queueItemResponse{Error: i18n.T("priority is required")})
+			return
+		}
+		item, err = s.downloads.SetPriority(id, *req.Priority)
+	default:
+		writeJSON(w, http.StatusNotFound, queueItemResponse{Error: i18n.T("unsupported queue action %q", action)})
+		return
+	}
+	switch {
+	case errors.Is(err, modelmanager.ErrQueueItemNotFound):
+		writeJSON(w, http.StatusNotFound, queueItemResponse{Error: i18n.T("queued download %s not found", id)})
+	case errors.Is(err, modelmanager.ErrQueueItemFinished):
+		writeJSON(w, http.StatusConflict, queueItemResponse{Error: i18n.T("queued download %s already finished", id)})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, queueItemResponse{Error: err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, queueItemResponse{OK: true, Item: &item})
+	}
+}
+
+func (s *Server) modelDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	src, modelID, err := resolveModelRef(r.PathValue("source"), r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, modelActionResponse{Error: err.Error()})
+		return
+	}
+	if _, err := s.models.ResolveLocalModelDir(src, modelID); err != nil && src != modelmanager.SourceOllama {
+		writeJSON(w, http.StatusNotFound, modelActionResponse{Error: err.Error()})
+		return
+	}
+	if err := s.models.RemoveModel(src, modelID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, modelActionResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, modelActionResponse{OK: true, Message: i18n.T("Model %s removed successfully.", modelID)})
+}
+
+// modelRepairHandler serves POST /api/v1/models/{id}/repair. Model IDs may
+// contain slashes, so the route matches the remaining path and strips the
+// action suffix.
+func (s *Server) modelRepairHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := strings.CutSuffix(r.PathValue("path"), "/repair")
+	if !ok {
+		writeJSON(w, http.StatusNotFound, modelActionResponse{Error: i18n.T("not found")})
+		return
+	}
+	source := r.URL.Query().Get("source")
+	explicit := source != "" || modelmanager.HasSourcePrefix(id)
+	src, modelID, err := resolveModelRef(source, id)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, modelActionResponse{Error: err.Error()})
+		return
+	}
+
+	err = s.models.RepairModel(r.Context(), src, modelID, explicit)
+	switch {
+	case errors.Is(err, modelmanager.ErrRepairNotRequired):
+		writeJSON(w, http.StatusOK, modelActionResponse{OK: true, Message: err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusBadRequest, modelActionResponse{Error: err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, modelActionResponse{OK: true, Message: i18n.T("Model %s repaired.", modelID)})
+	}
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/jobs"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/modelmanager"
+)
+
+// fakeModelProvider serves a fixed catalogue and "downloads" by writing a
+// small GGUF file.
+type fakeModelProvider struct {
+	source modelmanager.ModelSource
+}
+
+func (p *fakeModelProvider) Name() modelmanager.ModelSource { return p.source }
+
+func (p *fakeModelProvider) Search(ctx context.Context, query string, limit int) ([]modelmanager.ModelInfo, error) {
+	return []modelmanager.ModelInfo{{ID: "org/" + query, Source: p.source}}, nil
+}
+
+func (p *fakeModelProvider) Download(ctx context.Context, modelID string, destPath string, progress func(downloaded, total int64), opts modelmanager.DownloadOptions) error {
+	dir := filepath.Join(destPath, strings.ReplaceAll(modelID, "/", "_"))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	payload := []byte("GGUF-weights")
+	if err := os.WriteFile(filepath.Join(dir, "model.gguf"), payload, 0o644); err != nil {
+		return err
+	}
+	progress(int64(len(payload)), int64(len(payload)))
+	metadata, _ := json.Marshal(modelmanager.DownloadedModel{
+		ModelInfo:    modelmanager.ModelInfo{ID: modelID, Name: modelID, Source: p.source},
+		DownloadedAt: 1,
+	})
+	return os.WriteFile(filepath.Join(dir, "metadata.json"), metadata, 0o644)
+}
+
+func (p *fakeModelProvider) Delete(ctx context.Context, modelID string) error { return nil }
+
+func (p *fakeModelProvider) GetModelInfo(ctx context.Context, modelID string) (*modelmanager.ModelInfo, error) {
+	return &modelmanager.ModelInfo{ID: modelID, Source: p.source}, nil
+}
+
+func newModelTestServer(t *testing.T) *Server {
+	t.Helper()
+	server := NewServer(testConfig(), nil)
+	server.models = modelmanager.NewManager(t.TempDir())
+	for _, source := range []modelmanager.ModelSource{modelmanager.SourceHuggingFace, modelmanager.SourceOllama} {
+		if err := server.models.RegisterProvider(&fakeModelProvider{source: source}); err != nil {
+			t.Fatalf("failed to register provider: %v", err)
+		}
+	}
+	return server
+}
+
+func serveModels(t *testing.T, server *Server, method, path, body string, payload any) int {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	if err := json.Unmarshal(recorder.Body.Bytes(), payload); err != nil {
+		t.Fatalf("failed to decode %s %s response %q: %v", method, path, recorder.Body.String(), err)
+	}
+	return recorder.Code
+}
+
+func TestModelDownloadListAndDelete(t *testing.T) {
+	server := newModelTestServer(t)
+
+	var started jobResponse
+	if code := serveModels(t, server, http.MethodPost, "/api/v1/models/download", `{"id":"hf:org/demo"}`, &started); code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d %+v", code, started)
+	}
+	job := pollJob(t, server, started.Job.ID)
+	if job.Status != jobs.StatusSucceeded || job.Target != "model/huggingface:org/demo" {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	if job.Transfer == nil || job.Transfer.Downloaded != job.Transfer.Total || job.Completed != 1 {
+		t.Fatalf("expected completed transfer, got %+v", job)
+	}
+
+	var list modelsResponse
+	if code := serveModels(t, server, http.MethodGet, "/api/v1/models", "", &list); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(list.Models) != 1 || list.Models[0].ID != "org/demo" || list.Models[0].Format != modelmanager.FormatGGUF || list.Models[0].Size == 0 {
+		t.Fatalf("unexpected models %+v", list.Models)
+	}
+
+	var removed modelActionResponse
+	if code := serveModels(t, server, http.MethodDelete, "/api/v1/models/huggingface/org/demo", "", &removed); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %+v", code, removed)
+	}
+	if code := serveModels(t, server, http.MethodDelete, "/api/v1/models/huggingface/org/demo", "", &removed); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a removed model, got %d", code)
+	}
+}
+
+func TestModelSearchAndRepair(t *testing.T) {
+	server := newModelTestServer(t)
+
+	var search modelSearchResponse
+	if code := serveModels(t, server, http.MethodGet, "/api/v1/models/search?q=qwen&source=hf", "", &search); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %+v", code, search)
+	}
+	if results := search.Results[modelmanager.SourceHuggingFace]; len(results) != 1 || results[0].ID != "org/qwen" {
+		t.Fatalf("unexpected search results %+v", search.Results)
+	}
+	if code := serveModels(t, server, http.MethodGet, "/api/v1/models/search", "", &search); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a query, got %d", code)
+	}
+
+	var repaired modelActionResponse
+	if code := serveModels(t, server, http.MethodPost, "/api/v1/models/org/missing/repair?source=hf", "", &repaired); code != http.StatusBadRequest {
+		t.Fatalf("expected an error for a missing model, got %d %+v", code, repaired)
+	}
+	if err := os.MkdirAll(filepath.Join(server.models.GetModelDir(), "llama3"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if code := serveModels(t, server, http.MethodPost, "/api/v1/models/ollama:llama3/repair", "", &repaired); code != http.StatusOK || !repaired.OK {
+		t.Fatalf("expected ollama repair to be a no-op, got %d %+v", code, repaired)
+	}
+}
+
+func TestModelDownloadQueue(t *testing.T) {
+	server := newModelTestServer(t)
+	queue, err := modelmanager.NewDownloadQueue(server.models, modelmanager.QueueOptions{})
+	if err != nil {
+		t.Fatalf("NewDownloadQueue: %v", err)
+	}
+	server.downloads = queue
+	server.startDownloads()
+	t.Cleanup(server.stopDownloads)
+
+	var queued queueItemResponse
+	if code := serveModels(t, server, http.MethodPost, "/api/v1/models/download", `{"id":"hf:org/demo","queue":true,"priority":2}`, &queued); code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d %+v", code, queued)
+	}
+	if queued.Item == nil || queued.Item.Priority != 2 || queued.Job == nil {
+		t.Fatalf("unexpected queued download %+v", queued)
+	}
+	job := pollJob(t, server, queued.Job.ID)
+	if job.Status != jobs.StatusSucceeded || job.Target != "model/huggingface:org/demo" || job.Transfer == nil {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	if !server.models.HasLocalModel(modelmanager.SourceHuggingFace, "org/demo", "") {
+		t.Fatalf("the queued download did not run")
+	}
+
+	var list queueResponse
+	if code := serveModels(t, server, http.MethodGet, "/api/v1/models/queue", "", &list); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(list.Items) != 1 || list.Items[0].State != modelmanager.QueueDone {
+		t.Fatalf("unexpected queue %+v", list.Items)
+	}
+	id := list.Items[0].ID
+	var action queueItemResponse
+	if code := serveModels(t, server, http.MethodPost, "/api/v1/models/queue/"+id+"/cancel", "", &action); code != http.StatusConflict {
+		t.Fatalf("expected 409 for a finished download, got %d %+v", code, action)
+	}
+	if code := serveModels(t, server, http.MethodPost, "/api/v1/models/queue/"+id+"/priority", `{}`, &action); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a priority, got %d %+v", code, action)
+	}
+	if code := serveModels(t, server, http.MethodPost, "/api/v1/models/queue/missing/pause", "", &action); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown download, got %d %+v", code, action)
+	}
+	if code := serveModels(t, server, http.MethodDelete, "/api/v1/models/queue", "", &list); code != http.StatusOK || list.Removed != 1 || len(list.Items) != 0 {
+		t.Fatalf("unexpected clear response %d %+v", code, list)
+	}
+}
@@ -1,42 +1,18 @@
 package api
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"os/exec"
-	"path/filepath"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/jobs"
 	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
 )
 
-type moduleRequest struct {
-	Name string `json:"name"`
-}
-
-type cliResult struct {
-	ExitCode   int    `json:"exit_code"`
-	Stdout     string `json:"stdout"`
-	Stderr     string `json:"stderr"`
-	DurationMs int64  `json:"duration_ms"`
-}
-
-type cliResponse struct {
-	OK     bool       `json:"ok"`
-	Error  string     `json:"error,omitempty"`
-	Output string     `json:"output,omitempty"`
-	Result *cliResult `json:"result,omitempty"`
-}
-
 type moduleInfo struct {
 	Name        string `json:"name"`
 	Category    string `json:"category"`
@@ -51,178 +27,114 @@ type modulesResponse struct {
 	Modules []moduleInfo `json:"modules,omitempty"`
 }
 
-func (s *Server) modulesListHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, i18n.T("method not allowed"), http.StatusMethodNotAllowed)
-		return
-	}
-
-	if _, err := s.runCLI(r.Context(), []string{"module", "list"}); err != nil {
-		writeModulesResponse(w, nil, err)
-		return
-	}
-
-	modules, err := listModules()
-	writeModulesResponse(w, modules, err)
+type jobResponse struct {
+	OK    bool      `json:"ok"`
+	Error string    `json:"error,omitempty"`
+	Job   *jobs.Job `json:"job,omitempty"`
 }
 
-func (s *Server) moduleListHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, i18n.T("method not allowed"), http.StatusMethodNotAllowed)
-		return
-	}
-	result, err := s.runCLI(r.Context(), []string{"module", "list"})
-	writeCLIResponse(w, result, err)
+type jobsResponse struct {
+	OK   bool       `json:"ok"`
+	Jobs []jobs.Job `json:"jobs"`
 }
 
-func (s *Server) moduleInstallHandler(w http.ResponseWriter, r *http.Request) {
-	s.moduleActionHandler(w, r, "install")
+type checkResponse struct {
+	OK        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+	Module    string `json:"module"`
+	Installed bool   `json:"installed"`
 }
 
-func (s *Server) moduleUninstallHandler(w http.ResponseWriter, r *http.Request) {
-	s.moduleActionHandler(w, r, "uninstall")
-}
+type moduleActionFunc func(ctx context.Context, name string, opts module.RunOptions) error
 
-func (s *Server) moduleCheckHandler(w http.ResponseWriter, r *http.Request) {
-	s.moduleActionHandler(w, r, "check")
+var moduleActions = map[string]moduleActionFunc{
+	"install":   module.InstallWithContext,
+	"uninstall": module.UninstallWithContext,
+	"purge":     module.PurgeWithContext,
 }
 
-func (s *Server) moduleActionHandler(w http.ResponseWriter, r *http.Request, action string) {
-	if r.Method != http.MethodPost {
-		http.Error(w, i18n.T("method not allowed"), http.StatusMethodNotAllowed)
-		return
-	}
+func (s *Server) modulesListHandler(w http.ResponseWriter, r *http.Request) {
+	modules, err := listModules()
+	writeModulesResponse(w, modules, err)
+}
 
-	var req moduleRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeCLIResponse(w, nil, fmt.Errorf("invalid request body: %w", err))
+// moduleActionHandler starts a lifecycle action as a background job and
+// responds with 202 and the queued job.
+func (s *Server) moduleActionHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.PathValue("name"))
+	action := r.PathValue("action")
+	run, ok := moduleActions[action]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, jobResponse{Error: i18n.T("unsupported module action %q", action)})
 		return
 	}
-
-	name := strings.TrimSpace(req.Name)
 	if name == "" {
-		writeCLIResponse(w, nil, errors.New("module name cannot be empty"))
+		writeJSON(w, http.StatusBadRequest, jobResponse{Error: i18n.T("module name cannot be empty")})
 		return
 	}
-
-	args := []string{"module", action, name}
-	result, err := s.runCLI(r.Context(), args)
-	writeCLIResponse(w, result, err)
-}
-
-func (s *Server) runCLI(ctx context.Context, args []string) (*cliResult, error) {
-	cliPath, err := findCLIPath()
-	if err != nil {
-		return nil, err
+	if _, err := module.FindModuleDir(name); err != nil {
+		writeJSON(w, http.StatusNotFound, jobResponse{Error: err.Error()})
+		return
 	}
 
-	start := time.Now()
-	cmd := exec.CommandContext(ctx, cliPath, args...)
-	cmd.Env = os.Environ()
-
-	var stdoutBuf bytes.Buffer
-	var stderrBuf bytes.Buffer
-	cmd.Stdout = io.MultiWriter(os.Stdout, &stdoutBuf)
-	cmd.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
-	cmd.Stdin = os.Stdin
-
-	err = cmd.Run()
-	exitCode := 0
-	if err != nil {
-		if exitErr := new(exec.ExitError); errors.As(err, &exitErr) {
-			exitCode = exitErr.ExitCode()
-		} else {
-			return nil, err
+	job, err := s.jobs.Submit("module."+action, "module/"+name, func(ctx context.Context, reporter *jobs.Reporter) error {
+		if err := run(ctx, name, s.moduleRunOptions(name, reporter)); err != nil {
+			return err
 		}
+		s.recordModuleAction(name, action, reporter)
+		return nil
+	})
+	if err != nil {
+		writeJSON(w, http.StatusConflict, jobResponse{Error: err.Error()})
+		return
 	}
-
-	return &cliResult{
-		ExitCode:   exitCode,
-		Stdout:     strings.TrimSpace(stdoutBuf.String()),
-		Stderr:     strings.TrimSpace(stderrBuf.String()),
-		DurationMs: time.Since(start).Milliseconds(),
-	}, nil
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, jobResponse{OK: true, Job: &job})
 }
 
-func findCLIPath() (string, error) {
-	if env := strings.TrimSpace(os.Getenv("LAS_CLI_PATH")); env != "" {
-		if fileExists(env) {
-			return env, nil
-		}
-		return "", fmt.Errorf("LAS_CLI_PATH not found: %s", env)
-	}
-
-	if exe, err := os.Executable(); err == nil {
-		dir := filepath.Dir(exe)
-		path := filepath.Join(dir, "las")
-		if fileExists(path) {
-			return path, nil
-		}
-	}
-
-	if cwd, err := os.Getwd(); err == nil {
-		candidates := []string{
-			filepath.Join(cwd, "build", "las"),
-			filepath.Join(cwd, "las"),
-		}
-		for _, candidate := range candidates {
-			if fileExists(candidate) {
-				return candidate, nil
-			}
-		}
-	}
-
-	if path, err := exec.LookPath("las"); err == nil {
-		return path, nil
+func (s *Server) moduleCheckHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.PathValue("name"))
+	if _, err := module.FindModuleDir(name); err != nil {
+		writeJSON(w, http.StatusNotFound, checkResponse{Module: name, Error: err.Error()})
+		return
 	}
-	if path, err := exec.LookPath("localaistack"); err == nil {
-		return path, nil
+	response := checkResponse{OK: true, Module: name, Installed: true}
+	if err := module.Check(name); err != nil {
+		response.Installed = false
+		response.Error = err.Error()
 	}
-
-	return "", errors.New("las CLI not found; build it first with `make build` or set LAS_CLI_PATH")
+	writeJSON(w, http.StatusOK, response)
 }
 
-func fileExists(path string) bool {
-	info, err := os.Stat(path)
-	if err != nil {
-		return false
-	}
-	return !info.IsDir()
+func (s *Server) jobsListHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, jobsResponse{OK: true, Jobs: s.jobs.List()})
 }
 
-func writeCLIResponse(w http.ResponseWriter, result *cliResult, err error) {
-	w.Header().Set("Content-Type", "application/json")
-	response := cliResponse{}
+func (s *Server) jobGetHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := s.jobs.Get(r.PathValue("id"))
 	if err != nil {
-		response.OK = false
-		response.Error = err.Error()
-		if result != nil {
-			response.Output = combineOutput(result)
-			response.Result = result
-		}
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(response)
+		writeJSON(w, http.StatusNotFound, jobResponse{Error: err.Error()})
 		return
 	}
-
-	response.OK = true
-	response.Result = result
-	if result != nil {
-		response.Output = combineOutput(result)
-	}
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, jobResponse{OK: true, Job: &job})
 }
 
-func combineOutput(result *cliResult) string {
-	parts := make([]string, 0, 2)
-	if strings.TrimSpace(result.Stdout) != "" {
-		parts = append(parts, result.Stdout)
+func (s *Server) jobCancelHandler(w http.ResponseWriter, r *http.Request) {
+	if job, err := s.jobs.Get(r.PathValue("id")); err == nil && !s.authorized(r, jobScope(job.Kind)) {
+		writeJSON(w, http.StatusForbidden, jobResponse{Error: i18n.T("token lacks the %q scope", jobScope(job.Kind))})
+		return
 	}
-	if strings.TrimSpace(result.Stderr) != "" {
-		parts = append(parts, result.Stderr)
+	job, err := s.jobs.Cancel(r.PathValue("id"))
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, jobResponse{Error: err.Error()})
+	case errors.Is(err, jobs.ErrFinished):
+		writeJSON(w, http.StatusConflict, jobResponse{Error: err.Error(), Job: &job})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, jobResponse{Error: err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, jobResponse{OK: true, Job: &job})
 	}
-	return strings.Join(parts, "\n")
 }
 
 func listModules() ([]moduleInfo, error) {
@@ -265,17 +177,15 @@ func listModules() ([]moduleInfo, error) {
 }
 
 func writeModulesResponse(w http.ResponseWriter, modules []moduleInfo, err error) {
-	w.Header().Set("Content-Type", "application/json")
-	response := modulesResponse{}
 	if err != nil {
-		response.OK = false
-		response.Error = err.Error()
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(response)
+		writeJSON(w, http.StatusBadRequest, modulesResponse{Error: err.Error()})
 		return
 	}
-	response.OK = true
-	response.Modules = modules
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, modulesResponse{OK: true, Modules: modules})
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
 }
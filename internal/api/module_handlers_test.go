@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/jobs"
+)
+
+const testModuleManifest = `name: demo
+category: tool
+version: 0.1.0
+description: Demo module
+runtime:
+  modes:
+    - native
+`
+
+const testModuleInstallPlan = `apiVersion: las.installspec/v0.1.2
+kind: InstallPlan
+id: demo
+install_modes:
+  - native
+decision_matrix:
+  default: native
+install:
+  native:
+    - id: S10
+      intent: Prepare
+      tool: shell
+      command: echo preparing
+      expected:
+        exit_code: 0
+    - id: S20
+      intent: Install
+      tool: shell
+      command: bash scripts/install.sh
+      expected:
+        exit_code: 0
+verification:
+  script: scripts/verify.sh
+uninstall:
+  script: scripts/uninstall.sh
+`
+
+func setupTestModule(t *testing.T, installScript string) {
+	t.Helper()
+	root := t.TempDir()
+	moduleDir := filepath.Join(root, "modules", "demo")
+	if err := os.MkdirAll(filepath.Join(moduleDir, "scripts"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := map[string]string{
+		"manifest.yaml":        testModuleManifest,
+		"INSTALL.yaml":         testModuleInstallPlan,
+		"scripts/install.sh":   installScript,
+		"scripts/verify.sh":    "#!/usr/bin/env bash\ntest -f \"$(dirname \"$0\")/../installed\"\n",
+		"scripts/uninstall.sh": "#!/usr/bin/env bash\nrm -f \"$(dirname \"$0\")/../installed\"\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(moduleDir, name), []byte(content), 0o755); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	t.Chdir(root)
+	t.Setenv("HOME", root)
+	t.Setenv("LOCALAISTACK_CONTROL_DATA_DIR", filepath.Join(root, "data"))
+}
+
+func serve(t *testing.T, server *Server, method, path string) (int, jobResponse) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+	var payload jobResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to decode %s %s response %q: %v", method, path, recorder.Body.String(), err)
+	}
+	return recorder.Code, payload
+}
+
+func pollJob(t *testing.T, server *Server, id string) jobs.Job {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		code, payload := serve(t, server, http.MethodGet, "/api/v1/jobs/"+id)
+		if code != http.StatusOK || payload.Job == nil {
+			t.Fatalf("unexpected job response %d %+v", code, payload)
+		}
+		if payload.Job.Done() {
+			return *payload.Job
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return jobs.Job{}
+}
+
+func TestModuleInstallJob_Succeeds(t *testing.T) {
+	setupTestModule(t, "#!/usr/bin/env bash\necho installing\ntouch \"$(dirname \"$0\")/../installed\"\n")
+	server := NewServer(testConfig(), nil)
+
+	code, payload := serve(t, server, http.MethodPost, "/api/v1/modules/demo/install")
+	if code != http.StatusAccepted || payload.Job == nil {
+		t.Fatalf("expected 202 with job, got %d %+v", code, payload)
+	}
+
+	job := pollJob(t, server, payload.Job.ID)
+	if job.Status != jobs.StatusSucceeded {
+		t.Fatalf("expected succeeded job, got %+v", job)
+	}
+	if job.Completed != 2 || job.Total != 2 {
+		t.Fatalf("unexpected progress %d/%d", job.Completed, job.Total)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/modules/demo/check", nil))
+	var check checkResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &check); err != nil {
+		t.Fatalf("failed to decode check response: %v", err)
+	}
+	if !check.Installed {
+		t.Fatalf("expected module to be installed: %+v", check)
+	}
+}
+
+func TestModuleInstallJob_ReportsFailedStep(t *testing.T) {
+	setupTestModule(t, "#!/usr/bin/env bash\necho broken >&2\nexit 3\n")
+	server := NewServer(testConfig(), nil)
+
+	_, payload := serve(t, server, http.MethodPost, "/api/v1/modules/demo/install")
+	job := pollJob(t, server, payload.Job.ID)
+	if job.Status != jobs.StatusFailed {
+		t.Fatalf("expected failed job, got %+v", job)
+	}
+	if job.Error == nil || job.Error.Step != "S20" {
+		t.Fatalf("expected error on step S20, got %+v", job.Error)
+	}
+}
+
+func TestModuleInstallJob_Cancel(t *testing.T) {
+	setupTestModule(t, "#!/usr/bin/env bash\nsleep 30\n")
+	server := NewServer(testConfig(), nil)
+
+	_, payload := serve(t, server, http.MethodPost, "/api/v1/modules/demo/install")
+	code, _ := serve(t, server, http.MethodPost, "/api/v1/modules/demo/uninstall")
+	if code != http.StatusConflict {
+		t.Fatalf("expected 409 for a concurrent action, got %d", code)
+	}
+
+	code, canceled := serve(t, server, http.MethodDelete, "/api/v1/jobs/"+payload.Job.ID)
+	if code != http.StatusOK || canceled.Job == nil || canceled.Job.Status != jobs.StatusCanceled {
+		t.Fatalf("expected canceled job, got %d %+v", code, canceled)
+	}
+}
+
+func TestModuleAction_UnknownModule(t *testing.T) {
+	setupTestModule(t, "#!/usr/bin/env bash\n")
+	server := NewServer(testConfig(), nil)
+
+	if code, _ := serve(t, server, http.MethodPost, "/api/v1/modules/missing/install"); code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", code)
+	}
+	if code, _ := serve(t, server, http.MethodPost, "/api/v1/modules/demo/explode"); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown action, got %d", code)
+	}
+}
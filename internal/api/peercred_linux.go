@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+)
+
+const peerCredentialsSupported = true
+
+// peerUID returns the user ID of the process on the other end of a Unix
+// socket connection.
+func peerUID(conn net.Conn) (uint32, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, i18n.Errorf("not a unix socket connection")
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return 0, err
+	}
+	if credErr != nil {
+		return 0, i18n.Errorf("failed to read peer credentials: %w", credErr)
+	}
+	return cred.Uid, nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package api
+
+import (
+	"net"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+)
+
+const peerCredentialsSupported = false
+
+func peerUID(net.Conn) (uint32, error) {
+	return 0, i18n.Errorf("peer credentials are not supported on this platform")
+}
@@ -2,32 +2,60 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/auth"
 	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
 	"github.com/zhuangbiaowei/LocalAIStack/internal/control"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/events"
 	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/jobs"
 	"github.com/zhuangbiaowei/LocalAIStack/internal/llm"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/modelmanager"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/runtime"
 )
 
 type Server struct {
 	cfg          *config.Config
 	controlLayer *control.ControlLayer
+	jobs         *jobs.Manager
+	events       *events.Bus
+	models       *modelmanager.Manager
+	downloads    *modelmanager.DownloadQueue
+	tokens       *auth.Store
+	metrics      *serverMetrics
+	gateway      *gateway
+	runtime      *runtime.Manager
+	startedAt    time.Time
 	server       *http.Server
+	socket       *http.Server
+	// stopDownloads stops the download queue when the server stops.
+	stopDownloads context.CancelFunc
 }
 
 func NewServer(cfg *config.Config, controlLayer *control.ControlLayer) *Server {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/health", healthHandler)
-	mux.HandleFunc("/api/v1/status", statusHandler)
+
+	tokenPath, err := auth.StorePath(cfg.Control.DataDir)
+	if err != nil {
+		log.Warn().Err(err).Msg(i18n.T("Failed to resolve token store"))
+	}
 
 	server := &Server{
 		cfg:          cfg,
 		controlLayer: controlLayer,
+		jobs:         jobs.NewManager(0),
+		events:       events.NewBus(),
+		models:       modelmanager.NewDefaultManager(),
+		tokens:       auth.NewStore(tokenPath),
+		metrics:      newServerMetrics(),
+		startedAt:    time.Now(),
 		server: &http.Server{
 			Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
 			Handler:      mux,
@@ -36,20 +64,113 @@ func NewServer(cfg *config.Config, controlLayer *control.ControlLayer) *Server {
 		},
 	}
 
-	mux.HandleFunc("/api/v1/providers", server.providersHandler)
+	if quota, err := modelmanager.ParseQuota(cfg.Storage.ModelQuota); err != nil {
+		log.Warn().Err(err).Msg(i18n.T("Ignoring invalid storage.model_quota"))
+	} else {
+		server.models.SetQuota(quota)
+	}
+	sources := modelmanager.ArtifactOptions{IndexURL: cfg.ModelSources.IndexURL, Auth: map[string]modelmanager.ArtifactAuth{}}
+	for host, auth := range cfg.ModelSources.Auth {
+		sources.Auth[host] = modelmanager.ArtifactAuth(auth)
+	}
+	server.models.RegisterProvider(modelmanager.NewHTTPProvider(sources))
+	server.models.RegisterProvider(modelmanager.NewOCIProvider(sources))
+	var bandwidth int64
+	if raw := strings.TrimSpace(cfg.Downloads.BandwidthLimit); raw != "" {
+		if bandwidth, err = modelmanager.ParseSize(raw); err != nil {
+			log.Warn().Err(err).Msg(i18n.T("Ignoring invalid downloads.bandwidth_limit"))
+		}
+	}
+	server.downloads, err = modelmanager.NewDownloadQueue(server.models, modelmanager.QueueOptions{
+		MaxConcurrent:  cfg.Downloads.MaxConcurrent,
+		PerHost:        cfg.Downloads.PerHost,
+		BandwidthLimit: bandwidth,
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg(i18n.T("Failed to load the download queue"))
+	}
+
+	server.jobs.Watch(newJobPublisher(server.events).publish)
+	server.jobs.Watch(server.metrics.observeJob)
+	server.gateway = newGateway(cfg.Gateway.Endpoints, time.Duration(cfg.Gateway.TimeoutSeconds)*time.Second, server.metrics.registry)
+	server.metrics.registry.Collect(func() { server.metrics.collectRuntime(server.runtime) })
+	server.metrics.registry.Collect(server.metrics.collectHardware)
+
+	read := func(handler http.HandlerFunc) http.HandlerFunc { return server.requireScope(auth.ScopeRead, handler) }
+	modules := func(handler http.HandlerFunc) http.HandlerFunc {
+		return server.requireScope(auth.ScopeModules, handler)
+	}
+	models := func(handler http.HandlerFunc) http.HandlerFunc { return server.requireScope(auth.ScopeModels, handler) }
+
+	mux.HandleFunc("/health", healthHandler)
 	mux.HandleFunc("/", server.uiHandler)
-	mux.HandleFunc("/api/v1/modules", server.modulesListHandler)
-	mux.HandleFunc("/api/v1/module/list", server.moduleListHandler)
-	mux.HandleFunc("/api/v1/module/install", server.moduleInstallHandler)
-	mux.HandleFunc("/api/v1/module/uninstall", server.moduleUninstallHandler)
-	mux.HandleFunc("/api/v1/module/check", server.moduleCheckHandler)
+	mux.HandleFunc("GET /metrics", read(server.metricsHandler))
+	mux.HandleFunc("GET /api/v1/status", read(server.statusHandler))
+	mux.HandleFunc("GET /api/v1/hardware", read(server.hardwareHandler))
+	mux.HandleFunc("/api/v1/providers", read(server.providersHandler))
+	mux.HandleFunc("GET /api/v1/modules", read(server.modulesListHandler))
+	mux.HandleFunc("POST /api/v1/modules/{name}/check", modules(server.moduleCheckHandler))
+	mux.HandleFunc("POST /api/v1/modules/{name}/{action}", modules(server.moduleActionHandler))
+	mux.HandleFunc("GET /api/v1/jobs", read(server.jobsListHandler))
+	mux.HandleFunc("GET /api/v1/jobs/{id}", read(server.jobGetHandler))
+	mux.HandleFunc("DELETE /api/v1/jobs/{id}", read(server.jobCancelHandler))
+	mux.HandleFunc("GET /api/v1/events", server.requireScopeOrQuery(auth.ScopeRead, server.eventsHandler))
+	mux.HandleFunc("GET /api/v1/models", read(server.modelsListHandler))
+	mux.HandleFunc("GET /api/v1/models/search", read(server.modelSearchHandler))
+	mux.HandleFunc("POST /api/v1/models/download", models(server.modelDownloadHandler))
+	mux.HandleFunc("GET /api/v1/models/queue", read(server.queueListHandler))
+	mux.HandleFunc("DELETE /api/v1/models/queue", models(server.queueClearHandler))
+	mux.HandleFunc("POST /api/v1/models/queue/{id}/{action}", models(server.queueActionHandler))
+	mux.HandleFunc("GET /api/v1/models/{source}/{id...}", read(server.modelInfoHandler))
+	mux.HandleFunc("DELETE /api/v1/models/{source}/{id...}", models(server.modelDeleteHandler))
+	mux.HandleFunc("POST /api/v1/models/{path...}", models(server.modelRepairHandler))
+	mux.HandleFunc("GET /api/v1/state/history", read(server.stateHistoryHandler))
+	mux.HandleFunc("GET /api/v1/state/diff", read(server.stateDiffHandler))
+	mux.HandleFunc("GET /api/v1/state/rollback/{id}", read(server.rollbackPlanHandler))
+	mux.HandleFunc("POST /api/v1/state/rollback/{id}", modules(server.rollbackHandler))
+	mux.HandleFunc("POST /api/v1/state/reconcile", modules(server.reconcileHandler))
+	mux.HandleFunc("GET /api/v1/services", read(server.servicesListHandler))
+	mux.HandleFunc("POST /api/v1/services", modules(server.serviceStartHandler))
+	mux.HandleFunc("GET /api/v1/services/{name}/logs", read(server.serviceLogsHandler))
+	mux.HandleFunc("POST /api/v1/services/{name}/{action}", modules(server.serviceActionHandler))
+	mux.HandleFunc("GET /api/v1/chat/models", read(server.chatModelsHandler))
+	mux.HandleFunc("POST /api/v1/chat/completions", models(server.chatCompletionsHandler))
 
 	return server
 }
 
+// Start serves the API, over TLS when server.enable_tls is set, and on the
+// control socket in the data dir.
 func (s *Server) Start() error {
-	log.Info().Str("addr", s.server.Addr).Msg(i18n.T("Starting API server"))
-	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if err := s.serveSocket(); err != nil {
+		return err
+	}
+	go s.reconcileState(context.Background())
+	s.startDownloads()
+
+	if s.cfg.Server.AuthEnabled {
+		if tokens, err := s.tokens.List(); err == nil && len(tokens) == 0 {
+			log.Warn().Str("path", s.tokens.Path()).Msg(i18n.T("API authentication is enabled but no tokens exist; create one with `las token create`"))
+		}
+	} else {
+		log.Warn().Msg(i18n.T("API authentication is disabled"))
+	}
+
+	var err error
+	if s.cfg.Server.EnableTLS {
+		certFile := strings.TrimSpace(s.cfg.Server.TLSCertFile)
+		keyFile := strings.TrimSpace(s.cfg.Server.TLSKeyFile)
+		if certFile == "" || keyFile == "" {
+			return i18n.Errorf("server.enable_tls requires server.tls_cert_file and server.tls_key_file")
+		}
+		s.server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		log.Info().Str("addr", s.server.Addr).Msg(i18n.T("Starting API server with TLS"))
+		err = s.server.ListenAndServeTLS(certFile, keyFile)
+	} else {
+		log.Info().Str("addr", s.server.Addr).Msg(i18n.T("Starting API server"))
+		err = s.server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		return err
 	}
 	return nil
@@ -59,6 +180,14 @@ func (s *Server) Stop() error {
 	log.Info().Msg(i18n.T("Stopping API server"))
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	if s.stopDownloads != nil {
+		s.stopDownloads()
+	}
+	if s.socket != nil {
+		if err := s.socket.Shutdown(ctx); err != nil {
+			log.Warn().Err(err).Msg(i18n.T("Error stopping control socket"))
+		}
+	}
 	return s.server.Shutdown(ctx)
 }
 
@@ -67,12 +196,6 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-func statusHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(fmt.Sprintf(`{"status":"running","version":"%s"}`, config.Version)))
-}
-
 type providersResponse struct {
 	Default   string   `json:"default"`
 	Providers []string `json:"providers"`
@@ -9,13 +9,21 @@ import (
 	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
 )
 
+// testConfig returns the default configuration with API authentication
+// disabled; auth_test.go covers authentication.
+func testConfig() *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.Server.AuthEnabled = false
+	return cfg
+}
+
 type providersPayload struct {
 	Default   string   `json:"default"`
 	Providers []string `json:"providers"`
 }
 
 func TestProvidersHandler(t *testing.T) {
-	cfg := config.DefaultConfig()
+	cfg := testConfig()
 	cfg.LLM.Provider = "eino"
 
 	server := NewServer(cfg, nil)
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/client"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/runtime"
+)
+
+const (
+	serviceKindRuntime = "runtime"
+	serviceKindSystemd = "systemd"
+
+	serviceActionTimeout = 60 * time.Second
+	defaultLogLines      = 200
+	maxLogLines          = 5000
+)
+
+// serviceInfo describes either a process supervised by the runtime manager
+// or the systemd unit of an installed module.
+type serviceInfo struct {
+	Name      string     `json:"name"`
+	Kind      string     `json:"kind"`
+	Unit      string     `json:"unit,omitempty"`
+	State     string     `json:"state"`
+	Health    string     `json:"health"`
+	PID       int        `json:"pid,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Restarts  int        `json:"restarts"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+type servicesResponse struct {
+	OK       bool          `json:"ok"`
+	Error    string        `json:"error,omitempty"`
+	Services []serviceInfo `json:"services"`
+}
+
+type serviceActionResponse struct {
+	OK      bool         `json:"ok"`
+	Error   string       `json:"error,omitempty"`
+	Service *serviceInfo `json:"service,omitempty"`
+}
+
+type serviceLogsResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	Name  string `json:"name"`
+	Logs  string `json:"logs"`
+}
+
+func runtimeServiceInfo(status runtime.Status) serviceInfo {
+	info := serviceInfo{
+		Name:      status.Name,
+		Kind:      serviceKindRuntime,
+		State:     string(status.State),
+		Health:    string(status.Health),
+		PID:       status.PID,
+		Restarts:  status.Restarts,
+		LastError: status.LastError,
+	}
+	if !status.StartedAt.IsZero() {
+		startedAt := status.StartedAt.UTC()
+		info.StartedAt = &startedAt
+	}
+	return info
+}
+
+func systemdServiceInfo(status module.ServiceStatus) serviceInfo {
+	health := string(runtime.HealthUnhealthy)
+	if status.Healthy {
+		health = string(runtime.HealthHealthy)
+	}
+	return serviceInfo{
+		Name:   status.Module,
+		Kind:   serviceKindSystemd,
+		Unit:   status.Unit,
+		State:  status.ActiveState,
+		Health: health,
+	}
+}
+
+// servicesListHandler lists runtime processes and the systemd services of
+// modules whose units are loaded.
+func (s *Server) servicesListHandler(w http.ResponseWriter, r *http.Request) {
+	services := []serviceInfo{}
+	seen := map[string]bool{}
+	if s.runtime != nil {
+		for _, status := range s.runtime.List() {
+			services = append(services, runtimeServiceInfo(status))
+			seen[status.Name] = true
+		}
+	}
+
+	if modulesRoot, err := module.FindModulesRoot(); err == nil {
+		if registry, err := module.LoadRegistryFromDir(modulesRoot); err == nil {
+			for name := range registry.All() {
+				if seen[name] {
+					continue
+				}
+				status, err := module.GetServiceStatus(r.Context(), name)
+				if err != nil || status.LoadState == "" || status.LoadState == "not-found" {
+					continue
+				}
+				services = append(services, systemdServiceInfo(status))
+			}
+		}
+	}
+
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	writeJSON(w, http.StatusOK, servicesResponse{OK: true, Services: services})
+}
+
+// serviceStartHandler starts a native process under the runtime manager, so
+// the server supervises processes launched by the CLI.
+func (s *Server) serviceStartHandler(w http.ResponseWriter, r *http.Request) {
+	if s.runtime == nil {
+		writeJSON(w, http.StatusServiceUnavailable, serviceActionResponse{Error: i18n.T("runtime manager is not available")})
+		return
+	}
+	var spec client.ProcessSpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		writeJSON(w, http.StatusBadRequest, serviceActionResponse{Error: i18n.T("invalid request body: %v", err)})
+		return
+	}
+	spec.Name = strings.TrimSpace(spec.Name)
+	if spec.Name == "" || len(spec.Command) == 0 {
+		writeJSON(w, http.StatusBadRequest, serviceActionResponse{Error: i18n.T("name and command are required")})
+		return
+	}
+	if status, ok := s.runtime.Status(spec.Name); ok && status.State == runtime.StateRunning {
+		writeJSON(w, http.StatusConflict, serviceActionResponse{Error: i18n.T("service %q is already running", spec.Name)})
+		return
+	}
+
+	status, err := s.runtime.Start(r.Context(), runtime.ModuleSpec{
+		Name:        spec.Name,
+		Mode:        runtime.ModeNative,
+		Command:     spec.Command,
+		Env:         spec.Env,
+		WorkDir:     spec.WorkDir,
+		HealthCheck: runtime.HealthCheck{Command: spec.HealthCommand},
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, serviceActionResponse{Error: err.Error()})
+		return
+	}
+	info := runtimeServiceInfo(*status)
+	writeJSON(w, http.StatusCreated, serviceActionResponse{OK: true, Service: &info})
+}
+
+// serviceActionHandler starts, stops or restarts a service and responds with
+// its state afterwards.
+func (s *Server) serviceActionHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.PathValue("name"))
+	action := r.PathValue("action")
+	switch action {
+	case "start", "stop", "restart":
+	default:
+		writeJSON(w, http.StatusNotFound, serviceActionResponse{Error: i18n.T("unsupported service action %q", action)})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), serviceActionTimeout)
+	defer cancel()
+
+	if s.runtime != nil {
+		if status, ok := s.runtime.Status(name); ok {
+			var err error
+			switch {
+			case action == "stop":
+				err = s.runtime.Stop(ctx, name)
+			case action == "start" && status.State == runtime.StateRunning:
+			default:
+				_, err = s.runtime.Restart(ctx, name)
+			}
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, serviceActionResponse{Error: err.Error()})
+				return
+			}
+			status, _ = s.runtime.Status(name)
+			info := runtimeServiceInfo(status)
+			writeJSON(w, http.StatusOK, serviceActionResponse{OK: true, Service: &info})
+			return
+		}
+	}
+
+	unit, err := module.ServiceUnit(name)
+	if err != nil || unit == "" {
+		writeJSON(w, http.StatusNotFound, serviceActionResponse{Error: i18n.T("service %q not found", name)})
+		return
+	}
+	if err := module.ControlService(ctx, name, action); err != nil {
+		writeJSON(w, http.StatusInternalServerError, serviceActionResponse{Error: err.Error()})
+		return
+	}
+	response := serviceActionResponse{OK: true}
+	if status, err := module.GetServiceStatus(ctx, name); err == nil {
+		info := systemdServiceInfo(status)
+		response.Service = &info
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// serviceLogsHandler returns the last ?lines= lines of a service's output:
+// the runtime log file, or the journal of a systemd unit.
+func (s *Server) serviceLogsHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.PathValue("name"))
+	lines := defaultLogLines
+	if value := r.URL.Query().Get("lines"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, serviceLogsResponse{Name: name, Error: i18n.T("lines must be a positive integer")})
+			return
+		}
+		lines = min(parsed, maxLogLines)
+	}
+
+	if s.runtime != nil {
+		if status, ok := s.runtime.Status(name); ok {
+			logs, err := tailFile(status.LogPath, lines)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, serviceLogsResponse{Name: name, Error: err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, serviceLogsResponse{OK: true, Name: name, Logs: logs})
+			return
+		}
+	}
+
+	if unit, err := module.ServiceUnit(name); err != nil || unit == "" {
+		writeJSON(w, http.StatusNotFound, serviceLogsResponse{Name: name, Error: i18n.T("service %q not found", name)})
+		return
+	}
+	logs, err := module.ServiceLogs(r.Context(), name, lines)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, serviceLogsResponse{Name: name, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, serviceLogsResponse{OK: true, Name: name, Logs: logs})
+}
+
+// tailFile returns the last n lines of path, reading at most the final
+// 1 MiB of the file.
+func tailFile(path string, n int) (string, error) {
+	const window = 1 << 20
+	file, err := os.Open(path)
+	if err != nil {
+		return "", i18n.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", i18n.Errorf("failed to stat log file: %w", err)
+	}
+	offset := max(info.Size()-window, 0)
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return "", i18n.Errorf("failed to read log file: %w", err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", i18n.Errorf("failed to read log file: %w", err)
+	}
+	content := strings.TrimRight(string(data), "\n")
+	lines := strings.Split(content, "\n")
+	if offset > 0 && len(lines) > 1 {
+		// The first line is most likely cut in the middle.
+		lines = lines[1:]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/runtime"
+)
+
+func TestServiceHandlers_RuntimeProcess(t *testing.T) {
+	setupTestModule(t, "#!/usr/bin/env bash\nexit 0\n")
+	server := NewServer(testConfig(), nil)
+	manager := runtime.NewManager(config.RuntimeConfig{NativeEnabled: true, DefaultMode: "native", LogDir: t.TempDir()})
+	server.AttachRuntime(manager)
+	if _, err := manager.Start(context.Background(), runtime.ModuleSpec{
+		Name:    "echo",
+		Command: []string{"sh", "-c", "echo service-started; sleep 30"},
+	}); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Stop(context.Background(), "echo") })
+
+	request := func(method, path string, payload any) int {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		if err := json.Unmarshal(recorder.Body.Bytes(), payload); err != nil {
+			t.Fatalf("failed to decode %s %s response %q: %v", method, path, recorder.Body.String(), err)
+		}
+		return recorder.Code
+	}
+
+	var list servicesResponse
+	if code := request(http.MethodGet, "/api/v1/services", &list); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(list.Services) != 1 || list.Services[0].Name != "echo" || list.Services[0].Kind != serviceKindRuntime {
+		t.Fatalf("unexpected services %+v", list.Services)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var logs serviceLogsResponse
+	for {
+		if code := request(http.MethodGet, "/api/v1/services/echo/logs?lines=10", &logs); code != http.StatusOK {
+			t.Fatalf("expected 200 for logs, got %d: %s", code, logs.Error)
+		}
+		if strings.Contains(logs.Logs, "service-started") || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !strings.Contains(logs.Logs, "service-started") {
+		t.Fatalf("expected process output in logs, got %q", logs.Logs)
+	}
+
+	var restarted serviceActionResponse
+	if code := request(http.MethodPost, "/api/v1/services/echo/restart", &restarted); code != http.StatusOK {
+		t.Fatalf("expected 200 for restart, got %d: %s", code, restarted.Error)
+	}
+	if restarted.Service == nil || restarted.Service.Restarts != 1 || restarted.Service.State != string(runtime.StateRunning) {
+		t.Fatalf("unexpected service after restart %+v", restarted.Service)
+	}
+
+	var stopped serviceActionResponse
+	if code := request(http.MethodPost, "/api/v1/services/echo/stop", &stopped); code != http.StatusOK {
+		t.Fatalf("expected 200 for stop, got %d: %s", code, stopped.Error)
+	}
+
+	var missing serviceActionResponse
+	if code := request(http.MethodPost, "/api/v1/services/missing/start", &missing); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown service, got %d", code)
+	}
+	if code := request(http.MethodPost, "/api/v1/services/echo/reload", &missing); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unsupported action, got %d", code)
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/client"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+)
+
+type peerContextKey struct{}
+
+// peerListener accepts only connections from processes running as the
+// server's user or as root.
+type peerListener struct {
+	net.Listener
+	uid uint32
+}
+
+func (l *peerListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		uid, err := peerUID(conn)
+		if err == nil && (uid == l.uid || uid == 0) {
+			return &peerConn{Conn: conn, uid: uid}, nil
+		}
+		log.Warn().Err(err).Uint32("uid", uid).Msg(i18n.T("Rejected control socket connection"))
+		conn.Close()
+	}
+}
+
+type peerConn struct {
+	net.Conn
+	uid uint32
+}
+
+// listenSocket opens the control socket in the data dir, replacing a stale
+// socket file left by a server that did not shut down cleanly.
+func (s *Server) listenSocket() (net.Listener, error) {
+	path, err := client.SocketPath(s.cfg.Control.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, i18n.Errorf("failed to create socket directory: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		if _, err := client.Connect(path); err == nil {
+			return nil, i18n.Errorf("another server is already listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, i18n.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, i18n.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, i18n.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	return &peerListener{Listener: listener, uid: uint32(os.Geteuid())}, nil
+}
+
+// serveSocket serves the API on the control socket. Requests arriving on it
+// are authenticated by the peer's credentials instead of a bearer token.
+func (s *Server) serveSocket() error {
+	if !peerCredentialsSupported {
+		log.Info().Msg(i18n.T("Control socket is not supported on this platform"))
+		return nil
+	}
+	listener, err := s.listenSocket()
+	if err != nil {
+		return err
+	}
+	s.socket = &http.Server{
+		Handler:     s.server.Handler,
+		ReadTimeout: s.server.ReadTimeout,
+		ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+			if peer, ok := conn.(*peerConn); ok {
+				return context.WithValue(ctx, peerContextKey{}, peer.uid)
+			}
+			return ctx
+		},
+	}
+	log.Info().Str("path", listener.Addr().String()).Msg(i18n.T("Listening on control socket"))
+	go func() {
+		if err := s.socket.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg(i18n.T("Control socket error"))
+		}
+	}()
+	return nil
+}
+
+// fromSocket reports whether the request came through the control socket.
+func fromSocket(r *http.Request) bool {
+	_, ok := r.Context().Value(peerContextKey{}).(uint32)
+	return ok
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/client"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/runtime"
+)
+
+func TestControlSocket(t *testing.T) {
+	if !peerCredentialsSupported {
+		t.Skip("control socket requires peer credentials")
+	}
+	cfg := testConfig()
+	cfg.Server.AuthEnabled = true
+	cfg.Control.DataDir = t.TempDir()
+	server := NewServer(cfg, nil)
+	manager := runtime.NewManager(config.RuntimeConfig{NativeEnabled: true, DefaultMode: "native", LogDir: t.TempDir()})
+	server.AttachRuntime(manager)
+	if err := server.serveSocket(); err != nil {
+		t.Fatalf("serveSocket returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = server.socket.Close() })
+
+	socketPath, err := client.SocketPath(cfg.Control.DataDir)
+	if err != nil {
+		t.Fatalf("SocketPath returned error: %v", err)
+	}
+	c, err := client.Connect(socketPath)
+	if err != nil {
+		t.Fatalf("Connect returned error: %v", err)
+	}
+
+	// Socket peers skip bearer authentication; TCP clients do not.
+	recorder := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/services", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", recorder.Code)
+	}
+
+	service, err := c.StartProcess(context.Background(), client.ProcessSpec{Name: "sleeper", Command: []string{"sleep", "30"}})
+	if err != nil {
+		t.Fatalf("StartProcess returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Stop(context.Background(), "sleeper") })
+	if service.Name != "sleeper" || service.State != string(runtime.StateRunning) || service.PID == 0 {
+		t.Fatalf("unexpected service %+v", service)
+	}
+	if _, err := c.StartProcess(context.Background(), client.ProcessSpec{Name: "sleeper", Command: []string{"sleep", "30"}}); err == nil {
+		t.Fatalf("expected starting a running service to fail")
+	}
+
+	services, err := c.Services(context.Background())
+	if err != nil || len(services) != 1 || services[0].Name != "sleeper" {
+		t.Fatalf("unexpected services %+v (err %v)", services, err)
+	}
+	stopped, err := c.ServiceAction(context.Background(), "sleeper", "stop")
+	if err != nil || stopped.State != string(runtime.StateStopped) {
+		t.Fatalf("unexpected stop result %+v (err %v)", stopped, err)
+	}
+
+	if err := server.serveSocket(); err == nil {
+		t.Fatalf("expected a second listener on the same socket to fail")
+	}
+}
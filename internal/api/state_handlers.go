@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/control"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/jobs"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/runtime"
+)
+
+type stateHistoryResponse struct {
+	OK      bool                    `json:"ok"`
+	Error   string                  `json:"error,omitempty"`
+	History []control.StateSnapshot `json:"history"`
+}
+
+type stateDiffResponse struct {
+	OK      bool                   `json:"ok"`
+	Error   string                 `json:"error,omitempty"`
+	From    string                 `json:"from"`
+	To      string                 `json:"to"`
+	Changes []control.ModuleChange `json:"changes"`
+}
+
+type reconcileResponse struct {
+	OK          bool                      `json:"ok"`
+	Error       string                    `json:"error,omitempty"`
+	DryRun      bool                      `json:"dry_run"`
+	Corrections []control.StateCorrection `json:"corrections"`
+}
+
+type rollbackPlanResponse struct {
+	OK    bool                  `json:"ok"`
+	Error string                `json:"error,omitempty"`
+	Plan  *control.RollbackPlan `json:"plan,omitempty"`
+}
+
+func (s *Server) stateManager() *control.StateManager {
+	if s.controlLayer == nil {
+		return nil
+	}
+	return s.controlLayer.StateManager()
+}
+
+// recordModuleAction stores the outcome of a module action in the state
+// history, logging rather than failing the action when it cannot.
+func (s *Server) recordModuleAction(name, action string, reporter *jobs.Reporter) {
+	manager := s.stateManager()
+	if manager == nil {
+		return
+	}
+	if err := manager.RecordModuleAction(name, action); err != nil {
+		_, _ = reporter.Write([]byte(i18n.T("failed to record module state: %v", err) + "\n"))
+	}
+}
+
+func (s *Server) stateHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	manager := s.stateManager()
+	if manager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, stateHistoryResponse{Error: i18n.T("state manager is not available")})
+		return
+	}
+	writeJSON(w, http.StatusOK, stateHistoryResponse{OK: true, History: manager.History()})
+}
+
+// stateDiffHandler compares ?from= and ?to=, each a snapshot ID or
+// "current" (the default for to).
+func (s *Server) stateDiffHandler(w http.ResponseWriter, r *http.Request) {
+	manager := s.stateManager()
+	if manager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, stateDiffResponse{Error: i18n.T("state manager is not available")})
+		return
+	}
+	from := strings.TrimSpace(r.URL.Query().Get("from"))
+	to := strings.TrimSpace(r.URL.Query().Get("to"))
+	if to == "" {
+		to = control.CurrentStateRef
+	}
+	if from == "" {
+		writeJSON(w, http.StatusBadRequest, stateDiffResponse{Error: i18n.T("query parameter from is required")})
+		return
+	}
+	changes, err := manager.Diff(from, to)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, stateDiffResponse{From: from, To: to, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, stateDiffResponse{OK: true, From: from, To: to, Changes: changes})
+}
+
+func (s *Server) rollbackPlanHandler(w http.ResponseWriter, r *http.Request) {
+	manager := s.stateManager()
+	if manager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, rollbackPlanResponse{Error: i18n.T("state manager is not available")})
+		return
+	}
+	plan, err := manager.PlanRollback(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, rollbackPlanResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, rollbackPlanResponse{OK: true, Plan: &plan})
+}
+
+// rollbackHandler converges the host to a snapshot as a background job,
+// running one step per module change.
+func (s *Server) rollbackHandler(w http.ResponseWriter, r *http.Request) {
+	manager := s.stateManager()
+	if manager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, jobResponse{Error: i18n.T("state manager is not available")})
+		return
+	}
+	id := r.PathValue("id")
+	plan, err := manager.PlanRollback(id)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, jobResponse{Error: err.Error()})
+		return
+	}
+
+	job, err := s.jobs.Submit("state.rollback", "state/"+id, func(ctx context.Context, reporter *jobs.Reporter) error {
+		run := func(ctx context.Context, name, action string) error {
+			return moduleActions[action](ctx, name, module.RunOptions{Output: reporter})
+		}
+		return manager.ExecuteRollback(ctx, plan, run, func(change control.ModuleChange, err error, done bool) {
+			status, message := module.StepRunning, ""
+			if done {
+				status = module.StepSucceeded
+				if err != nil {
+					status, message = module.StepFailed, err.Error()
+				}
+			}
+			reporter.Step(change.Module, string(change.Action)+" "+change.Module, status, message, len(plan.Changes))
+		})
+	})
+	if err != nil {
+		writeJSON(w, http.StatusConflict, jobResponse{Error: err.Error()})
+		return
+	}
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, jobResponse{OK: true, Job: &job})
+}
+
+// processState reports whether the runtime manager supervises a running
+// process with the given name.
+func (s *Server) processState(name string) (running, known bool) {
+	if s.runtime == nil {
+		return false, false
+	}
+	status, ok := s.runtime.Status(name)
+	if !ok {
+		return false, false
+	}
+	return status.State == runtime.StateRunning, true
+}
+
+// reconcileState corrects drift between the state file and the host when
+// the server starts.
+func (s *Server) reconcileState(ctx context.Context) {
+	manager := s.stateManager()
+	if manager == nil {
+		return
+	}
+	corrections, err := manager.Reconcile(ctx, control.ReconcileOptions{Process: s.processState})
+	if err != nil {
+		log.Warn().Err(err).Msg(i18n.T("State reconciliation failed"))
+		return
+	}
+	for _, correction := range corrections {
+		log.Info().
+			Str("module", correction.ModuleName).
+			Str("previous", string(correction.Previous)).
+			Str("corrected", string(correction.Corrected)).
+			Str("reason", correction.Reason).
+			Msg(i18n.T("Corrected module state"))
+	}
+}
+
+// reconcileHandler probes the host and corrects the recorded module state;
+// with ?dry_run=true it only reports the corrections.
+func (s *Server) reconcileHandler(w http.ResponseWriter, r *http.Request) {
+	manager := s.stateManager()
+	if manager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, reconcileResponse{Error: i18n.T("state manager is not available")})
+		return
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	corrections, err := manager.Reconcile(r.Context(), control.ReconcileOptions{Process: s.processState, DryRun: dryRun})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, reconcileResponse{DryRun: dryRun, Error: err.Error()})
+		return
+	}
+	if corrections == nil {
+		corrections = []control.StateCorrection{}
+	}
+	writeJSON(w, http.StatusOK, reconcileResponse{OK: true, DryRun: dryRun, Corrections: corrections})
+}
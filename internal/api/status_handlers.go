@@ -0,0 +1,158 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/control"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/modelmanager"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/runtime"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/system/info"
+	"github.com/zhuangbiaowei/LocalAIStack/pkg/hardware"
+)
+
+type statusResponse struct {
+	OK            bool                        `json:"ok"`
+	Error         string                      `json:"error,omitempty"`
+	Status        string                      `json:"status"`
+	Version       string                      `json:"version"`
+	StartedAt     time.Time                   `json:"started_at"`
+	UptimeSeconds int64                       `json:"uptime_seconds"`
+	Hardware      *hardware.NormalizedProfile `json:"hardware,omitempty"`
+	Capabilities  *control.CapabilitySet      `json:"capabilities,omitempty"`
+	Modules       []installedModule           `json:"modules"`
+	Services      []runtime.Status            `json:"services"`
+	Models        modelStorage                `json:"models"`
+}
+
+// installedModule is an installed module with the version and lifecycle
+// state recorded by the state manager, falling back to its manifest.
+type installedModule struct {
+	Name      string       `json:"name"`
+	Category  string       `json:"category,omitempty"`
+	Version   string       `json:"version"`
+	State     module.State `json:"state"`
+	UpdatedAt *time.Time   `json:"updated_at,omitempty"`
+}
+
+type modelStorage struct {
+	Dir       string `json:"dir"`
+	Count     int    `json:"count"`
+	UsedBytes int64  `json:"used_bytes"`
+	FreeBytes uint64 `json:"free_bytes"`
+}
+
+type hardwareResponse struct {
+	OK           bool                        `json:"ok"`
+	Error        string                      `json:"error,omitempty"`
+	Profile      *hardware.HardwareProfile   `json:"profile,omitempty"`
+	Normalized   *hardware.NormalizedProfile `json:"normalized,omitempty"`
+	Capabilities *control.CapabilitySet      `json:"capabilities,omitempty"`
+	Utilization  info.Utilization            `json:"utilization"`
+}
+
+// statusHandler summarizes the server: detected hardware, policy decisions,
+// installed modules, running services and model storage.
+func (s *Server) statusHandler(w http.ResponseWriter, r *http.Request) {
+	response := statusResponse{
+		OK:            true,
+		Status:        "running",
+		Version:       config.Version,
+		StartedAt:     s.startedAt.UTC(),
+		UptimeSeconds: int64(time.Since(s.startedAt).Seconds()),
+		Modules:       s.installedModules(),
+		Services:      []runtime.Status{},
+		Models:        s.modelStorage(),
+	}
+	if s.controlLayer != nil {
+		if profile := s.controlLayer.Profile(); profile != nil {
+			normalized := hardware.NormalizeProfile(profile)
+			response.Hardware = &normalized
+		}
+		response.Capabilities = s.controlLayer.Capabilities()
+	}
+	if s.runtime != nil {
+		response.Services = s.runtime.List()
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// hardwareHandler returns the hardware profile and live utilization. Pass
+// ?refresh=1 to detect hardware and evaluate policies again.
+func (s *Server) hardwareHandler(w http.ResponseWriter, r *http.Request) {
+	response := hardwareResponse{OK: true, Utilization: s.metrics.hardwareUsage()}
+	if s.controlLayer != nil {
+		if r.URL.Query().Get("refresh") != "" {
+			if err := s.controlLayer.RefreshHardware(r.Context()); err != nil {
+				response.OK = false
+				response.Error = err.Error()
+			}
+		}
+		response.Profile = s.controlLayer.Profile()
+		if response.Profile != nil {
+			normalized := hardware.NormalizeProfile(response.Profile)
+			response.Normalized = &normalized
+		}
+		response.Capabilities = s.controlLayer.Capabilities()
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// installedModules lists modules whose verification passes, using the state
+// manager's record for version and state when one exists.
+func (s *Server) installedModules() []installedModule {
+	var recorded map[string]control.ModuleState
+	if s.controlLayer != nil {
+		if state, ok := s.controlLayer.State(); ok {
+			recorded = state.Modules
+		}
+	}
+	available, err := listModules()
+	if err != nil {
+		available = nil
+	}
+	installed := []installedModule{}
+	for _, candidate := range available {
+		if candidate.Status != "installed" {
+			continue
+		}
+		entry := installedModule{
+			Name:     candidate.Name,
+			Category: candidate.Category,
+			Version:  candidate.Version,
+			State:    module.StateInstalled,
+		}
+		if record, ok := recorded[candidate.Name]; ok {
+			if record.Version != "" {
+				entry.Version = record.Version
+			}
+			if record.State != "" {
+				entry.State = record.State
+			}
+			updatedAt := record.UpdatedAt
+			entry.UpdatedAt = &updatedAt
+		}
+		installed = append(installed, entry)
+	}
+	return installed
+}
+
+func (s *Server) modelStorage() modelStorage {
+	storage := modelStorage{Dir: s.models.GetModelDir()}
+	if downloaded, err := s.models.ListDownloadedModels(); err == nil {
+		storage.Count = len(downloaded)
+	}
+	if _, err := os.Stat(storage.Dir); err != nil {
+		return storage
+	}
+	storage.UsedBytes, _ = modelmanager.PathSize(storage.Dir)
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(storage.Dir, &stat); err == nil {
+		storage.FreeBytes = stat.Bavail * uint64(stat.Bsize)
+	}
+	return storage
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/control"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/system/info"
+)
+
+func TestStatusAndHardwareHandlers(t *testing.T) {
+	policyFile, err := filepath.Abs(filepath.Join("..", "..", "configs", "policies.yaml"))
+	if err != nil {
+		t.Fatalf("failed to resolve policy file: %v", err)
+	}
+	setupTestModule(t, "#!/usr/bin/env bash\ntouch \"$(dirname \"$0\")/../installed\"\n")
+
+	cfg := testConfig()
+	cfg.Control.PolicyFile = policyFile
+	cfg.Control.DataDir = t.TempDir()
+	controlLayer, err := control.New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("control.New returned error: %v", err)
+	}
+	if err := controlLayer.Start(context.Background()); err != nil {
+		t.Fatalf("control layer failed to start: %v", err)
+	}
+	server := NewServer(cfg, controlLayer)
+	server.metrics.utilization = func(context.Context) info.Utilization {
+		return info.Utilization{MemoryTotalBytes: 1 << 30}
+	}
+
+	_, installed := serve(t, server, http.MethodPost, "/api/v1/modules/demo/install")
+	pollJob(t, server, installed.Job.ID)
+
+	recorder := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/status", nil))
+	var status statusResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	if !status.OK || status.Hardware == nil || status.Capabilities == nil || len(status.Capabilities.MatchedPolicies) == 0 {
+		t.Fatalf("expected hardware and capabilities in status, got %+v", status)
+	}
+	if len(status.Modules) != 1 || status.Modules[0].Name != "demo" || status.Modules[0].Version != "0.1.0" || status.Modules[0].State != module.StateInstalled {
+		t.Fatalf("unexpected installed modules %+v", status.Modules)
+	}
+	if status.Models.Dir == "" {
+		t.Fatalf("expected model storage in status, got %+v", status.Models)
+	}
+
+	recorder = httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/hardware?refresh=1", nil))
+	var hw hardwareResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &hw); err != nil {
+		t.Fatalf("failed to decode hardware: %v", err)
+	}
+	if !hw.OK || hw.Profile == nil || hw.Normalized == nil || hw.Utilization.MemoryTotalBytes != 1<<30 {
+		t.Fatalf("unexpected hardware response %+v", hw)
+	}
+}
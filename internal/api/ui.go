@@ -33,6 +33,56 @@ type uiData struct {
 	RunningLabel       string
 	ErrorPrefix        string
 	OKLabel            string
+	TokenPrompt        string
+
+	ModelsTab         string
+	ServicesTab       string
+	SystemTab         string
+	ChatTab           string
+	LocalModelsTitle  string
+	SearchModelsTitle string
+	SearchPlaceholder string
+	SearchButton      string
+	AllSources        string
+	SourceLabel       string
+	FormatLabel       string
+	SizeLabel         string
+	DownloadButton    string
+	DeleteButton      string
+	DeleteConfirm     string
+	NoModels          string
+	NoResults         string
+	KindLabel         string
+	HealthLabel       string
+	RestartsLabel     string
+	StartButton       string
+	StopButton        string
+	RestartButton     string
+	LogsButton        string
+	LogsTitle         string
+	NoServices        string
+	HardwareTitle     string
+	CapabilitiesTitle string
+	StorageTitle      string
+	CPULabel          string
+	MemoryLabel       string
+	GPULabel          string
+	UtilizationLabel  string
+	PoliciesLabel     string
+	MaxModelSizeLabel string
+	RuntimesLabel     string
+	FeaturesLabel     string
+	DeniedLabel       string
+	ModelCountLabel   string
+	UsedLabel         string
+	FreeLabel         string
+	UptimeLabel       string
+	NoneLabel         string
+	ModelLabel        string
+	ChatPlaceholder   string
+	SendButton        string
+	ClearButton       string
+	NoChatModels      string
 }
 
 func (s *Server) uiHandler(w http.ResponseWriter, r *http.Request) {
@@ -48,8 +98,8 @@ func (s *Server) uiHandler(w http.ResponseWriter, r *http.Request) {
 
 	data := uiData{
 		Lang:               lang,
-		Title:              stripQuotes(i18n.T("LocalAIStack - Module Manager")),
-		Heading:            stripQuotes(i18n.T("LocalAIStack Module Manager")),
+		Title:              stripQuotes(i18n.T("LocalAIStack - Dashboard")),
+		Heading:            stripQuotes(i18n.T("LocalAIStack Dashboard")),
 		ModuleSectionTitle: stripQuotes(i18n.T("Modules")),
 		InstallButton:      stripQuotes(i18n.T("Install")),
 		UninstallButton:    stripQuotes(i18n.T("Uninstall")),
@@ -71,6 +121,56 @@ func (s *Server) uiHandler(w http.ResponseWriter, r *http.Request) {
 		RunningLabel:       stripQuotes(i18n.T("Running...")),
 		ErrorPrefix:        stripQuotes(i18n.T("Error")),
 		OKLabel:            stripQuotes(i18n.T("OK")),
+		TokenPrompt:        stripQuotes(i18n.T("API token (create one with `las token create`):")),
+
+		ModelsTab:         stripQuotes(i18n.T("Models")),
+		ServicesTab:       stripQuotes(i18n.T("Services")),
+		SystemTab:         stripQuotes(i18n.T("System")),
+		ChatTab:           stripQuotes(i18n.T("Chat")),
+		LocalModelsTitle:  stripQuotes(i18n.T("Local models")),
+		SearchModelsTitle: stripQuotes(i18n.T("Search models")),
+		SearchPlaceholder: stripQuotes(i18n.T("Search models...")),
+		SearchButton:      stripQuotes(i18n.T("Search")),
+		AllSources:        stripQuotes(i18n.T("All sources")),
+		SourceLabel:       stripQuotes(i18n.T("Source")),
+		FormatLabel:       stripQuotes(i18n.T("Format")),
+		SizeLabel:         stripQuotes(i18n.T("Size")),
+		DownloadButton:    stripQuotes(i18n.T("Download")),
+		DeleteButton:      stripQuotes(i18n.T("Delete")),
+		DeleteConfirm:     stripQuotes(i18n.T("Delete this model?")),
+		NoModels:          stripQuotes(i18n.T("No local models.")),
+		NoResults:         stripQuotes(i18n.T("No results.")),
+		KindLabel:         stripQuotes(i18n.T("Kind")),
+		HealthLabel:       stripQuotes(i18n.T("Health")),
+		RestartsLabel:     stripQuotes(i18n.T("Restarts")),
+		StartButton:       stripQuotes(i18n.T("Start")),
+		StopButton:        stripQuotes(i18n.T("Stop")),
+		RestartButton:     stripQuotes(i18n.T("Restart")),
+		LogsButton:        stripQuotes(i18n.T("Logs")),
+		LogsTitle:         stripQuotes(i18n.T("Logs")),
+		NoServices:        stripQuotes(i18n.T("No services found.")),
+		HardwareTitle:     stripQuotes(i18n.T("Hardware")),
+		CapabilitiesTitle: stripQuotes(i18n.T("Capabilities")),
+		StorageTitle:      stripQuotes(i18n.T("Model storage")),
+		CPULabel:          stripQuotes(i18n.T("CPU")),
+		MemoryLabel:       stripQuotes(i18n.T("Memory")),
+		GPULabel:          stripQuotes(i18n.T("GPU")),
+		UtilizationLabel:  stripQuotes(i18n.T("Utilization")),
+		PoliciesLabel:     stripQuotes(i18n.T("Matched policies")),
+		MaxModelSizeLabel: stripQuotes(i18n.T("Max model size")),
+		RuntimesLabel:     stripQuotes(i18n.T("Runtimes")),
+		FeaturesLabel:     stripQuotes(i18n.T("Features")),
+		DeniedLabel:       stripQuotes(i18n.T("Denied")),
+		ModelCountLabel:   stripQuotes(i18n.T("Models")),
+		UsedLabel:         stripQuotes(i18n.T("Used")),
+		FreeLabel:         stripQuotes(i18n.T("Free")),
+		UptimeLabel:       stripQuotes(i18n.T("Uptime")),
+		NoneLabel:         stripQuotes(i18n.T("none")),
+		ModelLabel:        stripQuotes(i18n.T("Model")),
+		ChatPlaceholder:   stripQuotes(i18n.T("Type a message...")),
+		SendButton:        stripQuotes(i18n.T("Send")),
+		ClearButton:       stripQuotes(i18n.T("Clear")),
+		NoChatModels:      stripQuotes(i18n.T("No running models found.")),
 	}
 
 	tmpl := template.Must(template.New("ui").Parse(uiHTML))
@@ -124,7 +224,7 @@ const uiHTML = `<!doctype html>
       justify-content: center;
     }
     .container {
-      width: min(960px, 100%);
+      width: min(1100px, 100%);
       display: grid;
       gap: 20px;
     }
@@ -154,7 +254,7 @@ const uiHTML = `<!doctype html>
     label {
       font-weight: 600;
     }
-    input[type="text"] {
+    input[type="text"], select, textarea {
       flex: 1 1 260px;
       min-width: 200px;
       padding: 10px 12px;
@@ -212,6 +312,71 @@ const uiHTML = `<!doctype html>
       color: var(--muted);
       margin-top: 8px;
     }
+    .progress {
+      display: none;
+      margin-top: 6px;
+      height: 6px;
+      border-radius: 999px;
+      background: #efe6da;
+      overflow: hidden;
+    }
+    .progress.active { display: block; }
+    .progress .bar {
+      height: 100%;
+      width: 0;
+      background: var(--accent);
+      transition: width 0.3s ease;
+    }
+    .progress-label {
+      font-size: 12px;
+      color: var(--muted);
+      margin-top: 4px;
+    }
+    nav {
+      display: flex;
+      gap: 8px;
+      flex-wrap: wrap;
+    }
+    nav button.active { background: var(--accent); color: #fff; }
+    .tab { display: none; }
+    .tab.active { display: grid; gap: 12px; }
+    h2 {
+      margin: 0;
+      font-size: 16px;
+    }
+    .facts {
+      display: grid;
+      grid-template-columns: max-content 1fr;
+      gap: 6px 16px;
+      font-size: 14px;
+    }
+    .facts dt { color: var(--muted); }
+    .facts dd { margin: 0; }
+    pre.logs {
+      max-height: 420px;
+      overflow: auto;
+      margin: 0;
+      padding: 12px;
+      border-radius: 12px;
+      background: #1f1f1f;
+      color: #e8e4de;
+      font-size: 12px;
+      white-space: pre-wrap;
+    }
+    .transcript {
+      display: grid;
+      gap: 10px;
+      max-height: 480px;
+      overflow-y: auto;
+    }
+    .message {
+      padding: 10px 14px;
+      border-radius: 12px;
+      white-space: pre-wrap;
+      background: #f3ede4;
+    }
+    .message.user { background: #e3efe9; justify-self: end; }
+    textarea { min-height: 80px; font-family: inherit; }
     @media (max-width: 720px) {
       .row { flex-direction: column; align-items: stretch; }
       button { width: 100%; }
@@ -223,17 +388,23 @@ const uiHTML = `<!doctype html>
     <div class="container">
       <header>
         <h1>{{.Heading}}</h1>
-        <div class="status">{{.ModuleSectionTitle}}</div>
+        <nav>
+          <button class="ghost" data-tab="modules">{{.ModuleSectionTitle}}</button>
+          <button class="ghost" data-tab="models">{{.ModelsTab}}</button>
+          <button class="ghost" data-tab="services">{{.ServicesTab}}</button>
+          <button class="ghost" data-tab="system">{{.SystemTab}}</button>
+          <button class="ghost" data-tab="chat">{{.ChatTab}}</button>
+        </nav>
+        <div class="status" id="statusText">{{.StatusIdle}}</div>
       </header>
 
-      <section class="panel">
+      <section class="panel tab" id="modulesTab">
         <div class="row" style="justify-content: space-between;">
           <strong>{{.ModuleSectionTitle}}</strong>
           <div class="row" style="gap: 8px;">
             <button class="ghost" id="refreshButton">{{.RefreshButton}}</button>
           </div>
         </div>
-        <div class="status" id="statusText">{{.StatusIdle}}</div>
         <div class="table-wrap">
           <table>
             <thead>
@@ -251,6 +422,112 @@ const uiHTML = `<!doctype html>
         </div>
         <div id="emptyHint" class="hint" style="display:none;">{{.EmptyHint}}</div>
       </section>
+
+      <section class="panel tab" id="modelsTab">
+        <h2>{{.SearchModelsTitle}}</h2>
+        <form class="row" id="searchForm">
+          <input type="text" id="searchQuery" placeholder="{{.SearchPlaceholder}}" />
+          <select id="searchSource" style="flex: 0 0 180px;">
+            <option value="">{{.AllSources}}</option>
+            <option value="huggingface">Hugging Face</option>
+            <option value="modelscope">ModelScope</option>
+            <option value="ollama">Ollama</option>
+          </select>
+          <button class="primary" type="submit">{{.SearchButton}}</button>
+        </form>
+        <div class="table-wrap">
+          <table>
+            <thead>
+              <tr>
+                <th>{{.NameLabel}}</th>
+                <th>{{.SourceLabel}}</th>
+                <th>{{.FormatLabel}}</th>
+                <th>{{.SizeLabel}}</th>
+                <th>{{.ActionsLabel}}</th>
+              </tr>
+            </thead>
+            <tbody id="searchTableBody">
+            </tbody>
+          </table>
+        </div>
+        <div id="searchEmpty" class="hint" style="display:none;">{{.NoResults}}</div>
+
+        <div class="row" style="justify-content: space-between;">
+          <h2>{{.LocalModelsTitle}}</h2>
+          <button class="ghost" id="refreshModelsButton">{{.RefreshButton}}</button>
+        </div>
+        <div class="table-wrap">
+          <table>
+            <thead>
+              <tr>
+                <th>{{.NameLabel}}</th>
+                <th>{{.SourceLabel}}</th>
+                <th>{{.FormatLabel}}</th>
+                <th>{{.SizeLabel}}</th>
+                <th>{{.ActionsLabel}}</th>
+              </tr>
+            </thead>
+            <tbody id="modelTableBody">
+            </tbody>
+          </table>
+        </div>
+        <div id="modelsEmpty" class="hint" style="display:none;">{{.NoModels}}</div>
+      </section>
+
+      <section class="panel tab" id="servicesTab">
+        <div class="row" style="justify-content: space-between;">
+          <strong>{{.ServicesTab}}</strong>
+          <button class="ghost" id="refreshServicesButton">{{.RefreshButton}}</button>
+        </div>
+        <div class="table-wrap">
+          <table>
+            <thead>
+              <tr>
+                <th>{{.NameLabel}}</th>
+                <th>{{.KindLabel}}</th>
+                <th>{{.StatusLabel}}</th>
+                <th>{{.HealthLabel}}</th>
+                <th>{{.RestartsLabel}}</th>
+                <th>{{.ActionsLabel}}</th>
+              </tr>
+            </thead>
+            <tbody id="serviceTableBody">
+            </tbody>
+          </table>
+        </div>
+        <div id="servicesEmpty" class="hint" style="display:none;">{{.NoServices}}</div>
+        <div id="logsPanel" style="display:none;">
+          <h2>{{.LogsTitle}}: <span id="logsName"></span></h2>
+          <pre class="logs" id="logsOutput"></pre>
+        </div>
+      </section>
+
+      <section class="panel tab" id="systemTab">
+        <div class="row" style="justify-content: space-between;">
+          <h2>{{.HardwareTitle}}</h2>
+          <button class="ghost" id="refreshSystemButton">{{.RefreshButton}}</button>
+        </div>
+        <dl class="facts" id="hardwareFacts"></dl>
+        <h2>{{.CapabilitiesTitle}}</h2>
+        <dl class="facts" id="capabilityFacts"></dl>
+        <h2>{{.StorageTitle}}</h2>
+        <dl class="facts" id="storageFacts"></dl>
+      </section>
+
+      <section class="panel tab" id="chatTab">
+        <div class="row">
+          <label for="chatModel">{{.ModelLabel}}</label>
+          <select id="chatModel"></select>
+          <button class="ghost" id="refreshChatButton">{{.RefreshButton}}</button>
+          <button class="ghost" id="clearChatButton">{{.ClearButton}}</button>
+        </div>
+        <div id="chatEmpty" class="hint" style="display:none;">{{.NoChatModels}}</div>
+        <div class="transcript" id="chatTranscript"></div>
+        <form class="row" id="chatForm">
+          <textarea id="chatInput" placeholder="{{.ChatPlaceholder}}"></textarea>
+          <button class="primary" type="submit" id="chatSendButton">{{.SendButton}}</button>
+        </form>
+      </section>
     </div>
   </div>
 
@@ -271,14 +548,75 @@ const uiHTML = `<!doctype html>
     const statusLoading = {{printf "%q" .StatusLoading}};
     const statusError = {{printf "%q" .StatusError}};
     const statusReady = {{printf "%q" .StatusReady}};
+    const tokenPrompt = {{printf "%q" .TokenPrompt}};
+    const tokenKey = "localaistack.token";
+    const labels = {
+      download: {{printf "%q" .DownloadButton}},
+      delete: {{printf "%q" .DeleteButton}},
+      deleteConfirm: {{printf "%q" .DeleteConfirm}},
+      start: {{printf "%q" .StartButton}},
+      stop: {{printf "%q" .StopButton}},
+      restart: {{printf "%q" .RestartButton}},
+      logs: {{printf "%q" .LogsButton}},
+      cpu: {{printf "%q" .CPULabel}},
+      memory: {{printf "%q" .MemoryLabel}},
+      gpu: {{printf "%q" .GPULabel}},
+      utilization: {{printf "%q" .UtilizationLabel}},
+      policies: {{printf "%q" .PoliciesLabel}},
+      maxModelSize: {{printf "%q" .MaxModelSizeLabel}},
+      runtimes: {{printf "%q" .RuntimesLabel}},
+      features: {{printf "%q" .FeaturesLabel}},
+      denied: {{printf "%q" .DeniedLabel}},
+      modelCount: {{printf "%q" .ModelCountLabel}},
+      used: {{printf "%q" .UsedLabel}},
+      free: {{printf "%q" .FreeLabel}},
+      uptime: {{printf "%q" .UptimeLabel}},
+      version: {{printf "%q" .VersionLabel}},
+      none: {{printf "%q" .NoneLabel}},
+    };
+
+    function label(key) {
+      return cleanText(labels[key]);
+    }
 
     const endpoints = {
       list: "/api/v1/modules",
-      install: "/api/v1/module/install",
-      uninstall: "/api/v1/module/uninstall",
-      check: "/api/v1/module/check",
+      action: (name, action) => "/api/v1/modules/" + encodeURIComponent(name) + "/" + action,
+      job: (id) => "/api/v1/jobs/" + encodeURIComponent(id),
+      models: "/api/v1/models",
+      search: (query, source) => "/api/v1/models/search?q=" + encodeURIComponent(query) + (source ? "&source=" + encodeURIComponent(source) : ""),
+      download: "/api/v1/models/download",
+      model: (source, id) => "/api/v1/models/" + encodeURIComponent(source) + "/" + id.split("/").map(encodeURIComponent).join("/"),
+      services: "/api/v1/services",
+      service: (name, action) => "/api/v1/services/" + encodeURIComponent(name) + "/" + action,
+      status: "/api/v1/status",
+      hardware: "/api/v1/hardware",
+      chatModels: "/api/v1/chat/models",
+      chat: "/api/v1/chat/completions",
     };
 
+    // apiFetch sends the stored bearer token and asks for a new one when the
+    // server rejects it.
+    async function apiFetch(url, options) {
+      for (let attempt = 0; attempt < 2; attempt++) {
+        const token = localStorage.getItem(tokenKey);
+        const headers = Object.assign({}, (options && options.headers) || {});
+        if (token) {
+          headers["Authorization"] = "Bearer " + token;
+        }
+        const resp = await fetch(url, Object.assign({}, options, { headers }));
+        if (resp.status !== 401 || attempt > 0) {
+          return resp;
+        }
+        const entered = window.prompt(cleanText(tokenPrompt));
+        if (!entered) {
+          return resp;
+        }
+        localStorage.setItem(tokenKey, entered.trim());
+        connectEvents();
+      }
+    }
+
     function clearTable() {
       while (tableBody.firstChild) {
         tableBody.removeChild(tableBody.firstChild);
@@ -333,7 +671,20 @@ const uiHTML = `<!doctype html>
         row.appendChild(versionCell);
 
         const statusCell = document.createElement("td");
-        statusCell.textContent = statusLabel(module.status);
+        const statusValue = document.createElement("div");
+        statusValue.textContent = statusLabel(module.status);
+        statusCell.appendChild(statusValue);
+        const progress = document.createElement("div");
+        progress.className = "progress";
+        progress.dataset.target = "module/" + module.name;
+        const bar = document.createElement("div");
+        bar.className = "bar";
+        progress.appendChild(bar);
+        statusCell.appendChild(progress);
+        const progressLabel = document.createElement("div");
+        progressLabel.className = "progress-label";
+        progressLabel.dataset.target = "module/" + module.name;
+        statusCell.appendChild(progressLabel);
         row.appendChild(statusCell);
 
         const actionsCell = document.createElement("td");
@@ -359,7 +710,7 @@ const uiHTML = `<!doctype html>
     async function fetchModules() {
         statusText.textContent = cleanText(statusLoading);
       try {
-        const resp = await fetch(endpoints.list, { method: "GET" });
+        const resp = await apiFetch(endpoints.list, { method: "GET" });
         const data = await resp.json();
         if (!resp.ok || !data.ok) {
           const message = data && data.error ? data.error : resp.statusText;
@@ -374,32 +725,620 @@ const uiHTML = `<!doctype html>
       }
     }
 
+    const pendingJobs = new Map();
+    let events = null;
+
+    function progressElements(target) {
+      const selector = "[data-target=\"" + CSS.escape(target) + "\"]";
+      return {
+        progress: document.querySelector(".progress" + selector),
+        label: document.querySelector(".progress-label" + selector),
+      };
+    }
+
+    function showProgress(target, ratio, text) {
+      const elements = progressElements(target);
+      if (!elements.progress) {
+        return;
+      }
+      elements.progress.classList.add("active");
+      elements.progress.firstChild.style.width = Math.max(0, Math.min(1, ratio)) * 100 + "%";
+      elements.label.textContent = text;
+    }
+
+    function hideProgress(target) {
+      const elements = progressElements(target);
+      if (!elements.progress) {
+        return;
+      }
+      elements.progress.classList.remove("active");
+      elements.label.textContent = "";
+    }
+
+    function formatBytes(bytes) {
+      const units = ["B", "KB", "MB", "GB", "TB"];
+      let value = bytes;
+      let unit = 0;
+      while (value >= 1024 && unit < units.length - 1) {
+        value /= 1024;
+        unit++;
+      }
+      return value.toFixed(unit === 0 ? 0 : 1) + " " + units[unit];
+    }
+
+    function finishJob(job) {
+      const refresh = pendingJobs.get(job.id);
+      if (!refresh) {
+        return;
+      }
+      pendingJobs.delete(job.id);
+      hideProgress(job.target);
+      if (job.status === "succeeded") {
+        statusText.textContent = cleanText(okLabel);
+      } else {
+        const message = job.error ? job.error.message : job.status;
+        statusText.textContent = cleanText(errorPrefix) + ": " + message;
+      }
+      refresh();
+    }
+
+    function handleJob(job) {
+      if (job.status === "succeeded" || job.status === "failed" || job.status === "canceled") {
+        finishJob(job);
+        return;
+      }
+      const ratio = job.total > 0 ? job.completed / job.total : 0;
+      const running = (job.steps || []).filter((step) => step.status === "running").pop();
+      const detail = running ? " " + cleanText(running.intent || running.id) : "";
+      showProgress(job.target, ratio, job.completed + "/" + (job.total || "?") + detail);
+    }
+
+    function handleDownload(progress) {
+      let text = formatBytes(progress.downloaded);
+      let ratio = 0;
+      if (progress.total > 0) {
+        ratio = progress.downloaded / progress.total;
+        text += " / " + formatBytes(progress.total);
+      }
+      showProgress(progress.target, ratio, text);
+    }
+
+    function connectEvents() {
+      if (!window.EventSource) {
+        return;
+      }
+      if (events) {
+        events.close();
+      }
+      let url = "/api/v1/events?types=job,download.progress";
+      const token = localStorage.getItem(tokenKey);
+      if (token) {
+        url += "&access_token=" + encodeURIComponent(token);
+      }
+      events = new EventSource(url);
+      events.addEventListener("job", (event) => handleJob(JSON.parse(event.data)));
+      events.addEventListener("download.progress", (event) => handleDownload(JSON.parse(event.data)));
+    }
+
+    function sleep(ms) {
+      return new Promise((resolve) => setTimeout(resolve, ms));
+    }
+
+    // pollJob is the fallback for browsers without EventSource.
+    async function pollJob(job) {
+      while (job.status === "queued" || job.status === "running") {
+        handleJob(job);
+        await sleep(1000);
+        const resp = await apiFetch(endpoints.job(job.id), { method: "GET" });
+        const data = await resp.json();
+        if (!resp.ok || !data.ok) {
+          throw new Error(data && data.error ? data.error : resp.statusText);
+        }
+        job = data.job;
+      }
+      finishJob(job);
+    }
+
+    // trackJob follows a submitted job until it finishes and then calls
+    // refresh.
+    async function trackJob(job, refresh) {
+      pendingJobs.set(job.id, refresh);
+      if (events) {
+        handleJob(job);
+        // The job may have finished before the stream delivered it.
+        const current = await apiFetch(endpoints.job(job.id), { method: "GET" }).then((r) => r.json());
+        if (current.ok && current.job) {
+          handleJob(current.job);
+        }
+        return;
+      }
+      await pollJob(job);
+    }
+
     async function runAction(action, name) {
       statusText.textContent = cleanText(runningLabel);
-      const payload = { name: name };
 
       try {
-        const resp = await fetch(endpoints[action], {
-          method: "POST",
-          headers: { "Content-Type": "application/json" },
-          body: JSON.stringify(payload),
-        });
-
+        const resp = await apiFetch(endpoints.action(name, action), { method: "POST" });
         const data = await resp.json();
         if (!resp.ok || !data.ok) {
           const message = data && data.error ? data.error : resp.statusText;
           statusText.textContent = cleanText(errorPrefix) + ": " + message;
           return;
         }
-        statusText.textContent = cleanText(okLabel);
-        fetchModules();
+        if (!data.job) {
+          statusText.textContent = cleanText(okLabel);
+          fetchModules();
+          return;
+        }
+        await trackJob(data.job, fetchModules);
       } catch (err) {
         statusText.textContent = cleanText(errorPrefix) + ": " + err.message;
       }
     }
 
+    // requestJSON calls the API and throws the server's error message when
+    // the request fails.
+    async function requestJSON(url, options) {
+      const resp = await apiFetch(url, options || { method: "GET" });
+      const data = await resp.json();
+      if (!resp.ok || !data.ok) {
+        throw new Error(data && data.error ? data.error : resp.statusText);
+      }
+      return data;
+    }
+
+    function reportError(err) {
+      statusText.textContent = cleanText(errorPrefix) + ": " + err.message;
+    }
+
+    function cell(row, text) {
+      const td = document.createElement("td");
+      td.textContent = text === undefined || text === null ? "" : String(text);
+      row.appendChild(td);
+      return td;
+    }
+
+    function button(text, className, onClick) {
+      const element = document.createElement("button");
+      element.textContent = cleanText(text);
+      element.className = className;
+      element.addEventListener("click", onClick);
+      return element;
+    }
+
+    function progressCell(row, target) {
+      const td = document.createElement("td");
+      const progress = document.createElement("div");
+      progress.className = "progress";
+      progress.dataset.target = target;
+      const bar = document.createElement("div");
+      bar.className = "bar";
+      progress.appendChild(bar);
+      const progressLabel = document.createElement("div");
+      progressLabel.className = "progress-label";
+      progressLabel.dataset.target = target;
+      td.className = "actions";
+      row.appendChild(td);
+      return { cell: td, progress, label: progressLabel };
+    }
+
+    function replaceRows(body, empty, rows) {
+      while (body.firstChild) {
+        body.removeChild(body.firstChild);
+      }
+      rows.forEach((row) => body.appendChild(row));
+      empty.style.display = rows.length === 0 ? "block" : "none";
+    }
+
+    // Models
+
+    const searchForm = document.getElementById("searchForm");
+    const searchQuery = document.getElementById("searchQuery");
+    const searchSource = document.getElementById("searchSource");
+    const searchTableBody = document.getElementById("searchTableBody");
+    const searchEmpty = document.getElementById("searchEmpty");
+    const modelTableBody = document.getElementById("modelTableBody");
+    const modelsEmpty = document.getElementById("modelsEmpty");
+
+    function modelTarget(source, id) {
+      return "model/" + source + ":" + id;
+    }
+
+    function modelRow(model, actions) {
+      const row = document.createElement("tr");
+      const nameCell = cell(row, model.id);
+      if (model.name && model.name !== model.id) {
+        const hint = document.createElement("div");
+        hint.className = "hint";
+        hint.textContent = model.name;
+        nameCell.appendChild(hint);
+      }
+      cell(row, model.source);
+      cell(row, model.format);
+      cell(row, model.size > 0 ? formatBytes(model.size) : "");
+      const target = progressCell(row, modelTarget(model.source, model.id));
+      actions.forEach((action) => target.cell.appendChild(action));
+      target.cell.appendChild(target.progress);
+      target.cell.appendChild(target.label);
+      return row;
+    }
+
+    async function fetchModels() {
+      try {
+        const data = await requestJSON(endpoints.models);
+        const rows = (data.models || []).map((model) => modelRow(model, [
+          button(labels.delete, "secondary", () => deleteModel(model)),
+        ]));
+        replaceRows(modelTableBody, modelsEmpty, rows);
+      } catch (err) {
+        reportError(err);
+      }
+    }
+
+    async function searchModels() {
+      const query = searchQuery.value.trim();
+      if (!query) {
+        return;
+      }
+      statusText.textContent = cleanText(statusLoading);
+      try {
+        const data = await requestJSON(endpoints.search(query, searchSource.value));
+        const rows = [];
+        Object.keys(data.results || {}).sort().forEach((source) => {
+          (data.results[source] || []).forEach((model) => {
+            model.source = model.source || source;
+            rows.push(modelRow(model, [
+              button(labels.download, "primary", () => downloadModel(model)),
+            ]));
+          });
+        });
+        replaceRows(searchTableBody, searchEmpty, rows);
+        statusText.textContent = cleanText(statusReady);
+      } catch (err) {
+        reportError(err);
+      }
+    }
+
+    async function downloadModel(model) {
+      statusText.textContent = cleanText(runningLabel);
+      try {
+        const data = await requestJSON(endpoints.download, {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ source: model.source, id: model.id }),
+        });
+        await trackJob(data.job, fetchModels);
+      } catch (err) {
+        reportError(err);
+      }
+    }
+
+    async function deleteModel(model) {
+      if (!window.confirm(cleanText(labels.deleteConfirm) + "\n" + model.id)) {
+        return;
+      }
+      try {
+        await requestJSON(endpoints.model(model.source, model.id), { method: "DELETE" });
+        statusText.textContent = cleanText(okLabel);
+      } catch (err) {
+        reportError(err);
+      }
+      fetchModels();
+    }
+
+    // Services
+
+    const serviceTableBody = document.getElementById("serviceTableBody");
+    const servicesEmpty = document.getElementById("servicesEmpty");
+    const logsPanel = document.getElementById("logsPanel");
+    const logsName = document.getElementById("logsName");
+    const logsOutput = document.getElementById("logsOutput");
+
+    async function fetchServices() {
+      try {
+        const data = await requestJSON(endpoints.services);
+        const rows = (data.services || []).map((service) => {
+          const row = document.createElement("tr");
+          const nameCell = cell(row, service.name);
+          if (service.unit) {
+            const hint = document.createElement("div");
+            hint.className = "hint";
+            hint.textContent = service.unit;
+            nameCell.appendChild(hint);
+          }
+          cell(row, service.kind);
+          cell(row, service.state + (service.pid ? " (" + service.pid + ")" : ""));
+          cell(row, service.health);
+          cell(row, service.restarts);
+          const actions = cell(row, "");
+          actions.className = "actions";
+          actions.appendChild(button(labels.start, "primary", () => serviceAction(service.name, "start")));
+          actions.appendChild(button(labels.stop, "secondary", () => serviceAction(service.name, "stop")));
+          actions.appendChild(button(labels.restart, "ghost", () => serviceAction(service.name, "restart")));
+          actions.appendChild(button(labels.logs, "ghost", () => showLogs(service.name)));
+          return row;
+        });
+        replaceRows(serviceTableBody, servicesEmpty, rows);
+      } catch (err) {
+        reportError(err);
+      }
+    }
+
+    async function serviceAction(name, action) {
+      statusText.textContent = cleanText(runningLabel);
+      try {
+        await requestJSON(endpoints.service(name, action), { method: "POST" });
+        statusText.textContent = cleanText(okLabel);
+      } catch (err) {
+        reportError(err);
+      }
+      fetchServices();
+    }
+
+    async function showLogs(name) {
+      logsPanel.style.display = "block";
+      logsName.textContent = name;
+      logsOutput.textContent = cleanText(statusLoading);
+      try {
+        const data = await requestJSON(endpoints.service(name, "logs") + "?lines=300");
+        logsOutput.textContent = data.logs;
+        logsOutput.scrollTop = logsOutput.scrollHeight;
+      } catch (err) {
+        logsOutput.textContent = cleanText(errorPrefix) + ": " + err.message;
+      }
+    }
+
+    // System
+
+    const hardwareFacts = document.getElementById("hardwareFacts");
+    const capabilityFacts = document.getElementById("capabilityFacts");
+    const storageFacts = document.getElementById("storageFacts");
+
+    function renderFacts(list, facts) {
+      while (list.firstChild) {
+        list.removeChild(list.firstChild);
+      }
+      facts.forEach(([key, value]) => {
+        const term = document.createElement("dt");
+        term.textContent = label(key);
+        const detail = document.createElement("dd");
+        detail.textContent = value === "" || value === undefined || value === null ? label("none") : value;
+        list.appendChild(term);
+        list.appendChild(detail);
+      });
+    }
+
+    function joinList(values) {
+      return values && values.length > 0 ? values.join(", ") : "";
+    }
+
+    function formatDuration(seconds) {
+      const days = Math.floor(seconds / 86400);
+      const hours = Math.floor((seconds % 86400) / 3600);
+      const minutes = Math.floor((seconds % 3600) / 60);
+      return (days > 0 ? days + "d " : "") + hours + "h " + minutes + "m";
+    }
+
+    async function fetchSystem(refresh) {
+      try {
+        const [status, hardware] = await Promise.all([
+          requestJSON(endpoints.status),
+          requestJSON(endpoints.hardware + (refresh ? "?refresh=1" : "")),
+        ]);
+        const profile = hardware.profile || {};
+        const cpu = profile.cpu || {};
+        const usage = hardware.utilization || {};
+        const facts = [
+          ["version", status.version + " · " + label("uptime").toLowerCase() + " " + formatDuration(status.uptime_seconds)],
+          ["cpu", [cpu.model_name, cpu.arch, cpu.cores ? cpu.cores + "C/" + cpu.threads + "T" : ""].filter(Boolean).join(" · ")],
+          ["memory", usage.memory_total_bytes ? formatBytes(usage.memory_total_bytes - usage.memory_available_bytes) + " / " + formatBytes(usage.memory_total_bytes) : ""],
+        ];
+        const gpus = usage.gpus && usage.gpus.length > 0 ? usage.gpus : (profile.gpus || []);
+        gpus.forEach((gpu) => {
+          let text = gpu.name;
+          if (gpu.memory_total_bytes) {
+            text += " · " + formatBytes(gpu.memory_used_bytes) + " / " + formatBytes(gpu.memory_total_bytes);
+            text += " · " + label("utilization").toLowerCase() + " " + Math.round(gpu.utilization_ratio * 100) + "%";
+          } else if (gpu.vram_total) {
+            text += " · " + formatBytes(gpu.vram_total);
+          }
+          facts.push(["gpu", "#" + gpu.index + " " + text]);
+        });
+        if (gpus.length === 0) {
+          facts.push(["gpu", ""]);
+        }
+        renderFacts(hardwareFacts, facts);
+
+        const capabilities = hardware.capabilities || status.capabilities || {};
+        renderFacts(capabilityFacts, [
+          ["policies", joinList(capabilities.matched_policies)],
+          ["maxModelSize", capabilities.max_model_size],
+          ["runtimes", joinList(capabilities.runtimes)],
+          ["features", joinList(capabilities.features)],
+          ["denied", joinList(capabilities.denied)],
+        ]);
+
+        const models = status.models || {};
+        renderFacts(storageFacts, [
+          ["modelCount", String(models.count || 0) + " · " + models.dir],
+          ["used", formatBytes(models.used_bytes || 0)],
+          ["free", formatBytes(models.free_bytes || 0)],
+        ]);
+      } catch (err) {
+        reportError(err);
+      }
+    }
+
+    // Chat
+
+    const chatModel = document.getElementById("chatModel");
+    const chatEmpty = document.getElementById("chatEmpty");
+    const chatTranscript = document.getElementById("chatTranscript");
+    const chatForm = document.getElementById("chatForm");
+    const chatInput = document.getElementById("chatInput");
+    const chatSendButton = document.getElementById("chatSendButton");
+    let chatHistory = [];
+
+    async function fetchChatModels(refresh) {
+      try {
+        const data = await requestJSON(endpoints.chatModels + (refresh ? "?refresh=1" : ""));
+        const selected = chatModel.value;
+        while (chatModel.firstChild) {
+          chatModel.removeChild(chatModel.firstChild);
+        }
+        (data.models || []).forEach((model) => {
+          const option = document.createElement("option");
+          option.value = model.id;
+          option.textContent = model.id;
+          option.title = model.endpoint;
+          chatModel.appendChild(option);
+        });
+        if (selected) {
+          chatModel.value = selected;
+        }
+        chatEmpty.style.display = chatModel.options.length === 0 ? "block" : "none";
+      } catch (err) {
+        reportError(err);
+      }
+    }
+
+    function appendMessage(role, text) {
+      const message = document.createElement("div");
+      message.className = "message " + role;
+      message.textContent = text;
+      chatTranscript.appendChild(message);
+      chatTranscript.scrollTop = chatTranscript.scrollHeight;
+      return message;
+    }
+
+    // readChatStream appends each streamed delta to element and returns the
+    // full reply.
+    async function readChatStream(resp, element) {
+      const reader = resp.body.getReader();
+      const decoder = new TextDecoder();
+      let buffer = "";
+      let reply = "";
+      for (;;) {
+        const { done, value } = await reader.read();
+        if (done) {
+          break;
+        }
+        buffer += decoder.decode(value, { stream: true });
+        const lines = buffer.split("\n");
+        buffer = lines.pop();
+        lines.forEach((line) => {
+          const trimmed = line.trim();
+          if (!trimmed.startsWith("data:")) {
+            return;
+          }
+          const payload = trimmed.slice(5).trim();
+          if (payload === "[DONE]") {
+            return;
+          }
+          try {
+            const chunk = JSON.parse(payload);
+            const delta = chunk.choices && chunk.choices[0] && chunk.choices[0].delta;
+            if (delta && delta.content) {
+              reply += delta.content;
+              element.textContent = reply;
+              chatTranscript.scrollTop = chatTranscript.scrollHeight;
+            }
+          } catch (err) {
+            // Ignore keep-alive and partial lines.
+          }
+        });
+      }
+      return reply;
+    }
+
+    async function sendChat() {
+      const text = chatInput.value.trim();
+      if (!text || !chatModel.value) {
+        return;
+      }
+      chatInput.value = "";
+      chatHistory.push({ role: "user", content: text });
+      appendMessage("user", text);
+      const reply = appendMessage("assistant", "…");
+      chatSendButton.disabled = true;
+      try {
+        const resp = await apiFetch(endpoints.chat, {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ model: chatModel.value, messages: chatHistory, stream: true }),
+        });
+        if (!resp.ok) {
+          const data = await resp.json().catch(() => null);
+          const message = data && data.error ? (data.error.message || data.error) : resp.statusText;
+          throw new Error(message);
+        }
+        const content = await readChatStream(resp, reply);
+        chatHistory.push({ role: "assistant", content });
+      } catch (err) {
+        chatHistory.pop();
+        reply.textContent = cleanText(errorPrefix) + ": " + err.message;
+      } finally {
+        chatSendButton.disabled = false;
+      }
+    }
+
+    // Tabs
+
+    const tabLoaders = {
+      modules: fetchModules,
+      models: fetchModels,
+      services: fetchServices,
+      system: () => fetchSystem(false),
+      chat: () => fetchChatModels(false),
+    };
+
+    function showTab(name) {
+      if (!tabLoaders[name]) {
+        name = "modules";
+      }
+      document.querySelectorAll("nav button").forEach((tab) => {
+        tab.classList.toggle("active", tab.dataset.tab === name);
+      });
+      document.querySelectorAll("section.tab").forEach((panel) => {
+        panel.classList.toggle("active", panel.id === name + "Tab");
+      });
+      if (window.location.hash !== "#" + name) {
+        history.replaceState(null, "", "#" + name);
+      }
+      tabLoaders[name]();
+    }
+
+    document.querySelectorAll("nav button").forEach((tab) => {
+      tab.addEventListener("click", () => showTab(tab.dataset.tab));
+    });
+    searchForm.addEventListener("submit", (event) => {
+      event.preventDefault();
+      searchModels();
+    });
+    chatForm.addEventListener("submit", (event) => {
+      event.preventDefault();
+      sendChat();
+    });
+    chatInput.addEventListener("keydown", (event) => {
+      if (event.key === "Enter" && !event.shiftKey) {
+        event.preventDefault();
+        sendChat();
+      }
+    });
+    document.getElementById("refreshModelsButton").addEventListener("click", () => fetchModels());
+    document.getElementById("refreshServicesButton").addEventListener("click", () => fetchServices());
+    document.getElementById("refreshSystemButton").addEventListener("click", () => fetchSystem(true));
+    document.getElementById("refreshChatButton").addEventListener("click", () => fetchChatModels(true));
+    document.getElementById("clearChatButton").addEventListener("click", () => {
+      chatHistory = [];
+      while (chatTranscript.firstChild) {
+        chatTranscript.removeChild(chatTranscript.firstChild);
+      }
+    });
     refreshButton.addEventListener("click", () => fetchModules());
-    fetchModules();
+    connectEvents();
+    showTab(window.location.hash.slice(1));
   </script>
 </body>
 </html>`
@@ -0,0 +1,289 @@
+// Package auth manages the bearer tokens accepted by the API server. Tokens
+// are stored as SHA-256 hashes; the secret is only shown once on creation.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+)
+
+type Scope string
+
+const (
+	ScopeRead    Scope = "read"
+	ScopeModels  Scope = "models"
+	ScopeModules Scope = "modules"
+	ScopeAdmin   Scope = "admin"
+)
+
+// Scopes lists every valid scope.
+var Scopes = []Scope{ScopeRead, ScopeModels, ScopeModules, ScopeAdmin}
+
+const secretPrefix = "las_"
+
+var (
+	ErrTokenNotFound = errors.New("token not found")
+	ErrInvalidToken  = errors.New("invalid token")
+)
+
+// Token is a stored API token. Hash is the hex SHA-256 of the secret.
+type Token struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Hash      string    `json:"hash"`
+	Scopes    []Scope   `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Allows reports whether the token grants scope. admin grants every scope,
+// and any scope grants read.
+func (t Token) Allows(scope Scope) bool {
+	for _, granted := range t.Scopes {
+		if granted == ScopeAdmin || granted == scope || scope == ScopeRead {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseScopes validates scope names, accepting comma separated lists.
+func ParseScopes(values []string) ([]Scope, error) {
+	seen := map[Scope]bool{}
+	var scopes []Scope
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			scope := Scope(strings.ToLower(strings.TrimSpace(part)))
+			if scope == "" || seen[scope] {
+				continue
+			}
+			valid := false
+			for _, known := range Scopes {
+				if scope == known {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return nil, i18n.Errorf("unknown scope %q (valid: read, models, modules, admin)", scope)
+			}
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, i18n.Errorf("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+// StorePath returns the token file for dataDir, defaulting to
+// ~/.localaistack when dataDir is empty.
+func StorePath(dataDir string) (string, error) {
+	if strings.TrimSpace(dataDir) == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", i18n.Errorf("failed to resolve home directory: %w", err)
+		}
+		dataDir = filepath.Join(home, config.DefaultConfigDirName)
+	}
+	return filepath.Join(dataDir, "auth", "tokens.json"), nil
+}
+
+// Store reads and writes tokens in a JSON file. It reloads the file when it
+// changes on disk, so tokens created by the CLI apply to a running server.
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	tokens  []Token
+	modTime time.Time
+	size    int64
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the location of the token file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Create adds a token and returns it with its secret.
+func (s *Store) Create(name string, scopes []Scope) (Token, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Token{}, "", i18n.Errorf("token name is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return Token{}, "", err
+	}
+	for _, token := range s.tokens {
+		if token.Name == name {
+			return Token{}, "", i18n.Errorf("token %q already exists", name)
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return Token{}, "", i18n.Errorf("failed to generate token: %w", err)
+	}
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return Token{}, "", i18n.Errorf("failed to generate token: %w", err)
+	}
+	secret := secretPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	token := Token{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Hash:      hashSecret(secret),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+	s.tokens = append(s.tokens, token)
+	if err := s.saveLocked(); err != nil {
+		s.tokens = s.tokens[:len(s.tokens)-1]
+		return Token{}, "", err
+	}
+	return token, secret, nil
+}
+
+// List returns all tokens sorted by creation time.
+func (s *Store) List() ([]Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	tokens := append([]Token(nil), s.tokens...)
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.Before(tokens[j].CreatedAt) })
+	return tokens, nil
+}
+
+// Revoke deletes the token with the given ID or name.
+func (s *Store) Revoke(ref string) (Token, error) {
+	ref = strings.TrimSpace(ref)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return Token{}, err
+	}
+	for i, token := range s.tokens {
+		if token.ID == ref || token.Name == ref {
+			remaining := append(append([]Token(nil), s.tokens[:i]...), s.tokens[i+1:]...)
+			previous := s.tokens
+			s.tokens = remaining
+			if err := s.saveLocked(); err != nil {
+				s.tokens = previous
+				return Token{}, err
+			}
+			return token, nil
+		}
+	}
+	return Token{}, ErrTokenNotFound
+}
+
+// Authenticate returns the token matching secret.
+func (s *Store) Authenticate(secret string) (Token, error) {
+	if !strings.HasPrefix(secret, secretPrefix) {
+		return Token{}, ErrInvalidToken
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return Token{}, err
+	}
+	hash := []byte(hashSecret(secret))
+	for _, token := range s.tokens {
+		if subtle.ConstantTimeCompare(hash, []byte(token.Hash)) == 1 {
+			return token, nil
+		}
+	}
+	return Token{}, ErrInvalidToken
+}
+
+func (s *Store) loadLocked() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.tokens = nil
+			s.modTime = time.Time{}
+			s.size = 0
+			return nil
+		}
+		return i18n.Errorf("failed to read token store %s: %w", s.path, err)
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil
+	}
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return i18n.Errorf("failed to read token store %s: %w", s.path, err)
+	}
+	var tokens []Token
+	if err := json.Unmarshal(raw, &tokens); err != nil {
+		return i18n.Errorf("failed to parse token store %s: %w", s.path, err)
+	}
+	s.tokens = tokens
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+	return nil
+}
+
+func (s *Store) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return i18n.Errorf("failed to create token directory: %w", err)
+	}
+	tokens := s.tokens
+	if tokens == nil {
+		tokens = []Token{}
+	}
+	raw, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".tokens-*")
+	if err != nil {
+		return i18n.Errorf("failed to write token store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(raw, '\n')); err != nil {
+		tmp.Close()
+		return i18n.Errorf("failed to write token store: %w", err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return i18n.Errorf("failed to write token store: %w", err)
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+		s.size = info.Size()
+	}
+	return nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
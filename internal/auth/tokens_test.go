@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore_CreateAuthenticateRevoke(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth", "tokens.json")
+	store := NewStore(path)
+
+	token, secret, err := store.Create("ci", []Scope{ScopeModels})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if !strings.HasPrefix(secret, "las_") {
+		t.Fatalf("unexpected secret %q", secret)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read store: %v", err)
+	}
+	if strings.Contains(string(raw), secret) {
+		t.Fatalf("token store must not contain the secret")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected 0600 token file, got %v", info.Mode().Perm())
+	}
+	if _, _, err := store.Create("ci", []Scope{ScopeRead}); err == nil {
+		t.Fatalf("expected duplicate name error")
+	}
+
+	// A second store sees tokens written by the first, as the server does
+	// for tokens created by the CLI.
+	other := NewStore(path)
+	found, err := other.Authenticate(secret)
+	if err != nil || found.ID != token.ID {
+		t.Fatalf("Authenticate returned %+v, %v", found, err)
+	}
+	if !found.Allows(ScopeModels) || !found.Allows(ScopeRead) || found.Allows(ScopeModules) {
+		t.Fatalf("unexpected scope checks for %+v", found.Scopes)
+	}
+	if _, err := other.Authenticate("las_wrong"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+
+	if _, err := store.Revoke("ci"); err != nil {
+		t.Fatalf("Revoke returned error: %v", err)
+	}
+	if _, err := other.Authenticate(secret); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected revoked token to be rejected, got %v", err)
+	}
+	if _, err := store.Revoke(token.ID); !errors.Is(err, ErrTokenNotFound) {
+		t.Fatalf("expected ErrTokenNotFound, got %v", err)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes([]string{"read,models", "models"})
+	if err != nil || len(scopes) != 2 {
+		t.Fatalf("unexpected scopes %v, %v", scopes, err)
+	}
+	if _, err := ParseScopes([]string{"root"}); err == nil {
+		t.Fatalf("expected unknown scope error")
+	}
+	if _, err := ParseScopes(nil); err == nil {
+		t.Fatalf("expected missing scope error")
+	}
+	if !(Token{Scopes: []Scope{ScopeAdmin}}).Allows(ScopeModules) {
+		t.Fatalf("admin should grant every scope")
+	}
+}
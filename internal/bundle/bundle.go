@@ -0,0 +1,372 @@
+// Package bundle packages modules, the artifacts their install steps
+// download and downloaded models into a single tar file, and unpacks such
+// a bundle on a host without network access.
+package bundle
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/modelmanager"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+)
+
+const (
+	// ManifestName is the bundle entry that describes the bundle. It is
+	// written last, once the checksums of all other entries are known.
+	ManifestName = "manifest.json"
+	// FormatVersion is the version of the bundle layout.
+	FormatVersion = 1
+)
+
+// Manifest describes the content of a bundle.
+type Manifest struct {
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	Modules   []Module   `json:"modules"`
+	Artifacts []Artifact `json:"artifacts"`
+	Models    []Model    `json:"models"`
+	// NetworkSteps are install steps of the bundled modules that still
+	// need network access: those of install modes other than the default
+	// and, when the bundle was created with AllowNetwork, any others.
+	NetworkSteps []module.NetworkStep `json:"network_steps,omitempty"`
+	// Checksums maps the path of every other entry in the bundle to its
+	// sha256 digest.
+	Checksums map[string]string `json:"checksums"`
+}
+
+// Module is a module directory in the bundle. Modules are listed in
+// install order, dependencies first.
+type Module struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Path    string `json:"path"`
+}
+
+// Artifact is a cacheable download of a module's install steps.
+type Artifact struct {
+	module.Artifact
+	Path string `json:"path"`
+}
+
+// Model is a downloaded model directory in the bundle, metadata.json
+// included.
+type Model struct {
+	ID     string                   `json:"id"`
+	Source modelmanager.ModelSource `json:"source"`
+	Format modelmanager.ModelFormat `json:"format"`
+	Size   int64                    `json:"size"`
+	Path   string                   `json:"path"`
+}
+
+type CreateOptions struct {
+	// Modules are module names; their module dependencies are added.
+	Modules []string
+	// ModelRefs are model references as accepted by `las model download`;
+	// the models must be downloaded.
+	ModelRefs []string
+	Models    *modelmanager.Manager
+	// AllowNetwork bundles modules whose default install mode has steps
+	// that need network access, recording those steps in the manifest.
+	// Without it, such modules are refused.
+	AllowNetwork bool
+	// Progress is called with the path of each entry before it is written.
+	Progress func(path string)
+}
+
+// Create writes a bundle to w.
+func Create(ctx context.Context, w io.Writer, opts CreateOptions) (*Manifest, error) {
+	manifest := &Manifest{
+		Version:   FormatVersion,
+		CreatedAt: time.Now().UTC(),
+		Checksums: map[string]string{},
+	}
+	writer := &bundleWriter{tw: tar.NewWriter(w), manifest: manifest, progress: opts.Progress}
+
+	if len(opts.Modules) > 0 {
+		if err := writer.addModules(ctx, opts.Modules, opts.AllowNetwork); err != nil {
+			return nil, err
+		}
+	}
+	for _, ref := range opts.ModelRefs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := writer.addModel(opts.Models, ref); err != nil {
+			return nil, err
+		}
+	}
+
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.tw.WriteHeader(&tar.Header{
+		Name:    ManifestName,
+		Mode:    0o644,
+		Size:    int64(len(encoded)),
+		ModTime: manifest.CreatedAt,
+	}); err != nil {
+		return nil, err
+	}
+	if _, err := writer.tw.Write(encoded); err != nil {
+		return nil, err
+	}
+	if err := writer.tw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+type bundleWriter struct {
+	tw       *tar.Writer
+	manifest *Manifest
+	progress func(path string)
+}
+
+// addModules adds the modules and their module dependencies, and the
+// cacheable artifacts of their install plans. Modules whose default install
+// mode has steps that need network access are refused unless allowNetwork
+// is set.
+func (w *bundleWriter) addModules(ctx context.Context, names []string, allowNetwork bool) error {
+	root, err := module.FindModulesRoot()
+	if err != nil {
+		return err
+	}
+	registry, err := module.LoadRegistryFromDir(root)
+	if err != nil {
+		return err
+	}
+	resolved, err := module.NewResolver(registry).ResolveInstallPlan(names)
+	if err != nil {
+		return err
+	}
+	var described []string
+	for _, name := range resolved.Order {
+		steps, err := module.NetworkSteps(name)
+		if err != nil {
+			return err
+		}
+		for _, step := range steps {
+			if step.DefaultMode {
+				described = append(described, DescribeNetworkStep(step))
+			}
+		}
+		w.manifest.NetworkSteps = append(w.manifest.NetworkSteps, steps...)
+	}
+	if len(described) > 0 && !allowNetwork {
+		return i18n.Errorf("the bundle would not install offline; these install steps need network access:\n  %s\nconvert them to cacheable downloads, or bundle anyway with --allow-network", strings.Join(described, "\n  "))
+	}
+
+	tmpDir, err := os.MkdirTemp("", "las-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, name := range resolved.Order {
+		dir, err := module.ModuleDir(name)
+		if err != nil {
+			return err
+		}
+		entry := Module{Name: name, Version: resolved.Modules[name].Version.String(), Path: path.Join("modules", name)}
+		if err := w.addDir(dir, entry.Path, false); err != nil {
+			return i18n.Errorf("failed to add module %s: %w", name, err)
+		}
+		w.manifest.Modules = append(w.manifest.Modules, entry)
+
+		artifacts, err := module.CacheableArtifacts(name)
+		if err != nil {
+			return err
+		}
+		for _, artifact := range artifacts {
+			if err := w.addArtifact(ctx, artifact, filepath.Join(tmpDir, "artifact")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addArtifact adds a cacheable download, fetched through tmp. An artifact
+// whose sha256 is left empty by the module configuration is recorded with
+// the checksum of the file that is bundled.
+func (w *bundleWriter) addArtifact(ctx context.Context, artifact module.Artifact, tmp string) error {
+	if artifact.SHA256 != "" {
+		if _, ok := w.manifest.Checksums[artifactPath(artifact)]; ok {
+			w.manifest.Artifacts = append(w.manifest.Artifacts, Artifact{Artifact: artifact, Path: artifactPath(artifact)})
+			return nil
+		}
+	}
+	if err := module.FetchArtifact(ctx, artifact, tmp); err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	if artifact.SHA256 == "" {
+		sum, err := fileChecksum(tmp)
+		if err != nil {
+			return err
+		}
+		artifact.SHA256 = sum
+	}
+	entry := Artifact{Artifact: artifact, Path: artifactPath(artifact)}
+	if _, ok := w.manifest.Checksums[entry.Path]; !ok {
+		if err := w.addFile(tmp, entry.Path); err != nil {
+			return err
+		}
+	}
+	w.manifest.Artifacts = append(w.manifest.Artifacts, entry)
+	return nil
+}
+
+func artifactPath(artifact module.Artifact) string {
+	return path.Join("artifacts", strings.ToLower(artifact.SHA256))
+}
+
+// fileChecksum returns the sha256 digest of the file at src.
+func fileChecksum(src string) (string, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// DescribeNetworkStep names an install step that needs network access and
+// why.
+func DescribeNetworkStep(step module.NetworkStep) string {
+	return i18n.T("module %s (%s) step %s: %s", step.Module, step.Mode, step.Step, step.Reason)
+}
+
+// addModel adds a downloaded model's directory.
+func (w *bundleWriter) addModel(mgr *modelmanager.Manager, ref string) error {
+	source, modelID, err := modelmanager.ParseModelID(ref)
+	if err != nil {
+		return err
+	}
+	if source == modelmanager.SourceOllama {
+		return i18n.Errorf("model %s is managed by Ollama and cannot be bundled", ref)
+	}
+	if mgr == nil {
+		return i18n.Errorf("model manager is required")
+	}
+	dir, err := mgr.ResolveLocalModelDir(source, modelID)
+	if err != nil {
+		return i18n.Errorf("model %s is not downloaded; run `las model download %s` first", ref, ref)
+	}
+	models, err := mgr.ListDownloadedModels()
+	if err != nil {
+		return err
+	}
+	entry := Model{ID: modelID, Source: source, Path: path.Join("models", filepath.Base(dir))}
+	for _, model := range models {
+		if model.LocalPath == dir {
+			entry.ID, entry.Source, entry.Format, entry.Size = model.ID, model.Source, model.Format, model.Size
+			break
+		}
+	}
+	for _, existing := range w.manifest.Models {
+		if existing.Path == entry.Path {
+			return nil
+		}
+	}
+	// Model files may be links into the blob store; their content is
+	// bundled.
+	if err := w.addDir(dir, entry.Path, true); err != nil {
+		return i18n.Errorf("failed to add model %s: %w", ref, err)
+	}
+	w.manifest.Models = append(w.manifest.Models, entry)
+	return nil
+}
+
+// addDir adds the files under dir below prefix. In a model directory,
+// hidden directories such as download caches and partial downloads are
+// skipped and symlinks are followed; a module directory may not contain
+// symlinks.
+func (w *bundleWriter) addDir(dir, prefix string, modelFiles bool) error {
+	var files []string
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if modelFiles && file != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Type()&fs.ModeSymlink != 0 && !modelFiles {
+			return i18n.Errorf("%s is a symlink", file)
+		}
+		if modelFiles && modelmanager.IsPartialFile(entry.Name()) {
+			return nil
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		if err := w.addFile(file, path.Join(prefix, filepath.ToSlash(rel))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addFile writes the file at src as the entry name and records its
+// checksum.
+func (w *bundleWriter) addFile(src, name string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return i18n.Errorf("%s is not a regular file", src)
+	}
+	if w.progress != nil {
+		w.progress(name)
+	}
+	if err := w.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    int64(info.Mode().Perm()),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}); err != nil {
+		return err
+	}
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w.tw, hasher), file); err != nil {
+		return err
+	}
+	w.manifest.Checksums[name] = hex.EncodeToString(hasher.Sum(nil))
+	return nil
+}
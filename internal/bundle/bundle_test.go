@@ -0,0 +1,358 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/modelmanager"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+)
+
+const testManifest = `name: demo
+category: tool
+version: 1.2.0
+description: Demo module
+runtime:
+  modes:
+    - native
+  preferred: native
+`
+
+const testInstallPlan = `apiVersion: las.installspec/v0.1.2
+kind: InstallPlan
+id: demo
+category: tool
+install_modes:
+  - native
+install:
+  native:
+    - id: S10
+      intent: Fetch the release
+      tool: download
+      download:
+        url: "{{ release_url }}"
+        sha256: "%s"
+        dest: bin/demo.tar
+        cacheable: true
+    - id: S20
+      intent: Copy a local script
+      tool: download
+      download:
+        url: file:///usr/share/demo/uncached.sh
+        dest: bin/uncached.sh
+configuration:
+  defaults:
+    release_url: "file://%s"
+`
+
+// setupHost makes root the working directory, home and data directory of
+// a host with a modules directory.
+func setupHost(t *testing.T, root string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(root, "modules"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	t.Chdir(root)
+	t.Setenv("HOME", root)
+	t.Setenv("LOCALAISTACK_CONTROL_DATA_DIR", filepath.Join(root, "data"))
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+// writeModel writes a small safetensors model downloaded from Hugging Face.
+func writeModel(t *testing.T, dir string) {
+	t.Helper()
+	header, _ := json.Marshal(map[string]any{
+		"embed.weight": map[string]any{"dtype": "F32", "shape": []int{2, 2}, "data_offsets": []int{0, 16}},
+	})
+	data := binary.LittleEndian.AppendUint64(nil, uint64(len(header)))
+	data = append(append(data, header...), make([]byte, 16)...)
+	writeFile(t, filepath.Join(dir, "model.safetensors"), data)
+	writeFile(t, filepath.Join(dir, "config.json"), []byte(`{"num_hidden_layers": 2, "hidden_size": 64, "num_attention_heads": 4, "torch_dtype": "float32"}`))
+	writeFile(t, filepath.Join(dir, "metadata.json"), []byte(`{"id": "org/tiny", "source": "huggingface", "format": "safetensors"}`))
+}
+
+func createTestBundle(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+	online := t.TempDir()
+	setupHost(t, online)
+	release := []byte("release archive")
+	sum := sha256.Sum256(release)
+	releasePath := filepath.Join(online, "release.tar")
+	writeFile(t, releasePath, release)
+	moduleDir := filepath.Join(online, "modules", "demo")
+	writeFile(t, filepath.Join(moduleDir, "manifest.yaml"), []byte(testManifest))
+	writeFile(t, filepath.Join(moduleDir, "INSTALL.yaml"), []byte(strings.ReplaceAll(
+		strings.Replace(testInstallPlan, "%s", hex.EncodeToString(sum[:]), 1), "%s", releasePath)))
+
+	mgr := modelmanager.NewManager(filepath.Join(online, "models"))
+	writeModel(t, filepath.Join(mgr.GetModelDir(), "org_tiny"))
+
+	var buf bytes.Buffer
+	manifest, err := Create(t.Context(), &buf, CreateOptions{
+		Modules:   []string{"demo"},
+		ModelRefs: []string{"hf:org/tiny"},
+		Models:    mgr,
+	})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if len(manifest.Modules) != 1 || manifest.Modules[0].Version != "1.2.0" {
+		t.Fatalf("unexpected modules %+v", manifest.Modules)
+	}
+	if len(manifest.Artifacts) != 1 || manifest.Artifacts[0].URL != "file://"+releasePath {
+		t.Fatalf("expected only the cacheable download, got %+v", manifest.Artifacts)
+	}
+	if len(manifest.Models) != 1 || manifest.Models[0].Source != modelmanager.SourceHuggingFace || manifest.Models[0].Path != "models/org_tiny" {
+		t.Fatalf("unexpected models %+v", manifest.Models)
+	}
+	if _, ok := manifest.Checksums["models/org_tiny/metadata.json"]; !ok {
+		t.Fatalf("expected metadata.json in the bundle, got %v", manifest.Checksums)
+	}
+	// The artifact must come from the bundle on the offline host.
+	if err := os.Remove(releasePath); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	return buf.Bytes(), release
+}
+
+func TestCreateAndInstall(t *testing.T) {
+	data, release := createTestBundle(t)
+
+	offline := t.TempDir()
+	setupHost(t, offline)
+	bundlePath := filepath.Join(offline, "bundle.tar")
+	writeFile(t, bundlePath, data)
+	if manifest, err := ReadManifest(bundlePath); err != nil || len(manifest.Modules) != 1 {
+		t.Fatalf("ReadManifest = %+v, %v", manifest, err)
+	}
+
+	mgr := modelmanager.NewManager(filepath.Join(offline, "models"))
+	result, err := Install(bundlePath, InstallOptions{Models: mgr})
+	if err != nil {
+		t.Fatalf("Install returned error: %v", err)
+	}
+	if len(result.Modules) != 1 || result.Artifacts != 1 || len(result.Models) != 1 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(offline, "modules", "demo", "INSTALL.yaml")); err != nil {
+		t.Fatalf("module was not placed: %v", err)
+	}
+	cached, err := os.ReadDir(filepath.Join(offline, "data", "artifacts"))
+	if err != nil || len(cached) != 1 {
+		t.Fatalf("expected one cached artifact, got %v (%v)", cached, err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(offline, "data", "artifacts", cached[0].Name())); !bytes.Equal(got, release) {
+		t.Fatalf("unexpected cached artifact %q", got)
+	}
+	if !mgr.HasLocalModel(modelmanager.SourceHuggingFace, "org/tiny", "") {
+		t.Fatalf("model was not imported under its source")
+	}
+	models, err := mgr.ListDownloadedModels()
+	if err != nil || len(models) != 1 || models[0].Source != modelmanager.SourceHuggingFace || models[0].Metadata["imported_from"] != bundlePath {
+		t.Fatalf("unexpected models %+v (%v)", models, err)
+	}
+	if staged, _ := filepath.Glob(filepath.Join(offline, ".bundle-*")); len(staged) != 0 {
+		t.Fatalf("the staging directory was left behind: %v", staged)
+	}
+
+	// Installing again leaves identical modules and downloaded models alone.
+	result, err = Install(bundlePath, InstallOptions{Models: mgr})
+	if err != nil {
+		t.Fatalf("second Install returned error: %v", err)
+	}
+	if len(result.Modules) != 0 || len(result.SkippedModels) != 1 {
+		t.Fatalf("unexpected second result %+v", result)
+	}
+}
+
+func TestInstall_RejectsTamperedBundle(t *testing.T) {
+	data, _ := createTestBundle(t)
+
+	var tampered bytes.Buffer
+	tr := tar.NewReader(bytes.NewReader(data))
+	tw := tar.NewWriter(&tampered)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		if header.Name == "modules/demo/INSTALL.yaml" {
+			content = append(content, "# changed\n"...)
+			header.Size = int64(len(content))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		tw.Write(content)
+	}
+	tw.Close()
+
+	offline := t.TempDir()
+	setupHost(t, offline)
+	bundlePath := filepath.Join(offline, "bundle.tar")
+	writeFile(t, bundlePath, tampered.Bytes())
+	mgr := modelmanager.NewManager(filepath.Join(offline, "models"))
+	if _, err := Install(bundlePath, InstallOptions{Models: mgr}); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(offline, "modules", "demo")); !os.IsNotExist(err) {
+		t.Fatalf("a tampered bundle placed a module: %v", err)
+	}
+}
+
+const networkInstallPlan = `apiVersion: las.installspec/v0.1.2
+kind: InstallPlan
+id: demo
+category: tool
+install_modes:
+  - native
+install:
+  native:
+    - id: S10
+      intent: Run the upstream installer
+      tool: shell
+      command: curl -fsSL https://example.com/install.sh | bash
+`
+
+func TestCreate_ModuleNeedingNetwork(t *testing.T) {
+	online := t.TempDir()
+	setupHost(t, online)
+	moduleDir := filepath.Join(online, "modules", "demo")
+	writeFile(t, filepath.Join(moduleDir, "manifest.yaml"), []byte(testManifest))
+	writeFile(t, filepath.Join(moduleDir, "INSTALL.yaml"), []byte(networkInstallPlan))
+
+	// The installer is fetched with curl, so the bundle would fail offline.
+	var buf bytes.Buffer
+	_, err := Create(t.Context(), &buf, CreateOptions{Modules: []string{"demo"}})
+	if err == nil || !strings.Contains(err.Error(), "module demo (native) step S10: runs curl") || !strings.Contains(err.Error(), "--allow-network") {
+		t.Fatalf("expected the network step to be refused, got %v", err)
+	}
+
+	buf.Reset()
+	manifest, err := Create(t.Context(), &buf, CreateOptions{Modules: []string{"demo"}, AllowNetwork: true})
+	if err != nil {
+		t.Fatalf("Create with AllowNetwork returned error: %v", err)
+	}
+	if len(manifest.NetworkSteps) != 1 || manifest.NetworkSteps[0].Step != "S10" || len(manifest.Artifacts) != 0 {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+
+	offline := t.TempDir()
+	setupHost(t, offline)
+	bundlePath := filepath.Join(offline, "bundle.tar")
+	writeFile(t, bundlePath, buf.Bytes())
+	result, err := Install(bundlePath, InstallOptions{Models: modelmanager.NewManager(filepath.Join(offline, "models"))})
+	if err != nil {
+		t.Fatalf("Install returned error: %v", err)
+	}
+	if len(result.Modules) != 1 || len(result.Manifest.NetworkSteps) != 1 {
+		t.Fatalf("expected the module and its network step, got %+v", result)
+	}
+}
+
+// TestCreateAndInstall_ShippedModules bundles the shipped ollama and
+// llama.cpp modules with their releases served from a file:// mirror, and
+// reads the releases from the artifact cache on the offline host.
+func TestCreateAndInstall_ShippedModules(t *testing.T) {
+	names := []string{"ollama", "llama.cpp"}
+	shipped, err := filepath.Abs(filepath.Join("..", "..", "modules"))
+	if err != nil {
+		t.Fatalf("abs: %v", err)
+	}
+	online := t.TempDir()
+	setupHost(t, online)
+	mirror := "file://" + filepath.Join(online, "mirror")
+	releases := map[string][]byte{}
+	for _, name := range names {
+		if err := os.CopyFS(filepath.Join(online, "modules", name), os.DirFS(filepath.Join(shipped, name))); err != nil {
+			t.Fatalf("copy module: %v", err)
+		}
+		if _, err := module.SetConfig(name, map[string]string{"mirror": mirror}, module.ConfigApplyOptions{}); err != nil {
+			t.Fatalf("SetConfig: %v", err)
+		}
+		artifacts, err := module.CacheableArtifacts(name)
+		if err != nil || len(artifacts) != 1 || !strings.HasPrefix(artifacts[0].URL, mirror+"/") {
+			t.Fatalf("expected one release download from the mirror, got %+v (%v)", artifacts, err)
+		}
+		releases[artifacts[0].URL] = []byte(name + " release")
+		writeFile(t, strings.TrimPrefix(artifacts[0].URL, "file://"), releases[artifacts[0].URL])
+	}
+
+	var buf bytes.Buffer
+	manifest, err := Create(t.Context(), &buf, CreateOptions{Modules: names})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if len(manifest.Modules) != 2 || len(manifest.Artifacts) != 2 {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+	for _, artifact := range manifest.Artifacts {
+		// The releases are not pinned by default, so the bundle records
+		// the checksum of what it packaged.
+		if sum := sha256.Sum256(releases[artifact.URL]); artifact.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Fatalf("artifact %s has checksum %q", artifact.URL, artifact.SHA256)
+		}
+	}
+	// Only llama.cpp's source build, which is not the default mode, needs
+	// the network.
+	for _, step := range manifest.NetworkSteps {
+		if step.Module != "llama.cpp" || step.Mode != "source" || step.DefaultMode {
+			t.Fatalf("unexpected network step %+v", step)
+		}
+	}
+	if err := os.RemoveAll(filepath.Join(online, "mirror")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	offline := t.TempDir()
+	setupHost(t, offline)
+	bundlePath := filepath.Join(offline, "bundle.tar")
+	writeFile(t, bundlePath, buf.Bytes())
+	result, err := Install(bundlePath, InstallOptions{Models: modelmanager.NewManager(filepath.Join(offline, "models"))})
+	if err != nil {
+		t.Fatalf("Install returned error: %v", err)
+	}
+	if len(result.Modules) != 2 || result.Artifacts != 2 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	for _, name := range names {
+		// The artifact cache is keyed by URL, so the offline host uses the
+		// same mirror.
+		if _, err := module.SetConfig(name, map[string]string{"mirror": mirror}, module.ConfigApplyOptions{}); err != nil {
+			t.Fatalf("SetConfig: %v", err)
+		}
+		artifacts, err := module.CacheableArtifacts(name)
+		if err != nil || len(artifacts) != 1 {
+			t.Fatalf("CacheableArtifacts = %+v, %v", artifacts, err)
+		}
+		dest := filepath.Join(offline, name+".tar")
+		if err := module.FetchArtifact(t.Context(), artifacts[0], dest); err != nil {
+			t.Fatalf("FetchArtifact returned error offline: %v", err)
+		}
+		if got, _ := os.ReadFile(dest); !bytes.Equal(got, releases[artifacts[0].URL]) {
+			t.Fatalf("unexpected release %q", got)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package bundle
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/modelmanager"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+)
+
+type InstallOptions struct {
+	Models *modelmanager.Manager
+	// IgnoreQuota imports models even when the model quota would be
+	// exceeded.
+	IgnoreQuota bool
+	// Progress is called with the path of each entry as it is unpacked.
+	Progress func(path string)
+}
+
+// InstallResult reports what Install placed on the host.
+type InstallResult struct {
+	Manifest *Manifest
+	// Modules are the module directories copied into the modules
+	// directory; modules already present with the same files are left out.
+	Modules   []string
+	Artifacts int
+	Models    []string
+	// SkippedModels were already downloaded.
+	SkippedModels []string
+}
+
+// Install unpacks the bundle at bundlePath and verifies every entry
+// against the manifest checksums before placing anything. Module
+// directories are copied into the modules directory, artifacts into the
+// artifact cache, and models are imported under their original source and
+// ID. Installing the modules is left to the caller, in manifest order.
+func Install(bundlePath string, opts InstallOptions) (*InstallResult, error) {
+	if opts.Models == nil {
+		return nil, i18n.Errorf("model manager is required")
+	}
+	if err := opts.Models.EnsureModelDir(); err != nil {
+		return nil, err
+	}
+	// Unpack next to the model directory so that models are moved into
+	// place instead of copied.
+	staging, err := os.MkdirTemp(filepath.Dir(opts.Models.GetModelDir()), ".bundle-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, err := unpack(bundlePath, staging, opts.Progress)
+	if err != nil {
+		return nil, err
+	}
+	result := &InstallResult{Manifest: manifest}
+
+	if len(manifest.Modules) > 0 {
+		root, err := module.FindModulesRoot()
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range manifest.Modules {
+			changed, err := placeModule(manifest, staging, root, entry)
+			if err != nil {
+				return nil, i18n.Errorf("failed to place module %s: %w", entry.Name, err)
+			}
+			if changed {
+				result.Modules = append(result.Modules, entry.Name)
+			}
+		}
+	}
+	for _, artifact := range manifest.Artifacts {
+		if err := module.CacheArtifact(artifact.Artifact, filepath.Join(staging, filepath.FromSlash(artifact.Path))); err != nil {
+			return nil, err
+		}
+		result.Artifacts++
+	}
+	for _, model := range manifest.Models {
+		if opts.Models.HasLocalModel(model.Source, model.ID, "") {
+			result.SkippedModels = append(result.SkippedModels, model.ID)
+			continue
+		}
+		_, err := opts.Models.ImportModel(filepath.Join(staging, filepath.FromSlash(model.Path)), model.ID, modelmanager.ImportOptions{
+			Mode:        modelmanager.ImportMove,
+			IgnoreQuota: opts.IgnoreQuota,
+			Source:      model.Source,
+			Origin:      bundlePath,
+		})
+		if err != nil {
+			return nil, i18n.Errorf("failed to import model %s: %w", model.ID, err)
+		}
+		result.Models = append(result.Models, model.ID)
+	}
+	return result, nil
+}
+
+// ReadManifest returns the manifest of the bundle at bundlePath without
+// unpacking it.
+func ReadManifest(bundlePath string) (*Manifest, error) {
+	file, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, i18n.Errorf("%s has no %s", bundlePath, ManifestName)
+		}
+		if err != nil {
+			return nil, i18n.Errorf("failed to read bundle: %w", err)
+		}
+		if header.Name == ManifestName {
+			return decodeManifest(tr)
+		}
+	}
+}
+
+func decodeManifest(r io.Reader) (*Manifest, error) {
+	var manifest Manifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, i18n.Errorf("failed to parse bundle manifest: %w", err)
+	}
+	if manifest.Version != FormatVersion {
+		return nil, i18n.Errorf("unsupported bundle version %d", manifest.Version)
+	}
+	return &manifest, nil
+}
+
+// unpack extracts the bundle into dir and checks the entries against the
+// manifest checksums.
+func unpack(bundlePath, dir string, progress func(string)) (*Manifest, error) {
+	file, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var manifest *Manifest
+	checksums := map[string]string{}
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, i18n.Errorf("failed to read bundle: %w", err)
+		}
+		if header.Name == ManifestName {
+			if manifest, err = decodeManifest(tr); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, i18n.Errorf("unexpected bundle entry %s", header.Name)
+		}
+		dest, err := entryPath(dir, header.Name)
+		if err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			progress(header.Name)
+		}
+		sum, err := writeEntry(tr, dest, os.FileMode(header.Mode).Perm())
+		if err != nil {
+			return nil, err
+		}
+		checksums[header.Name] = sum
+	}
+	if manifest == nil {
+		return nil, i18n.Errorf("%s has no %s", bundlePath, ManifestName)
+	}
+
+	for name, want := range manifest.Checksums {
+		got, ok := checksums[name]
+		if !ok {
+			return nil, i18n.Errorf("bundle entry %s is missing", name)
+		}
+		if !strings.EqualFold(got, want) {
+			return nil, i18n.Errorf("checksum mismatch for bundle entry %s: expected %s got %s", name, want, got)
+		}
+	}
+	for name := range checksums {
+		if _, ok := manifest.Checksums[name]; !ok {
+			return nil, i18n.Errorf("bundle entry %s is not in the manifest", name)
+		}
+	}
+	for _, entry := range manifest.Modules {
+		if entry.Name == "" || entry.Name == "." || entry.Name == ".." || strings.ContainsAny(entry.Name, `/\`) || entry.Path != path.Join("modules", entry.Name) {
+			return nil, i18n.Errorf("invalid module %s in bundle manifest", entry.Name)
+		}
+	}
+	for _, artifact := range manifest.Artifacts {
+		if _, ok := checksums[artifact.Path]; !ok {
+			return nil, i18n.Errorf("bundle entry %s is missing", artifact.Path)
+		}
+	}
+	for _, model := range manifest.Models {
+		if _, err := entryPath(dir, model.Path); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// entryPath validates a bundle path and returns it under dir.
+func entryPath(dir, name string) (string, error) {
+	clean := path.Clean(name)
+	if name == "" || clean == "." || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", i18n.Errorf("invalid bundle entry %s", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(clean)), nil
+}
+
+func writeEntry(r io.Reader, dest string, mode os.FileMode) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return "", err
+	}
+	file, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return "", err
+	}
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, hasher), r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// placeModule copies a module directory from the staging directory into
+// the modules directory, replacing a different version of it. It reports
+// whether anything changed.
+func placeModule(manifest *Manifest, staging, root string, entry Module) (bool, error) {
+	prefix := entry.Path + "/"
+	var files []string
+	for name := range manifest.Checksums {
+		if strings.HasPrefix(name, prefix) {
+			files = append(files, strings.TrimPrefix(name, prefix))
+		}
+	}
+	sort.Strings(files)
+
+	dest := filepath.Join(root, entry.Name)
+	if sameFiles(dest, files, func(rel string) string { return manifest.Checksums[prefix+rel] }) {
+		return false, nil
+	}
+	if err := os.RemoveAll(dest); err != nil {
+		return false, err
+	}
+	src := filepath.Join(staging, filepath.FromSlash(entry.Path))
+	for _, rel := range files {
+		if err := copyFile(filepath.Join(src, filepath.FromSlash(rel)), filepath.Join(dest, filepath.FromSlash(rel))); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// sameFiles reports whether dir holds exactly files, with the checksums
+// digest returns.
+func sameFiles(dir string, files []string, digest func(rel string) string) bool {
+	count := 0
+	err := filepath.WalkDir(dir, func(file string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			count++
+		}
+		return nil
+	})
+	if err != nil || count != len(files) {
+		return false
+	}
+	for _, rel := range files {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return false
+		}
+		sum := sha256.Sum256(data)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), digest(rel)) {
+			return false
+		}
+	}
+	return true
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/bundle"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+)
+
+func RegisterBundleCommands(rootCmd *cobra.Command) {
+	bundleCmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Package modules and models for hosts without network access",
+	}
+
+	var modules, models []string
+	var output string
+	var allowNetwork bool
+	createCmd := &cobra.Command{
+		Use:   "create --modules a,b --models hf:org/model -o bundle.tar",
+		Short: "Package modules, their cacheable downloads and downloaded models into a bundle",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(modules) == 0 && len(models) == 0 {
+				return i18n.Errorf("nothing to bundle: pass --modules or --models")
+			}
+			ctx, cancel := interruptContext(cmd)
+			defer cancel()
+
+			if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+				return err
+			}
+			tmp := output + ".part"
+			file, err := os.Create(tmp)
+			if err != nil {
+				return err
+			}
+			defer os.Remove(tmp)
+			manifest, err := bundle.Create(ctx, file, bundle.CreateOptions{
+				Modules:      modules,
+				ModelRefs:    models,
+				Models:       createModelManager(),
+				AllowNetwork: allowNetwork,
+				Progress: func(path string) {
+					cmd.Printf("%s\n", i18n.T("Adding %s", path))
+				},
+			})
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+			if err := os.Rename(tmp, output); err != nil {
+				return err
+			}
+			cmd.Printf("%s\n", i18n.T("Wrote %s: %d modules, %d artifacts, %d models", output,
+				len(manifest.Modules), len(manifest.Artifacts), len(manifest.Models)))
+			writeNetworkSteps(cmd, manifest.NetworkSteps)
+			return nil
+		},
+	}
+	createCmd.Flags().StringSliceVar(&modules, "modules", nil, "Modules to bundle; their module dependencies are added")
+	createCmd.Flags().StringSliceVar(&models, "models", nil, "Downloaded models to bundle, e.g. hf:Qwen/Qwen2.5-7B-Instruct-GGUF")
+	createCmd.Flags().StringVarP(&output, "output", "o", "", "Bundle file to write")
+	createCmd.Flags().BoolVar(&allowNetwork, "allow-network", false, "Bundle modules whose install steps still need network access")
+	_ = createCmd.MarkFlagRequired("output")
+
+	var ignoreQuota bool
+	installCmd := &cobra.Command{
+		Use:   "install <bundle.tar>",
+		Short: "Install the modules and models of a bundle without network access",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr := createModelManager()
+			cmd.Printf("%s\n", i18n.T("Verifying %s", args[0]))
+			result, err := bundle.Install(args[0], bundle.InstallOptions{
+				Models:      mgr,
+				IgnoreQuota: ignoreQuota,
+			})
+			if err != nil {
+				return err
+			}
+			if len(result.Modules) > 0 {
+				cmd.Printf("%s\n", i18n.T("Updated modules: %s", strings.Join(result.Modules, ", ")))
+			}
+			if result.Artifacts > 0 {
+				cmd.Printf("%s\n", i18n.T("Cached %d artifacts", result.Artifacts))
+			}
+			for _, id := range result.Models {
+				cmd.Printf("%s\n", i18n.T("Imported model %s", id))
+			}
+			for _, id := range result.SkippedModels {
+				cmd.Printf("%s\n", i18n.T("Model %s is already downloaded", id))
+			}
+
+			writeNetworkSteps(cmd, result.Manifest.NetworkSteps)
+
+			manager, err := openStateManager()
+			if err != nil {
+				return err
+			}
+			recorded := manager.GetState().Modules
+			for index, entry := range result.Manifest.Modules {
+				state, ok := recorded[entry.Name]
+				present := ok && state.Present()
+				if present && state.Version == entry.Version {
+					cmd.Printf("%s\n", i18n.T("[%d/%d] module %s %s is already installed", index+1, len(result.Manifest.Modules), entry.Name, entry.Version))
+					continue
+				}
+				cmd.Printf("%s\n", i18n.T("[%d/%d] install module %s %s", index+1, len(result.Manifest.Modules), entry.Name, entry.Version))
+				if present {
+					if err := runModuleAction(cmd, entry.Name, "uninstall", module.Uninstall); err != nil {
+						return i18n.Errorf("failed to uninstall module %s: %w", entry.Name, err)
+					}
+				}
+				if err := runModuleAction(cmd, entry.Name, "install", module.Install); err != nil {
+					return i18n.Errorf("failed to install module %s: %w", entry.Name, err)
+				}
+			}
+			cmd.Println(i18n.T("Bundle installed."))
+			return nil
+		},
+	}
+	installCmd.Flags().BoolVar(&ignoreQuota, "ignore-quota", false, "Import models even when the model quota would be exceeded")
+
+	bundleCmd.AddCommand(createCmd)
+	bundleCmd.AddCommand(installCmd)
+	rootCmd.AddCommand(bundleCmd)
+}
+
+// writeNetworkSteps warns about bundled install steps that need network
+// access.
+func writeNetworkSteps(cmd *cobra.Command, steps []module.NetworkStep) {
+	for _, step := range steps {
+		cmd.Printf("%s\n", i18n.T("warning: %s", bundle.DescribeNetworkStep(step)))
+	}
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -15,11 +16,16 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/client"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/control"
 	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
 	"github.com/zhuangbiaowei/LocalAIStack/internal/llm"
 	"github.com/zhuangbiaowei/LocalAIStack/internal/modelmanager"
 	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/stack"
 	"github.com/zhuangbiaowei/LocalAIStack/internal/system"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/system/info"
 )
 
 func init() {
@@ -38,8 +44,16 @@ func RegisterModuleCommands(rootCmd *cobra.Command) {
 		Short: "Install a module",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+				format, _ := cmd.Flags().GetString("format")
+				plan, err := module.PlanInstall(args[0])
+				if err != nil {
+					return err
+				}
+				return writeModulePlan(cmd, plan, format)
+			}
 			cmd.Printf("%s\n", i18n.T("Installing module: %s", args[0]))
-			if err := module.Install(args[0]); err != nil {
+			if err := runModuleAction(cmd, args[0], "install", module.Install); err != nil {
 				cmd.Printf("%s\n", i18n.T("Module install failed: %s", err))
 				return err
 			}
@@ -53,8 +67,16 @@ func RegisterModuleCommands(rootCmd *cobra.Command) {
 		Short: "Uninstall a module",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+				format, _ := cmd.Flags().GetString("format")
+				plan, err := module.PlanUninstall(args[0])
+				if err != nil {
+					return err
+				}
+				return writeModulePlan(cmd, plan, format)
+			}
 			cmd.Printf("%s\n", i18n.T("Uninstalling module: %s", args[0]))
-			if err := module.Uninstall(args[0]); err != nil {
+			if err := runModuleAction(cmd, args[0], "uninstall", module.Uninstall); err != nil {
 				cmd.Printf("%s\n", i18n.T("Module uninstall failed: %s", err))
 				return err
 			}
@@ -68,8 +90,16 @@ func RegisterModuleCommands(rootCmd *cobra.Command) {
 		Short: "Purge a module",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+				format, _ := cmd.Flags().GetString("format")
+				plan, err := module.PlanPurge(args[0])
+				if err != nil {
+					return err
+				}
+				return writeModulePlan(cmd, plan, format)
+			}
 			cmd.Printf("%s\n", i18n.T("Purging module: %s", args[0]))
-			if err := module.Purge(args[0]); err != nil {
+			if err := runModuleAction(cmd, args[0], "purge", module.Purge); err != nil {
 				cmd.Printf("%s\n", i18n.T("Module purge failed: %s", err))
 				return err
 			}
@@ -78,6 +108,11 @@ func RegisterModuleCommands(rootCmd *cobra.Command) {
 		},
 	}
 
+	for _, planCmd := range []*cobra.Command{installCmd, uninstallCmd, purgeCmd} {
+		planCmd.Flags().Bool("dry-run", false, "Print the execution plan without running it")
+		planCmd.Flags().String("format", "text", "Dry-run output format (text or json)")
+	}
+
 	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all available modules",
@@ -133,7 +168,7 @@ func RegisterModuleCommands(rootCmd *cobra.Command) {
 	settingCmd := &cobra.Command{
 		Use:   "setting [module-name] [setting-args...]",
 		Short: "Run module-specific settings",
-		Args:  cobra.MinimumNArgs(2),
+		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			name := args[0]
 			settingArgs := args[1:]
@@ -147,50 +182,339 @@ func RegisterModuleCommands(rootCmd *cobra.Command) {
 		},
 	}
 
+	configCmd := &cobra.Command{
+		Use:   "config [module-name] get|set|unset [key[=value]...]",
+		Short: "Show or change module configuration",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, action, rest := args[0], strings.ToLower(args[1]), args[2:]
+			cmd.SilenceUsage = true
+			restart, _ := cmd.Flags().GetBool("restart")
+			opts := module.ConfigApplyOptions{Restart: restart}
+
+			var (
+				result module.ConfigApplyResult
+				err    error
+			)
+			switch action {
+			case "get":
+				values, err := module.GetConfig(name)
+				if err != nil {
+					return err
+				}
+				return writeModuleConfig(cmd, values, rest)
+			case "set":
+				values := make(map[string]string, len(rest))
+				for _, arg := range rest {
+					key, value, ok := strings.Cut(arg, "=")
+					if !ok || strings.TrimSpace(key) == "" {
+						return i18n.Errorf("invalid argument %q, expected key=value", arg)
+					}
+					values[strings.TrimSpace(key)] = value
+				}
+				result, err = module.SetConfig(name, values, opts)
+			case "unset":
+				result, err = module.UnsetConfig(name, rest, opts)
+			default:
+				return i18n.Errorf("unknown config action %q (expected get, set or unset)", action)
+			}
+			if err != nil {
+				return err
+			}
+			writeConfigApplyResult(cmd, name, result)
+			return nil
+		},
+	}
+	configCmd.Flags().Bool("restart", false, "Restart the module service after applying the change")
+
+	lintCmd := &cobra.Command{
+		Use:   "lint [dir]",
+		Short: "Validate module manifest.yaml and INSTALL.yaml",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			dir := ""
+			if len(args) > 0 {
+				dir = args[0]
+			} else {
+				modulesRoot, err := module.FindModulesRoot()
+				if err != nil {
+					return err
+				}
+				dir = modulesRoot
+			}
+
+			reports, err := module.LintModules(dir)
+			if err != nil {
+				return err
+			}
+			if err := writeLintReports(cmd, reports, format); err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, report := range reports {
+				if report.HasErrors() {
+					failed++
+				}
+			}
+			if failed > 0 {
+				cmd.SilenceUsage = true
+				return i18n.Errorf("%d module(s) failed lint", failed)
+			}
+			return nil
+		},
+	}
+	lintCmd.Flags().String("format", "text", "Output format (text or json)")
+
+	newCmd := &cobra.Command{
+		Use:   "new [module-name]",
+		Short: "Create a new module skeleton",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			category, _ := cmd.Flags().GetString("category")
+			kind, _ := cmd.Flags().GetString("kind")
+			description, _ := cmd.Flags().GetString("description")
+			dir, _ := cmd.Flags().GetString("dir")
+			if dir == "" {
+				modulesRoot, err := module.FindModulesRoot()
+				if err != nil {
+					modulesRoot = "modules"
+				}
+				dir = modulesRoot
+			}
+
+			moduleDir, err := module.Scaffold(module.ScaffoldOptions{
+				Name:        args[0],
+				Category:    module.Category(strings.ToLower(strings.TrimSpace(category))),
+				Kind:        strings.ToLower(strings.TrimSpace(kind)),
+				Description: description,
+				Dir:         dir,
+			})
+			if err != nil {
+				return err
+			}
+			cmd.Printf("%s\n", i18n.T("Module created: %s", moduleDir))
+			return writeLintReports(cmd, []module.LintReport{module.LintModuleDir(moduleDir)}, "text")
+		},
+	}
+	newCmd.Flags().String("category", string(module.CategoryRuntime), "Module category")
+	newCmd.Flags().String("kind", module.ScaffoldKindService, "Module kind (service, binary or pip)")
+	newCmd.Flags().String("description", "", "Module description")
+	newCmd.Flags().String("dir", "", "Modules directory (defaults to ./modules)")
+
 	moduleCmd.AddCommand(installCmd)
 	moduleCmd.AddCommand(uninstallCmd)
 	moduleCmd.AddCommand(purgeCmd)
 	moduleCmd.AddCommand(listCmd)
 	moduleCmd.AddCommand(checkCmd)
 	moduleCmd.AddCommand(settingCmd)
+	moduleCmd.AddCommand(configCmd)
+	moduleCmd.AddCommand(lintCmd)
+	moduleCmd.AddCommand(newCmd)
 	rootCmd.AddCommand(moduleCmd)
 }
 
+func writeModuleConfig(cmd *cobra.Command, values []module.ConfigValue, keys []string) error {
+	if len(keys) > 0 {
+		byKey := make(map[string]module.ConfigValue, len(values))
+		for _, value := range values {
+			byKey[value.Key] = value
+		}
+		for _, key := range keys {
+			value, ok := byKey[key]
+			if !ok {
+				return i18n.Errorf("unknown configuration key %q", key)
+			}
+			cmd.Println(value.Value)
+		}
+		return nil
+	}
+
+	if len(values) == 0 {
+		cmd.Println(i18n.T("Module has no configuration keys."))
+		return nil
+	}
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, i18n.T("KEY\tVALUE\tTYPE\tSOURCE"))
+	for _, value := range values {
+		source := i18n.T("default")
+		if value.Overridden {
+			source = i18n.T("set")
+		}
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", value.Key, value.Value, value.Type, source)
+	}
+	return writer.Flush()
+}
+
+func writeConfigApplyResult(cmd *cobra.Command, name string, result module.ConfigApplyResult) {
+	cmd.Printf("%s\n", i18n.T("Configuration for %s saved.", name))
+	if len(result.Applied) > 0 {
+		cmd.Printf("%s\n", i18n.T("Applied: %s", strings.Join(result.Applied, ", ")))
+	}
+	if !result.Installed {
+		cmd.Println(i18n.T("Module is not installed; the values apply on the next install."))
+		return
+	}
+	if len(result.Rendered) > 0 {
+		cmd.Printf("%s\n", i18n.T("Re-ran steps: %s", strings.Join(result.Rendered, ", ")))
+	}
+	if result.Restarted != "" {
+		cmd.Printf("%s\n", i18n.T("Restarted service: %s", result.Restarted))
+	} else if len(result.Rendered) > 0 {
+		cmd.Println(i18n.T("Restart the service (or pass --restart) for running processes to pick up the change."))
+	}
+}
+
+func writeLintReports(cmd *cobra.Command, reports []module.LintReport, format string) error {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "json":
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	case "", "text":
+		for _, report := range reports {
+			if len(report.Issues) == 0 {
+				cmd.Printf("%s\n", i18n.T("%s: ok", report.Module))
+				continue
+			}
+			for _, issue := range report.Issues {
+				location := issue.File
+				if issue.Field != "" {
+					location += ":" + issue.Field
+				}
+				cmd.Printf("%s: %s: %s: %s\n", report.Module, issue.Severity, location, issue.Message)
+			}
+		}
+		return nil
+	default:
+		return i18n.Errorf("unsupported output format %q", format)
+	}
+}
+
+func writeModulePlan(cmd *cobra.Command, plan module.Plan, format string) error {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "json":
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plan)
+	case "", "text":
+	default:
+		return i18n.Errorf("unsupported output format %q", format)
+	}
+
+	cmd.Printf("%s\n", i18n.T("Plan for %s %s (dry run, nothing will be executed)", plan.Action, plan.Module))
+	cmd.Printf("%s\n", i18n.T("Module directory: %s", plan.Dir))
+	if plan.Mode != "" {
+		cmd.Printf("%s\n", i18n.T("Mode: %s", plan.Mode))
+	}
+	if len(plan.Env) > 0 {
+		cmd.Println(i18n.T("Environment:"))
+		for _, key := range sortedKeys(plan.Env) {
+			cmd.Printf("  %s=%s\n", key, plan.Env[key])
+		}
+	}
+	if len(plan.Variables) > 0 {
+		cmd.Println(i18n.T("Variables:"))
+		for _, key := range sortedKeys(plan.Variables) {
+			cmd.Printf("  %s=%s\n", key, plan.Variables[key])
+		}
+	}
+	if len(plan.Preconditions) > 0 {
+		cmd.Println(i18n.T("Preconditions:"))
+		for _, pre := range plan.Preconditions {
+			status := i18n.T("pass")
+			if !pre.Passed {
+				status = i18n.T("fail")
+			}
+			cmd.Printf("  [%s] %s: %s\n", status, pre.ID, pre.Command)
+			if pre.Error != "" {
+				cmd.Printf("        %s\n", pre.Error)
+			}
+		}
+	}
+	cmd.Println(i18n.T("Steps:"))
+	for _, step := range plan.Steps {
+		cmd.Printf("  %s (%s)", step.ID, step.Tool)
+		if step.Intent != "" {
+			cmd.Printf(" %s", step.Intent)
+		}
+		cmd.Println()
+		if step.Command != "" {
+			cmd.Printf("      $ %s\n", step.Command)
+		}
+		if step.Template != "" {
+			cmd.Printf("      %s\n", i18n.T("render %s -> %s", step.Template, step.Destination))
+		}
+		for _, key := range sortedKeys(step.Details) {
+			cmd.Printf("      %s: %s\n", key, step.Details[key])
+		}
+		for _, key := range sortedKeys(step.Expected) {
+			cmd.Printf("      %s\n", i18n.T("expect %s: %s", key, step.Expected[key]))
+		}
+		if len(step.Undefined) > 0 {
+			cmd.Printf("      %s\n", i18n.T("warning: undefined variables render empty: %s", strings.Join(step.Undefined, ", ")))
+		}
+	}
+	if len(plan.Preserves) > 0 {
+		cmd.Println(i18n.T("Preserves:"))
+		for _, path := range plan.Preserves {
+			cmd.Printf("  %s\n", path)
+		}
+	}
+	if len(plan.Removes) > 0 {
+		cmd.Println(i18n.T("Removes:"))
+		for _, path := range plan.Removes {
+			cmd.Printf("  %s\n", path)
+		}
+	}
+	return nil
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func RegisterServiceCommands(rootCmd *cobra.Command) {
 	serviceCmd := &cobra.Command{
 		Use:   "service",
 		Short: "Manage services",
 	}
 
-	startCmd := &cobra.Command{
-		Use:   "start [service-name]",
-		Short: "Start a service",
-		Args:  cobra.ExactArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			cmd.Printf("%s\n", i18n.T("Starting service: %s", args[0]))
-		},
-	}
-
-	stopCmd := &cobra.Command{
-		Use:   "stop [service-name]",
-		Short: "Stop a service",
-		Args:  cobra.ExactArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			cmd.Printf("%s\n", i18n.T("Stopping service: %s", args[0]))
-		},
+	for _, action := range []struct {
+		name  string
+		short string
+		intro string
+	}{
+		{"start", "Start a service", "Starting service: %s"},
+		{"stop", "Stop a service", "Stopping service: %s"},
+		{"restart", "Restart a service", "Restarting service: %s"},
+	} {
+		serviceCmd.AddCommand(&cobra.Command{
+			Use:   action.name + " [service-name]",
+			Short: action.short,
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				cmd.Printf("%s\n", i18n.T(action.intro, args[0]))
+				return controlService(cmd, args[0], action.name)
+			},
+		})
 	}
 
 	statusCmd := &cobra.Command{
 		Use:   "status [service-name]",
 		Short: "Get service status",
 		Args:  cobra.ExactArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			cmd.Printf("%s\n", i18n.T("Service status: %s", args[0]))
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printServiceStatus(cmd, args[0])
 		},
 	}
 
-	serviceCmd.AddCommand(startCmd)
-	serviceCmd.AddCommand(stopCmd)
 	serviceCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(serviceCmd)
 }
@@ -214,15 +538,9 @@ func RegisterModelCommands(rootCmd *cobra.Command) {
 
 			if source != "" && source != "all" {
 				var src modelmanager.ModelSource
-				switch strings.ToLower(source) {
-				case "ollama":
-					src = modelmanager.SourceOllama
-				case "huggingface", "hf":
-					src = modelmanager.SourceHuggingFace
-				case "modelscope":
-					src = modelmanager.SourceModelScope
-				default:
-					return fmt.Errorf("unknown source: %s", source)
+				var err error
+				if src, err = modelmanager.ParseSource(source); err != nil {
+					return err
 				}
 
 				provider, err := mgr.GetProvider(src)
@@ -250,7 +568,7 @@ func RegisterModelCommands(rootCmd *cobra.Command) {
 			return nil
 		},
 	}
-	searchCmd.Flags().StringP("source", "s", "all", "Source to search (ollama, huggingface, modelscope, or all)")
+	searchCmd.Flags().StringP("source", "s", "all", "Source to search (ollama, huggingface, modelscope, local, url, oci, or all)")
 	searchCmd.Flags().IntP("limit", "n", 10, "Maximum number of results per source")
 
 	downloadCmd := &cobra.Command{
@@ -276,15 +594,9 @@ func RegisterModelCommands(rootCmd *cobra.Command) {
 
 			var src modelmanager.ModelSource
 			if source != "" {
-				switch strings.ToLower(source) {
-				case "ollama":
-					src = modelmanager.SourceOllama
-				case "huggingface", "hf":
-					src = modelmanager.SourceHuggingFace
-				case "modelscope":
-					src = modelmanager.SourceModelScope
-				default:
-					return fmt.Errorf("unknown source: %s", source)
+				var err error
+				if src, err = modelmanager.ParseSource(source); err != nil {
+					return err
 				}
 			} else {
 				var err error
@@ -294,17 +606,28 @@ func RegisterModelCommands(rootCmd *cobra.Command) {
 				}
 			}
 
-			cmd.Printf("Downloading model from %s: %s\n", src, modelID)
-
-			progress := func(downloaded, total int64) {
-				if total > 0 {
-					percent := float64(downloaded) * 100 / float64(total)
-					cmd.Printf("\rProgress: %.1f%% (%s / %s)", percent,
-						modelmanager.FormatBytes(downloaded), modelmanager.FormatBytes(total))
+			ignoreQuota, _ := cmd.Flags().GetBool("ignore-quota")
+			opts := modelmanager.DownloadOptions{FileHint: fileHint, IgnoreQuota: ignoreQuota}
+			if queue, _ := cmd.Flags().GetBool("queue"); queue {
+				priority, _ := cmd.Flags().GetInt("priority")
+				return queueModelDownload(cmd, src, modelID, opts, priority)
+			}
+			if quota, err := modelQuota(); err != nil {
+				cmd.PrintErrf("%s\n", i18n.T("warning: model quota not applied: %v", err))
+			} else if !quota.IsZero() {
+				status, err := mgr.CheckQuota(cmd.Context(), src, modelID, opts)
+				switch {
+				case err != nil || !status.SizeKnown:
+					cmd.PrintErrf("%s\n", i18n.T("warning: the download size is unknown; the model quota (%s) is not checked", quota))
+				case status.Exceeded() && ignoreQuota:
+					cmd.PrintErrf("%s\n", i18n.T("warning: the download (%s) exceeds the model quota: %s of %s used",
+						modelmanager.FormatBytes(status.Required), modelmanager.FormatBytes(status.Used), modelmanager.FormatBytes(status.Limit)))
 				}
 			}
 
-			if err := mgr.DownloadModel(src, modelID, progress, modelmanager.DownloadOptions{FileHint: fileHint}); err != nil {
+			cmd.Printf("Downloading model from %s: %s\n", src, modelID)
+
+			if err := mgr.DownloadModel(src, modelID, downloadProgressPrinter(cmd), opts); err != nil {
 				return fmt.Errorf("failed to download model: %w", err)
 			}
 
@@ -312,8 +635,11 @@ func RegisterModelCommands(rootCmd *cobra.Command) {
 			return nil
 		},
 	}
-	downloadCmd.Flags().StringP("source", "s", "", "Source to download from (ollama, huggingface, modelscope)")
+	downloadCmd.Flags().StringP("source", "s", "", "Source to download from (ollama, huggingface, modelscope, url, oci)")
 	downloadCmd.Flags().StringP("file", "f", "", "Specific model file to download (e.g. Q4_K_M.gguf)")
+	downloadCmd.Flags().Bool("ignore-quota", false, "Download even if the model quota would be exceeded")
+	downloadCmd.Flags().Bool("queue", false, "Add the download to the download queue and return")
+	downloadCmd.Flags().Int("priority", 0, "Priority of a queued download; higher runs first")
 
 	listCmd := &cobra.Command{
 		Use:   "list",
@@ -347,42 +673,46 @@ func RegisterModelCommands(rootCmd *cobra.Command) {
 		},
 	}
 
+	importCmd := &cobra.Command{
+		Use:   "import <path>",
+		Short: "Register a GGUF file or model directory already on disk",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			modelID, _ := cmd.Flags().GetString("id")
+			ignoreQuota, _ := cmd.Flags().GetBool("ignore-quota")
+			opts := modelmanager.ImportOptions{Mode: modelmanager.ImportCopy, IgnoreQuota: ignoreQuota}
+			for _, mode := range []modelmanager.ImportMode{modelmanager.ImportLink, modelmanager.ImportMove} {
+				if set, _ := cmd.Flags().GetBool(string(mode)); set {
+					opts.Mode = mode
+				}
+			}
+
+			model, err := createModelManager().ImportModel(args[0], modelID, opts)
+			if err != nil {
+				return fmt.Errorf("failed to import model: %w", err)
+			}
+			cmd.Printf("Imported %s model %s (%s) into %s\n", model.Format, model.ID,
+				modelmanager.FormatBytes(model.Size), model.LocalPath)
+			cmd.Printf("Run it with: las model run %s\n", model.ID)
+			return nil
+		},
+	}
+	importCmd.Flags().String("id", "", "Model ID to register the model under (e.g. myorg/my-model)")
+	importCmd.Flags().Bool("copy", false, "Copy the model files (default)")
+	importCmd.Flags().Bool("link", false, "Symlink the model files instead of copying them")
+	importCmd.Flags().Bool("move", false, "Move the model files into the model directory")
+	importCmd.Flags().Bool("ignore-quota", false, "Import even if the model quota would be exceeded")
+	_ = importCmd.MarkFlagRequired("id")
+	importCmd.MarkFlagsMutuallyExclusive("copy", "link", "move")
+
 	runCmd := &cobra.Command{
 		Use:   "run [model-id]",
 		Short: "Run a local model",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			modelID := args[0]
-			source, _ := cmd.Flags().GetString("source")
-			selectedFile, _ := cmd.Flags().GetString("file")
-			threads, _ := cmd.Flags().GetInt("threads")
-			ctxSize, _ := cmd.Flags().GetInt("ctx-size")
-			gpuLayers, _ := cmd.Flags().GetInt("n-gpu-layers")
-			host, _ := cmd.Flags().GetString("host")
-			port, _ := cmd.Flags().GetInt("port")
-			vllmMaxModelLen, _ := cmd.Flags().GetInt("vllm-max-model-len")
-			vllmGpuMemUtil, _ := cmd.Flags().GetFloat64("vllm-gpu-memory-utilization")
-
-			mgr := createModelManager()
-
-			var src modelmanager.ModelSource
-			if source != "" {
-				switch strings.ToLower(source) {
-				case "ollama":
-					src = modelmanager.SourceOllama
-				case "huggingface", "hf":
-					src = modelmanager.SourceHuggingFace
-				case "modelscope":
-					src = modelmanager.SourceModelScope
-				default:
-					return fmt.Errorf("unknown source: %s", source)
-				}
-			} else {
-				var err error
-				src, modelID, err = modelmanager.ParseModelID(modelID)
-				if err != nil {
-					return err
-				}
+			src, modelID, err := resolveRunModelRef(cmd, args[0])
+			if err != nil {
+				return err
 			}
 
 			if src == modelmanager.SourceOllama {
@@ -398,128 +728,60 @@ func RegisterModelCommands(rootCmd *cobra.Command) {
 				return runCmd.Run()
 			}
 
-			modelDir, err := mgr.ResolveLocalModelDir(src, modelID)
-			if err != nil {
-				return fmt.Errorf("local model not found: %w", err)
-			}
-
-			safetensorsFiles, err := modelmanager.FindSafetensorsFiles(modelDir)
+			runCmd, err := modelServerCommand(cmd, src, modelID, modelServerOptionsFromFlags(cmd))
 			if err != nil {
 				return err
 			}
-			ggufFiles, err := modelmanager.FindGGUFFiles(modelDir)
+			runCmd.Stdout = cmd.OutOrStdout()
+			runCmd.Stderr = cmd.ErrOrStderr()
+			runCmd.Stdin = cmd.InOrStdin()
+			return runCmd.Run()
+		},
+	}
+	addModelServerFlags(runCmd)
+
+	deployCmd := &cobra.Command{
+		Use:   "deploy [model-id]",
+		Short: "Serve a local model in the background under the LocalAIStack server",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, modelID, err := resolveRunModelRef(cmd, args[0])
 			if err != nil {
 				return err
 			}
-			if len(safetensorsFiles) == 0 && len(ggufFiles) == 0 {
-				return fmt.Errorf("no supported model files found for %s", modelID)
+			if src == modelmanager.SourceOllama {
+				return fmt.Errorf("ollama models are served by the ollama service; use `las model run %s`", modelID)
 			}
-
-			baseInfoPath := resolveBaseInfoPath()
-			baseInfo, err := system.LoadBaseInfoSummary(baseInfoPath)
+			runCmd, err := modelServerCommand(cmd, src, modelID, modelServerOptionsFromFlags(cmd))
 			if err != nil {
-				return fmt.Errorf("failed to read base info at %s (try `./build/las system init`): %w", baseInfoPath, err)
+				return err
 			}
 
-			if len(safetensorsFiles) > 0 {
-				modelRef := modelDir
-				if !hasVLLMConfig(modelDir) {
-					meta, err := readModelMetadata(modelDir)
-					if err != nil {
-						return fmt.Errorf("vLLM requires a local config.json/params.json or a known repo id: %w", err)
-					}
-					if meta.ID == "" {
-						return fmt.Errorf("metadata.json missing model id")
-					}
-					modelRef = meta.ID
-				}
-				vllmPath, err := exec.LookPath("vllm")
-				if err != nil {
-					return fmt.Errorf("vllm not found in PATH (install the vllm module first)")
-				}
-				vllmDefaults := defaultVLLMRunParams(baseInfo)
-				if vllmMaxModelLen > 0 {
-					vllmDefaults.maxModelLen = vllmMaxModelLen
-				}
-				if vllmGpuMemUtil > 0 {
-					vllmDefaults.gpuMemUtil = vllmGpuMemUtil
-				}
-				cmd.Printf("Starting vLLM server for %s\n", modelID)
-				args := []string{"serve", modelRef, "--host", host, "--port", strconv.Itoa(port)}
-				if vllmDefaults.maxModelLen > 0 {
-					args = append(args, "--max-model-len", strconv.Itoa(vllmDefaults.maxModelLen))
-				}
-				if vllmDefaults.gpuMemUtil > 0 {
-					args = append(args, "--gpu-memory-utilization", fmt.Sprintf("%.2f", vllmDefaults.gpuMemUtil))
-				}
-				runCmd := exec.CommandContext(cmd.Context(), vllmPath, args...)
+			c := connectServer()
+			if c == nil {
+				cmd.Printf("%s\n", i18n.T("No LocalAIStack server is running; serving the model in the foreground."))
 				runCmd.Stdout = cmd.OutOrStdout()
 				runCmd.Stderr = cmd.ErrOrStderr()
 				runCmd.Stdin = cmd.InOrStdin()
 				return runCmd.Run()
 			}
 
-			modelPath, autoSelected, err := resolveGGUFFile(modelDir, ggufFiles, selectedFile)
-			if err != nil {
-				return err
-			}
-			if autoSelected && len(ggufFiles) > 1 {
-				cmd.Printf("Auto-selected GGUF file: %s\n", filepath.Base(modelPath))
-			}
-
-			defaults := defaultLlamaRunParams(baseInfo)
-			defaults = autoTuneRunParams(defaults, baseInfo, modelPath)
-			if threads > 0 {
-				defaults.threads = threads
-			}
-			if ctxSize > 0 {
-				defaults.ctxSize = ctxSize
+			name, _ := cmd.Flags().GetString("name")
+			if name == "" {
+				name = stack.ServiceName(modelID)
 			}
-			if gpuLayers >= 0 {
-				defaults.gpuLayers = gpuLayers
-			}
-			if tensorSplit, _ := cmd.Flags().GetString("tensor-split"); tensorSplit != "" {
-				defaults.tensorSplit = tensorSplit
-			}
-
-			llamaPath, err := exec.LookPath("llama-server")
+			port, _ := cmd.Flags().GetInt("port")
+			service, err := c.StartProcess(cmd.Context(), deployProcessSpec(name, runCmd, port))
 			if err != nil {
-				return fmt.Errorf("llama-server not found in PATH (install the llama.cpp module first)")
-			}
-
-			argsList := []string{
-				"--model", modelPath,
-				"--threads", strconv.Itoa(defaults.threads),
-				"--ctx-size", strconv.Itoa(defaults.ctxSize),
-				"--n-gpu-layers", strconv.Itoa(defaults.gpuLayers),
-				"--host", host,
-				"--port", strconv.Itoa(port),
-			}
-			if defaults.tensorSplit != "" {
-				argsList = append(argsList, "--tensor-split", defaults.tensorSplit)
-			}
-
-			cmd.Printf("Starting llama.cpp server for %s\n", filepath.Base(modelPath))
-			runCmd := exec.CommandContext(cmd.Context(), llamaPath, argsList...)
-			if err := addLlamaCppLibraryPath(runCmd); err != nil {
 				return err
 			}
-			runCmd.Stdout = cmd.OutOrStdout()
-			runCmd.Stderr = cmd.ErrOrStderr()
-			runCmd.Stdin = cmd.InOrStdin()
-			return runCmd.Run()
+			cmd.Printf("%s\n", i18n.T("Deployed %s as service %s (PID %d)", modelID, service.Name, service.PID))
+			cmd.Printf("%s\n", i18n.T("Follow it with `las service status %s`; stop it with `las service stop %s`", service.Name, service.Name))
+			return nil
 		},
 	}
-	runCmd.Flags().StringP("source", "s", "", "Source of the model (ollama, huggingface, modelscope)")
-	runCmd.Flags().StringP("file", "f", "", "Specific GGUF filename to run")
-	runCmd.Flags().Int("threads", 0, "CPU threads for llama.cpp (0 = auto)")
-	runCmd.Flags().Int("ctx-size", 0, "Context size for llama.cpp (0 = auto)")
-	runCmd.Flags().Int("n-gpu-layers", -1, "GPU layers for llama.cpp (-1 = auto)")
-	runCmd.Flags().String("tensor-split", "", "Tensor split for multi-GPU (comma-separated percentages)")
-	runCmd.Flags().String("host", "0.0.0.0", "Host to bind llama.cpp server")
-	runCmd.Flags().Int("port", 8080, "Port to bind llama.cpp server")
-	runCmd.Flags().Int("vllm-max-model-len", 0, "vLLM max model length (safetensors only)")
-	runCmd.Flags().Float64("vllm-gpu-memory-utilization", 0, "vLLM GPU memory utilization (0-1, safetensors only)")
+	addModelServerFlags(deployCmd)
+	deployCmd.Flags().String("name", "", "Service name (default: derived from the model ID)")
 
 	rmCmd := &cobra.Command{
 		Use:   "rm [model-id]",
@@ -539,15 +801,9 @@ func RegisterModelCommands(rootCmd *cobra.Command) {
 
 			var src modelmanager.ModelSource
 			if source != "" {
-				switch strings.ToLower(source) {
-				case "ollama":
-					src = modelmanager.SourceOllama
-				case "huggingface", "hf":
-					src = modelmanager.SourceHuggingFace
-				case "modelscope":
-					src = modelmanager.SourceModelScope
-				default:
-					return fmt.Errorf("unknown source: %s", source)
+				var err error
+				if src, err = modelmanager.ParseSource(source); err != nil {
+					return err
 				}
 			} else {
 				var err error
@@ -566,7 +822,82 @@ func RegisterModelCommands(rootCmd *cobra.Command) {
 		},
 	}
 	rmCmd.Flags().BoolP("force", "f", false, "Force removal without confirmation")
-	rmCmd.Flags().StringP("source", "s", "", "Source of the model (ollama, huggingface, modelscope)")
+	rmCmd.Flags().StringP("source", "s", "", "Source of the model (ollama, huggingface, modelscope, local, url, oci)")
+
+	gcCmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove model blobs no downloaded model references",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			result, err := createModelManager().CollectGarbage(dryRun)
+			if err != nil {
+				return err
+			}
+			for _, digest := range result.Removed {
+				cmd.Printf("sha256:%s\n", digest)
+			}
+			switch {
+			case len(result.Removed) == 0:
+				cmd.Println("No unreferenced blobs.")
+			case dryRun:
+				cmd.Printf("Would remove %d blob(s), freeing %s.\n", len(result.Removed), modelmanager.FormatBytes(result.FreedBytes))
+			default:
+				cmd.Printf("Removed %d blob(s), freed %s.\n", len(result.Removed), modelmanager.FormatBytes(result.FreedBytes))
+			}
+			return nil
+		},
+	}
+	gcCmd.Flags().Bool("dry-run", false, "Only list the blobs that would be removed")
+
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove the least recently used models",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keepRecent, _ := cmd.Flags().GetInt("keep-recent")
+			maxSizeRaw, _ := cmd.Flags().GetString("max-size")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			yes, _ := cmd.Flags().GetBool("yes")
+			opts := modelmanager.PruneOptions{KeepRecent: keepRecent, DryRun: true}
+			if maxSizeRaw != "" {
+				maxSize, err := modelmanager.ParseSize(maxSizeRaw)
+				if err != nil {
+					return err
+				}
+				opts.MaxSize = maxSize
+			}
+
+			mgr := createModelManager()
+			plan, err := mgr.Prune(opts)
+			if err != nil {
+				return err
+			}
+			if len(plan.Removed) == 0 {
+				cmd.Println("Nothing to prune.")
+				return nil
+			}
+			writePruneResult(cmd, plan)
+			if dryRun {
+				return nil
+			}
+			if !yes && !confirm(cmd, fmt.Sprintf("Remove %d model(s)?", len(plan.Removed))) {
+				return fmt.Errorf("prune aborted")
+			}
+			opts.DryRun = false
+			result, err := mgr.Prune(opts)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Removed %d model(s), freed %s; %s used.\n", len(result.Removed),
+				modelmanager.FormatBytes(result.FreedBytes), modelmanager.FormatBytes(result.UsedBytes))
+			return nil
+		},
+	}
+	pruneCmd.Flags().Int("keep-recent", 0, "Always keep this many most recently used models")
+	pruneCmd.Flags().String("max-size", "", "Remove models until the model directory is within this size (e.g. 200GB)")
+	pruneCmd.Flags().Bool("dry-run", false, "Only list the models that would be removed")
+	pruneCmd.Flags().BoolP("yes", "y", false, "Remove without asking for confirmation")
 
 	repairCmd := &cobra.Command{
 		Use:     "repair [model-id]",
@@ -580,17 +911,11 @@ func RegisterModelCommands(rootCmd *cobra.Command) {
 			mgr := createModelManager()
 
 			var src modelmanager.ModelSource
-			explicitSource := source != "" || hasExplicitSource(modelID)
+			explicitSource := source != "" || modelmanager.HasSourcePrefix(modelID)
 			if source != "" {
-				switch strings.ToLower(source) {
-				case "ollama":
-					src = modelmanager.SourceOllama
-				case "huggingface", "hf":
-					src = modelmanager.SourceHuggingFace
-				case "modelscope":
-					src = modelmanager.SourceModelScope
-				default:
-					return fmt.Errorf("unknown source: %s", source)
+				var err error
+				if src, err = modelmanager.ParseSource(source); err != nil {
+					return err
 				}
 			} else {
 				var err error
@@ -600,72 +925,519 @@ func RegisterModelCommands(rootCmd *cobra.Command) {
 				}
 			}
 
+			err := mgr.RepairModel(cmd.Context(), src, modelID, explicitSource)
+			if errors.Is(err, modelmanager.ErrRepairNotRequired) {
+				cmd.Println("Ollama models do not require tokenizer/config repair.")
+				return nil
+			}
+			return err
+		},
+	}
+	repairCmd.Flags().StringP("source", "s", "", "Source of the model (ollama, huggingface, modelscope, local, url, oci)")
+
+	infoCmd := &cobra.Command{
+		Use:   "info [model-id]",
+		Short: "Show a downloaded model's metadata",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, modelID, err := resolveRunModelRef(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			mgr := createModelManager()
 			modelDir, err := mgr.ResolveLocalModelDir(src, modelID)
 			if err != nil {
-				return fmt.Errorf("local model not found: %w", err)
+				return err
 			}
+			size, _ := modelmanager.PathSize(modelDir)
 
-			if !explicitSource {
-				if meta, err := readModelMetadata(modelDir); err == nil && meta.ID != "" {
-					if meta.Source != "" {
-						src = modelmanager.ModelSource(meta.Source)
-					}
-					modelID = meta.ID
+			writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintf(writer, "ID:\t%s\n", modelID)
+			fmt.Fprintf(writer, "Source:\t%s\n", src)
+			fmt.Fprintf(writer, "Path:\t%s\n", modelDir)
+			fmt.Fprintf(writer, "Size:\t%s\n", modelmanager.FormatBytes(size))
+			writer.Flush()
+
+			infos, err := modelmanager.ModelGGUFInfo(modelDir)
+			if err != nil {
+				return err
+			}
+			for _, info := range infos {
+				cmd.Println()
+				writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintf(writer, "File:\t%s (%s, GGUF v%d)\n", info.File, modelmanager.FormatBytes(info.Size), info.Version)
+				if info.Name != "" {
+					fmt.Fprintf(writer, "Name:\t%s\n", info.Name)
+				}
+				fmt.Fprintf(writer, "Architecture:\t%s\n", info.Architecture)
+				fmt.Fprintf(writer, "Parameters:\t%s (%d tensors)\n", modelmanager.FormatParameters(info.ParameterCount), info.TensorCount)
+				fmt.Fprintf(writer, "Quantization:\t%s\n", info.Quantization)
+				fmt.Fprintf(writer, "Context length:\t%d\n", info.ContextLength)
+				fmt.Fprintf(writer, "Layers:\t%d\n", info.BlockCount)
+				fmt.Fprintf(writer, "Embedding length:\t%d\n", info.EmbeddingLength)
+				fmt.Fprintf(writer, "Attention heads:\t%d (KV %d)\n", info.HeadCount, info.HeadCountKV)
+				if info.SplitCount > 1 {
+					fmt.Fprintf(writer, "Splits:\t%d\n", info.SplitCount)
 				}
+				writer.Flush()
 			}
 
-			switch src {
-			case modelmanager.SourceOllama:
-				cmd.Println("Ollama models do not require tokenizer/config repair.")
+			safetensorsFiles, err := modelmanager.FindSafetensorsFiles(modelDir)
+			if err != nil || len(safetensorsFiles) == 0 {
+				return err
+			}
+			if _, err := os.Stat(filepath.Join(modelDir, "config.json")); err != nil {
 				return nil
-			case modelmanager.SourceHuggingFace:
-				provider, err := mgr.GetProvider(src)
-				if err != nil {
-					return err
-				}
-				hf, ok := provider.(*modelmanager.HuggingFaceProvider)
-				if !ok {
-					return fmt.Errorf("huggingface provider not available")
-				}
-				return hf.DownloadSupportFiles(cmd.Context(), modelID, mgr.GetModelDir())
-			case modelmanager.SourceModelScope:
-				provider, err := mgr.GetProvider(src)
-				if err != nil {
-					return err
-				}
-				ms, ok := provider.(*modelmanager.ModelScopeProvider)
-				if !ok {
-					return fmt.Errorf("modelscope provider not available")
-				}
-				return ms.DownloadSupportFiles(cmd.Context(), modelID, mgr.GetModelDir())
-			default:
-				return fmt.Errorf("unsupported model source: %s", src)
 			}
+			model, err := modelmanager.ReadSafetensorsModel(modelDir)
+			if err != nil {
+				return err
+			}
+			config := model.Config
+			cmd.Println()
+			writer = tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintf(writer, "Safetensors:\t%d file(s), %s of weights\n", model.FileCount, modelmanager.FormatBytes(int64(model.WeightBytes)))
+			fmt.Fprintf(writer, "Architecture:\t%s\n", strings.Join(config.Architectures, ", "))
+			fmt.Fprintf(writer, "Parameters:\t%s\n", modelmanager.FormatParameters(model.ParameterCount))
+			fmt.Fprintf(writer, "Dtype:\t%s\n", config.TorchDtype)
+			if quantization := model.Quantization(); quantization != "" {
+				fmt.Fprintf(writer, "Quantization:\t%s\n", quantization)
+			}
+			fmt.Fprintf(writer, "Context length:\t%d\n", config.MaxPositionEmbeddings)
+			fmt.Fprintf(writer, "Layers:\t%d\n", config.NumHiddenLayers)
+			fmt.Fprintf(writer, "Hidden size:\t%d\n", config.HiddenSize)
+			fmt.Fprintf(writer, "Attention heads:\t%d (KV %d)\n", config.NumAttentionHeads, config.NumKeyValueHeads)
+			writer.Flush()
+			return nil
 		},
 	}
-	repairCmd.Flags().StringP("source", "s", "", "Source of the model (ollama, huggingface, modelscope)")
+	infoCmd.Flags().StringP("source", "s", "", "Source of the model (ollama, huggingface, modelscope, local, url, oci)")
+
+	fitCmd := &cobra.Command{
+		Use:   "fit [model-id]",
+		Short: "Estimate how a GGUF model fits into GPU and system memory",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, modelID, err := resolveRunModelRef(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			file, _ := cmd.Flags().GetString("file")
+			ctxSize, _ := cmd.Flags().GetInt("ctx-size")
+
+			mgr := createModelManager()
+			modelDir, err := mgr.ResolveLocalModelDir(src, modelID)
+			if err != nil {
+				return fmt.Errorf("local model not found: %w", err)
+			}
+			ggufFiles, err := modelmanager.FindGGUFFiles(modelDir)
+			if err != nil {
+				return err
+			}
+			if len(ggufFiles) == 0 && file == "" {
+				return fmt.Errorf("no GGUF files found for %s", modelID)
+			}
+			modelPath, _, err := resolveGGUFFile(modelDir, ggufFiles, file)
+			if err != nil {
+				return err
+			}
+			model, err := modelmanager.ReadGGUFModel(modelPath)
+			if err != nil {
+				return err
+			}
+
+			baseInfo, _ := system.LoadBaseInfoSummary(resolveBaseInfoPath())
+			opts := machineFitOptions(cmd, baseInfo)
+			opts.ContextSize = ctxSize
+			fit, err := modelmanager.EstimateFit(model, opts)
+			if err != nil {
+				return err
+			}
+
+			writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintf(writer, "File:\t%s\n", filepath.Base(modelPath))
+			fmt.Fprintf(writer, "Weights:\t%s (%s)\n", modelmanager.FormatBytes(int64(fit.WeightBytes)), model.Quantization)
+			fmt.Fprintf(writer, "KV cache:\t%s per 1K tokens\n", modelmanager.FormatBytes(int64(fit.KVBytesPerToken*1024)))
+			if len(opts.GPUs) == 0 {
+				fmt.Fprintf(writer, "GPUs:\tnone detected\n")
+			}
+			for i, gpu := range opts.GPUs {
+				fmt.Fprintf(writer, "GPU %d:\t%s, %s free, %d layers\n", i, gpu.Name, modelmanager.FormatBytes(int64(gpu.FreeBytes)), fit.TensorSplit[i])
+			}
+			offload := "partial offload"
+			switch {
+			case fit.FullOffload():
+				offload = "full offload"
+			case fit.GPULayers == 0:
+				offload = "CPU only"
+			}
+			fmt.Fprintf(writer, "GPU layers:\t%d/%d (%s)\n", fit.GPULayers, fit.TotalLayers, offload)
+			fmt.Fprintf(writer, "Context size:\t%d\n", fit.ContextSize)
+			if split := fit.TensorSplitArg(); split != "" {
+				fmt.Fprintf(writer, "Tensor split:\t%s\n", split)
+			}
+			fmt.Fprintf(writer, "GPU memory:\t%s\n", modelmanager.FormatBytes(int64(fit.GPUBytes)))
+			fmt.Fprintf(writer, "System memory:\t%s\n", modelmanager.FormatBytes(int64(fit.HostBytes)))
+			writer.Flush()
+			if !fit.Fits {
+				cmd.PrintErrf("%s\n", i18n.T("warning: the layers left on the CPU need more than the %s of free system memory", modelmanager.FormatBytes(int64(opts.HostFreeBytes))))
+			}
+			return nil
+		},
+	}
+	fitCmd.Flags().StringP("source", "s", "", "Source of the model (ollama, huggingface, modelscope, local, url, oci)")
+	fitCmd.Flags().StringP("file", "f", "", "Specific GGUF filename to estimate")
+	fitCmd.Flags().Int("ctx-size", 0, "Context size to estimate for (0 = largest that fits)")
 
 	modelCmd.AddCommand(searchCmd)
 	modelCmd.AddCommand(downloadCmd)
+	modelCmd.AddCommand(newModelQueueCommand())
 	modelCmd.AddCommand(listCmd)
+	modelCmd.AddCommand(infoCmd)
+	modelCmd.AddCommand(fitCmd)
+	modelCmd.AddCommand(importCmd)
 	modelCmd.AddCommand(runCmd)
+	modelCmd.AddCommand(deployCmd)
 	modelCmd.AddCommand(rmCmd)
 	modelCmd.AddCommand(repairCmd)
+	modelCmd.AddCommand(gcCmd)
+	modelCmd.AddCommand(pruneCmd)
 	rootCmd.AddCommand(modelCmd)
 }
 
-func createModelManager() *modelmanager.Manager {
-	home, _ := os.UserHomeDir()
-	modelDir := filepath.Join(home, ".localaistack", "models")
-	mgr := modelmanager.NewManager(modelDir)
+// resolveRunModelRef splits a model reference for run and deploy, honoring
+// an explicit --source.
+func resolveRunModelRef(cmd *cobra.Command, modelID string) (modelmanager.ModelSource, string, error) {
+	source, _ := cmd.Flags().GetString("source")
+	if source != "" {
+		src, err := modelmanager.ParseSource(source)
+		return src, modelID, err
+	}
+	return modelmanager.ParseModelID(modelID)
+}
+
+func addModelServerFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("source", "s", "", "Source of the model (ollama, huggingface, modelscope, local, url, oci)")
+	cmd.Flags().StringP("file", "f", "", "Specific GGUF filename to run")
+	cmd.Flags().Int("threads", 0, "CPU threads for llama.cpp (0 = auto)")
+	cmd.Flags().Int("ctx-size", 0, "Context size for llama.cpp (0 = auto)")
+	cmd.Flags().Int("n-gpu-layers", -1, "GPU layers for llama.cpp (-1 = auto)")
+	cmd.Flags().String("tensor-split", "", "Tensor split for multi-GPU (comma-separated percentages)")
+	cmd.Flags().String("host", "0.0.0.0", "Host to bind llama.cpp server")
+	cmd.Flags().Int("port", 8080, "Port to bind llama.cpp server")
+	cmd.Flags().Int("vllm-max-model-len", 0, "vLLM max model length (safetensors only)")
+	cmd.Flags().Float64("vllm-gpu-memory-utilization", 0, "vLLM GPU memory utilization (0-1, safetensors only)")
+}
+
+// modelServerOptions are the backend settings for serving a model; zero
+// values (and a negative gpuLayers) mean automatic.
+type modelServerOptions struct {
+	file            string
+	threads         int
+	ctxSize         int
+	gpuLayers       int
+	tensorSplit     string
+	host            string
+	port            int
+	vllmMaxModelLen int
+	vllmGpuMemUtil  float64
+}
+
+func modelServerOptionsFromFlags(cmd *cobra.Command) modelServerOptions {
+	var opts modelServerOptions
+	opts.file, _ = cmd.Flags().GetString("file")
+	opts.threads, _ = cmd.Flags().GetInt("threads")
+	opts.ctxSize, _ = cmd.Flags().GetInt("ctx-size")
+	opts.gpuLayers, _ = cmd.Flags().GetInt("n-gpu-layers")
+	opts.tensorSplit, _ = cmd.Flags().GetString("tensor-split")
+	opts.host, _ = cmd.Flags().GetString("host")
+	opts.port, _ = cmd.Flags().GetInt("port")
+	opts.vllmMaxModelLen, _ = cmd.Flags().GetInt("vllm-max-model-len")
+	opts.vllmGpuMemUtil, _ = cmd.Flags().GetFloat64("vllm-gpu-memory-utilization")
+	return opts
+}
+
+// modelServerCommand builds the vLLM or llama.cpp server command that
+// serves a downloaded model, tuned for this machine and opts.
+func modelServerCommand(cmd *cobra.Command, src modelmanager.ModelSource, modelID string, opts modelServerOptions) (*exec.Cmd, error) {
+	selectedFile := opts.file
+	threads := opts.threads
+	ctxSize := opts.ctxSize
+	gpuLayers := opts.gpuLayers
+	host := opts.host
+	port := opts.port
+	vllmMaxModelLen := opts.vllmMaxModelLen
+	vllmGpuMemUtil := opts.vllmGpuMemUtil
+
+	mgr := createModelManager()
+	modelDir, err := mgr.ResolveLocalModelDir(src, modelID)
+	if err != nil {
+		return nil, fmt.Errorf("local model not found: %w", err)
+	}
+	_ = mgr.TouchModel(src, modelID)
 
-	mgr.RegisterProvider(modelmanager.NewOllamaProvider())
-	mgr.RegisterProvider(modelmanager.NewHuggingFaceProvider(""))
-	mgr.RegisterProvider(modelmanager.NewModelScopeProvider(""))
+	safetensorsFiles, err := modelmanager.FindSafetensorsFiles(modelDir)
+	if err != nil {
+		return nil, err
+	}
+	ggufFiles, err := modelmanager.FindGGUFFiles(modelDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(safetensorsFiles) == 0 && len(ggufFiles) == 0 {
+		return nil, fmt.Errorf("no supported model files found for %s", modelID)
+	}
+
+	baseInfoPath := resolveBaseInfoPath()
+	baseInfo, err := system.LoadBaseInfoSummary(baseInfoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read base info at %s (try `./build/las system init`): %w", baseInfoPath, err)
+	}
 
+	if len(safetensorsFiles) > 0 {
+		modelRef := modelDir
+		if !hasVLLMConfig(modelDir) {
+			meta, err := readModelMetadata(modelDir)
+			if err != nil {
+				return nil, fmt.Errorf("vLLM requires a local config.json/params.json or a known repo id: %w", err)
+			}
+			if meta.ID == "" {
+				return nil, fmt.Errorf("metadata.json missing model id")
+			}
+			if meta.Source == string(modelmanager.SourceLocal) {
+				return nil, fmt.Errorf("vLLM requires config.json in the imported model directory %s", modelDir)
+			}
+			modelRef = meta.ID
+		}
+		vllmPath, err := exec.LookPath("vllm")
+		if err != nil {
+			return nil, fmt.Errorf("vllm not found in PATH (install the vllm module first)")
+		}
+		vllmDefaults := defaultVLLMRunParams(baseInfo)
+		if vllmGpuMemUtil > 0 {
+			vllmDefaults.gpuMemUtil = vllmGpuMemUtil
+		}
+		if fitOpts := machineFitOptions(cmd, baseInfo); len(fitOpts.GPUs) > 0 && modelRef == modelDir {
+			model, err := modelmanager.ReadSafetensorsModel(modelDir)
+			if err != nil {
+				cmd.PrintErrf("%s\n", i18n.T("warning: %v; tuning without model metadata", err))
+			} else {
+				fitOpts.ContextSize = vllmMaxModelLen
+				fit, err := modelmanager.EstimateVLLMFit(model, fitOpts, vllmDefaults.gpuMemUtil)
+				if err != nil {
+					return nil, err
+				}
+				vllmDefaults = autoTuneVLLMParams(vllmDefaults, fit)
+			}
+		}
+		if vllmMaxModelLen > 0 {
+			vllmDefaults.maxModelLen = vllmMaxModelLen
+		}
+		cmd.Printf("Starting vLLM server for %s\n", modelID)
+		args := []string{"serve", modelRef, "--host", host, "--port", strconv.Itoa(port)}
+		if vllmDefaults.maxModelLen > 0 {
+			args = append(args, "--max-model-len", strconv.Itoa(vllmDefaults.maxModelLen))
+		}
+		if vllmDefaults.gpuMemUtil > 0 {
+			args = append(args, "--gpu-memory-utilization", fmt.Sprintf("%.2f", vllmDefaults.gpuMemUtil))
+		}
+		if vllmDefaults.tensorParallelSize > 1 {
+			args = append(args, "--tensor-parallel-size", strconv.Itoa(vllmDefaults.tensorParallelSize))
+		}
+		if vllmDefaults.dtype != "" {
+			args = append(args, "--dtype", vllmDefaults.dtype)
+		}
+		if vllmDefaults.quantization != "" {
+			args = append(args, "--quantization", vllmDefaults.quantization)
+		}
+		return exec.CommandContext(cmd.Context(), vllmPath, args...), nil
+	}
+
+	modelPath, autoSelected, err := resolveGGUFFile(modelDir, ggufFiles, selectedFile)
+	if err != nil {
+		return nil, err
+	}
+	if autoSelected && len(ggufFiles) > 1 {
+		cmd.Printf("Auto-selected GGUF file: %s\n", filepath.Base(modelPath))
+	}
+
+	model, err := modelmanager.ReadGGUFModel(modelPath)
+	if err != nil {
+		cmd.PrintErrf("%s\n", i18n.T("warning: %v; tuning without model metadata", err))
+		model = nil
+	}
+	if model != nil {
+		if err := checkModelPolicy(cmd, model); err != nil {
+			return nil, err
+		}
+	}
+
+	var fit *modelmanager.FitEstimate
+	if model != nil {
+		fitOpts := machineFitOptions(cmd, baseInfo)
+		fitOpts.ContextSize = ctxSize
+		if fit, err = modelmanager.EstimateFit(model, fitOpts); err != nil {
+			cmd.PrintErrf("%s\n", i18n.T("warning: %v; tuning without a memory estimate", err))
+			fit = nil
+		}
+	}
+
+	defaults := defaultLlamaRunParams(baseInfo)
+	defaults = autoTuneRunParams(defaults, baseInfo, fit)
+	if threads > 0 {
+		defaults.threads = threads
+	}
+	if ctxSize > 0 {
+		defaults.ctxSize = ctxSize
+	}
+	if gpuLayers >= 0 {
+		defaults.gpuLayers = gpuLayers
+	}
+	if opts.tensorSplit != "" {
+		defaults.tensorSplit = opts.tensorSplit
+	}
+
+	llamaPath, err := exec.LookPath("llama-server")
+	if err != nil {
+		return nil, fmt.Errorf("llama-server not found in PATH (install the llama.cpp module first)")
+	}
+
+	argsList := []string{
+		"--model", modelPath,
+		"--threads", strconv.Itoa(defaults.threads),
+		"--ctx-size", strconv.Itoa(defaults.ctxSize),
+		"--n-gpu-layers", strconv.Itoa(defaults.gpuLayers),
+		"--host", host,
+		"--port", strconv.Itoa(port),
+	}
+	if defaults.tensorSplit != "" {
+		argsList = append(argsList, "--tensor-split", defaults.tensorSplit)
+	}
+
+	cmd.Printf("Starting llama.cpp server for %s\n", filepath.Base(modelPath))
+	runCmd := exec.CommandContext(cmd.Context(), llamaPath, argsList...)
+	if err := addLlamaCppLibraryPath(runCmd); err != nil {
+		return nil, err
+	}
+	return runCmd, nil
+}
+
+// deployProcessSpec turns a prepared server command into a process for the
+// server's runtime manager. Only environment variables the command changed
+// are sent; the server supplies its own environment for the rest.
+func deployProcessSpec(name string, runCmd *exec.Cmd, port int) client.ProcessSpec {
+	spec := client.ProcessSpec{
+		Name:    name,
+		Command: append([]string{runCmd.Path}, runCmd.Args[1:]...),
+		WorkDir: runCmd.Dir,
+	}
+	if runCmd.Env != nil {
+		inherited := map[string]bool{}
+		for _, kv := range os.Environ() {
+			inherited[kv] = true
+		}
+		for _, kv := range runCmd.Env {
+			if key, value, ok := strings.Cut(kv, "="); ok && !inherited[kv] {
+				if spec.Env == nil {
+					spec.Env = map[string]string{}
+				}
+				spec.Env[key] = value
+			}
+		}
+	}
+	if curl, err := exec.LookPath("curl"); err == nil {
+		spec.HealthCommand = []string{curl, "-sf", "-o", "/dev/null", fmt.Sprintf("http://127.0.0.1:%d/health", port)}
+	}
+	return spec
+}
+
+// checkModelPolicy refuses models larger than the max_model_size the
+// hardware policies allow. It uses the running server's capabilities, or
+// evaluates the policy file against the detected hardware when no server is
+// running. Without a usable policy file, every model is allowed.
+func checkModelPolicy(cmd *cobra.Command, model *modelmanager.GGUFInfo) error {
+	if model.ParameterCount == 0 {
+		return nil
+	}
+	var capabilities *control.CapabilitySet
+	if c := connectServer(); c != nil {
+		capabilities, _ = c.Capabilities(cmd.Context())
+	} else {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			cfg = config.DefaultConfig()
+		}
+		capabilities, _ = control.DetectCapabilities(cfg.Control.PolicyFile)
+	}
+	if capabilities == nil {
+		return nil
+	}
+	if !capabilities.AllowsModel(model.ParameterCount) {
+		return fmt.Errorf("model has %s parameters, more than the %s allowed by the hardware policy", modelmanager.FormatParameters(model.ParameterCount), capabilities.MaxModelSize)
+	}
+	return nil
+}
+
+// writePruneResult lists the models a prune removes with their last use.
+func writePruneResult(cmd *cobra.Command, result modelmanager.PruneResult) {
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "MODEL\tSOURCE\tLAST USED")
+	for _, model := range result.Removed {
+		lastUsed := "never"
+		if model.LastUsed > 0 {
+			lastUsed = time.Unix(model.LastUsed, 0).Format(time.DateTime)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", model.ID, model.Source, lastUsed)
+	}
+	writer.Flush()
+	cmd.Printf("Frees %s, leaving %s.\n", modelmanager.FormatBytes(result.FreedBytes), modelmanager.FormatBytes(result.UsedBytes))
+}
+
+// downloadProgressPrinter prints download progress on a single line.
+func downloadProgressPrinter(cmd *cobra.Command) func(downloaded, total int64) {
+	return func(downloaded, total int64) {
+		if total > 0 {
+			percent := float64(downloaded) * 100 / float64(total)
+			cmd.Printf("\rProgress: %.1f%% (%s / %s)", percent,
+				modelmanager.FormatBytes(downloaded), modelmanager.FormatBytes(total))
+		}
+	}
+}
+
+func createModelManager() *modelmanager.Manager {
+	mgr := modelmanager.NewDefaultManager()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	if quota, err := modelmanager.ParseQuota(cfg.Storage.ModelQuota); err == nil {
+		mgr.SetQuota(quota)
+	}
+	sources := artifactOptions(cfg.ModelSources)
+	mgr.RegisterProvider(modelmanager.NewHTTPProvider(sources))
+	mgr.RegisterProvider(modelmanager.NewOCIProvider(sources))
 	return mgr
 }
 
+// artifactOptions converts the model_sources configuration for the url:
+// and oci: providers.
+func artifactOptions(cfg config.ModelSourcesConfig) modelmanager.ArtifactOptions {
+	opts := modelmanager.ArtifactOptions{IndexURL: cfg.IndexURL, Auth: map[string]modelmanager.ArtifactAuth{}}
+	for host, auth := range cfg.Auth {
+		opts.Auth[host] = modelmanager.ArtifactAuth(auth)
+	}
+	return opts
+}
+
+// modelQuota reads storage.model_quota from the configuration.
+func modelQuota() (modelmanager.Quota, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return modelmanager.Quota{}, err
+	}
+	return modelmanager.ParseQuota(cfg.Storage.ModelQuota)
+}
+
 func displaySearchResults(cmd *cobra.Command, source modelmanager.ModelSource, models []modelmanager.ModelInfo) {
 	if len(models) == 0 {
 		return
@@ -716,8 +1488,11 @@ type llamaRunDefaults struct {
 }
 
 type vllmRunDefaults struct {
-	maxModelLen int
-	gpuMemUtil  float64
+	maxModelLen        int
+	gpuMemUtil         float64
+	tensorParallelSize int
+	dtype              string
+	quantization       string
 }
 
 func resolveBaseInfoPath() string {
@@ -852,52 +1627,18 @@ func parseVRAMFromGPUName(name string) int {
 	return value
 }
 
-func inferModelInfo(filename string) (float64, string) {
-	base := strings.ToLower(filepath.Base(filename))
-	re := regexp.MustCompile(`(\d+(?:\.\d+)?)b`)
-	matches := re.FindAllStringSubmatch(base, -1)
-	var max float64
-	for _, match := range matches {
-		if len(match) < 2 {
-			continue
-		}
-		value, err := strconv.ParseFloat(match[1], 64)
-		if err != nil {
-			continue
-		}
-		if value > max {
-			max = value
-		}
-	}
-
-	quant := ""
-	quantPatterns := []string{
-		"q2_k",
-		"q3_k",
-		"q4_k_m",
-		"q4_k_s",
-		"q4",
-		"q5_k_m",
-		"q5_k_s",
-		"q5",
-		"q6_k",
-		"q6",
-		"q8_0",
-		"q8",
-	}
-	for _, pattern := range quantPatterns {
-		if strings.Contains(base, pattern) {
-			quant = pattern
-			break
-		}
+// autoTuneRunParams adjusts the llama.cpp defaults to this machine. With a
+// memory-fit estimate for the model, its GPU layers, context and tensor
+// split are used; otherwise only coarse guesses from the GPU name apply.
+func autoTuneRunParams(defaults llamaRunDefaults, info system.BaseInfoSummary, fit *modelmanager.FitEstimate) llamaRunDefaults {
+	result := defaults
+	if fit != nil {
+		result.gpuLayers = fit.GPULayers
+		result.ctxSize = fit.ContextSize
+		result.tensorSplit = fit.TensorSplitArg()
+		return result
 	}
 
-	return max, quant
-}
-
-func autoTuneRunParams(defaults llamaRunDefaults, info system.BaseInfoSummary, modelPath string) llamaRunDefaults {
-	result := defaults
-	sizeB, quant := inferModelInfo(modelPath)
 	vram := parseVRAMFromGPUName(info.GPUName)
 	gpuCount := info.GPUCount
 	if gpuCount <= 0 && vram > 0 {
@@ -910,12 +1651,6 @@ func autoTuneRunParams(defaults llamaRunDefaults, info system.BaseInfoSummary, m
 		result.ctxSize = maxInt(result.ctxSize, 4096)
 	}
 
-	if vram >= 16 && gpuCount >= 1 && sizeB > 0 && sizeB <= 30 {
-		if strings.HasPrefix(quant, "q4") || strings.HasPrefix(quant, "q5") || strings.HasPrefix(quant, "q6") || strings.HasPrefix(quant, "q8") || quant == "" {
-			result.gpuLayers = 999
-		}
-	}
-
 	if gpuCount > 1 && result.gpuLayers != 0 {
 		result.tensorSplit = makeTensorSplit(gpuCount)
 	}
@@ -923,6 +1658,44 @@ func autoTuneRunParams(defaults llamaRunDefaults, info system.BaseInfoSummary, m
 	return result
 }
 
+// machineFitOptions describes the free GPU and host memory for
+// modelmanager.EstimateFit. GPUs come from nvidia-smi; without it, the VRAM
+// in the base info GPU name is assumed to be free on every GPU.
+func machineFitOptions(cmd *cobra.Command, baseInfo system.BaseInfoSummary) modelmanager.FitOptions {
+	usage := info.CollectUtilization(cmd.Context())
+	var opts modelmanager.FitOptions
+	for _, gpu := range usage.GPUs {
+		if gpu.MemoryTotalBytes > gpu.MemoryUsedBytes {
+			opts.GPUs = append(opts.GPUs, modelmanager.GPUMemory{Name: gpu.Name, FreeBytes: gpu.MemoryTotalBytes - gpu.MemoryUsedBytes})
+		}
+	}
+	if len(usage.GPUs) == 0 {
+		if vram := parseVRAMFromGPUName(baseInfo.GPUName); vram > 0 {
+			for i := 0; i < maxInt(baseInfo.GPUCount, 1); i++ {
+				opts.GPUs = append(opts.GPUs, modelmanager.GPUMemory{Name: baseInfo.GPUName, FreeBytes: uint64(vram) << 30})
+			}
+		}
+	}
+	opts.HostFreeBytes = usage.MemoryAvailableBytes
+	if opts.HostFreeBytes == 0 && baseInfo.MemoryKB > 0 {
+		opts.HostFreeBytes = uint64(baseInfo.MemoryKB) * 1024
+	}
+	return opts
+}
+
+// autoTuneVLLMParams applies a vLLM fit estimate to the defaults.
+func autoTuneVLLMParams(defaults vllmRunDefaults, fit *modelmanager.VLLMFit) vllmRunDefaults {
+	result := defaults
+	result.maxModelLen = fit.MaxModelLen
+	result.gpuMemUtil = fit.GPUMemoryUtilization
+	result.tensorParallelSize = fit.TensorParallelSize
+	if fit.Dtype != "auto" {
+		result.dtype = fit.Dtype
+	}
+	result.quantization = fit.Quantization
+	return result
+}
+
 func makeTensorSplit(count int) string {
 	if count <= 1 {
 		return ""
@@ -976,14 +1749,6 @@ func readModelMetadata(modelDir string) (modelMetadata, error) {
 	return meta, nil
 }
 
-func hasExplicitSource(input string) bool {
-	inputLower := strings.ToLower(strings.TrimSpace(input))
-	return strings.HasPrefix(inputLower, "ollama:") ||
-		strings.HasPrefix(inputLower, "huggingface:") ||
-		strings.HasPrefix(inputLower, "hf:") ||
-		strings.HasPrefix(inputLower, "modelscope:")
-}
-
 func resolveGGUFFile(modelDir string, ggufFiles []string, selected string) (string, bool, error) {
 	if selected != "" {
 		modelPath := selected
@@ -0,0 +1,252 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/modelmanager"
+)
+
+func newModelQueueCommand() *cobra.Command {
+	queueCmd := &cobra.Command{
+		Use:   "queue",
+		Short: "List and control queued model downloads",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var items []modelmanager.QueueItem
+			if c := connectServer(); c != nil {
+				var err error
+				if items, err = c.DownloadQueue(cmd.Context()); err != nil {
+					return err
+				}
+			} else {
+				queue, err := localDownloadQueue()
+				if err != nil {
+					return err
+				}
+				items = queue.List()
+			}
+			if len(items) == 0 {
+				cmd.Println(i18n.T("The download queue is empty."))
+				return nil
+			}
+			writeDownloadQueue(cmd, items)
+			return nil
+		},
+	}
+
+	for _, action := range []struct{ name, short string }{
+		{"pause", "Pause a queued or running download"},
+		{"resume", "Queue a paused, failed or canceled download again"},
+		{"cancel", "Cancel a queued or running download"},
+	} {
+		queueCmd.AddCommand(&cobra.Command{
+			Use:   action.name + " <id>",
+			Short: action.short,
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				item, err := queueAction(cmd.Context(), args[0], action.name, 0)
+				if err != nil {
+					return err
+				}
+				cmd.Printf("%s\n", i18n.T("%s %s: %s", item.ID, item.Ref(), queueStateLabel(item)))
+				return nil
+			},
+		})
+	}
+
+	queueCmd.AddCommand(&cobra.Command{
+		Use:   "priority <id> <priority>",
+		Short: "Change the priority of a queued download; higher runs first",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			priority, err := strconv.Atoi(args[1])
+			if err != nil {
+				return i18n.Errorf("invalid priority %q", args[1])
+			}
+			item, err := queueAction(cmd.Context(), args[0], "priority", priority)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("%s\n", i18n.T("%s %s: priority %d", item.ID, item.Ref(), item.Priority))
+			return nil
+		},
+	})
+
+	queueCmd.AddCommand(&cobra.Command{
+		Use:   "clear",
+		Short: "Remove finished downloads from the queue",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var removed int
+			if c := connectServer(); c != nil {
+				var err error
+				if removed, err = c.ClearQueue(cmd.Context()); err != nil {
+					return err
+				}
+			} else {
+				queue, err := localDownloadQueue()
+				if err != nil {
+					return err
+				}
+				if removed, err = queue.Clear(); err != nil {
+					return err
+				}
+			}
+			cmd.Printf("%s\n", i18n.T("Removed %d finished downloads.", removed))
+			return nil
+		},
+	})
+
+	queueCmd.AddCommand(&cobra.Command{
+		Use:   "run",
+		Short: "Run the queued downloads in the foreground when no server is running",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if c := connectServer(); c != nil {
+				return i18n.Errorf("the server at %s runs the download queue; follow it with `las model queue`", c.Socket())
+			}
+			queue, err := localDownloadQueue()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := interruptContext(cmd)
+			defer cancel()
+			runCtx, stop := context.WithCancel(ctx)
+			done := make(chan error, 1)
+			go func() { done <- queue.Run(runCtx) }()
+			defer func() {
+				stop()
+				<-done
+			}()
+
+			var failed int
+			for _, item := range queue.List() {
+				if item.Done() || item.State == modelmanager.QueuePaused {
+					continue
+				}
+				cmd.Printf("%s\n", i18n.T("Downloading %s (%s)", item.Ref(), item.ID))
+				progress := downloadProgressPrinter(cmd)
+				final, err := queue.Wait(ctx, item.ID, func(current modelmanager.QueueItem) {
+					if current.Downloaded > 0 {
+						progress(current.Downloaded, current.Total)
+					}
+				})
+				if err != nil {
+					cmd.Println()
+					return err
+				}
+				cmd.Printf("\n%s\n", i18n.T("%s: %s", final.Ref(), queueStateLabel(final)))
+				if final.State == modelmanager.QueueFailed {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return i18n.Errorf("%d downloads failed", failed)
+			}
+			return nil
+		},
+	})
+	return queueCmd
+}
+
+// queueModelDownload adds a download to the server's queue, or to the
+// queue file when no server is running.
+func queueModelDownload(cmd *cobra.Command, src modelmanager.ModelSource, modelID string, opts modelmanager.DownloadOptions, priority int) error {
+	if c := connectServer(); c != nil {
+		item, job, err := c.QueueDownload(cmd.Context(), src, modelID, opts, priority)
+		if err != nil {
+			return err
+		}
+		cmd.Printf("%s\n", i18n.T("Queued %s as %s (job %s); follow it with `las model queue`.", item.Ref(), item.ID, job.ID))
+		return nil
+	}
+	queue, err := localDownloadQueue()
+	if err != nil {
+		return err
+	}
+	item, err := queue.Add(src, modelID, opts, priority)
+	if err != nil {
+		return err
+	}
+	cmd.Printf("%s\n", i18n.T("Queued %s as %s. No server is running; the download starts when the server starts, or run it now with `las model queue run`.", item.Ref(), item.ID))
+	return nil
+}
+
+// queueAction pauses, resumes, cancels or reprioritizes a download through
+// the server, or in the queue file when no server is running.
+func queueAction(ctx context.Context, id, action string, priority int) (modelmanager.QueueItem, error) {
+	if c := connectServer(); c != nil {
+		var body any
+		if action == "priority" {
+			body = map[string]int{"priority": priority}
+		}
+		return c.QueueAction(ctx, id, action, body)
+	}
+	queue, err := localDownloadQueue()
+	if err != nil {
+		return modelmanager.QueueItem{}, err
+	}
+	var item modelmanager.QueueItem
+	switch action {
+	case "pause":
+		item, err = queue.Pause(id)
+	case "resume":
+		item, err = queue.Resume(id)
+	case "cancel":
+		item, err = queue.Cancel(id)
+	case "priority":
+		item, err = queue.SetPriority(id, priority)
+	default:
+		return item, i18n.Errorf("unsupported queue action %q", action)
+	}
+	if err != nil {
+		return item, i18n.Errorf("%w: %s", err, id)
+	}
+	return item, nil
+}
+
+// localDownloadQueue opens the download queue in the model directory with
+// the limits from the downloads configuration.
+func localDownloadQueue() (*modelmanager.DownloadQueue, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	opts := modelmanager.QueueOptions{MaxConcurrent: cfg.Downloads.MaxConcurrent, PerHost: cfg.Downloads.PerHost}
+	if raw := strings.TrimSpace(cfg.Downloads.BandwidthLimit); raw != "" {
+		if opts.BandwidthLimit, err = modelmanager.ParseSize(raw); err != nil {
+			return nil, i18n.Errorf("invalid downloads.bandwidth_limit: %w", err)
+		}
+	}
+	return modelmanager.NewDownloadQueue(createModelManager(), opts)
+}
+
+func writeDownloadQueue(cmd *cobra.Command, items []modelmanager.QueueItem) {
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tMODEL\tSTATE\tPRIORITY\tHOST\tPROGRESS")
+	for _, item := range items {
+		progress := "-"
+		if item.Total > 0 {
+			progress = fmt.Sprintf("%.1f%% (%s / %s)", float64(item.Downloaded)*100/float64(item.Total),
+				modelmanager.FormatBytes(item.Downloaded), modelmanager.FormatBytes(item.Total))
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\t%s\n", item.ID, item.Ref(), queueStateLabel(item), item.Priority, item.Host, progress)
+	}
+	writer.Flush()
+}
+
+// queueStateLabel is the state of a download, with the error when it
+// failed.
+func queueStateLabel(item modelmanager.QueueItem) string {
+	if item.State == modelmanager.QueueFailed && item.Error != "" {
+		return fmt.Sprintf("%s: %s", item.State, item.Error)
+	}
+	return string(item.State)
+}
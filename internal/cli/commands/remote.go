@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/client"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/jobs"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+)
+
+// connectServer returns a client for the running server's control socket,
+// or nil when no server is reachable and the command should run locally.
+func connectServer() *client.Client {
+	dataDir := ""
+	if cfg, err := config.LoadConfig(); err == nil {
+		dataDir = cfg.Control.DataDir
+	}
+	path, err := client.SocketPath(dataDir)
+	if err != nil {
+		return nil
+	}
+	c, err := client.Connect(path)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+// interruptContext is the command's context, canceled on SIGINT or SIGTERM
+// so that server jobs started by the command are canceled with it.
+func interruptContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+}
+
+// jobStepPrinter returns a job progress callback that prints each step as
+// it starts.
+func jobStepPrinter(cmd *cobra.Command) func(jobs.Job) {
+	announced := map[string]bool{}
+	return func(job jobs.Job) {
+		for index, step := range job.Steps {
+			if step.Status == "pending" || announced[step.ID] {
+				continue
+			}
+			announced[step.ID] = true
+			label := step.Intent
+			if label == "" {
+				label = step.ID
+			}
+			cmd.Printf("[%d/%d] %s\n", index+1, max(job.Total, len(job.Steps)), label)
+		}
+	}
+}
+
+// jobResult turns a finished server job into the command's error, printing
+// the job output when it failed.
+func jobResult(cmd *cobra.Command, job jobs.Job) error {
+	if job.Status == jobs.StatusSucceeded {
+		return nil
+	}
+	if output := strings.TrimSpace(job.Output); output != "" {
+		cmd.PrintErrf("%s\n", output)
+	}
+	if job.Error != nil {
+		return errors.New(job.Error.Message)
+	}
+	return i18n.Errorf("job %s finished with status %s", job.ID, job.Status)
+}
+
+// runModuleActionRemote runs a module action as a server job, printing each
+// step as it starts.
+func runModuleActionRemote(cmd *cobra.Command, c *client.Client, name, action string) error {
+	ctx, cancel := interruptContext(cmd)
+	defer cancel()
+
+	cmd.Printf("%s\n", i18n.T("Running on the LocalAIStack server (%s)", c.Socket()))
+	job, err := c.ModuleAction(ctx, name, action, jobStepPrinter(cmd))
+	if err != nil {
+		return err
+	}
+	return jobResult(cmd, job)
+}
+
+// runModuleAction hands a module action to the running server, which then
+// owns the module's state, and falls back to running it in-process.
+func runModuleAction(cmd *cobra.Command, name, action string, local func(string) error) error {
+	if c := connectServer(); c != nil {
+		return runModuleActionRemote(cmd, c, name, action)
+	}
+	if err := local(name); err != nil {
+		return err
+	}
+	recordModuleAction(cmd, name, action)
+	return nil
+}
+
+// recordModuleAction records a module action performed in-process in the
+// state file the server reads. Failing to record does not fail the action.
+func recordModuleAction(cmd *cobra.Command, name, action string) {
+	manager, err := openStateManager()
+	if err == nil {
+		err = manager.RecordModuleAction(name, action)
+	}
+	if err != nil {
+		cmd.PrintErrf("%s\n", i18n.T("warning: failed to record state for module %s: %v", name, err))
+	}
+}
+
+// controlService starts, stops or restarts a service through the server,
+// which also supervises runtime processes, or a module's systemd unit
+// directly when no server is running.
+func controlService(cmd *cobra.Command, name, action string) error {
+	if c := connectServer(); c != nil {
+		service, err := c.ServiceAction(cmd.Context(), name, action)
+		if err != nil {
+			return err
+		}
+		if service != nil {
+			cmd.Printf("%s\n", i18n.T("Service %s is %s (%s)", service.Name, service.State, service.Health))
+		}
+		return nil
+	}
+	if err := module.ControlService(cmd.Context(), name, action); err != nil {
+		return err
+	}
+	return printServiceStatus(cmd, name)
+}
+
+func printServiceStatus(cmd *cobra.Command, name string) error {
+	if c := connectServer(); c != nil {
+		services, err := c.Services(cmd.Context())
+		if err != nil {
+			return err
+		}
+		for _, service := range services {
+			if service.Name != name {
+				continue
+			}
+			cmd.Printf("%s\n", i18n.T("Service %s is %s (%s)", service.Name, service.State, service.Health))
+			if service.PID > 0 {
+				cmd.Printf("%s\n", i18n.T("PID: %d, restarts: %d", service.PID, service.Restarts))
+			}
+			if service.LastError != "" {
+				cmd.Printf("%s\n", i18n.T("Last error: %s", service.LastError))
+			}
+			return nil
+		}
+		return i18n.Errorf("service %q not found", name)
+	}
+	status, err := module.GetServiceStatus(cmd.Context(), name)
+	if err != nil {
+		return err
+	}
+	health := "unhealthy"
+	if status.Healthy {
+		health = "healthy"
+	}
+	cmd.Printf("%s\n", i18n.T("Service %s is %s (%s)", status.Unit, status.ActiveState, health))
+	return nil
+}
@@ -0,0 +1,189 @@
+package commands
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/client"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/modelmanager"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/stack"
+)
+
+func RegisterStackCommands(rootCmd *cobra.Command) {
+	var diffFile string
+	diffCmd := &cobra.Command{
+		Use:   "diff -f stack.yaml",
+		Short: "Preview the changes `las apply` would make for a stack file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, plan, _, err := planStack(cmd, diffFile)
+			if err != nil {
+				return err
+			}
+			writeStackPlan(cmd, plan)
+			return nil
+		},
+	}
+	diffCmd.Flags().StringVarP(&diffFile, "file", "f", "", "Stack file")
+	_ = diffCmd.MarkFlagRequired("file")
+
+	var applyFile string
+	var assumeYes bool
+	applyCmd := &cobra.Command{
+		Use:   "apply -f stack.yaml",
+		Short: "Install, download and start whatever a stack file lists and the host lacks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, plan, c, err := planStack(cmd, applyFile)
+			if err != nil {
+				return err
+			}
+			writeStackPlan(cmd, plan)
+			changes := plan.Changes()
+			if len(changes) == 0 {
+				return nil
+			}
+			for _, item := range changes {
+				if item.Action == stack.ActionStart && c == nil {
+					return i18n.Errorf("deployment %s needs a running LocalAIStack server", item.Name)
+				}
+			}
+			if !assumeYes && !confirm(cmd, i18n.T("Apply these changes?")) {
+				return i18n.Errorf("apply aborted")
+			}
+
+			ctx, cancel := interruptContext(cmd)
+			defer cancel()
+			for index, item := range changes {
+				cmd.Printf("%s\n", i18n.T("[%d/%d] %s %s %s", index+1, len(changes), item.Action, item.Kind, item.Name))
+				var err error
+				switch item.Action {
+				case stack.ActionInstall:
+					err = runModuleAction(cmd, item.Name, "install", module.Install)
+				case stack.ActionReinstall:
+					if err = runModuleAction(cmd, item.Name, "uninstall", module.Uninstall); err == nil {
+						err = runModuleAction(cmd, item.Name, "install", module.Install)
+					}
+				case stack.ActionDownload:
+					err = mgr.DownloadModelWithContext(ctx, item.Source, item.ModelID, downloadProgressPrinter(cmd), modelmanager.DownloadOptions{FileHint: item.FileHint})
+					cmd.Println()
+				case stack.ActionStart:
+					err = startDeployment(cmd, c, item)
+				}
+				if err != nil {
+					return i18n.Errorf("failed to %s %s %s: %w", item.Action, item.Kind, item.Name, err)
+				}
+			}
+			cmd.Println(i18n.T("Stack applied."))
+			return nil
+		},
+	}
+	applyCmd.Flags().StringVarP(&applyFile, "file", "f", "", "Stack file")
+	applyCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Apply without asking for confirmation")
+	_ = applyCmd.MarkFlagRequired("file")
+
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(applyCmd)
+}
+
+// planStack loads a stack file and diffs it against the recorded module
+// state, the model directory and, when a server runs, its services. The
+// returned client is nil without a server.
+func planStack(cmd *cobra.Command, path string) (*modelmanager.Manager, stack.Plan, *client.Client, error) {
+	s, err := stack.Load(path)
+	if err != nil {
+		return nil, stack.Plan{}, nil, err
+	}
+	modulesRoot, err := module.FindModulesRoot()
+	if err != nil {
+		return nil, stack.Plan{}, nil, err
+	}
+	registry, err := module.LoadRegistryFromDir(modulesRoot)
+	if err != nil {
+		return nil, stack.Plan{}, nil, err
+	}
+	manager, err := openStateManager()
+	if err != nil {
+		return nil, stack.Plan{}, nil, err
+	}
+
+	mgr := createModelManager()
+	env := stack.Environment{
+		Registry: registry,
+		Modules:  manager.GetState().Modules,
+		Models:   mgr,
+		Running:  map[string]bool{},
+	}
+	c := connectServer()
+	if c != nil {
+		services, err := c.Services(cmd.Context())
+		if err != nil {
+			return nil, stack.Plan{}, nil, err
+		}
+		for _, service := range services {
+			if service.State == "running" {
+				env.Running[service.Name] = true
+			}
+		}
+	}
+	plan, err := stack.Diff(s, env)
+	if err != nil {
+		return nil, stack.Plan{}, nil, err
+	}
+	return mgr, plan, c, nil
+}
+
+func writeStackPlan(cmd *cobra.Command, plan stack.Plan) {
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "\tKIND\tNAME\tACTION\tDETAIL")
+	for _, item := range plan.Items {
+		marker := "+"
+		switch item.Action {
+		case stack.ActionNone:
+			marker = "="
+		case stack.ActionReinstall:
+			marker = "~"
+		}
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", marker, item.Kind, item.Name, item.Action, item.Detail)
+	}
+	_ = writer.Flush()
+	changes := len(plan.Changes())
+	if changes == 0 {
+		cmd.Println(i18n.T("The host already matches the stack."))
+		return
+	}
+	cmd.Printf("%s\n", i18n.T("%d of %d entries need changes.", changes, len(plan.Items)))
+}
+
+// startDeployment serves a deployment's model under the server's runtime
+// manager, as `las model deploy` does.
+func startDeployment(cmd *cobra.Command, c *client.Client, item stack.Item) error {
+	deployment := item.Deployment
+	opts := modelServerOptions{
+		file:            deployment.File,
+		threads:         deployment.Threads,
+		ctxSize:         deployment.CtxSize,
+		gpuLayers:       -1,
+		tensorSplit:     deployment.TensorSplit,
+		host:            deployment.Host,
+		port:            deployment.Port,
+		vllmMaxModelLen: deployment.VLLMMaxModelLen,
+		vllmGpuMemUtil:  deployment.VLLMGPUMemoryUtilization,
+	}
+	if deployment.GPULayers != nil {
+		opts.gpuLayers = *deployment.GPULayers
+	}
+	runCmd, err := modelServerCommand(cmd, item.Source, item.ModelID, opts)
+	if err != nil {
+		return err
+	}
+	service, err := c.StartProcess(cmd.Context(), deployProcessSpec(deployment.Name, runCmd, deployment.Port))
+	if err != nil {
+		return err
+	}
+	cmd.Printf("%s\n", i18n.T("Deployed %s as service %s (PID %d)", item.ModelID, service.Name, service.PID))
+	return nil
+}
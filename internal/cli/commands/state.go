@@ -0,0 +1,245 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/control"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+)
+
+func RegisterStateCommands(rootCmd *cobra.Command) {
+	stateCmd := &cobra.Command{
+		Use:   "state",
+		Short: "Inspect and roll back the recorded system state",
+	}
+
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "List state snapshots",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manager, err := openStateManager()
+			if err != nil {
+				return err
+			}
+			history := manager.History()
+			if len(history) == 0 {
+				cmd.Println(i18n.T("No state snapshots recorded yet."))
+				return nil
+			}
+			writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(writer, "ID\tCREATED\tREASON\tMODULES")
+			for i := len(history) - 1; i >= 0; i-- {
+				snapshot := history[i]
+				_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", snapshot.ID, snapshot.CreatedAt.Local().Format("2006-01-02 15:04:05"), snapshot.Reason, formatSnapshotModules(snapshot.Modules))
+			}
+			return writer.Flush()
+		},
+	}
+
+	diffCmd := &cobra.Command{
+		Use:   "diff [from] [to]",
+		Short: "Show module changes between two snapshots (to defaults to current)",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manager, err := openStateManager()
+			if err != nil {
+				return err
+			}
+			to := control.CurrentStateRef
+			if len(args) == 2 {
+				to = args[1]
+			}
+			changes, err := manager.Diff(args[0], to)
+			if err != nil {
+				return err
+			}
+			if len(changes) == 0 {
+				cmd.Println(i18n.T("No differences."))
+				return nil
+			}
+			writeModuleChanges(cmd, changes)
+			return nil
+		},
+	}
+
+	var assumeYes, dryRun bool
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback [snapshot-id]",
+		Short: "Converge installed modules to a snapshot",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manager, err := openStateManager()
+			if err != nil {
+				return err
+			}
+			plan, err := manager.PlanRollback(args[0])
+			if err != nil {
+				return err
+			}
+			cmd.Printf("%s\n", i18n.T("Rollback to snapshot %s (%s, %s):", plan.Snapshot, plan.Reason, plan.CreatedAt.Local().Format("2006-01-02 15:04:05")))
+			if len(plan.Changes) == 0 {
+				cmd.Println(i18n.T("Nothing to do; the recorded state already matches."))
+				return nil
+			}
+			writeModuleChanges(cmd, plan.Changes)
+			if dryRun {
+				return nil
+			}
+			if !assumeYes && !confirm(cmd, i18n.T("Apply these changes?")) {
+				return errors.New(i18n.T("rollback aborted"))
+			}
+
+			if c := connectServer(); c != nil {
+				ctx, cancel := interruptContext(cmd)
+				defer cancel()
+				cmd.Printf("%s\n", i18n.T("Running on the LocalAIStack server (%s)", c.Socket()))
+				job, err := c.Rollback(ctx, plan.Snapshot, jobStepPrinter(cmd))
+				if err != nil {
+					return err
+				}
+				return jobResult(cmd, job)
+			}
+
+			run := func(ctx context.Context, name, action string) error {
+				if action == "install" {
+					return module.InstallWithContext(ctx, name, module.RunOptions{Output: cmd.OutOrStdout()})
+				}
+				return module.UninstallWithContext(ctx, name, module.RunOptions{Output: cmd.OutOrStdout()})
+			}
+			ctx, cancel := interruptContext(cmd)
+			defer cancel()
+			err = manager.ExecuteRollback(ctx, plan, run, func(change control.ModuleChange, err error, done bool) {
+				if !done {
+					cmd.Printf("%s\n", i18n.T("==> %s %s", change.Action, change.Module))
+				}
+			})
+			if err != nil {
+				return err
+			}
+			cmd.Println(i18n.T("Rollback complete."))
+			return nil
+		},
+	}
+	rollbackCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Apply the plan without asking for confirmation")
+	rollbackCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the plan")
+
+	var reconcileDryRun bool
+	reconcileCmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Probe the host and correct drift in the recorded module state",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := interruptContext(cmd)
+			defer cancel()
+
+			var corrections []control.StateCorrection
+			if c := connectServer(); c != nil {
+				var err error
+				corrections, err = c.Reconcile(ctx, reconcileDryRun)
+				if err != nil {
+					return err
+				}
+			} else {
+				manager, err := openStateManager()
+				if err != nil {
+					return err
+				}
+				corrections, err = manager.Reconcile(ctx, control.ReconcileOptions{DryRun: reconcileDryRun})
+				if err != nil {
+					return err
+				}
+			}
+			if len(corrections) == 0 {
+				cmd.Println(i18n.T("Recorded state matches the host."))
+				return nil
+			}
+			writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(writer, "MODULE\tRECORDED\tACTUAL\tREASON")
+			for _, correction := range corrections {
+				previous := string(correction.Previous)
+				if previous == "" {
+					previous = "-"
+				}
+				_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", correction.ModuleName, previous, correction.Corrected, correction.Reason)
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			if reconcileDryRun {
+				cmd.Println(i18n.T("Dry run; the state file was not changed."))
+			}
+			return nil
+		},
+	}
+	reconcileCmd.Flags().BoolVar(&reconcileDryRun, "dry-run", false, "Only report the corrections")
+
+	stateCmd.AddCommand(historyCmd)
+	stateCmd.AddCommand(diffCmd)
+	stateCmd.AddCommand(rollbackCmd)
+	stateCmd.AddCommand(reconcileCmd)
+	rootCmd.AddCommand(stateCmd)
+}
+
+// openStateManager opens the state file the server uses, under the
+// configured control data directory.
+func openStateManager() (*control.StateManager, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return control.OpenStateManager(cfg.Control.DataDir)
+}
+
+func formatSnapshotModules(modules map[string]control.ModuleState) string {
+	if len(modules) == 0 {
+		return "-"
+	}
+	names := make([]string, 0, len(modules))
+	for name, state := range modules {
+		names = append(names, fmt.Sprintf("%s@%s(%s)", name, state.Version, state.State))
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+func describeModuleState(state *control.ModuleState) string {
+	if state == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%s (%s)", state.Version, state.State)
+}
+
+func writeModuleChanges(cmd *cobra.Command, changes []control.ModuleChange) {
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "ACTION\tMODULE\tFROM\tTO")
+	for _, change := range changes {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", change.Action, change.Module, describeModuleState(change.From), describeModuleState(change.To))
+	}
+	_ = writer.Flush()
+	for _, change := range changes {
+		if change.Warning != "" {
+			cmd.Printf("%s\n", i18n.T("warning: %s: %s", change.Module, change.Warning))
+		}
+	}
+}
+
+// confirm asks a yes/no question on the command's input.
+func confirm(cmd *cobra.Command, question string) bool {
+	cmd.Printf("%s [y/N] ", question)
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/auth"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+)
+
+func RegisterTokenCommands(rootCmd *cobra.Command) {
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage API access tokens",
+	}
+
+	var name string
+	var scopes []string
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an API token",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parsed, err := auth.ParseScopes(scopes)
+			if err != nil {
+				return err
+			}
+			store, err := openTokenStore()
+			if err != nil {
+				return err
+			}
+			token, secret, err := store.Create(name, parsed)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("%s\n", i18n.T("Created token %s (%s) with scopes %s", token.ID, token.Name, formatScopes(token.Scopes)))
+			cmd.Printf("%s\n", i18n.T("Store it now, it will not be shown again:"))
+			cmd.Println(secret)
+			return nil
+		},
+	}
+	createCmd.Flags().StringVar(&name, "name", "", "token name")
+	createCmd.Flags().StringSliceVar(&scopes, "scope", []string{string(auth.ScopeRead)}, "token scopes (read, models, modules, admin)")
+	_ = createCmd.MarkFlagRequired("name")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List API tokens",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openTokenStore()
+			if err != nil {
+				return err
+			}
+			tokens, err := store.List()
+			if err != nil {
+				return err
+			}
+			if len(tokens) == 0 {
+				cmd.Println(i18n.T("No API tokens. Create one with `las token create --name <name>`."))
+				return nil
+			}
+			writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(writer, "ID\tNAME\tSCOPES\tCREATED")
+			for _, token := range tokens {
+				_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", token.ID, token.Name, formatScopes(token.Scopes), token.CreatedAt.Local().Format("2006-01-02 15:04"))
+			}
+			return writer.Flush()
+		},
+	}
+
+	revokeCmd := &cobra.Command{
+		Use:   "revoke [id-or-name]",
+		Short: "Revoke an API token",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openTokenStore()
+			if err != nil {
+				return err
+			}
+			token, err := store.Revoke(args[0])
+			if err != nil {
+				return err
+			}
+			cmd.Printf("%s\n", i18n.T("Revoked token %s (%s)", token.ID, token.Name))
+			return nil
+		},
+	}
+
+	tokenCmd.AddCommand(createCmd)
+	tokenCmd.AddCommand(listCmd)
+	tokenCmd.AddCommand(revokeCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
+
+// openTokenStore opens the token file the API server reads, under the
+// configured control data directory.
+func openTokenStore() (*auth.Store, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	path, err := auth.StorePath(cfg.Control.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewStore(path), nil
+}
+
+func formatScopes(scopes []auth.Scope) string {
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		names = append(names, string(scope))
+	}
+	return strings.Join(names, ",")
+}
@@ -47,6 +47,10 @@ func init() {
 	commands.RegisterProviderCommands(rootCmd)
 	commands.RegisterSystemCommands(rootCmd)
 	commands.RegisterInitCommand(rootCmd)
+	commands.RegisterTokenCommands(rootCmd)
+	commands.RegisterStateCommands(rootCmd)
+	commands.RegisterStackCommands(rootCmd)
+	commands.RegisterBundleCommands(rootCmd)
 }
 
 func initConfig() {
@@ -0,0 +1,346 @@
+// Package client talks to a running LocalAIStack server over its Unix
+// control socket, so CLI commands can hand work to the server instead of
+// performing it in-process.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/control"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/jobs"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/modelmanager"
+)
+
+// SocketName is the file name of the control socket inside the data dir.
+const SocketName = "las.sock"
+
+const (
+	dialTimeout  = time.Second
+	jobPollDelay = 500 * time.Millisecond
+)
+
+// ErrNoServer is returned by Connect when no server listens on the socket.
+var ErrNoServer = errors.New("no LocalAIStack server is running")
+
+// SocketPath returns the control socket for dataDir, defaulting to
+// ~/.localaistack when dataDir is empty.
+func SocketPath(dataDir string) (string, error) {
+	if strings.TrimSpace(dataDir) == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", i18n.Errorf("failed to resolve home directory: %w", err)
+		}
+		dataDir = filepath.Join(home, config.DefaultConfigDirName)
+	}
+	return filepath.Join(dataDir, SocketName), nil
+}
+
+// Client calls the server's REST API over the control socket.
+type Client struct {
+	socket string
+	http   *http.Client
+}
+
+// Connect returns a client for the server listening on socketPath, or
+// ErrNoServer when the socket is missing or nothing accepts on it.
+func Connect(socketPath string) (*Client, error) {
+	if _, err := os.Stat(socketPath); err != nil {
+		return nil, ErrNoServer
+	}
+	conn, err := net.DialTimeout("unix", socketPath, dialTimeout)
+	if err != nil {
+		return nil, ErrNoServer
+	}
+	conn.Close()
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	return &Client{
+		socket: socketPath,
+		http: &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		}},
+	}, nil
+}
+
+// Socket returns the path the client is connected to.
+func (c *Client) Socket() string {
+	return c.socket
+}
+
+// do sends a JSON request and decodes the response into out. Responses
+// with ok=false become errors carrying the server's message.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, "http://localaistack"+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := c.http.Do(request)
+	if err != nil {
+		return i18n.Errorf("server request failed: %w", err)
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return i18n.Errorf("failed to read server response: %w", err)
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return i18n.Errorf("unexpected server response (%s): %s", response.Status, strings.TrimSpace(string(data)))
+	}
+	if !status.OK {
+		if status.Error == "" {
+			status.Error = response.Status
+		}
+		return errors.New(status.Error)
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// Job returns the current snapshot of a job.
+func (c *Client) Job(ctx context.Context, id string) (jobs.Job, error) {
+	var response struct {
+		Job *jobs.Job `json:"job"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/jobs/"+url.PathEscape(id), nil, &response); err != nil {
+		return jobs.Job{}, err
+	}
+	if response.Job == nil {
+		return jobs.Job{}, i18n.Errorf("server returned no job")
+	}
+	return *response.Job, nil
+}
+
+// WaitJob polls a job until it finishes, calling progress with every
+// snapshot. Canceling ctx cancels the job on the server.
+func (c *Client) WaitJob(ctx context.Context, job jobs.Job, progress func(jobs.Job)) (jobs.Job, error) {
+	for {
+		if progress != nil {
+			progress(job)
+		}
+		if job.Done() {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			cancelCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = c.do(cancelCtx, http.MethodDelete, "/api/v1/jobs/"+url.PathEscape(job.ID), nil, nil)
+			return job, ctx.Err()
+		case <-time.After(jobPollDelay):
+		}
+		next, err := c.Job(ctx, job.ID)
+		if err != nil {
+			return job, err
+		}
+		job = next
+	}
+}
+
+// ModuleAction runs install, uninstall or purge for a module as a server
+// job and waits for it to finish.
+func (c *Client) ModuleAction(ctx context.Context, name, action string, progress func(jobs.Job)) (jobs.Job, error) {
+	path := fmt.Sprintf("/api/v1/modules/%s/%s", url.PathEscape(name), url.PathEscape(action))
+	return c.runJob(ctx, path, progress)
+}
+
+// Rollback converges the host to a state snapshot as a server job and
+// waits for it to finish.
+func (c *Client) Rollback(ctx context.Context, snapshotID string, progress func(jobs.Job)) (jobs.Job, error) {
+	return c.runJob(ctx, "/api/v1/state/rollback/"+url.PathEscape(snapshotID), progress)
+}
+
+// Reconcile has the server probe the host and correct the recorded module
+// state, or only report the corrections when dryRun is set.
+func (c *Client) Reconcile(ctx context.Context, dryRun bool) ([]control.StateCorrection, error) {
+	var response struct {
+		Corrections []control.StateCorrection `json:"corrections"`
+	}
+	path := "/api/v1/state/reconcile"
+	if dryRun {
+		path += "?dry_run=true"
+	}
+	if err := c.do(ctx, http.MethodPost, path, nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Corrections, nil
+}
+
+// QueueDownload adds a model download to the server's download queue and
+// returns it with the job that tracks it.
+func (c *Client) QueueDownload(ctx context.Context, source modelmanager.ModelSource, modelID string, opts modelmanager.DownloadOptions, priority int) (modelmanager.QueueItem, jobs.Job, error) {
+	var response struct {
+		Item *modelmanager.QueueItem `json:"item"`
+		Job  *jobs.Job               `json:"job"`
+	}
+	body := map[string]any{
+		"source":       source,
+		"id":           modelID,
+		"file":         opts.FileHint,
+		"ignore_quota": opts.IgnoreQuota,
+		"queue":        true,
+		"priority":     priority,
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/models/download", body, &response); err != nil {
+		return modelmanager.QueueItem{}, jobs.Job{}, err
+	}
+	if response.Item == nil || response.Job == nil {
+		return modelmanager.QueueItem{}, jobs.Job{}, i18n.Errorf("server returned no queued download")
+	}
+	return *response.Item, *response.Job, nil
+}
+
+// DownloadQueue lists the server's download queue.
+func (c *Client) DownloadQueue(ctx context.Context) ([]modelmanager.QueueItem, error) {
+	var response struct {
+		Items []modelmanager.QueueItem `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/models/queue", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Items, nil
+}
+
+// QueueAction pauses, resumes or cancels a queued download. The priority
+// action takes body {"priority": n}.
+func (c *Client) QueueAction(ctx context.Context, id, action string, body any) (modelmanager.QueueItem, error) {
+	var response struct {
+		Item *modelmanager.QueueItem `json:"item"`
+	}
+	path := fmt.Sprintf("/api/v1/models/queue/%s/%s", url.PathEscape(id), url.PathEscape(action))
+	if err := c.do(ctx, http.MethodPost, path, body, &response); err != nil {
+		return modelmanager.QueueItem{}, err
+	}
+	if response.Item == nil {
+		return modelmanager.QueueItem{}, i18n.Errorf("server returned no queued download")
+	}
+	return *response.Item, nil
+}
+
+// ClearQueue removes finished downloads from the server's queue and
+// returns how many were removed.
+func (c *Client) ClearQueue(ctx context.Context) (int, error) {
+	var response struct {
+		Removed int `json:"removed"`
+	}
+	if err := c.do(ctx, http.MethodDelete, "/api/v1/models/queue", nil, &response); err != nil {
+		return 0, err
+	}
+	return response.Removed, nil
+}
+
+// runJob submits a job with a POST to path and waits for it.
+func (c *Client) runJob(ctx context.Context, path string, progress func(jobs.Job)) (jobs.Job, error) {
+	var response struct {
+		Job *jobs.Job `json:"job"`
+	}
+	if err := c.do(ctx, http.MethodPost, path, nil, &response); err != nil {
+		return jobs.Job{}, err
+	}
+	if response.Job == nil {
+		return jobs.Job{}, i18n.Errorf("server returned no job")
+	}
+	return c.WaitJob(ctx, *response.Job, progress)
+}
+
+// Capabilities returns what the server's matched hardware policies allow,
+// or nil when the server has not evaluated them.
+func (c *Client) Capabilities(ctx context.Context) (*control.CapabilitySet, error) {
+	var response struct {
+		Capabilities *control.CapabilitySet `json:"capabilities"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/status", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Capabilities, nil
+}
+
+// Service is a process supervised by the server or a module's systemd unit.
+type Service struct {
+	Name      string     `json:"name"`
+	Kind      string     `json:"kind"`
+	Unit      string     `json:"unit,omitempty"`
+	State     string     `json:"state"`
+	Health    string     `json:"health"`
+	PID       int        `json:"pid,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Restarts  int        `json:"restarts"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// Services lists the services the server knows about.
+func (c *Client) Services(ctx context.Context) ([]Service, error) {
+	var response struct {
+		Services []Service `json:"services"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/services", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Services, nil
+}
+
+// ServiceAction starts, stops or restarts a service.
+func (c *Client) ServiceAction(ctx context.Context, name, action string) (*Service, error) {
+	var response struct {
+		Service *Service `json:"service"`
+	}
+	path := fmt.Sprintf("/api/v1/services/%s/%s", url.PathEscape(name), url.PathEscape(action))
+	if err := c.do(ctx, http.MethodPost, path, nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Service, nil
+}
+
+// ProcessSpec describes a native process for the server to supervise.
+type ProcessSpec struct {
+	Name    string            `json:"name"`
+	Command []string          `json:"command"`
+	Env     map[string]string `json:"env,omitempty"`
+	WorkDir string            `json:"work_dir,omitempty"`
+	// HealthCommand is run periodically; the process is healthy while it
+	// exits with status 0.
+	HealthCommand []string `json:"health_command,omitempty"`
+}
+
+// StartProcess asks the server's runtime manager to run and supervise a
+// process.
+func (c *Client) StartProcess(ctx context.Context, spec ProcessSpec) (*Service, error) {
+	var response struct {
+		Service *Service `json:"service"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/services", spec, &response); err != nil {
+		return nil, err
+	}
+	return response.Service, nil
+}
@@ -24,9 +24,14 @@ type Config struct {
 	Logging LoggingConfig `mapstructure:"logging"`
 	Control ControlConfig `mapstructure:"control"`
 	Storage StorageConfig `mapstructure:"storage"`
-	Runtime RuntimeConfig `mapstructure:"runtime"`
-	LLM     LLMConfig     `mapstructure:"llm"`
-	I18n    I18nConfig    `mapstructure:"i18n"`
+	// ModelSources configures the url: and oci: model providers.
+	ModelSources ModelSourcesConfig `mapstructure:"model_sources"`
+	// Downloads limits the model download queue.
+	Downloads DownloadsConfig `mapstructure:"downloads"`
+	Runtime   RuntimeConfig   `mapstructure:"runtime"`
+	Gateway   GatewayConfig   `mapstructure:"gateway"`
+	LLM       LLMConfig       `mapstructure:"llm"`
+	I18n      I18nConfig      `mapstructure:"i18n"`
 }
 
 type ServerConfig struct {
@@ -37,6 +42,7 @@ type ServerConfig struct {
 	EnableTLS    bool   `mapstructure:"enable_tls"`
 	TLSCertFile  string `mapstructure:"tls_cert_file"`
 	TLSKeyFile   string `mapstructure:"tls_key_file"`
+	AuthEnabled  bool   `mapstructure:"auth_enabled"`
 }
 
 type LoggingConfig struct {
@@ -54,6 +60,35 @@ type StorageConfig struct {
 	ModelDir    string `mapstructure:"model_dir"`
 	CacheDir    string `mapstructure:"cache_dir"`
 	DownloadDir string `mapstructure:"download_dir"`
+	// ModelQuota caps the model directory, e.g. "500GB" or "80%" of its
+	// file system; empty means unlimited.
+	ModelQuota string `mapstructure:"model_quota"`
+}
+
+// ModelSourcesConfig points the url: and oci: model providers at an
+// artifact server or registry.
+type ModelSourcesConfig struct {
+	// IndexURL is a JSON model index used for search and to resolve model
+	// IDs to files or OCI references.
+	IndexURL string `mapstructure:"index_url"`
+	// Auth holds credentials keyed by host, e.g. "artifacts.example.com".
+	Auth map[string]ModelSourceAuth `mapstructure:"auth"`
+}
+
+type ModelSourceAuth struct {
+	Token     string            `mapstructure:"token"`
+	Username  string            `mapstructure:"username"`
+	Password  string            `mapstructure:"password"`
+	Headers   map[string]string `mapstructure:"headers"`
+	PlainHTTP bool              `mapstructure:"plain_http"`
+}
+
+type DownloadsConfig struct {
+	MaxConcurrent int `mapstructure:"max_concurrent"`
+	PerHost       int `mapstructure:"per_host"`
+	// BandwidthLimit caps the combined download rate per second, e.g.
+	// "20MB"; empty means unlimited.
+	BandwidthLimit string `mapstructure:"bandwidth_limit"`
 }
 
 type RuntimeConfig struct {
@@ -63,6 +98,13 @@ type RuntimeConfig struct {
 	LogDir        string `mapstructure:"log_dir"`
 }
 
+// GatewayConfig lists the OpenAI-compatible model servers the API proxies
+// chat requests to.
+type GatewayConfig struct {
+	Endpoints      []string `mapstructure:"endpoints"`
+	TimeoutSeconds int      `mapstructure:"timeout_seconds"`
+}
+
 type LLMConfig struct {
 	Provider       string `mapstructure:"provider"`
 	Model          string `mapstructure:"model"`
@@ -99,6 +141,7 @@ func DefaultConfig() *Config {
 			ReadTimeout:  30,
 			WriteTimeout: 30,
 			EnableTLS:    false,
+			AuthEnabled:  true,
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
@@ -114,12 +157,20 @@ func DefaultConfig() *Config {
 			CacheDir:    "/var/lib/localaistack/cache",
 			DownloadDir: "/var/lib/localaistack/downloads",
 		},
+		Downloads: DownloadsConfig{
+			MaxConcurrent: 3,
+			PerHost:       2,
+		},
 		Runtime: RuntimeConfig{
 			DockerEnabled: true,
 			NativeEnabled: true,
 			DefaultMode:   "container",
 			LogDir:        "/var/lib/localaistack/runtime",
 		},
+		Gateway: GatewayConfig{
+			Endpoints:      []string{"http://127.0.0.1:11434", "http://127.0.0.1:8000"},
+			TimeoutSeconds: 300,
+		},
 		LLM: LLMConfig{
 			Provider:       "siliconflow",
 			Model:          "deepseek-ai/DeepSeek-R1-0528-Qwen3-8B",
@@ -232,6 +283,7 @@ func applyDefaults(v *viper.Viper, defaults *Config) {
 	v.SetDefault("server.enable_tls", defaults.Server.EnableTLS)
 	v.SetDefault("server.tls_cert_file", defaults.Server.TLSCertFile)
 	v.SetDefault("server.tls_key_file", defaults.Server.TLSKeyFile)
+	v.SetDefault("server.auth_enabled", defaults.Server.AuthEnabled)
 
 	v.SetDefault("logging.level", defaults.Logging.Level)
 	v.SetDefault("logging.format", defaults.Logging.Format)
@@ -243,12 +295,20 @@ func applyDefaults(v *viper.Viper, defaults *Config) {
 	v.SetDefault("storage.model_dir", defaults.Storage.ModelDir)
 	v.SetDefault("storage.cache_dir", defaults.Storage.CacheDir)
 	v.SetDefault("storage.download_dir", defaults.Storage.DownloadDir)
+	v.SetDefault("storage.model_quota", defaults.Storage.ModelQuota)
+	v.SetDefault("model_sources.index_url", defaults.ModelSources.IndexURL)
+	v.SetDefault("downloads.max_concurrent", defaults.Downloads.MaxConcurrent)
+	v.SetDefault("downloads.per_host", defaults.Downloads.PerHost)
+	v.SetDefault("downloads.bandwidth_limit", defaults.Downloads.BandwidthLimit)
 
 	v.SetDefault("runtime.docker_enabled", defaults.Runtime.DockerEnabled)
 	v.SetDefault("runtime.native_enabled", defaults.Runtime.NativeEnabled)
 	v.SetDefault("runtime.default_mode", defaults.Runtime.DefaultMode)
 	v.SetDefault("runtime.log_dir", defaults.Runtime.LogDir)
 
+	v.SetDefault("gateway.endpoints", defaults.Gateway.Endpoints)
+	v.SetDefault("gateway.timeout_seconds", defaults.Gateway.TimeoutSeconds)
+
 	v.SetDefault("llm.provider", defaults.LLM.Provider)
 	v.SetDefault("llm.model", defaults.LLM.Model)
 	v.SetDefault("llm.api_key", defaults.LLM.APIKey)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/rs/zerolog/log"
 	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
@@ -16,6 +17,7 @@ type ControlLayer struct {
 	detector     hardware.Detector
 	policyEngine *PolicyEngine
 	stateManager *StateManager
+	mu           sync.RWMutex
 	profile      *hardware.HardwareProfile
 	capabilities *CapabilitySet
 }
@@ -57,6 +59,51 @@ func (c *ControlLayer) Stop(ctx context.Context) error {
 	return nil
 }
 
+// Profile returns the detected hardware profile, or nil before Start.
+func (c *ControlLayer) Profile() *hardware.HardwareProfile {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.profile == nil {
+		return nil
+	}
+	profile := *c.profile
+	profile.GPUs = append([]hardware.GPU(nil), c.profile.GPUs...)
+	profile.Storage = append([]hardware.Storage(nil), c.profile.Storage...)
+	return &profile
+}
+
+// Capabilities returns the capabilities granted by the matched policies, or
+// nil before Start.
+func (c *ControlLayer) Capabilities() *CapabilitySet {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.capabilities == nil {
+		return nil
+	}
+	capabilities := *c.capabilities
+	return &capabilities
+}
+
+// State returns the persisted system state, if the state manager is ready.
+func (c *ControlLayer) State() (SystemState, bool) {
+	if c.stateManager == nil {
+		return SystemState{}, false
+	}
+	return c.stateManager.GetState(), true
+}
+
+// RefreshHardware detects hardware again and re-evaluates policies against
+// the new profile.
+func (c *ControlLayer) RefreshHardware(ctx context.Context) error {
+	if err := c.detectHardware(ctx); err != nil {
+		return i18n.Errorf("failed to detect hardware: %w", err)
+	}
+	if err := c.evaluatePolicies(ctx); err != nil {
+		return i18n.Errorf("failed to evaluate policies: %w", err)
+	}
+	return nil
+}
+
 func (c *ControlLayer) initHardwareDetector(ctx context.Context) error {
 	log.Info().Msg(i18n.T("Initializing hardware detector"))
 	c.detector = hardware.NewNativeDetector()
@@ -65,21 +112,54 @@ func (c *ControlLayer) initHardwareDetector(ctx context.Context) error {
 
 func (c *ControlLayer) initPolicyEngine(ctx context.Context) error {
 	log.Info().Msg(i18n.T("Initializing policy engine"))
-	paths := policyCandidatePaths(c.cfg.Control.PolicyFile)
+	engine, path, err := openPolicyEngine(c.cfg.Control.PolicyFile)
+	if err != nil {
+		return err
+	}
+	c.policyEngine = engine
+	log.Info().Str("path", path).Msg(i18n.T("Loaded policy file"))
+	return nil
+}
+
+// openPolicyEngine loads the first readable candidate for policyFile and
+// returns it with its path.
+func openPolicyEngine(policyFile string) (*PolicyEngine, string, error) {
 	var lastErr error
-	for _, path := range paths {
+	for _, path := range policyCandidatePaths(policyFile) {
 		engine, err := LoadPolicyEngine(path)
 		if err == nil {
-			c.policyEngine = engine
-			log.Info().Str("path", path).Msg(i18n.T("Loaded policy file"))
-			return nil
+			return engine, path, nil
 		}
 		lastErr = err
 	}
 	if lastErr != nil {
-		return lastErr
+		return nil, "", lastErr
+	}
+	return nil, "", i18n.Errorf("policy file not found")
+}
+
+// detectProfile detects the host's hardware; tests replace it.
+var detectProfile = func() (*hardware.HardwareProfile, error) {
+	return hardware.NewNativeDetector().Detect()
+}
+
+// DetectCapabilities detects the host's hardware and evaluates the policy
+// file the server would load against it, for commands run without a
+// server.
+func DetectCapabilities(policyFile string) (*CapabilitySet, error) {
+	engine, _, err := openPolicyEngine(policyFile)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := detectProfile()
+	if err != nil {
+		return nil, i18n.Errorf("failed to detect hardware: %w", err)
 	}
-	return i18n.Errorf("policy file not found")
+	capabilities, err := engine.Evaluate(profile)
+	if err != nil {
+		return nil, err
+	}
+	return &capabilities, nil
 }
 
 func policyCandidatePaths(primary string) []string {
@@ -159,21 +239,35 @@ func stateCandidateDirs(primary string) []string {
 
 func (c *ControlLayer) initStateManager(ctx context.Context) error {
 	log.Info().Msg(i18n.T("Initializing state manager"))
-	paths := stateCandidateDirs(c.cfg.Control.DataDir)
+	manager, err := OpenStateManager(c.cfg.Control.DataDir)
+	if err != nil {
+		return err
+	}
+	c.stateManager = manager
+	log.Info().Str("path", filepath.Dir(manager.Path())).Msg(i18n.T("State directory ready"))
+	return nil
+}
+
+// OpenStateManager opens the state file in the first usable candidate
+// directory for dataDir, the same one the server uses.
+func OpenStateManager(dataDir string) (*StateManager, error) {
 	var lastErr error
-	for _, path := range paths {
+	for _, path := range stateCandidateDirs(dataDir) {
 		manager, err := NewStateManager(path)
 		if err == nil {
-			c.stateManager = manager
-			log.Info().Str("path", path).Msg(i18n.T("State directory ready"))
-			return nil
+			return manager, nil
 		}
 		lastErr = err
 	}
 	if lastErr != nil {
-		return lastErr
+		return nil, lastErr
 	}
-	return i18n.Errorf("state directory not available")
+	return nil, i18n.Errorf("state directory not available")
+}
+
+// StateManager returns the state manager, or nil before Start.
+func (c *ControlLayer) StateManager() *StateManager {
+	return c.stateManager
 }
 
 func (c *ControlLayer) detectHardware(ctx context.Context) error {
@@ -184,7 +278,9 @@ func (c *ControlLayer) detectHardware(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	c.mu.Lock()
 	c.profile = profile
+	c.mu.Unlock()
 	return nil
 }
 
@@ -192,13 +288,16 @@ func (c *ControlLayer) evaluatePolicies(ctx context.Context) error {
 	if c.policyEngine == nil {
 		return i18n.Errorf("policy engine not initialized")
 	}
-	if c.profile == nil {
+	profile := c.Profile()
+	if profile == nil {
 		return i18n.Errorf("hardware profile not available")
 	}
-	capabilities, err := c.policyEngine.Evaluate(c.profile)
+	capabilities, err := c.policyEngine.Evaluate(profile)
 	if err != nil {
 		return err
 	}
+	c.mu.Lock()
 	c.capabilities = &capabilities
+	c.mu.Unlock()
 	return nil
 }
@@ -0,0 +1,44 @@
+package control
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zhuangbiaowei/LocalAIStack/pkg/hardware"
+)
+
+func TestDetectCapabilities(t *testing.T) {
+	policyFile := filepath.Join(t.TempDir(), "policies.yaml")
+	policies := `policies:
+  - name: small
+    conditions:
+      ram_max: "32GB"
+    allow:
+      max_model_size: "14B"
+  - name: large
+    conditions:
+      ram_min: "64GB"
+    allow:
+      max_model_size: "70B"
+`
+	if err := os.WriteFile(policyFile, []byte(policies), 0o644); err != nil {
+		t.Fatalf("write policies: %v", err)
+	}
+	previous := detectProfile
+	detectProfile = func() (*hardware.HardwareProfile, error) {
+		return &hardware.HardwareProfile{Memory: hardware.Memory{Total: 16 << 30}}, nil
+	}
+	t.Cleanup(func() { detectProfile = previous })
+
+	capabilities, err := DetectCapabilities(policyFile)
+	if err != nil {
+		t.Fatalf("DetectCapabilities: %v", err)
+	}
+	if capabilities.MaxModelSize != "14B" || len(capabilities.MatchedPolicies) != 1 || capabilities.MatchedPolicies[0] != "small" {
+		t.Fatalf("capabilities = %+v, want the small policy", capabilities)
+	}
+	if capabilities.AllowsModel(30_000_000_000) {
+		t.Fatalf("a 30B model must exceed the 14B limit")
+	}
+}
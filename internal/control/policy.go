@@ -228,6 +228,12 @@ func parseBytes(raw string) (uint64, error) {
 	return uint64(value), nil
 }
 
+// AllowsModel reports whether a model with the given number of parameters
+// is within MaxModelSize, which is expressed in billions of parameters.
+func (c CapabilitySet) AllowsModel(parameters uint64) bool {
+	return float64(parameters)/1e9 <= modelSizeLimit(c.MaxModelSize)
+}
+
 func modelSizeLimit(raw string) float64 {
 	trimmed := strings.TrimSpace(strings.ToUpper(raw))
 	if trimmed == "" || trimmed == "UNLIMITED" {
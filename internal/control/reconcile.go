@@ -0,0 +1,202 @@
+package control
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+)
+
+// StateCorrection is one change Reconcile made, or would make, to the
+// recorded state of a module. An empty Previous means the module was not
+// recorded.
+type StateCorrection struct {
+	ModuleName string       `json:"module"`
+	Previous   module.State `json:"previous,omitempty"`
+	Corrected  module.State `json:"corrected"`
+	Reason     string       `json:"reason"`
+}
+
+// ReconcileOptions tunes Reconcile.
+type ReconcileOptions struct {
+	// Process reports whether a runtime process named after the module is
+	// running; known is false when no such process exists.
+	Process func(name string) (running, known bool)
+	// DryRun reports the corrections without saving them.
+	DryRun bool
+}
+
+// probeModule and moduleNames look at the host and the modules tree; tests
+// replace them.
+var (
+	probeModule = module.Probe
+	moduleNames = func() ([]string, error) {
+		root, err := module.FindModulesRoot()
+		if err != nil {
+			return nil, err
+		}
+		registry, err := module.LoadRegistryFromDir(root)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(registry.All()))
+		for name := range registry.All() {
+			names = append(names, name)
+		}
+		return names, nil
+	}
+)
+
+// Reconcile probes every recorded module, and every module in the modules
+// tree, and corrects the recorded state where it drifted from the host:
+// modules whose verification or binaries are gone become failed, modules
+// found installed are recorded, and running or stopped follows the
+// module's systemd unit or runtime process.
+func (m *StateManager) Reconcile(ctx context.Context, opts ReconcileOptions) ([]StateCorrection, error) {
+	recorded := m.GetState().Modules
+	candidates := map[string]bool{}
+	for name := range recorded {
+		candidates[name] = true
+	}
+	if names, err := moduleNames(); err == nil {
+		for _, name := range names {
+			candidates[name] = true
+		}
+	}
+	names := make([]string, 0, len(candidates))
+	for name := range candidates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Probes run scripts, so they run before taking the lock.
+	observations := map[string]module.Observation{}
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		observation, err := probeModule(ctx, name)
+		if err != nil {
+			continue
+		}
+		observations[name] = observation
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var corrections []StateCorrection
+	changed := false
+	updated := cloneModules(m.state.Modules)
+	for _, name := range names {
+		original, ok := updated[name]
+		current := original
+		next := current
+		if ok && !isValidModuleState(current.State) {
+			next.State = module.StateFailed
+			corrections = append(corrections, StateCorrection{
+				ModuleName: name,
+				Previous:   current.State,
+				Corrected:  module.StateFailed,
+				Reason:     i18n.T("invalid recorded state"),
+			})
+			current = next
+		}
+		if observation, probed := observations[name]; probed {
+			state, reason := observedState(current, ok, observation, opts.Process)
+			if reason != "" {
+				next.State = state
+				corrections = append(corrections, StateCorrection{
+					ModuleName: name,
+					Previous:   current.State,
+					Corrected:  state,
+					Reason:     reason,
+				})
+			}
+		}
+		if next.State == "" {
+			continue
+		}
+		if !ok {
+			next.Name = name
+			next.Version = "unknown"
+			if version, err := availableVersion(name); err == nil {
+				next.Version = version
+			}
+		}
+		if next.Version == "" {
+			next.Version = "unknown"
+		}
+		if next != original {
+			next.UpdatedAt = time.Now().UTC()
+			updated[name] = next
+			changed = true
+		}
+	}
+
+	if !changed || opts.DryRun {
+		return corrections, nil
+	}
+	m.pushSnapshotLocked(i18n.T("reconcile"))
+	m.state.Modules = updated
+	if err := m.saveLocked(); err != nil {
+		return nil, err
+	}
+	return corrections, nil
+}
+
+// observedState returns the state the probes say a module is in, with the
+// reason, or an empty reason when the recorded state already agrees.
+func observedState(current ModuleState, recorded bool, observation module.Observation, process func(string) (bool, bool)) (module.State, string) {
+	present := isPresent(current, recorded)
+	if !observation.Conclusive() {
+		if !present {
+			return current.State, ""
+		}
+	} else if !observation.Installed() {
+		if !present {
+			return current.State, ""
+		}
+		if len(observation.MissingBinaries) > 0 {
+			return module.StateFailed, i18n.T("expected binaries missing: %s", strings.Join(observation.MissingBinaries, ", "))
+		}
+		return module.StateFailed, observation.VerifyError
+	}
+
+	running, known := false, false
+	if process != nil {
+		running, known = process(observation.Module)
+	}
+	source := i18n.T("runtime process")
+	if observation.UnitState != "" {
+		known = true
+		running = running || observation.UnitState == "active"
+		source = i18n.T("unit %s", observation.Unit)
+	}
+
+	state := module.StateInstalled
+	switch {
+	case known && running:
+		state = module.StateRunning
+	case known && present && current.State != module.StateInstalled:
+		state = module.StateStopped
+	case present:
+		// Without a service to inspect, installed, running and stopped
+		// are all consistent with the probes.
+		return current.State, ""
+	}
+	if state == current.State && recorded {
+		return current.State, ""
+	}
+	switch {
+	case !present:
+		return state, i18n.T("found installed on the host")
+	case state == module.StateRunning:
+		return state, i18n.T("%s is running", source)
+	default:
+		return state, i18n.T("%s is not running", source)
+	}
+}
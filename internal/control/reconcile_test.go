@@ -0,0 +1,112 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+)
+
+func stubProbes(t *testing.T, observations map[string]module.Observation) {
+	t.Helper()
+	previousProbe, previousNames := probeModule, moduleNames
+	probeModule = func(_ context.Context, name string) (module.Observation, error) {
+		observation, ok := observations[name]
+		if !ok {
+			return module.Observation{}, errors.New("module not found")
+		}
+		return observation, nil
+	}
+	moduleNames = func() ([]string, error) {
+		names := make([]string, 0, len(observations))
+		for name := range observations {
+			names = append(names, name)
+		}
+		return names, nil
+	}
+	t.Cleanup(func() { probeModule, moduleNames = previousProbe, previousNames })
+}
+
+func TestReconcile_CorrectsDrift(t *testing.T) {
+	verified, failed := true, false
+	stubAvailableVersions(t, map[string]string{"found": "3.0"})
+	stubProbes(t, map[string]module.Observation{
+		"gone":    {Module: "gone", Binaries: []string{"/usr/local/bin/gone"}, MissingBinaries: []string{"/usr/local/bin/gone"}},
+		"broken":  {Module: "broken", Verified: &failed, VerifyError: "broken check failed"},
+		"daemon":  {Module: "daemon", Verified: &verified, Unit: "daemon.service", UnitState: "active"},
+		"halted":  {Module: "halted", Verified: &verified, Unit: "halted.service", UnitState: "inactive"},
+		"proc":    {Module: "proc", Verified: &verified},
+		"found":   {Module: "found", Verified: &verified},
+		"absent":  {Module: "absent", Verified: &failed, VerifyError: "not installed"},
+		"healthy": {Module: "healthy", Verified: &verified},
+	})
+
+	manager, err := NewStateManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStateManager: %v", err)
+	}
+	for name, state := range map[string]module.State{
+		"gone":    module.StateInstalled,
+		"broken":  module.StateRunning,
+		"daemon":  module.StateStopped,
+		"halted":  module.StateRunning,
+		"proc":    module.StateInstalled,
+		"healthy": module.StateInstalled,
+	} {
+		if err := manager.UpdateModule(name, "1.0", state); err != nil {
+			t.Fatalf("UpdateModule(%s): %v", name, err)
+		}
+	}
+
+	process := func(name string) (bool, bool) { return name == "proc", name == "proc" }
+	preview, err := manager.Reconcile(context.Background(), ReconcileOptions{Process: process, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry-run Reconcile: %v", err)
+	}
+	if state, _ := manager.GetModule("gone"); state.State != module.StateInstalled {
+		t.Fatalf("dry run changed the state: %+v", state)
+	}
+
+	corrections, err := manager.Reconcile(context.Background(), ReconcileOptions{Process: process})
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(preview) != len(corrections) {
+		t.Fatalf("dry run reported %d corrections, Reconcile made %d", len(preview), len(corrections))
+	}
+	want := map[string]module.State{
+		"gone":   module.StateFailed,
+		"broken": module.StateFailed,
+		"daemon": module.StateRunning,
+		"halted": module.StateStopped,
+		"proc":   module.StateRunning,
+		"found":  module.StateInstalled,
+	}
+	if len(corrections) != len(want) {
+		t.Fatalf("corrections = %+v, want %d", corrections, len(want))
+	}
+	for _, correction := range corrections {
+		if correction.Corrected != want[correction.ModuleName] || correction.Reason == "" {
+			t.Fatalf("unexpected correction %+v", correction)
+		}
+		state, ok := manager.GetModule(correction.ModuleName)
+		if !ok || state.State != correction.Corrected {
+			t.Fatalf("correction for %s not saved: %+v", correction.ModuleName, state)
+		}
+	}
+	if found, _ := manager.GetModule("found"); found.Version != "3.0" {
+		t.Fatalf("found module version = %q, want 3.0", found.Version)
+	}
+	if _, ok := manager.GetModule("absent"); ok {
+		t.Fatalf("a module that is not installed must not be recorded")
+	}
+
+	again, err := manager.Reconcile(context.Background(), ReconcileOptions{Process: process})
+	if err != nil {
+		t.Fatalf("second Reconcile: %v", err)
+	}
+	if len(again) != 0 {
+		t.Fatalf("second Reconcile should find no drift, got %+v", again)
+	}
+}
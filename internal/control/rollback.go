@@ -0,0 +1,293 @@
+package control
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+)
+
+// CurrentStateRef names the live module state where a snapshot ID is
+// expected.
+const CurrentStateRef = "current"
+
+type ChangeAction string
+
+const (
+	ActionInstall   ChangeAction = "install"
+	ActionUninstall ChangeAction = "uninstall"
+	ActionReinstall ChangeAction = "reinstall"
+	// ActionRecord changes only the recorded state, not the host.
+	ActionRecord ChangeAction = "record"
+	// ActionSkip leaves the module alone: the snapshot's version can no
+	// longer be installed, so a reinstall would only churn the host.
+	ActionSkip ChangeAction = "skip"
+)
+
+// ModuleChange is the difference of one module between two states and the
+// action that turns the first into the second.
+type ModuleChange struct {
+	Module  string       `json:"module"`
+	Action  ChangeAction `json:"action"`
+	From    *ModuleState `json:"from,omitempty"`
+	To      *ModuleState `json:"to,omitempty"`
+	Warning string       `json:"warning,omitempty"`
+}
+
+// RollbackPlan lists the changes that converge the host to a snapshot.
+type RollbackPlan struct {
+	Snapshot  string         `json:"snapshot"`
+	Reason    string         `json:"reason"`
+	CreatedAt time.Time      `json:"created_at"`
+	Changes   []ModuleChange `json:"changes"`
+}
+
+// ModuleRunner performs a module lifecycle action (install or uninstall)
+// on the host.
+type ModuleRunner func(ctx context.Context, name, action string) error
+
+// availableVersion is the module version an install provides; tests
+// replace it.
+var availableVersion = module.AvailableVersion
+
+// Present reports whether the recorded state means the module's software
+// is on the host.
+func (s ModuleState) Present() bool {
+	switch s.State {
+	case module.StateInstalled, module.StateRunning, module.StateStopped:
+		return true
+	default:
+		return false
+	}
+}
+
+func isPresent(state ModuleState, ok bool) bool {
+	return ok && state.Present()
+}
+
+// DiffModules returns the per-module changes from one module set to
+// another, sorted by module name.
+func DiffModules(from, to map[string]ModuleState) []ModuleChange {
+	names := map[string]bool{}
+	for name := range from {
+		names[name] = true
+	}
+	for name := range to {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	changes := []ModuleChange{}
+	for _, name := range sorted {
+		before, hadBefore := from[name]
+		after, hasAfter := to[name]
+		change := ModuleChange{Module: name}
+		if hadBefore {
+			change.From = &before
+		}
+		if hasAfter {
+			change.To = &after
+		}
+		wasPresent, isNowPresent := isPresent(before, hadBefore), isPresent(after, hasAfter)
+		switch {
+		case !wasPresent && isNowPresent:
+			change.Action = ActionInstall
+		case wasPresent && !isNowPresent:
+			change.Action = ActionUninstall
+		case wasPresent && before.Version != after.Version:
+			change.Action = ActionReinstall
+		case hadBefore != hasAfter || before.State != after.State || before.Version != after.Version:
+			change.Action = ActionRecord
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// Path returns the state file.
+func (m *StateManager) Path() string {
+	return m.dataPath
+}
+
+// History returns the recorded snapshots, oldest first.
+func (m *StateManager) History() []StateSnapshot {
+	return m.GetState().History
+}
+
+// Modules returns the module set of a snapshot, or the live one for
+// CurrentStateRef.
+func (m *StateManager) Modules(ref string) (map[string]ModuleState, error) {
+	state := m.GetState()
+	if ref == CurrentStateRef {
+		return state.Modules, nil
+	}
+	for _, snapshot := range state.History {
+		if snapshot.ID == ref {
+			return snapshot.Modules, nil
+		}
+	}
+	return nil, i18n.Errorf("snapshot %s not found", ref)
+}
+
+// Diff compares two snapshots, either of which may be CurrentStateRef.
+func (m *StateManager) Diff(from, to string) ([]ModuleChange, error) {
+	fromModules, err := m.Modules(from)
+	if err != nil {
+		return nil, err
+	}
+	toModules, err := m.Modules(to)
+	if err != nil {
+		return nil, err
+	}
+	return DiffModules(fromModules, toModules), nil
+}
+
+// PlanRollback computes the module actions that take the host from its
+// current state to a snapshot. Changes whose target version is no longer
+// the one the modules tree provides carry a warning; such reinstalls are
+// skipped, since they cannot restore the snapshot's version.
+func (m *StateManager) PlanRollback(snapshotID string) (RollbackPlan, error) {
+	state := m.GetState()
+	var target *StateSnapshot
+	for i := range state.History {
+		if state.History[i].ID == snapshotID {
+			target = &state.History[i]
+			break
+		}
+	}
+	if target == nil {
+		return RollbackPlan{}, i18n.Errorf("snapshot %s not found", snapshotID)
+	}
+
+	plan := RollbackPlan{
+		Snapshot:  target.ID,
+		Reason:    target.Reason,
+		CreatedAt: target.CreatedAt,
+		Changes:   DiffModules(state.Modules, target.Modules),
+	}
+	for i, change := range plan.Changes {
+		if change.Action != ActionInstall && change.Action != ActionReinstall {
+			continue
+		}
+		version, err := availableVersion(change.Module)
+		switch {
+		case change.Action == ActionReinstall && err != nil:
+			plan.Changes[i].Action = ActionSkip
+			plan.Changes[i].Warning = i18n.T("%v; keeping version %s", err, change.From.Version)
+		case change.Action == ActionReinstall && version != change.To.Version:
+			plan.Changes[i].Action = ActionSkip
+			plan.Changes[i].Warning = i18n.T("version %s is not available; keeping version %s", change.To.Version, change.From.Version)
+		case err != nil:
+			plan.Changes[i].Warning = err.Error()
+		case version != change.To.Version:
+			plan.Changes[i].Warning = i18n.T("version %s is not available; %s will be installed", change.To.Version, version)
+		}
+	}
+	return plan, nil
+}
+
+// ExecuteRollback applies a rollback plan with run and records each change
+// as it completes, so a failed rollback leaves the state matching the host.
+// Skipped changes are neither applied nor recorded. progress, if set, is
+// called before and after each applied change.
+func (m *StateManager) ExecuteRollback(ctx context.Context, plan RollbackPlan, run ModuleRunner, progress func(change ModuleChange, err error, done bool)) error {
+	m.mu.Lock()
+	m.pushSnapshotLocked(i18n.T("pre-rollback to %s", plan.Snapshot))
+	err := m.saveLocked()
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, change := range plan.Changes {
+		if change.Action == ActionSkip {
+			continue
+		}
+		if progress != nil {
+			progress(change, nil, false)
+		}
+		err := applyChange(ctx, change, run)
+		if progress != nil {
+			progress(change, err, true)
+		}
+		if err != nil {
+			return i18n.Errorf("rollback of %s failed: %w", change.Module, err)
+		}
+		if err := m.recordChange(change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyChange(ctx context.Context, change ModuleChange, run ModuleRunner) error {
+	switch change.Action {
+	case ActionInstall:
+		return run(ctx, change.Module, "install")
+	case ActionUninstall:
+		return run(ctx, change.Module, "uninstall")
+	case ActionReinstall:
+		if err := run(ctx, change.Module, "uninstall"); err != nil {
+			return err
+		}
+		return run(ctx, change.Module, "install")
+	default:
+		return nil
+	}
+}
+
+// recordChange stores the post-change state of a module. Installs record
+// the version that was actually installed.
+func (m *StateManager) recordChange(change ModuleChange) error {
+	var target ModuleState
+	if change.To != nil {
+		target = *change.To
+	}
+	if change.Action == ActionInstall || change.Action == ActionReinstall {
+		if version, err := availableVersion(change.Module); err == nil {
+			target.Version = version
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if change.To == nil {
+		delete(m.state.Modules, change.Module)
+	} else {
+		target.UpdatedAt = time.Now().UTC()
+		m.state.Modules[change.Module] = target
+	}
+	return m.saveLocked()
+}
+
+// RecordModuleAction records the outcome of a successful install,
+// uninstall or purge of a module.
+func (m *StateManager) RecordModuleAction(name, action string) error {
+	switch action {
+	case "install":
+		version, err := availableVersion(name)
+		if err != nil {
+			version = "unknown"
+		}
+		return m.UpdateModule(name, version, module.StateInstalled)
+	case "uninstall", "purge":
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, ok := m.state.Modules[name]; !ok {
+			return nil
+		}
+		m.pushSnapshotLocked(i18n.T("%s module %s", action, name))
+		delete(m.state.Modules, name)
+		return m.saveLocked()
+	default:
+		return i18n.Errorf("unsupported module action %q", action)
+	}
+}
@@ -0,0 +1,173 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+)
+
+func stubAvailableVersions(t *testing.T, versions map[string]string) {
+	t.Helper()
+	previous := availableVersion
+	availableVersion = func(name string) (string, error) {
+		version, ok := versions[name]
+		if !ok {
+			return "", errors.New("module not found")
+		}
+		return version, nil
+	}
+	t.Cleanup(func() { availableVersion = previous })
+}
+
+func TestDiffModules(t *testing.T) {
+	from := map[string]ModuleState{
+		"keep":    {Name: "keep", Version: "1.0", State: module.StateInstalled},
+		"remove":  {Name: "remove", Version: "1.0", State: module.StateInstalled},
+		"upgrade": {Name: "upgrade", Version: "1.0", State: module.StateRunning},
+		"failed":  {Name: "failed", Version: "1.0", State: module.StateFailed},
+	}
+	to := map[string]ModuleState{
+		"keep":    {Name: "keep", Version: "1.0", State: module.StateInstalled},
+		"add":     {Name: "add", Version: "2.0", State: module.StateInstalled},
+		"upgrade": {Name: "upgrade", Version: "2.0", State: module.StateRunning},
+		"failed":  {Name: "failed", Version: "1.0", State: module.StateAvailable},
+	}
+
+	got := map[string]ChangeAction{}
+	for _, change := range DiffModules(from, to) {
+		got[change.Module] = change.Action
+	}
+	want := map[string]ChangeAction{
+		"add":     ActionInstall,
+		"remove":  ActionUninstall,
+		"upgrade": ActionReinstall,
+		"failed":  ActionRecord,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("DiffModules actions = %v, want %v", got, want)
+	}
+}
+
+func TestRollbackConvergesModules(t *testing.T) {
+	stubAvailableVersions(t, map[string]string{"alpha": "1.0", "beta": "2.0"})
+	manager, err := NewStateManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStateManager: %v", err)
+	}
+	if err := manager.RecordModuleAction("alpha", "install"); err != nil {
+		t.Fatalf("record alpha: %v", err)
+	}
+	if err := manager.RecordModuleAction("alpha", "uninstall"); err != nil {
+		t.Fatalf("uninstall alpha: %v", err)
+	}
+	if err := manager.UpdateModule("beta", "1.5", module.StateInstalled); err != nil {
+		t.Fatalf("record beta: %v", err)
+	}
+
+	// Roll back to the snapshot taken just before alpha was uninstalled.
+	history := manager.History()
+	target := history[len(history)-2].ID
+	plan, err := manager.PlanRollback(target)
+	if err != nil {
+		t.Fatalf("PlanRollback: %v", err)
+	}
+	if len(plan.Changes) != 2 {
+		t.Fatalf("plan changes = %+v, want 2", plan.Changes)
+	}
+	if plan.Changes[0].Module != "alpha" || plan.Changes[0].Action != ActionInstall || plan.Changes[0].Warning != "" {
+		t.Fatalf("alpha change = %+v", plan.Changes[0])
+	}
+	if plan.Changes[1].Module != "beta" || plan.Changes[1].Action != ActionUninstall {
+		t.Fatalf("beta change = %+v", plan.Changes[1])
+	}
+
+	var calls []string
+	run := func(_ context.Context, name, action string) error {
+		calls = append(calls, action+" "+name)
+		return nil
+	}
+	if err := manager.ExecuteRollback(context.Background(), plan, run, nil); err != nil {
+		t.Fatalf("ExecuteRollback: %v", err)
+	}
+	if want := []string{"install alpha", "uninstall beta"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("runner calls = %v, want %v", calls, want)
+	}
+	changes, err := manager.Diff(target, CurrentStateRef)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	for _, change := range changes {
+		if change.Action != ActionRecord {
+			t.Fatalf("state after rollback differs from snapshot: %+v", change)
+		}
+	}
+}
+
+func TestRollbackWarnsAndStopsOnFailure(t *testing.T) {
+	stubAvailableVersions(t, map[string]string{"alpha": "2.0"})
+	manager, err := NewStateManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStateManager: %v", err)
+	}
+	if err := manager.UpdateModule("alpha", "1.0", module.StateInstalled); err != nil {
+		t.Fatalf("record alpha: %v", err)
+	}
+	if err := manager.RecordModuleAction("alpha", "uninstall"); err != nil {
+		t.Fatalf("uninstall alpha: %v", err)
+	}
+	history := manager.History()
+	plan, err := manager.PlanRollback(history[len(history)-1].ID)
+	if err != nil {
+		t.Fatalf("PlanRollback: %v", err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Warning == "" {
+		t.Fatalf("expected a version warning, got %+v", plan.Changes)
+	}
+
+	failure := errors.New("boom")
+	err = manager.ExecuteRollback(context.Background(), plan, func(context.Context, string, string) error {
+		return failure
+	}, nil)
+	if !errors.Is(err, failure) {
+		t.Fatalf("ExecuteRollback error = %v, want %v", err, failure)
+	}
+	if _, ok := manager.GetModule("alpha"); ok {
+		t.Fatalf("failed install must not be recorded")
+	}
+}
+
+func TestRollbackSkipsReinstallOfUnavailableVersion(t *testing.T) {
+	stubAvailableVersions(t, map[string]string{"alpha": "2.0"})
+	manager, err := NewStateManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStateManager: %v", err)
+	}
+	if err := manager.UpdateModule("alpha", "1.0", module.StateInstalled); err != nil {
+		t.Fatalf("record alpha 1.0: %v", err)
+	}
+	if err := manager.UpdateModule("alpha", "2.0", module.StateInstalled); err != nil {
+		t.Fatalf("record alpha 2.0: %v", err)
+	}
+	history := manager.History()
+	plan, err := manager.PlanRollback(history[len(history)-1].ID)
+	if err != nil {
+		t.Fatalf("PlanRollback: %v", err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Action != ActionSkip || plan.Changes[0].Warning == "" {
+		t.Fatalf("expected a skipped change with a warning, got %+v", plan.Changes)
+	}
+
+	err = manager.ExecuteRollback(context.Background(), plan, func(_ context.Context, name, action string) error {
+		t.Fatalf("unexpected %s of %s", action, name)
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("ExecuteRollback: %v", err)
+	}
+	if state, ok := manager.GetModule("alpha"); !ok || state.Version != "2.0" {
+		t.Fatalf("alpha state = %+v, want version 2.0 kept", state)
+	}
+}
@@ -44,12 +44,6 @@ type StateSnapshot struct {
 	Modules   map[string]ModuleState `json:"modules"`
 }
 
-type StateCorrection struct {
-	ModuleName string
-	Previous   module.State
-	Corrected  module.State
-}
-
 func NewStateManager(dataDir string) (*StateManager, error) {
 	if dataDir == "" {
 		return nil, i18n.Errorf("state data directory is empty")
@@ -185,39 +179,6 @@ func (m *StateManager) RollbackLast() error {
 	return m.saveLocked()
 }
 
-func (m *StateManager) Reconcile() ([]StateCorrection, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	var corrections []StateCorrection
-	for name, moduleState := range m.state.Modules {
-		if !isValidModuleState(moduleState.State) {
-			corrections = append(corrections, StateCorrection{
-				ModuleName: name,
-				Previous:   moduleState.State,
-				Corrected:  module.StateFailed,
-			})
-			moduleState.State = module.StateFailed
-			moduleState.UpdatedAt = time.Now().UTC()
-			m.state.Modules[name] = moduleState
-		}
-		if moduleState.Version == "" {
-			moduleState.Version = "unknown"
-			moduleState.UpdatedAt = time.Now().UTC()
-			m.state.Modules[name] = moduleState
-		}
-	}
-
-	if len(corrections) > 0 {
-		m.pushSnapshotLocked(i18n.T("reconcile"))
-		if err := m.saveLocked(); err != nil {
-			return nil, err
-		}
-	}
-
-	return corrections, nil
-}
-
 func (m *StateManager) pushSnapshotLocked(reason string) {
 	snapshot := StateSnapshot{
 		ID:        fmt.Sprintf("%d", time.Now().UTC().UnixNano()),
@@ -0,0 +1,141 @@
+// Package events provides an in-process publish/subscribe bus for server
+// events such as job progress and runtime state changes.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published by the server.
+const (
+	TypeJob              = "job"
+	TypeStep             = "step"
+	TypeDownloadProgress = "download.progress"
+	TypeProcessState     = "runtime.state"
+	TypeHealth           = "runtime.health"
+)
+
+const (
+	defaultHistory    = 256
+	subscriberBacklog = 64
+)
+
+// Event is a single published event. IDs increase monotonically so clients
+// can resume with Last-Event-ID.
+type Event struct {
+	ID   uint64    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// DownloadProgress is the payload of TypeDownloadProgress events.
+type DownloadProgress struct {
+	Target     string `json:"target"`
+	JobID      string `json:"job_id,omitempty"`
+	Downloaded int64  `json:"downloaded"`
+	Total      int64  `json:"total"`
+}
+
+type subscriber struct {
+	ch    chan Event
+	types map[string]bool
+}
+
+// Bus fans published events out to subscribers and keeps a short history for
+// reconnecting clients. Slow subscribers drop events rather than block
+// publishers.
+type Bus struct {
+	mu          sync.Mutex
+	nextID      uint64
+	history     []Event
+	maxHistory  int
+	subscribers map[*subscriber]struct{}
+}
+
+// NewBus returns an empty Bus.
+func NewBus() *Bus {
+	return &Bus{maxHistory: defaultHistory, subscribers: make(map[*subscriber]struct{})}
+}
+
+// Publish records an event of the given type and delivers it to subscribers.
+func (b *Bus) Publish(eventType string, data any) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	event := Event{ID: b.nextID, Type: eventType, Time: time.Now().UTC(), Data: data}
+	b.history = append(b.history, event)
+	if len(b.history) > b.maxHistory {
+		b.history = b.history[len(b.history)-b.maxHistory:]
+	}
+	for sub := range b.subscribers {
+		if len(sub.types) > 0 && !sub.types[eventType] {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+	return event
+}
+
+// Subscribe returns a channel receiving events of the given types (all types
+// when none are given), preceded by buffered events newer than afterID. The
+// returned function unsubscribes and closes the channel.
+func (b *Bus) Subscribe(afterID uint64, types ...string) (<-chan Event, func()) {
+	sub := &subscriber{ch: make(chan Event, subscriberBacklog+defaultHistory)}
+	if len(types) > 0 {
+		sub.types = make(map[string]bool, len(types))
+		for _, eventType := range types {
+			sub.types[eventType] = true
+		}
+	}
+
+	b.mu.Lock()
+	if afterID > 0 {
+		for _, event := range b.history {
+			if event.ID > afterID && (sub.types == nil || sub.types[event.Type]) {
+				sub.ch <- event
+			}
+		}
+	}
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, sub)
+			b.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+}
+
+// DownloadReporter returns a progress callback that publishes
+// TypeDownloadProgress events based on base, at most once per interval plus
+// the final update.
+func (b *Bus) DownloadReporter(base DownloadProgress, interval time.Duration) func(downloaded, total int64) {
+	var (
+		mu   sync.Mutex
+		last time.Time
+	)
+	return func(downloaded, total int64) {
+		mu.Lock()
+		now := time.Now()
+		done := total > 0 && downloaded >= total
+		if !done && now.Sub(last) < interval {
+			mu.Unlock()
+			return
+		}
+		last = now
+		mu.Unlock()
+		payload := base
+		payload.Downloaded = downloaded
+		payload.Total = total
+		b.Publish(TypeDownloadProgress, payload)
+	}
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for event")
+		return Event{}
+	}
+}
+
+func TestBus_FiltersAndReplays(t *testing.T) {
+	bus := NewBus()
+	seen := bus.Publish(TypeJob, "seen")
+	first := bus.Publish(TypeJob, "a")
+	bus.Publish(TypeHealth, "b")
+
+	ch, cancel := bus.Subscribe(seen.ID, TypeJob, TypeStep)
+	defer cancel()
+	if event := receive(t, ch); event.ID != first.ID || event.Data != "a" {
+		t.Fatalf("expected replayed job event, got %+v", event)
+	}
+
+	bus.Publish(TypeHealth, "c")
+	bus.Publish(TypeStep, "d")
+	if event := receive(t, ch); event.Type != TypeStep || event.Data != "d" {
+		t.Fatalf("expected step event, got %+v", event)
+	}
+}
+
+func TestBus_UnsubscribeClosesChannel(t *testing.T) {
+	bus := NewBus()
+	ch, cancel := bus.Subscribe(0)
+	cancel()
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Fatalf("expected closed channel")
+	}
+	bus.Publish(TypeJob, nil)
+}
+
+func TestDownloadReporter_Throttles(t *testing.T) {
+	bus := NewBus()
+	ch, cancel := bus.Subscribe(0, TypeDownloadProgress)
+	defer cancel()
+
+	report := bus.DownloadReporter(DownloadProgress{Target: "model/demo"}, time.Hour)
+	report(10, 100)
+	report(20, 100)
+	report(100, 100)
+
+	first := receive(t, ch).Data.(DownloadProgress)
+	last := receive(t, ch).Data.(DownloadProgress)
+	if first.Downloaded != 10 || last.Downloaded != 100 || last.Target != "model/demo" {
+		t.Fatalf("unexpected progress events %+v %+v", first, last)
+	}
+	select {
+	case event := <-ch:
+		t.Fatalf("unexpected extra event %+v", event)
+	default:
+	}
+}
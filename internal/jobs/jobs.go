@@ -0,0 +1,350 @@
+// Package jobs runs long-lived operations in the background and keeps their
+// status, step progress and output available for polling.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+)
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// maxOutputBytes bounds the output kept per job; older output is dropped.
+const maxOutputBytes = 64 * 1024
+
+var (
+	ErrNotFound = errors.New("job not found")
+	ErrBusy     = errors.New("another job is already running for this target")
+	ErrFinished = errors.New("job already finished")
+)
+
+// Step is the progress of one step reported by a job.
+type Step struct {
+	ID     string `json:"id"`
+	Intent string `json:"intent,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Error is the structured failure of a job.
+type Error struct {
+	Message string `json:"message"`
+	Step    string `json:"step,omitempty"`
+}
+
+// Transfer is the byte progress of a job that downloads data. Total is -1
+// when unknown.
+type Transfer struct {
+	Downloaded int64 `json:"downloaded"`
+	Total      int64 `json:"total"`
+}
+
+// Job is a snapshot of a background operation.
+type Job struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Target     string     `json:"target"`
+	Status     Status     `json:"status"`
+	Steps      []Step     `json:"steps"`
+	Completed  int        `json:"completed"`
+	Total      int        `json:"total"`
+	Transfer   *Transfer  `json:"transfer,omitempty"`
+	Error      *Error     `json:"error,omitempty"`
+	Output     string     `json:"output,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Done reports whether the job reached a terminal status.
+func (j Job) Done() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed || j.Status == StatusCanceled
+}
+
+// Func is the work performed by a job. It must return promptly once ctx is
+// cancelled.
+type Func func(ctx context.Context, reporter *Reporter) error
+
+type entry struct {
+	job    Job
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Manager tracks jobs in memory. At most one unfinished job may exist per
+// target.
+type Manager struct {
+	mu       sync.Mutex
+	jobs     map[string]*entry
+	retain   int
+	watchers []func(Job)
+}
+
+// NewManager returns a Manager that keeps up to retain finished jobs.
+func NewManager(retain int) *Manager {
+	if retain <= 0 {
+		retain = 100
+	}
+	return &Manager{jobs: make(map[string]*entry), retain: retain}
+}
+
+// Watch registers fn to be called with a snapshot after every job change.
+func (m *Manager) Watch(fn func(Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.watchers = append(m.watchers, fn)
+}
+
+// Submit starts fn in the background and returns the queued job.
+func (m *Manager) Submit(kind, target string, fn Func) (Job, error) {
+	m.mu.Lock()
+	for _, existing := range m.jobs {
+		if existing.job.Target == target && !existing.job.Done() {
+			m.mu.Unlock()
+			return Job{}, ErrBusy
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &entry{
+		job: Job{
+			ID:        newJobID(),
+			Kind:      kind,
+			Target:    target,
+			Status:    StatusQueued,
+			Steps:     []Step{},
+			CreatedAt: time.Now().UTC(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	m.jobs[e.job.ID] = e
+	m.pruneLocked()
+	snapshot := e.job
+	m.mu.Unlock()
+	m.notify(snapshot)
+
+	go m.run(ctx, e, fn)
+	return snapshot, nil
+}
+
+func (m *Manager) run(ctx context.Context, e *entry, fn Func) {
+	defer close(e.done)
+	defer e.cancel()
+
+	m.update(e, func(job *Job) {
+		now := time.Now().UTC()
+		job.Status = StatusRunning
+		job.StartedAt = &now
+	})
+
+	err := fn(ctx, &Reporter{manager: m, entry: e})
+
+	m.update(e, func(job *Job) {
+		now := time.Now().UTC()
+		job.FinishedAt = &now
+		switch {
+		case ctx.Err() != nil:
+			job.Status = StatusCanceled
+			job.Error = &Error{Message: i18n.T("job canceled")}
+		case err != nil:
+			job.Status = StatusFailed
+			job.Error = &Error{Message: err.Error()}
+			for i := len(job.Steps) - 1; i >= 0; i-- {
+				if job.Steps[i].Status == "failed" {
+					job.Error.Step = job.Steps[i].ID
+					break
+				}
+			}
+		default:
+			job.Status = StatusSucceeded
+		}
+	})
+}
+
+// Get returns a snapshot of the job with the given ID.
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return cloneJob(e.job), nil
+}
+
+// List returns snapshots of all known jobs, newest first.
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Job, 0, len(m.jobs))
+	for _, e := range m.jobs {
+		list = append(list, cloneJob(e.job))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// Cancel stops a running job and waits for it to finish.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return Job{}, ErrNotFound
+	}
+	if e.job.Done() {
+		snapshot := cloneJob(e.job)
+		m.mu.Unlock()
+		return snapshot, ErrFinished
+	}
+	m.mu.Unlock()
+
+	e.cancel()
+	<-e.done
+	return m.Get(id)
+}
+
+// Wait blocks until the job finishes or ctx is done.
+func (m *Manager) Wait(ctx context.Context, id string) (Job, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	select {
+	case <-e.done:
+		return m.Get(id)
+	case <-ctx.Done():
+		return Job{}, ctx.Err()
+	}
+}
+
+func (m *Manager) update(e *entry, fn func(job *Job)) {
+	m.mu.Lock()
+	fn(&e.job)
+	snapshot := cloneJob(e.job)
+	m.mu.Unlock()
+	m.notify(snapshot)
+}
+
+func (m *Manager) notify(job Job) {
+	m.mu.Lock()
+	watchers := append([]func(Job){}, m.watchers...)
+	m.mu.Unlock()
+	for _, watch := range watchers {
+		watch(job)
+	}
+}
+
+// pruneLocked drops the oldest finished jobs beyond the retention limit.
+func (m *Manager) pruneLocked() {
+	var finished []*entry
+	for _, e := range m.jobs {
+		if e.job.Done() {
+			finished = append(finished, e)
+		}
+	}
+	if len(finished) <= m.retain {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].job.CreatedAt.Before(finished[j].job.CreatedAt) })
+	for _, e := range finished[:len(finished)-m.retain] {
+		delete(m.jobs, e.job.ID)
+	}
+}
+
+// Reporter lets a running job publish step progress and output.
+type Reporter struct {
+	manager *Manager
+	entry   *entry
+}
+
+// JobID returns the ID of the job being reported.
+func (r *Reporter) JobID() string {
+	return r.entry.job.ID
+}
+
+// Step records the status of a step, adding it on first use. total, when
+// positive, updates the expected number of steps.
+func (r *Reporter) Step(id, intent, status, errMessage string, total int) {
+	r.manager.update(r.entry, func(job *Job) {
+		if total > 0 {
+			job.Total = total
+		}
+		index := -1
+		for i := range job.Steps {
+			if job.Steps[i].ID == id {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			job.Steps = append(job.Steps, Step{ID: id, Intent: intent})
+			index = len(job.Steps) - 1
+		}
+		job.Steps[index].Status = status
+		job.Steps[index].Error = errMessage
+		completed := 0
+		for _, step := range job.Steps {
+			if step.Status == "succeeded" {
+				completed++
+			}
+		}
+		job.Completed = completed
+	})
+}
+
+// Transfer records the byte progress of a download.
+func (r *Reporter) Transfer(downloaded, total int64) {
+	r.manager.update(r.entry, func(job *Job) {
+		job.Transfer = &Transfer{Downloaded: downloaded, Total: total}
+	})
+}
+
+// Write appends command output to the job; it implements io.Writer.
+func (r *Reporter) Write(p []byte) (int, error) {
+	r.manager.update(r.entry, func(job *Job) {
+		job.Output += string(p)
+		if len(job.Output) > maxOutputBytes {
+			job.Output = job.Output[len(job.Output)-maxOutputBytes:]
+		}
+	})
+	return len(p), nil
+}
+
+func cloneJob(job Job) Job {
+	job.Steps = append([]Step(nil), job.Steps...)
+	if job.Transfer != nil {
+		transferCopy := *job.Transfer
+		job.Transfer = &transferCopy
+	}
+	if job.Error != nil {
+		errCopy := *job.Error
+		job.Error = &errCopy
+	}
+	return job
+}
+
+func newJobID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func waitJob(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	job, err := m.Wait(ctx, id)
+	if err != nil {
+		t.Fatalf("Wait returned error: %v", err)
+	}
+	return job
+}
+
+func TestManager_TracksStepsAndOutput(t *testing.T) {
+	m := NewManager(0)
+	job, err := m.Submit("module.install", "module/demo", func(ctx context.Context, r *Reporter) error {
+		r.Step("S10", "Download", "running", "", 2)
+		fmt.Fprint(r, "downloading\n")
+		r.Step("S10", "Download", "succeeded", "", 0)
+		r.Step("S20", "Configure", "running", "", 0)
+		r.Step("S20", "Configure", "failed", "boom", 0)
+		return errors.New("step S20 failed")
+	})
+	if err != nil {
+		t.Fatalf("Submit returned error: %v", err)
+	}
+
+	job = waitJob(t, m, job.ID)
+	if job.Status != StatusFailed {
+		t.Fatalf("expected failed status, got %q", job.Status)
+	}
+	if job.Completed != 1 || job.Total != 2 || len(job.Steps) != 2 {
+		t.Fatalf("unexpected progress %d/%d steps=%+v", job.Completed, job.Total, job.Steps)
+	}
+	if job.Error == nil || job.Error.Step != "S20" || job.Error.Message != "step S20 failed" {
+		t.Fatalf("unexpected error %+v", job.Error)
+	}
+	if job.Output != "downloading\n" {
+		t.Fatalf("unexpected output %q", job.Output)
+	}
+	if job.StartedAt == nil || job.FinishedAt == nil {
+		t.Fatalf("expected timestamps to be set")
+	}
+}
+
+func TestManager_RejectsConcurrentJobsForTarget(t *testing.T) {
+	m := NewManager(0)
+	release := make(chan struct{})
+	first, err := m.Submit("module.install", "module/demo", func(ctx context.Context, r *Reporter) error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Submit returned error: %v", err)
+	}
+	if _, err := m.Submit("module.uninstall", "module/demo", func(ctx context.Context, r *Reporter) error { return nil }); !errors.Is(err, ErrBusy) {
+		t.Fatalf("expected ErrBusy, got %v", err)
+	}
+	close(release)
+	if job := waitJob(t, m, first.ID); job.Status != StatusSucceeded {
+		t.Fatalf("expected succeeded status, got %q", job.Status)
+	}
+	if _, err := m.Submit("module.uninstall", "module/demo", func(ctx context.Context, r *Reporter) error { return nil }); err != nil {
+		t.Fatalf("Submit after completion returned error: %v", err)
+	}
+}
+
+func TestManager_Cancel(t *testing.T) {
+	m := NewManager(0)
+	started := make(chan struct{})
+	job, err := m.Submit("module.install", "module/demo", func(ctx context.Context, r *Reporter) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("Submit returned error: %v", err)
+	}
+	<-started
+
+	job, err = m.Cancel(job.ID)
+	if err != nil {
+		t.Fatalf("Cancel returned error: %v", err)
+	}
+	if job.Status != StatusCanceled {
+		t.Fatalf("expected canceled status, got %q", job.Status)
+	}
+	if _, err := m.Cancel(job.ID); !errors.Is(err, ErrFinished) {
+		t.Fatalf("expected ErrFinished, got %v", err)
+	}
+	if _, err := m.Cancel("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
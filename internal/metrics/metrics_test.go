@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.Counter("las_test_total", "Test counter.", "module")
+	gauge := registry.Gauge("las_test_up", "Test gauge.\nSecond line.")
+	histogram := registry.Histogram("las_test_seconds", "Test histogram.", []float64{1, 5}, "outcome")
+
+	counter.Inc(`a"b`)
+	counter.Add(2, `a"b`)
+	counter.Add(-1, `a"b`)
+	registry.Collect(func() { gauge.Set(1) })
+	histogram.Observe(0.5, "ok")
+	histogram.Observe(3, "ok")
+	histogram.Observe(10, "ok")
+
+	var out strings.Builder
+	if err := registry.WriteText(&out); err != nil {
+		t.Fatalf("WriteText returned error: %v", err)
+	}
+	expected := `# HELP las_test_total Test counter.
+# TYPE las_test_total counter
+las_test_total{module="a\"b"} 3
+# HELP las_test_up Test gauge.\nSecond line.
+# TYPE las_test_up gauge
+las_test_up 1
+# HELP las_test_seconds Test histogram.
+# TYPE las_test_seconds histogram
+las_test_seconds_bucket{outcome="ok",le="1"} 1
+las_test_seconds_bucket{outcome="ok",le="5"} 2
+las_test_seconds_bucket{outcome="ok",le="+Inf"} 3
+las_test_seconds_sum{outcome="ok"} 13.5
+las_test_seconds_count{outcome="ok"} 3
+`
+	if out.String() != expected {
+		t.Fatalf("unexpected exposition:\n%s", out.String())
+	}
+}
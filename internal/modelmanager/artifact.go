@@ -0,0 +1,315 @@
+package modelmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	artifactAPITimeout      = 60 * time.Second
+	artifactDownloadTimeout = 2 * time.Hour
+)
+
+// ArtifactAuth holds the credentials sent to one artifact server or
+// registry. A token is sent as a bearer token; otherwise a username and
+// password are used for basic auth, or to obtain a registry token.
+type ArtifactAuth struct {
+	Token    string
+	Username string
+	Password string
+	// Headers are added to every request to the host.
+	Headers map[string]string
+	// PlainHTTP talks to an OCI registry over http instead of https.
+	PlainHTTP bool
+}
+
+// ArtifactOptions configure the url: and oci: providers.
+type ArtifactOptions struct {
+	// IndexURL points to a JSON model index used for search and for
+	// resolving model IDs to files or OCI references.
+	IndexURL string
+	// Auth is keyed by host, optionally with its port.
+	Auth map[string]ArtifactAuth
+}
+
+// artifactIndex is the JSON model index:
+//
+//	{"models": [{"id": "approved/qwen2.5-7b", "format": "gguf",
+//	  "files": [{"url": "qwen2.5-7b-q4_k_m.gguf", "size": 4683074048, "sha256": "…"}]},
+//	 {"id": "approved/llama-3.1-8b", "oci": "registry.example.com/models/llama:3.1-8b"}]}
+//
+// File URLs may be relative to the index.
+type artifactIndex struct {
+	Models []artifactEntry `json:"models"`
+}
+
+type artifactEntry struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Format      ModelFormat    `json:"format,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Files       []artifactFile `json:"files,omitempty"`
+	OCI         string         `json:"oci,omitempty"`
+}
+
+type artifactFile struct {
+	URL string `json:"url"`
+	// Path is where the file goes in the model directory; it defaults to
+	// the last element of the URL.
+	Path   string `json:"path,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// info returns the index entry as a ModelInfo of source.
+func (e artifactEntry) info(source ModelSource) ModelInfo {
+	info := ModelInfo{
+		ID:          e.ID,
+		Name:        e.Name,
+		Description: e.Description,
+		Source:      source,
+		Format:      e.Format,
+		Tags:        e.Tags,
+	}
+	if info.Name == "" {
+		info.Name = e.ID
+	}
+	for _, file := range e.Files {
+		info.Size += file.Size
+	}
+	if info.Format == "" {
+		info.Format = detectFilesFormat(artifactPaths(e.Files))
+	}
+	return info
+}
+
+// artifactClient sends requests with the configured per-host credentials.
+type artifactClient struct {
+	opts     ArtifactOptions
+	api      *http.Client
+	download *http.Client
+}
+
+func newArtifactClient(opts ArtifactOptions) *artifactClient {
+	c := &artifactClient{opts: opts}
+	c.api = &http.Client{Timeout: artifactAPITimeout, CheckRedirect: c.checkRedirect}
+	c.download = &http.Client{Timeout: artifactDownloadTimeout, CheckRedirect: c.checkRedirect}
+	return c
+}
+
+// auth returns the credentials for a host, trying host:port before host.
+func (c *artifactClient) auth(host string) ArtifactAuth {
+	for key, auth := range c.opts.Auth {
+		if strings.EqualFold(key, host) {
+			return auth
+		}
+	}
+	if hostname, _, ok := strings.Cut(host, ":"); ok {
+		return c.auth(hostname)
+	}
+	return ArtifactAuth{}
+}
+
+// authorize adds the static credentials for the request's host.
+func (c *artifactClient) authorize(req *http.Request) {
+	auth := c.auth(req.URL.Host)
+	for name, value := range auth.Headers {
+		req.Header.Set(name, value)
+	}
+	switch {
+	case auth.Token != "":
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	case auth.Username != "":
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+}
+
+// checkRedirect keeps the credentials and configured headers of the original
+// host from following a redirect to another host, such as a CDN serving the
+// blob. Credentials configured for the new host are added instead.
+func (c *artifactClient) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after 10 redirects")
+	}
+	original := via[0].URL.Host
+	if strings.EqualFold(req.URL.Host, original) {
+		return nil
+	}
+	req.Header.Del("Authorization")
+	for name := range c.auth(original).Headers {
+		req.Header.Del(name)
+	}
+	c.authorize(req)
+	return nil
+}
+
+// send sends a GET for rawURL with the extra headers and returns the
+// response whatever its status.
+func (c *artifactClient) send(ctx context.Context, client *http.Client, rawURL string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.authorize(req)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	return client.Do(req)
+}
+
+// get fetches rawURL and fails on any status but 200 OK.
+func (c *artifactClient) get(ctx context.Context, client *http.Client, rawURL string) (*http.Response, error) {
+	resp, err := c.send(ctx, client, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: HTTP %d", redactURL(rawURL), resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// index fetches the model index, resolving relative file URLs.
+func (c *artifactClient) index(ctx context.Context) (*artifactIndex, error) {
+	if c.opts.IndexURL == "" {
+		return nil, fmt.Errorf("no model index configured (model_sources.index_url)")
+	}
+	base, err := url.Parse(c.opts.IndexURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model index URL: %w", err)
+	}
+	resp, err := c.get(ctx, c.api, c.opts.IndexURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch model index: %w", err)
+	}
+	defer resp.Body.Close()
+	var index artifactIndex
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, fmt.Errorf("failed to parse model index: %w", err)
+	}
+	for i := range index.Models {
+		for j := range index.Models[i].Files {
+			file := &index.Models[i].Files[j]
+			ref, err := url.Parse(file.URL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid URL for %s: %w", index.Models[i].ID, err)
+			}
+			file.URL = base.ResolveReference(ref).String()
+			if file.Path == "" {
+				file.Path = filepath.Base(ref.Path)
+			}
+		}
+	}
+	return &index, nil
+}
+
+// lookup returns the index entry with the given ID.
+func (c *artifactClient) lookup(ctx context.Context, modelID string) (*artifactEntry, error) {
+	index, err := c.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range index.Models {
+		if strings.EqualFold(index.Models[i].ID, modelID) {
+			return &index.Models[i], nil
+		}
+	}
+	return nil, fmt.Errorf("model %s not found in the model index", modelID)
+}
+
+// search matches index entries selected by keep against query.
+func (c *artifactClient) search(ctx context.Context, source ModelSource, query string, limit int, keep func(artifactEntry) bool) ([]ModelInfo, error) {
+	index, err := c.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query = strings.ToLower(strings.TrimSpace(query))
+	var results []ModelInfo
+	for _, entry := range index.Models {
+		if !keep(entry) {
+			continue
+		}
+		text := strings.ToLower(entry.ID + " " + entry.Name + " " + entry.Description + " " + strings.Join(entry.Tags, " "))
+		if query != "" && !strings.Contains(text, query) {
+			continue
+		}
+		results = append(results, entry.info(source))
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+// artifactFilePath validates a file path from an index or manifest and
+// returns it under modelDir.
+func artifactFilePath(modelDir, rel string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(rel))
+	if rel == "" || clean == "." || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) || clean == "metadata.json" {
+		return "", fmt.Errorf("invalid file path %q", rel)
+	}
+	return filepath.Join(modelDir, clean), nil
+}
+
+// matchesFileHint reports whether a file is kept for a download with
+// hint: files containing the hint and every file that is not a model
+// weight, such as configs and tokenizers.
+func matchesFileHint(path, hint string) bool {
+	hint = strings.ToLower(strings.TrimSpace(hint))
+	if hint == "" || DetectFormat(strings.ToLower(path)) == FormatUnknown {
+		return true
+	}
+	return strings.Contains(strings.ToLower(path), hint)
+}
+
+// detectFilesFormat returns the model format of a list of files,
+// preferring safetensors.
+func detectFilesFormat(paths []string) ModelFormat {
+	format := FormatUnknown
+	for _, path := range paths {
+		switch DetectFormat(strings.ToLower(path)) {
+		case FormatSafetensors:
+			return FormatSafetensors
+		case FormatGGUF:
+			format = FormatGGUF
+		}
+	}
+	return format
+}
+
+// writeArtifactMetadata writes metadata.json for a model downloaded by
+// the url: or oci: provider.
+func writeArtifactMetadata(modelDir, modelID string, source ModelSource, format ModelFormat, extra map[string]string) error {
+	encoded, err := json.MarshalIndent(map[string]any{
+		"id":            modelID,
+		"source":        source,
+		"format":        format,
+		"metadata":      extra,
+		"downloaded_at": time.Now().Unix(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(modelDir, "metadata.json"), encoded, 0o644); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	return nil
+}
+
+// redactURL drops the query string, which may carry a signature or token.
+func redactURL(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.RawQuery != "" {
+		u.RawQuery = ""
+		return u.String() + "?…"
+	}
+	return rawURL
+}
@@ -0,0 +1,267 @@
+package modelmanager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParseModelID_ArtifactSources(t *testing.T) {
+	cases := []struct {
+		input  string
+		source ModelSource
+		id     string
+	}{
+		{"url:https://artifacts.example.com/models/tiny.gguf", SourceURL, "https://artifacts.example.com/models/tiny.gguf"},
+		{"url:approved/tiny", SourceURL, "approved/tiny"},
+		{"OCI:registry.example.com/models/llama:3.1", SourceOCI, "registry.example.com/models/llama:3.1"},
+	}
+	for _, tc := range cases {
+		source, id, err := ParseModelID(tc.input)
+		if err != nil || source != tc.source || id != tc.id {
+			t.Fatalf("ParseModelID(%q) = %s, %s, %v", tc.input, source, id, err)
+		}
+		if !HasSourcePrefix(tc.input) {
+			t.Fatalf("HasSourcePrefix(%q) = false", tc.input)
+		}
+	}
+}
+
+func TestParseOCIReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	cases := []struct {
+		input string
+		want  ociReference
+	}{
+		{"registry.example.com/models/llama:3.1-8b", ociReference{"registry.example.com", "models/llama", "3.1-8b"}},
+		{"localhost:5000/llama", ociReference{"localhost:5000", "llama", "latest"}},
+		{"registry.example.com/models/llama@" + digest, ociReference{"registry.example.com", "models/llama", digest}},
+	}
+	for _, tc := range cases {
+		got, err := parseOCIReference(tc.input)
+		if err != nil || got != tc.want {
+			t.Fatalf("parseOCIReference(%q) = %+v, %v", tc.input, got, err)
+		}
+		if got.String() != tc.input && !strings.HasSuffix(got.String(), ":latest") {
+			t.Fatalf("String() = %s, want %s", got.String(), tc.input)
+		}
+	}
+	for _, input := range []string{"models/llama", "registry.example.com/Models", "registry.example.com/llama@md5:abc"} {
+		if _, err := parseOCIReference(input); err == nil {
+			t.Fatalf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestHTTPProvider_DownloadsFromIndex(t *testing.T) {
+	weights := []byte("gguf weights")
+	config := []byte(`{"model_type":"llama"}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/models/index.json":
+			json.NewEncoder(w).Encode(map[string]any{"models": []map[string]any{
+				{"id": "approved/tiny", "description": "Approved tiny model", "files": []map[string]any{
+					{"url": "files/tiny.Q4_K_M.gguf", "size": len(weights), "sha256": sha256Hex(weights)},
+					{"url": "files/tiny.Q8_0.gguf", "size": 1},
+					{"url": "files/config.json"},
+				}},
+				{"id": "approved/llama", "oci": "registry.example.com/models/llama:1"},
+			}})
+		case "/models/files/tiny.Q4_K_M.gguf":
+			w.Write(weights)
+		case "/models/files/config.json":
+			w.Write(config)
+		case "/models/files/bad.gguf":
+			w.Write([]byte("tampered"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	provider := NewHTTPProvider(ArtifactOptions{
+		IndexURL: server.URL + "/models/index.json",
+		Auth:     map[string]ArtifactAuth{host: {Token: "secret"}},
+	})
+	mgr := NewManager(t.TempDir())
+	if err := mgr.RegisterProvider(provider); err != nil {
+		t.Fatalf("RegisterProvider: %v", err)
+	}
+
+	results, err := provider.Search(t.Context(), "approved", 10)
+	if err != nil || len(results) != 1 || results[0].ID != "approved/tiny" || results[0].Format != FormatGGUF {
+		t.Fatalf("expected only the file-based entry, got %+v (%v)", results, err)
+	}
+	size, err := provider.DownloadSize(t.Context(), "approved/tiny", DownloadOptions{FileHint: "Q4_K_M"})
+	if err != nil || size != int64(len(weights)+len(config)) {
+		t.Fatalf("DownloadSize = %d, %v", size, err)
+	}
+
+	if err := mgr.DownloadModel(SourceURL, "approved/tiny", nil, DownloadOptions{FileHint: "Q4_K_M"}); err != nil {
+		t.Fatalf("DownloadModel returned error: %v", err)
+	}
+	modelDir, err := mgr.ResolveLocalModelDir(SourceURL, "approved/tiny")
+	if err != nil {
+		t.Fatalf("ResolveLocalModelDir: %v", err)
+	}
+	for name, want := range map[string][]byte{"tiny.Q4_K_M.gguf": weights, "config.json": config} {
+		if got, err := os.ReadFile(filepath.Join(modelDir, name)); err != nil || string(got) != string(want) {
+			t.Fatalf("%s = %q (%v)", name, got, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(modelDir, "tiny.Q8_0.gguf")); !os.IsNotExist(err) {
+		t.Fatalf("the file hint should skip other quantizations")
+	}
+	meta, err := readLocalMetadata(modelDir)
+	if err != nil || meta.Source != SourceURL || meta.Format != FormatGGUF || meta.ID != "approved/tiny" {
+		t.Fatalf("unexpected metadata %+v (%v)", meta, err)
+	}
+
+	bad := server.URL + "/models/files/bad.gguf#sha256=" + sha256Hex(weights)
+	err = mgr.DownloadModel(SourceURL, bad, nil, DownloadOptions{})
+	if err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Fatalf("expected a digest mismatch, got %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(mgr.GetModelDir(), localDirName(SourceURL, bad))); len(entries) != 0 {
+		t.Fatalf("a failed download left files behind: %v", entries)
+	}
+}
+
+func TestHTTPProvider_DropsCredentialsOnCrossHostRedirect(t *testing.T) {
+	weights := []byte("gguf weights")
+	var cdnHeaders http.Header
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cdnHeaders = r.Header.Clone()
+		w.Write(weights)
+	}))
+	defer cdn.Close()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Api-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, cdn.URL+"/blobs/tiny.gguf?signature=abc", http.StatusFound)
+	}))
+	defer origin.Close()
+
+	provider := NewHTTPProvider(ArtifactOptions{Auth: map[string]ArtifactAuth{
+		strings.TrimPrefix(origin.URL, "http://"): {Token: "secret", Headers: map[string]string{"X-Api-Key": "key"}},
+		strings.TrimPrefix(cdn.URL, "http://"):    {Headers: map[string]string{"X-Cdn-Tenant": "team"}},
+	}})
+	mgr := NewManager(t.TempDir())
+	if err := mgr.RegisterProvider(provider); err != nil {
+		t.Fatalf("RegisterProvider: %v", err)
+	}
+	rawURL := origin.URL + "/files/tiny.gguf#sha256=" + sha256Hex(weights)
+	if err := mgr.DownloadModel(SourceURL, rawURL, nil, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadModel returned error: %v", err)
+	}
+	if cdnHeaders == nil {
+		t.Fatalf("the download was not redirected to the CDN")
+	}
+	if cdnHeaders.Get("Authorization") != "" || cdnHeaders.Get("X-Api-Key") != "" {
+		t.Fatalf("origin credentials were sent to the CDN: %v", cdnHeaders)
+	}
+	if cdnHeaders.Get("X-Cdn-Tenant") != "team" {
+		t.Fatalf("the CDN's own headers were not sent: %v", cdnHeaders)
+	}
+}
+
+func TestOCIProvider_PullsArtifact(t *testing.T) {
+	weights := []byte("oci gguf weights")
+	config := []byte("{}")
+	manifest, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     ociManifestMediaType,
+		"config":        map[string]any{"mediaType": "application/vnd.oci.empty.v1+json", "digest": "sha256:" + sha256Hex(config), "size": len(config)},
+		"layers": []map[string]any{
+			{"mediaType": "application/vnd.oci.image.layer.v1.tar", "digest": "sha256:" + sha256Hex(weights), "size": len(weights),
+				"annotations": map[string]string{ociTitleAnnotation: "llama.Q4_K_M.gguf"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var tokenRequests int
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			tokenRequests++
+			if user, pass, ok := r.BasicAuth(); !ok || user != "robot" || pass != "pw" || r.URL.Query().Get("scope") != "repository:models/llama:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": "registry-token"})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer registry-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry",scope="repository:models/llama:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/models/llama/manifests/1.0":
+			w.Header().Set("Content-Type", ociManifestMediaType)
+			w.Write(manifest)
+		case "/v2/models/llama/blobs/sha256:" + sha256Hex(weights):
+			w.Write(weights)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	ref := u.Host + "/models/llama:1.0"
+
+	provider := NewOCIProvider(ArtifactOptions{Auth: map[string]ArtifactAuth{
+		u.Hostname(): {Username: "robot", Password: "pw", PlainHTTP: true},
+	}})
+	mgr := NewManager(t.TempDir())
+	if err := mgr.RegisterProvider(provider); err != nil {
+		t.Fatalf("RegisterProvider: %v", err)
+	}
+	info, err := mgr.GetModelInfo(SourceOCI, ref)
+	if err != nil || info.Size != int64(len(weights)) || info.Format != FormatGGUF {
+		t.Fatalf("unexpected model info %+v (%v)", info, err)
+	}
+	if err := mgr.DownloadModel(SourceOCI, ref, nil, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadModel returned error: %v", err)
+	}
+	if tokenRequests != 1 {
+		t.Fatalf("expected the registry token to be reused, got %d token requests", tokenRequests)
+	}
+	modelDir, err := mgr.ResolveLocalModelDir(SourceOCI, ref)
+	if err != nil {
+		t.Fatalf("ResolveLocalModelDir: %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(modelDir, "llama.Q4_K_M.gguf")); err != nil || string(got) != string(weights) {
+		t.Fatalf("unexpected layer content %q (%v)", got, err)
+	}
+	meta, err := readLocalMetadata(modelDir)
+	if err != nil || meta.Source != SourceOCI || meta.Metadata["manifest_digest"] != "sha256:"+sha256Hex(manifest) {
+		t.Fatalf("unexpected metadata %+v (%v)", meta, err)
+	}
+}
+
+func TestParseAuthChallenge(t *testing.T) {
+	params := parseAuthChallenge(`realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a/b:pull,push"`)
+	if params["realm"] != "https://auth.example.com/token" || params["service"] != "registry.example.com" || params["scope"] != "repository:a/b:pull,push" {
+		t.Fatalf("unexpected challenge params %v", params)
+	}
+}
@@ -0,0 +1,271 @@
+package modelmanager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Blob store layout. Model files of at least blobMinSize are moved to
+// <model dir>/.blobs/sha256/<digest> and linked back into the model's
+// directory, so identical files are stored once.
+const (
+	blobDirName = ".blobs"
+	blobMinSize = 1 << 20
+)
+
+// GCResult reports the blobs a garbage collection removed.
+type GCResult struct {
+	Removed    []string `json:"removed"`
+	FreedBytes int64    `json:"freed_bytes"`
+}
+
+// BlobDir returns the directory holding the model blobs.
+func (m *Manager) BlobDir() string {
+	return filepath.Join(m.modelDir, blobDirName, "sha256")
+}
+
+// BlobPath returns the stable path of the blob with the given sha256.
+func (m *Manager) BlobPath(digest string) string {
+	return filepath.Join(m.BlobDir(), digest)
+}
+
+// storeBlobs moves the large files of a model into the blob store and
+// links them back, hard links where possible and symlinks otherwise. The
+// digests are recorded in metadata.json under "blobs" before any file is
+// moved; these records are the blobs' references.
+func (m *Manager) storeBlobs(modelDir string) error {
+	known := modelBlobs(modelDir)
+	blobs := map[string]string{}
+	err := filepath.WalkDir(modelDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || entry.Name() == "metadata.json" || IsPartialFile(entry.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(modelDir, path)
+		if err != nil {
+			return err
+		}
+		if digest, ok := known[rel]; ok && m.isBlobLink(path, digest) {
+			blobs[rel] = digest
+			return nil
+		}
+		if entry.Type()&fs.ModeSymlink != 0 {
+			// Symlinks into the store survive a metadata.json rewrite.
+			if target, err := os.Readlink(path); err == nil && filepath.Dir(target) == m.BlobDir() && m.isBlobLink(path, filepath.Base(target)) {
+				blobs[rel] = filepath.Base(target)
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() < blobMinSize {
+			return nil
+		}
+		digest, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		blobs[rel] = digest
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(blobs) == 0 {
+		return nil
+	}
+
+	if err := updateMetadata(modelDir, func(metadata map[string]any) {
+		metadata["blobs"] = blobs
+	}); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.BlobDir(), 0o755); err != nil {
+		return err
+	}
+	rels := make([]string, 0, len(blobs))
+	for rel := range blobs {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	for _, rel := range rels {
+		if err := m.linkBlob(filepath.Join(modelDir, rel), blobs[rel]); err != nil {
+			return fmt.Errorf("failed to store %s in the blob store: %w", rel, err)
+		}
+	}
+	return nil
+}
+
+// linkBlob replaces path with a link to its blob, moving the file into the
+// store when the blob does not exist yet. Blobs are made read-only so a
+// write through one model cannot change another.
+func (m *Manager) linkBlob(path, digest string) error {
+	if m.isBlobLink(path, digest) {
+		return nil
+	}
+	blob := m.BlobPath(digest)
+	if _, err := os.Stat(blob); os.IsNotExist(err) {
+		if err := os.Rename(path, blob); err != nil {
+			return err
+		}
+		if err := linkFile(blob, path); err != nil {
+			_ = os.Rename(blob, path)
+			return err
+		}
+		return os.Chmod(blob, 0o444)
+	} else if err != nil {
+		return err
+	}
+	// Link under a temporary name first so the file is replaced atomically.
+	tmp := path + ".blob"
+	_ = os.Remove(tmp)
+	if err := linkFile(blob, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// linkFile hard links path to blob, or symlinks it where hard links are
+// not possible, e.g. across file systems.
+func linkFile(blob, path string) error {
+	if err := os.Link(blob, path); err != nil {
+		return os.Symlink(blob, path)
+	}
+	return nil
+}
+
+// isBlobLink reports whether path is a hard link or symlink to the blob.
+func (m *Manager) isBlobLink(path, digest string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	blob, err := os.Stat(m.BlobPath(digest))
+	return err == nil && os.SameFile(info, blob)
+}
+
+// modelBlobs returns the blobs recorded for a model, keyed by file path
+// relative to the model directory.
+func modelBlobs(modelDir string) map[string]string {
+	raw, err := os.ReadFile(filepath.Join(modelDir, "metadata.json"))
+	if err != nil {
+		return nil
+	}
+	var metadata struct {
+		Blobs map[string]string `json:"blobs"`
+	}
+	if json.Unmarshal(raw, &metadata) != nil {
+		return nil
+	}
+	return metadata.Blobs
+}
+
+// blobReferences counts, per digest, the model files referencing a blob.
+func (m *Manager) blobReferences() (map[string]int, error) {
+	entries, err := os.ReadDir(m.modelDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]int{}, nil
+		}
+		return nil, err
+	}
+	refs := map[string]int{}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == blobDirName {
+			continue
+		}
+		for _, digest := range modelBlobs(filepath.Join(m.modelDir, entry.Name())) {
+			refs[digest]++
+		}
+	}
+	return refs, nil
+}
+
+// releaseBlobs removes the given blobs that no model references anymore.
+func (m *Manager) releaseBlobs(digests []string) error {
+	refs, err := m.blobReferences()
+	if err != nil {
+		return err
+	}
+	for _, digest := range digests {
+		if refs[digest] > 0 {
+			continue
+		}
+		if err := os.Remove(m.BlobPath(digest)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove blob %s: %w", digest, err)
+		}
+	}
+	return nil
+}
+
+// CollectGarbage removes blobs no model references. With dryRun, the
+// blobs are only reported.
+func (m *Manager) CollectGarbage(dryRun bool) (GCResult, error) {
+	result := GCResult{Removed: []string{}}
+	entries, err := os.ReadDir(m.BlobDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return result, err
+	}
+	refs, err := m.blobReferences()
+	if err != nil {
+		return result, err
+	}
+	for _, entry := range entries {
+		digest := entry.Name()
+		if entry.IsDir() || !isBlobDigest(digest) || refs[digest] > 0 {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return result, err
+		}
+		if !dryRun {
+			if err := os.Remove(m.BlobPath(digest)); err != nil {
+				return result, fmt.Errorf("failed to remove blob %s: %w", digest, err)
+			}
+		}
+		result.Removed = append(result.Removed, digest)
+		result.FreedBytes += info.Size()
+	}
+	return result, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// isBlobDigest reports whether name looks like a sha256 blob name.
+func isBlobDigest(name string) bool {
+	if len(name) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil && strings.ToLower(name) == name
+}
@@ -0,0 +1,178 @@
+package modelmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// blobTestProvider downloads a model with one large weight file whose
+// content is the same for every model ID, and a small config file.
+type blobTestProvider struct {
+	source ModelSource
+}
+
+func (p blobTestProvider) Name() ModelSource { return p.source }
+
+func (p blobTestProvider) Search(context.Context, string, int) ([]ModelInfo, error) {
+	return nil, nil
+}
+
+func (p blobTestProvider) Download(_ context.Context, modelID string, destPath string, _ func(downloaded, total int64), _ DownloadOptions) error {
+	modelDir := filepath.Join(destPath, localDirName(p.source, modelID))
+	if err := os.MkdirAll(modelDir, 0o755); err != nil {
+		return err
+	}
+	weights := bytes.Repeat([]byte("weights!"), blobMinSize/8+1)
+	if err := os.WriteFile(filepath.Join(modelDir, "model.gguf"), weights, 0o644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(modelDir, "config.json"), []byte(`{"id":"`+modelID+`"}`), 0o644); err != nil {
+		return err
+	}
+	metadata, err := json.Marshal(map[string]any{"id": modelID, "source": p.source, "downloaded_at": 1})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(modelDir, "metadata.json"), metadata, 0o644)
+}
+
+func (p blobTestProvider) DownloadSize(context.Context, string, DownloadOptions) (int64, error) {
+	return int64(len("weights!") * (blobMinSize/8 + 1)), nil
+}
+
+func (p blobTestProvider) Delete(context.Context, string) error { return nil }
+
+func (p blobTestProvider) GetModelInfo(context.Context, string) (*ModelInfo, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func newBlobTestManager(t *testing.T) *Manager {
+	t.Helper()
+	mgr := NewManager(t.TempDir())
+	for _, source := range []ModelSource{SourceHuggingFace, SourceModelScope} {
+		if err := mgr.RegisterProvider(blobTestProvider{source: source}); err != nil {
+			t.Fatalf("RegisterProvider: %v", err)
+		}
+	}
+	return mgr
+}
+
+func blobCount(t *testing.T, mgr *Manager) int {
+	t.Helper()
+	entries, err := os.ReadDir(mgr.BlobDir())
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatalf("read blob dir: %v", err)
+	}
+	return len(entries)
+}
+
+func TestDownloadModel_DeduplicatesIdenticalFiles(t *testing.T) {
+	mgr := newBlobTestManager(t)
+	if err := mgr.DownloadModel(SourceHuggingFace, "org/model", nil, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadModel returned error: %v", err)
+	}
+	if err := mgr.DownloadModel(SourceModelScope, "mirror/model", nil, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadModel returned error: %v", err)
+	}
+
+	if count := blobCount(t, mgr); count != 1 {
+		t.Fatalf("expected one shared blob, got %d", count)
+	}
+	hfDir, _ := mgr.ResolveLocalModelDir(SourceHuggingFace, "org/model")
+	msDir, _ := mgr.ResolveLocalModelDir(SourceModelScope, "mirror/model")
+	digest := modelBlobs(hfDir)["model.gguf"]
+	if digest == "" || modelBlobs(msDir)["model.gguf"] != digest {
+		t.Fatalf("expected both models to reference the same blob, got %v and %v", modelBlobs(hfDir), modelBlobs(msDir))
+	}
+	if _, ok := modelBlobs(hfDir)["config.json"]; ok {
+		t.Fatalf("small files should stay in the model directory")
+	}
+	for _, dir := range []string{hfDir, msDir} {
+		if !mgr.isBlobLink(filepath.Join(dir, "model.gguf"), digest) {
+			t.Fatalf("%s is not linked to its blob", dir)
+		}
+	}
+	models, err := mgr.ListDownloadedModels()
+	if err != nil || len(models) != 2 {
+		t.Fatalf("expected the blob store to be skipped when listing, got %d models (%v)", len(models), err)
+	}
+
+	// Downloading again keeps the single blob.
+	if err := mgr.DownloadModel(SourceHuggingFace, "org/model", nil, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadModel returned error: %v", err)
+	}
+	if count := blobCount(t, mgr); count != 1 || modelBlobs(hfDir)["model.gguf"] != digest {
+		t.Fatalf("re-download changed the blob store: %d blobs, %v", count, modelBlobs(hfDir))
+	}
+}
+
+func TestRemoveModel_FreesUnreferencedBlobs(t *testing.T) {
+	mgr := newBlobTestManager(t)
+	if err := mgr.DownloadModel(SourceHuggingFace, "org/model", nil, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadModel returned error: %v", err)
+	}
+	if err := mgr.DownloadModel(SourceModelScope, "mirror/model", nil, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadModel returned error: %v", err)
+	}
+
+	if err := mgr.RemoveModel(SourceHuggingFace, "org/model"); err != nil {
+		t.Fatalf("RemoveModel returned error: %v", err)
+	}
+	if count := blobCount(t, mgr); count != 1 {
+		t.Fatalf("expected the blob to stay while referenced, got %d blobs", count)
+	}
+	msDir, _ := mgr.ResolveLocalModelDir(SourceModelScope, "mirror/model")
+	if _, err := os.ReadFile(filepath.Join(msDir, "model.gguf")); err != nil {
+		t.Fatalf("remaining model lost its file: %v", err)
+	}
+
+	if err := mgr.RemoveModel(SourceModelScope, "mirror/model"); err != nil {
+		t.Fatalf("RemoveModel returned error: %v", err)
+	}
+	if count := blobCount(t, mgr); count != 0 {
+		t.Fatalf("expected the blob to be freed, got %d blobs", count)
+	}
+}
+
+func TestCollectGarbage(t *testing.T) {
+	mgr := newBlobTestManager(t)
+	if err := mgr.DownloadModel(SourceHuggingFace, "org/model", nil, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadModel returned error: %v", err)
+	}
+	orphan := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	if err := os.WriteFile(mgr.BlobPath(orphan), []byte("orphan"), 0o444); err != nil {
+		t.Fatalf("write orphan: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(mgr.BlobDir(), "notes.txt"), []byte("keep"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	result, err := mgr.CollectGarbage(true)
+	if err != nil {
+		t.Fatalf("CollectGarbage returned error: %v", err)
+	}
+	if len(result.Removed) != 1 || result.Removed[0] != orphan || result.FreedBytes != 6 {
+		t.Fatalf("unexpected dry run result %+v", result)
+	}
+	if _, err := os.Stat(mgr.BlobPath(orphan)); err != nil {
+		t.Fatalf("dry run removed the orphan: %v", err)
+	}
+
+	if _, err := mgr.CollectGarbage(false); err != nil {
+		t.Fatalf("CollectGarbage returned error: %v", err)
+	}
+	if _, err := os.Stat(mgr.BlobPath(orphan)); !os.IsNotExist(err) {
+		t.Fatalf("expected the orphan to be removed, got %v", err)
+	}
+	if count := blobCount(t, mgr); count != 2 {
+		t.Fatalf("expected the referenced blob and the unrelated file to stay, got %d entries", count)
+	}
+}
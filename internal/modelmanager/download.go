@@ -0,0 +1,270 @@
+package modelmanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A file is downloaded to <name>.part and renamed once complete. The ETag
+// or Last-Modified date of the response is kept in <name>.part.etag, so an
+// interrupted download can later be resumed with a Range request that
+// falls back to the whole file when it changed on the server.
+const (
+	partialSuffix   = ".part"
+	validatorSuffix = ".part.etag"
+)
+
+// httpStatusError is an unexpected HTTP status of a download.
+type httpStatusError struct {
+	StatusCode int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP %d", e.StatusCode)
+}
+
+// fetchFile downloads a file to destPath, checking the size and sha256
+// digest when they are known. get sends the request with the given extra
+// headers and returns the response whatever its status.
+//
+// A partial file left by an interrupted download is resumed when it can be
+// validated: by the stored ETag through If-Range, or by the final digest.
+// A file already at destPath with the expected digest is kept.
+//
+// progress is called with 0 when the file starts, then with the bytes of
+// the file written so far, including those resumed from the partial file.
+func fetchFile(ctx context.Context, destPath string, size int64, digest string, progress func(downloaded, total int64), get func(header http.Header) (*http.Response, error)) error {
+	if progress == nil {
+		progress = func(int64, int64) {}
+	}
+	progress(0, size)
+	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+		return err
+	}
+	if digest != "" {
+		if info, err := os.Stat(destPath); err == nil && (size <= 0 || info.Size() == size) {
+			if got, err := fileSHA256(destPath); err == nil && strings.EqualFold(got, digest) {
+				progress(info.Size(), size)
+				return nil
+			}
+		}
+	}
+
+	part := destPath + partialSuffix
+	validatorPath := destPath + validatorSuffix
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+	validator := ""
+	if raw, err := os.ReadFile(validatorPath); err == nil {
+		validator = strings.TrimSpace(string(raw))
+	}
+	if (validator == "" && digest == "") || (size > 0 && offset > size) {
+		// The partial file cannot be told apart from a stale one.
+		offset = 0
+	}
+
+	for {
+		header := http.Header{}
+		if offset > 0 {
+			header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			if validator != "" {
+				header.Set("If-Range", validator)
+			}
+		}
+		resp, err := get(header)
+		if err != nil {
+			return err
+		}
+		flags := os.O_RDWR | os.O_APPEND
+		var body io.Reader = resp.Body
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			offset = 0
+			flags = os.O_RDWR | os.O_CREATE | os.O_TRUNC
+			if err := saveValidator(validatorPath, resp.Header); err != nil {
+				resp.Body.Close()
+				return err
+			}
+		case offset > 0 && resp.StatusCode == http.StatusPartialContent && contentRangeStart(resp.Header) == offset:
+		case offset > 0 && offset == size && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+			// The partial file is complete; it only needs to be checked.
+			body = http.NoBody
+		case offset > 0 && (resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable):
+			// The server answered another range; fetch the whole file.
+			resp.Body.Close()
+			offset = 0
+			continue
+		default:
+			resp.Body.Close()
+			return &httpStatusError{StatusCode: resp.StatusCode}
+		}
+		err = writePartial(ctx, part, flags, offset, body, size, digest, progress)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		break
+	}
+
+	_ = os.Remove(validatorPath)
+	// Unlink first: the file may be linked to a shared blob.
+	if err := os.Remove(destPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Rename(part, destPath)
+}
+
+// writePartial appends body to the partial file, which holds offset bytes,
+// and verifies the result. A transfer that breaks off keeps the partial
+// file for the next attempt; one that is too long or has the wrong digest
+// removes it.
+func writePartial(ctx context.Context, part string, flags int, offset int64, body io.Reader, size int64, digest string, progress func(downloaded, total int64)) error {
+	file, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var sum hash.Hash
+	writers := []io.Writer{file, &progressWriter{written: offset, total: size, progress: progress}}
+	if digest != "" {
+		sum = sha256.New()
+		if offset > 0 {
+			if _, err := io.Copy(sum, io.NewSectionReader(file, 0, offset)); err != nil {
+				return err
+			}
+		}
+		writers = append(writers, sum)
+	}
+	if offset > 0 {
+		progress(offset, size)
+	}
+	if limiter := rateLimitFrom(ctx); limiter != nil {
+		body = &throttledReader{ctx: ctx, limiter: limiter, r: body}
+	}
+	written, err := io.CopyBuffer(io.MultiWriter(writers...), body, make([]byte, chunkSize))
+	if err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if total := offset + written; size > 0 && total != size {
+		if total > size {
+			discardPartial(part)
+		}
+		return fmt.Errorf("expected %d bytes, got %d", size, total)
+	}
+	if sum != nil {
+		if got := hex.EncodeToString(sum.Sum(nil)); !strings.EqualFold(got, digest) {
+			discardPartial(part)
+			return fmt.Errorf("digest mismatch: expected sha256:%s, got sha256:%s", digest, got)
+		}
+	}
+	return nil
+}
+
+// discardPartial removes a partial file and its validator.
+func discardPartial(part string) {
+	_ = os.Remove(part)
+	_ = os.Remove(strings.TrimSuffix(part, partialSuffix) + validatorSuffix)
+}
+
+// IsPartialFile reports whether name is a partial download or its
+// validator, which are left in a model directory by interrupted downloads.
+func IsPartialFile(name string) bool {
+	return strings.HasSuffix(name, partialSuffix) || strings.HasSuffix(name, validatorSuffix)
+}
+
+// saveValidator records the strong ETag of a response, or its Last-Modified
+// date, to validate a later Range request with If-Range.
+func saveValidator(path string, header http.Header) error {
+	validator := header.Get("ETag")
+	if strings.HasPrefix(validator, "W/") {
+		validator = ""
+	}
+	if validator == "" {
+		validator = header.Get("Last-Modified")
+	}
+	if validator == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(path, []byte(validator+"\n"), 0o644)
+}
+
+// contentRangeStart returns the first byte of a "bytes start-end/size"
+// Content-Range header, or -1.
+func contentRangeStart(header http.Header) int64 {
+	value, ok := strings.CutPrefix(header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return -1
+	}
+	start, _, ok := strings.Cut(value, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+type progressWriter struct {
+	written  int64
+	total    int64
+	progress func(downloaded, total int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	if w.progress != nil {
+		w.progress(w.written, w.total)
+	}
+	return len(p), nil
+}
+
+type rateLimitKey struct{}
+
+// withRateLimit makes downloads run with ctx share limiter's bandwidth.
+func withRateLimit(ctx context.Context, limiter *rateLimiter) context.Context {
+	if limiter == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, rateLimitKey{}, limiter)
+}
+
+func rateLimitFrom(ctx context.Context) *rateLimiter {
+	limiter, _ := ctx.Value(rateLimitKey{}).(*rateLimiter)
+	return limiter
+}
+
+// throttledReader charges the bytes it reads to a rate limiter.
+type throttledReader struct {
+	ctx     context.Context
+	limiter *rateLimiter
+	r       io.Reader
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if waitErr := t.limiter.wait(t.ctx, int64(n)); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,173 @@
+package modelmanager
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rangeServer serves content with Range support, validated by etag when
+// it is set, and records the Range header of every request.
+type rangeServer struct {
+	content []byte
+	etag    string
+	mu      sync.Mutex
+	ranges  []string
+}
+
+func (s *rangeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+	s.mu.Unlock()
+	if s.etag != "" {
+		w.Header().Set("ETag", s.etag)
+	}
+	http.ServeContent(w, r, "model.gguf", time.Time{}, bytes.NewReader(s.content))
+}
+
+func (s *rangeServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ranges...)
+}
+
+func TestFetchFile_ResumesPartialFile(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	digest := sha256Hex(content)
+
+	cases := []struct {
+		name      string
+		etag      string
+		validator string
+		partial   []byte
+		digest    string
+		wantRange string
+	}{
+		{name: "etag", etag: `"v1"`, validator: `"v1"`, partial: content[:400], wantRange: "bytes=400-"},
+		{name: "digest", partial: content[:400], digest: digest, wantRange: "bytes=400-"},
+		// The file changed on the server, so If-Range yields all of it.
+		{name: "stale", etag: `"v2"`, validator: `"v1"`, partial: bytes.Repeat([]byte("x"), 400), wantRange: "bytes=400-"},
+		// Without a validator or digest the partial file is not trusted.
+		{name: "unvalidated", partial: bytes.Repeat([]byte("x"), 400), wantRange: ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := &rangeServer{content: content, etag: tc.etag}
+			srv := httptest.NewServer(server)
+			defer srv.Close()
+			dest := filepath.Join(t.TempDir(), "model.gguf")
+			if err := os.WriteFile(dest+partialSuffix, tc.partial, 0o644); err != nil {
+				t.Fatalf("write partial: %v", err)
+			}
+			if tc.validator != "" {
+				if err := os.WriteFile(dest+validatorSuffix, []byte(tc.validator+"\n"), 0o644); err != nil {
+					t.Fatalf("write validator: %v", err)
+				}
+			}
+
+			var reports []int64
+			progress := func(downloaded, total int64) { reports = append(reports, downloaded) }
+			err := fetchFile(t.Context(), dest, int64(len(content)), tc.digest, progress, func(header http.Header) (*http.Response, error) {
+				req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+				req.Header = header
+				return http.DefaultClient.Do(req)
+			})
+			if err != nil {
+				t.Fatalf("fetchFile returned error: %v", err)
+			}
+			if got := server.requests(); len(got) != 1 || got[0] != tc.wantRange {
+				t.Fatalf("requests sent Range %q, want %q", got, tc.wantRange)
+			}
+			if got, err := os.ReadFile(dest); err != nil || !bytes.Equal(got, content) {
+				t.Fatalf("downloaded file differs: %v", err)
+			}
+			for _, leftover := range []string{dest + partialSuffix, dest + validatorSuffix} {
+				if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+					t.Fatalf("%s was left behind", filepath.Base(leftover))
+				}
+			}
+			if len(reports) < 2 || reports[0] != 0 || reports[len(reports)-1] != int64(len(content)) {
+				t.Fatalf("unexpected progress %v", reports)
+			}
+		})
+	}
+}
+
+func TestFetchFile_DiscardsCorruptPartialFile(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	server := &rangeServer{content: content}
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "model.gguf")
+	if err := os.WriteFile(dest+partialSuffix, bytes.Repeat([]byte("x"), 400), 0o644); err != nil {
+		t.Fatalf("write partial: %v", err)
+	}
+	get := func(header http.Header) (*http.Response, error) {
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+		req.Header = header
+		return http.DefaultClient.Do(req)
+	}
+
+	err := fetchFile(t.Context(), dest, int64(len(content)), sha256Hex(content), nil, get)
+	if err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Fatalf("expected a digest mismatch, got %v", err)
+	}
+	if _, err := os.Stat(dest + partialSuffix); !os.IsNotExist(err) {
+		t.Fatalf("the corrupt partial file was kept")
+	}
+	if err := fetchFile(t.Context(), dest, int64(len(content)), sha256Hex(content), nil, get); err != nil {
+		t.Fatalf("fetchFile returned error on retry: %v", err)
+	}
+	if got := server.requests(); len(got) != 2 || got[1] != "" {
+		t.Fatalf("expected the retry to fetch the whole file, got %q", got)
+	}
+}
+
+func TestHTTPProvider_ResumesInterruptedDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("gguf"), 64<<10)
+	server := &rangeServer{content: content, etag: `"blob"`}
+	var interrupted sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cut := false
+		interrupted.Do(func() { cut = true })
+		if !cut {
+			server.ServeHTTP(w, r)
+			return
+		}
+		// The first transfer breaks off half way.
+		w.Header().Set("ETag", server.etag)
+		w.Header().Set("Content-Length", "262144")
+		w.Write(content[:len(content)/2])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}))
+	defer srv.Close()
+	mgr := NewManager(t.TempDir())
+	if err := mgr.RegisterProvider(NewHTTPProvider(ArtifactOptions{})); err != nil {
+		t.Fatalf("RegisterProvider: %v", err)
+	}
+	rawURL := srv.URL + "/tiny.gguf"
+
+	if err := mgr.DownloadModel(SourceURL, rawURL, nil, DownloadOptions{}); err == nil {
+		t.Fatalf("expected the interrupted download to fail")
+	}
+	modelDir := filepath.Join(mgr.GetModelDir(), localDirName(SourceURL, rawURL))
+	if info, err := os.Stat(filepath.Join(modelDir, "tiny.gguf"+partialSuffix)); err != nil || info.Size() != int64(len(content)/2) {
+		t.Fatalf("expected half of the file to be kept, got %v", err)
+	}
+	if err := mgr.DownloadModel(SourceURL, rawURL, nil, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadModel returned error: %v", err)
+	}
+	if got := server.requests(); len(got) != 1 || got[0] != "bytes=131072-" {
+		t.Fatalf("expected the second download to resume, got Range %q", got)
+	}
+	if got, err := os.ReadFile(filepath.Join(modelDir, "tiny.gguf")); err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("resumed file differs: %v", err)
+	}
+}
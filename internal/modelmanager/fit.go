@@ -0,0 +1,397 @@
+package modelmanager
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Memory-fit defaults. The reserve covers the CUDA context and llama.cpp's
+// fixed buffers on each GPU; the compute buffer grows with the context.
+const (
+	fitGPUReserveBytes    = 512 << 20
+	fitComputeBytesPerEmb = 4
+	fitDefaultMinContext  = 2048
+	fitDefaultMaxContext  = 32768
+	fitUnknownContext     = 4096
+	fitContextStep        = 256
+	fitKVBytesPerElement  = 2
+)
+
+// quantizationBits approximates bits per weight for a quantization, used
+// when a model's file size is unknown. More specific prefixes come first.
+var quantizationBits = []struct {
+	prefix string
+	bits   float64
+}{
+	{"F32", 32}, {"F16", 16}, {"BF16", 16},
+	{"Q8", 8.5}, {"Q6", 6.56}, {"Q5", 5.5}, {"Q4_K", 4.85}, {"Q4", 4.5},
+	{"Q3", 3.9}, {"Q2", 2.6}, {"IQ4", 4.5}, {"IQ3", 3.4}, {"IQ2", 2.3},
+	{"IQ1", 1.6}, {"TQ2", 2.06}, {"TQ1", 1.69},
+}
+
+// GPUMemory is the memory one GPU can give to a model.
+type GPUMemory struct {
+	Name      string
+	FreeBytes uint64
+}
+
+// FitOptions describe the machine and the constraints for EstimateFit.
+type FitOptions struct {
+	GPUs []GPUMemory
+	// HostFreeBytes is available system memory; 0 means unknown.
+	HostFreeBytes uint64
+	// ContextSize fixes the context; 0 picks the largest that fits
+	// between MinContext and MaxContext.
+	ContextSize int
+	MinContext  int
+	MaxContext  int
+}
+
+// FitEstimate is how a GGUF model fits into GPU and host memory.
+type FitEstimate struct {
+	// TotalLayers counts the repeating blocks plus the output layer, the
+	// way llama.cpp's --n-gpu-layers does.
+	TotalLayers int
+	GPULayers   int
+	ContextSize int
+	// TensorSplit holds the layers placed on each GPU.
+	TensorSplit     []int
+	WeightBytes     uint64
+	KVBytesPerToken uint64
+	GPUBytes        uint64
+	HostBytes       uint64
+	// Fits is false when the layers left on the CPU exceed free host memory.
+	Fits bool
+}
+
+// FullOffload reports whether every layer runs on the GPUs.
+func (e FitEstimate) FullOffload() bool {
+	return e.TotalLayers > 0 && e.GPULayers >= e.TotalLayers
+}
+
+// TensorSplitArg formats the split for llama.cpp's --tensor-split; it is
+// empty unless layers are spread across several GPUs.
+func (e FitEstimate) TensorSplitArg() string {
+	used := 0
+	for _, layers := range e.TensorSplit {
+		if layers > 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		return ""
+	}
+	parts := make([]string, len(e.TensorSplit))
+	for i, layers := range e.TensorSplit {
+		parts[i] = strconv.Itoa(layers)
+	}
+	return strings.Join(parts, ",")
+}
+
+// fitModel holds the per-layer costs derived from a GGUF header.
+type fitModel struct {
+	layers          int
+	layerBytes      uint64
+	weightBytes     uint64
+	kvPerLayerToken uint64
+	computePerToken uint64
+}
+
+// EstimateFit works out how many layers of a GGUF model fit in the GPUs'
+// free memory and the largest context that still fits. Weights are spread
+// evenly over the layers; each GPU layer also holds its share of the f16
+// KV cache.
+func EstimateFit(model *GGUFInfo, opts FitOptions) (*FitEstimate, error) {
+	m, err := newFitModel(model)
+	if err != nil {
+		return nil, err
+	}
+
+	maxContext := opts.MaxContext
+	if maxContext <= 0 {
+		maxContext = fitUnknownContext
+		if model.ContextLength > 0 {
+			maxContext = int(min(model.ContextLength, fitDefaultMaxContext))
+		}
+	}
+	minContext := opts.MinContext
+	if minContext <= 0 {
+		minContext = fitDefaultMinContext
+	}
+	minContext = min(minContext, maxContext)
+
+	contextSize := opts.ContextSize
+	if contextSize <= 0 {
+		contextSize = minContext
+		if len(opts.GPUs) > 0 {
+			if m.gpuLayers(opts.GPUs, minContext) >= m.layers {
+				contextSize = m.largestContext(minContext, maxContext, func(ctx int) bool {
+					return m.gpuLayers(opts.GPUs, ctx) >= m.layers
+				})
+			}
+		} else if opts.HostFreeBytes > 0 {
+			contextSize = m.largestContext(minContext, maxContext, func(ctx int) bool {
+				return m.weightBytes+m.kvPerLayerToken*uint64(m.layers)*uint64(ctx) <= opts.HostFreeBytes
+			})
+		}
+	}
+
+	estimate := &FitEstimate{
+		TotalLayers:     m.layers,
+		ContextSize:     contextSize,
+		WeightBytes:     m.weightBytes,
+		KVBytesPerToken: m.kvPerLayerToken * uint64(m.layers),
+		Fits:            true,
+	}
+	if len(opts.GPUs) > 0 {
+		estimate.TensorSplit = m.split(opts.GPUs, contextSize)
+		for _, layers := range estimate.TensorSplit {
+			estimate.GPULayers += layers
+		}
+	}
+	perLayer := m.layerBytes + m.kvPerLayerToken*uint64(contextSize)
+	if estimate.GPULayers > 0 {
+		estimate.GPUBytes = perLayer * uint64(estimate.GPULayers)
+	}
+	estimate.HostBytes = perLayer * uint64(m.layers-estimate.GPULayers)
+	if opts.HostFreeBytes > 0 && estimate.HostBytes > opts.HostFreeBytes {
+		estimate.Fits = false
+	}
+	return estimate, nil
+}
+
+func newFitModel(model *GGUFInfo) (fitModel, error) {
+	if model == nil || model.BlockCount == 0 {
+		return fitModel{}, fmt.Errorf("model metadata has no layer count")
+	}
+	m := fitModel{layers: int(model.BlockCount) + 1}
+
+	m.weightBytes = uint64(max(model.Size, 0))
+	if m.weightBytes == 0 {
+		bits := bitsPerWeight(model.Quantization)
+		if bits == 0 || model.ParameterCount == 0 {
+			return fitModel{}, fmt.Errorf("model size is unknown for quantization %q", model.Quantization)
+		}
+		m.weightBytes = uint64(float64(model.ParameterCount) * bits / 8)
+	}
+	m.layerBytes = m.weightBytes / uint64(m.layers)
+
+	heads := model.HeadCount
+	kvHeads := model.HeadCountKV
+	if kvHeads == 0 {
+		kvHeads = heads
+	}
+	keyLength, valueLength := model.KeyLength, model.ValueLength
+	if heads > 0 && model.EmbeddingLength > 0 {
+		if keyLength == 0 {
+			keyLength = model.EmbeddingLength / heads
+		}
+		if valueLength == 0 {
+			valueLength = model.EmbeddingLength / heads
+		}
+	}
+	if kvHeads > 0 && keyLength+valueLength > 0 {
+		m.kvPerLayerToken = kvHeads * (keyLength + valueLength) * fitKVBytesPerElement
+	} else {
+		m.kvPerLayerToken = 2 * model.EmbeddingLength * fitKVBytesPerElement
+	}
+	m.computePerToken = model.EmbeddingLength * fitComputeBytesPerEmb
+	return m, nil
+}
+
+// capacity is the number of layers one GPU holds at the given context.
+func (m fitModel) capacity(gpu GPUMemory, contextSize int) int {
+	reserve := uint64(fitGPUReserveBytes) + m.computePerToken*uint64(contextSize)
+	if gpu.FreeBytes <= reserve {
+		return 0
+	}
+	perLayer := m.layerBytes + m.kvPerLayerToken*uint64(contextSize)
+	if perLayer == 0 {
+		return m.layers
+	}
+	return int(min((gpu.FreeBytes-reserve)/perLayer, uint64(m.layers)))
+}
+
+func (m fitModel) gpuLayers(gpus []GPUMemory, contextSize int) int {
+	total := 0
+	for _, gpu := range gpus {
+		total += m.capacity(gpu, contextSize)
+	}
+	return min(total, m.layers)
+}
+
+// split places layers on the GPUs in proportion to what each can hold.
+func (m fitModel) split(gpus []GPUMemory, contextSize int) []int {
+	capacities := make([]int, len(gpus))
+	total := 0
+	for i, gpu := range gpus {
+		capacities[i] = m.capacity(gpu, contextSize)
+		total += capacities[i]
+	}
+	if total <= m.layers {
+		return capacities
+	}
+	layers := make([]int, len(gpus))
+	assigned := 0
+	for i, capacity := range capacities {
+		layers[i] = capacity * m.layers / total
+		assigned += layers[i]
+	}
+	for i := 0; assigned < m.layers; i = (i + 1) % len(layers) {
+		if layers[i] < capacities[i] {
+			layers[i]++
+			assigned++
+		}
+	}
+	return layers
+}
+
+// largestContext searches, in fitContextStep increments, for the largest
+// context between low and high that fits; low is assumed to fit.
+func (m fitModel) largestContext(low, high int, fits func(contextSize int) bool) int {
+	if fits(high) {
+		return high
+	}
+	best := low
+	lo, hi := low/fitContextStep, high/fitContextStep
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		contextSize := mid * fitContextStep
+		if contextSize >= low && fits(contextSize) {
+			best = contextSize
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	return best
+}
+
+func bitsPerWeight(quantization string) float64 {
+	quantization = strings.ToUpper(quantization)
+	for _, entry := range quantizationBits {
+		if strings.HasPrefix(quantization, entry.prefix) {
+			return entry.bits
+		}
+	}
+	return 0
+}
+
+// vLLM defaults. The reserve covers the CUDA context and the activations
+// vLLM profiles before sizing its KV cache.
+const (
+	vllmGPUReserveBytes             = 1 << 30
+	vllmDefaultGPUMemoryUtilization = 0.90
+	vllmKVBytesPerElement           = 2
+)
+
+// VLLMFit is how vLLM should serve a safetensors model on this machine.
+type VLLMFit struct {
+	TensorParallelSize   int
+	MaxModelLen          int
+	Dtype                string
+	Quantization         string
+	GPUMemoryUtilization float64
+	WeightBytes          uint64
+	KVBytesPerToken      uint64
+}
+
+// EstimateVLLMFit picks the smallest tensor-parallel size whose GPUs hold
+// the weights and a KV cache for the longest context, and that context.
+// opts.ContextSize fixes the max model length; gpuMemoryUtilization is
+// vLLM's share of each GPU (0 = default). It fails when no tensor-parallel
+// size leaves room for MinContext tokens.
+func EstimateVLLMFit(model *SafetensorsModel, opts FitOptions, gpuMemoryUtilization float64) (*VLLMFit, error) {
+	config := model.Config
+	if config.NumHiddenLayers == 0 {
+		return nil, fmt.Errorf("config.json has no num_hidden_layers")
+	}
+	if len(opts.GPUs) == 0 {
+		return nil, fmt.Errorf("no GPUs detected for vLLM")
+	}
+	if gpuMemoryUtilization <= 0 {
+		gpuMemoryUtilization = vllmDefaultGPUMemoryUtilization
+	}
+
+	fit := &VLLMFit{
+		TensorParallelSize:   1,
+		Dtype:                "auto",
+		Quantization:         model.Quantization(),
+		GPUMemoryUtilization: gpuMemoryUtilization,
+		WeightBytes:          model.WeightBytes,
+	}
+	switch strings.ToLower(config.TorchDtype) {
+	case "bfloat16":
+		fit.Dtype = "bfloat16"
+	case "float16", "float32":
+		// float32 checkpoints are served in half precision.
+		fit.Dtype = "float16"
+	}
+	if fit.Quantization == "" && model.ParameterCount > 0 {
+		fit.WeightBytes = model.ParameterCount * 2
+	}
+
+	kvHeads := config.NumKeyValueHeads
+	if kvHeads == 0 {
+		kvHeads = config.NumAttentionHeads
+	}
+	headDim := config.HeadDim
+	if headDim == 0 && config.NumAttentionHeads > 0 {
+		headDim = config.HiddenSize / config.NumAttentionHeads
+	}
+	if kvHeads > 0 && headDim > 0 {
+		fit.KVBytesPerToken = config.NumHiddenLayers * kvHeads * headDim * 2 * vllmKVBytesPerElement
+	} else {
+		fit.KVBytesPerToken = config.NumHiddenLayers * config.HiddenSize * 2 * vllmKVBytesPerElement
+	}
+
+	maxContext := opts.MaxContext
+	if maxContext <= 0 {
+		maxContext = fitUnknownContext
+		if config.MaxPositionEmbeddings > 0 {
+			maxContext = int(min(config.MaxPositionEmbeddings, fitDefaultMaxContext))
+		}
+	}
+	minContext := opts.MinContext
+	if minContext <= 0 {
+		minContext = fitDefaultMinContext
+	}
+	minContext = min(minContext, maxContext)
+	if opts.ContextSize > 0 {
+		minContext, maxContext = opts.ContextSize, opts.ContextSize
+	}
+
+	gpus := append([]GPUMemory(nil), opts.GPUs...)
+	sort.Slice(gpus, func(i, j int) bool { return gpus[i].FreeBytes > gpus[j].FreeBytes })
+	best := 0
+	for tp := 1; tp <= len(gpus); tp *= 2 {
+		if config.NumAttentionHeads > 0 && config.NumAttentionHeads%uint64(tp) != 0 {
+			continue
+		}
+		budget := uint64(float64(gpus[tp-1].FreeBytes) * gpuMemoryUtilization)
+		needed := vllmGPUReserveBytes + fit.WeightBytes/uint64(tp)
+		if budget <= needed || fit.KVBytesPerToken == 0 {
+			continue
+		}
+		tokens := (budget - needed) * uint64(tp) / fit.KVBytesPerToken
+		maxModelLen := int(min(tokens, uint64(maxContext)))
+		if maxModelLen < maxContext {
+			maxModelLen -= maxModelLen % fitContextStep
+		}
+		if maxModelLen < minContext || maxModelLen <= best {
+			continue
+		}
+		best = maxModelLen
+		fit.TensorParallelSize, fit.MaxModelLen = tp, maxModelLen
+		if maxModelLen == maxContext {
+			break
+		}
+	}
+	if best == 0 {
+		return nil, fmt.Errorf("model needs %s of weights plus %s of KV cache for %d tokens, more than the free memory of %d GPU(s) allows",
+			FormatBytes(int64(fit.WeightBytes)), FormatBytes(int64(fit.KVBytesPerToken*uint64(minContext))), minContext, len(gpus))
+	}
+	return fit, nil
+}
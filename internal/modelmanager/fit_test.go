@@ -0,0 +1,129 @@
+package modelmanager
+
+import (
+	"strings"
+	"testing"
+)
+
+const gib = 1 << 30
+
+// llama8B resembles a Llama 3 8B Q4_K_M file.
+func llama8B() *GGUFInfo {
+	return &GGUFInfo{
+		Size:            4_900_000_000,
+		ContextLength:   131072,
+		EmbeddingLength: 4096,
+		BlockCount:      32,
+		HeadCount:       32,
+		HeadCountKV:     8,
+		Quantization:    "Q4_K_M",
+		ParameterCount:  8_030_000_000,
+	}
+}
+
+func TestEstimateFit_FullOffloadMaximizesContext(t *testing.T) {
+	fit, err := EstimateFit(llama8B(), FitOptions{GPUs: []GPUMemory{{Name: "RTX 4090", FreeBytes: 24 * gib}}})
+	if err != nil {
+		t.Fatalf("EstimateFit returned error: %v", err)
+	}
+	if !fit.FullOffload() || fit.GPULayers != 33 {
+		t.Fatalf("expected all 33 layers on the GPU, got %+v", fit)
+	}
+	if fit.ContextSize != fitDefaultMaxContext {
+		t.Fatalf("expected the capped maximum context, got %d", fit.ContextSize)
+	}
+	if want := uint64(32+1) * 8 * 256 * 2; fit.KVBytesPerToken != want {
+		t.Fatalf("KV cache per token = %d, want %d", fit.KVBytesPerToken, want)
+	}
+	if fit.TensorSplitArg() != "" {
+		t.Fatalf("a single GPU needs no tensor split, got %q", fit.TensorSplitArg())
+	}
+}
+
+func TestEstimateFit_ContextShrinksBeforeLayers(t *testing.T) {
+	fit, err := EstimateFit(llama8B(), FitOptions{GPUs: []GPUMemory{{FreeBytes: 7 * gib}}})
+	if err != nil {
+		t.Fatalf("EstimateFit returned error: %v", err)
+	}
+	if !fit.FullOffload() {
+		t.Fatalf("expected a full offload, got %+v", fit)
+	}
+	if fit.ContextSize <= fitDefaultMinContext || fit.ContextSize >= fitDefaultMaxContext || fit.ContextSize%fitContextStep != 0 {
+		t.Fatalf("expected a context between the bounds, got %d", fit.ContextSize)
+	}
+	if fit.GPUBytes+fitGPUReserveBytes > 7*gib {
+		t.Fatalf("estimate uses %d bytes of a 7 GiB GPU", fit.GPUBytes)
+	}
+}
+
+func TestEstimateFit_PartialOffload(t *testing.T) {
+	fit, err := EstimateFit(llama8B(), FitOptions{GPUs: []GPUMemory{{FreeBytes: 4 * gib}}, HostFreeBytes: 2 * gib})
+	if err != nil {
+		t.Fatalf("EstimateFit returned error: %v", err)
+	}
+	if fit.FullOffload() || fit.GPULayers == 0 {
+		t.Fatalf("expected a partial offload, got %+v", fit)
+	}
+	if fit.ContextSize != fitDefaultMinContext {
+		t.Fatalf("expected the minimum context for a partial offload, got %d", fit.ContextSize)
+	}
+	if !fit.Fits {
+		t.Fatalf("expected the remaining layers to fit in system memory, got %+v", fit)
+	}
+
+	fit, err = EstimateFit(llama8B(), FitOptions{HostFreeBytes: 2 * gib})
+	if err != nil {
+		t.Fatalf("EstimateFit returned error: %v", err)
+	}
+	if fit.GPULayers != 0 || fit.Fits {
+		t.Fatalf("expected a CPU-only estimate that does not fit, got %+v", fit)
+	}
+}
+
+func TestEstimateFit_TensorSplitAcrossGPUs(t *testing.T) {
+	model := llama8B()
+	model.Size = 40 * gib
+	model.BlockCount = 80
+	fit, err := EstimateFit(model, FitOptions{
+		GPUs:        []GPUMemory{{FreeBytes: 24 * gib}, {FreeBytes: 12 * gib}},
+		ContextSize: 4096,
+	})
+	if err != nil {
+		t.Fatalf("EstimateFit returned error: %v", err)
+	}
+	if fit.ContextSize != 4096 {
+		t.Fatalf("expected the requested context, got %d", fit.ContextSize)
+	}
+	if len(fit.TensorSplit) != 2 || fit.TensorSplit[0] <= fit.TensorSplit[1] || fit.TensorSplit[1] == 0 {
+		t.Fatalf("expected more layers on the larger GPU, got %v", fit.TensorSplit)
+	}
+	if fit.GPULayers != fit.TensorSplit[0]+fit.TensorSplit[1] || fit.FullOffload() {
+		t.Fatalf("unexpected split estimate %+v", fit)
+	}
+	if !strings.Contains(fit.TensorSplitArg(), ",") {
+		t.Fatalf("expected a tensor split argument, got %q", fit.TensorSplitArg())
+	}
+
+	fit, err = EstimateFit(model, FitOptions{GPUs: []GPUMemory{{FreeBytes: 48 * gib}, {FreeBytes: 48 * gib}}})
+	if err != nil {
+		t.Fatalf("EstimateFit returned error: %v", err)
+	}
+	if !fit.FullOffload() || fit.TensorSplit[0]+fit.TensorSplit[1] != fit.TotalLayers {
+		t.Fatalf("expected the layers split over both GPUs, got %+v", fit)
+	}
+}
+
+func TestEstimateFit_RequiresLayerCount(t *testing.T) {
+	if _, err := EstimateFit(&GGUFInfo{Size: gib}, FitOptions{}); err == nil {
+		t.Fatalf("expected an error without a layer count")
+	}
+	model := llama8B()
+	model.Size = 0
+	fit, err := EstimateFit(model, FitOptions{})
+	if err != nil {
+		t.Fatalf("EstimateFit returned error: %v", err)
+	}
+	if fit.WeightBytes < 4*gib || fit.WeightBytes > 5*gib {
+		t.Fatalf("expected weights estimated from the quantization, got %d", fit.WeightBytes)
+	}
+}
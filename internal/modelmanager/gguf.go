@@ -0,0 +1,451 @@
+package modelmanager
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ggufMagic is "GGUF" read as a little-endian uint32.
+const ggufMagic = 0x46554747
+
+// Limits that keep a corrupt header from causing huge allocations.
+const (
+	maxGGUFStringLength = 1 << 20
+	maxGGUFTensors      = 1 << 20
+	maxGGUFDims         = 8
+	maxGGUFNumericArray = 4096
+	maxGGUFArrayDepth   = 2
+)
+
+// GGUF metadata value types.
+const (
+	ggufTypeUint8 uint32 = iota
+	ggufTypeInt8
+	ggufTypeUint16
+	ggufTypeInt16
+	ggufTypeUint32
+	ggufTypeInt32
+	ggufTypeFloat32
+	ggufTypeBool
+	ggufTypeString
+	ggufTypeArray
+	ggufTypeUint64
+	ggufTypeInt64
+	ggufTypeFloat64
+)
+
+// ggufFileTypes names the values of general.file_type (llama_ftype).
+var ggufFileTypes = map[uint64]string{
+	0: "F32", 1: "F16", 2: "Q4_0", 3: "Q4_1", 7: "Q8_0", 8: "Q5_0", 9: "Q5_1",
+	10: "Q2_K", 11: "Q3_K_S", 12: "Q3_K_M", 13: "Q3_K_L", 14: "Q4_K_S", 15: "Q4_K_M",
+	16: "Q5_K_S", 17: "Q5_K_M", 18: "Q6_K", 19: "IQ2_XXS", 20: "IQ2_XS", 21: "Q2_K_S",
+	22: "IQ3_XS", 23: "IQ3_XXS", 24: "IQ1_S", 25: "IQ4_NL", 26: "IQ3_S", 27: "IQ3_M",
+	28: "IQ2_S", 29: "IQ2_M", 30: "IQ4_XS", 31: "IQ1_M", 32: "BF16", 36: "TQ1_0", 37: "TQ2_0",
+}
+
+// ggmlTypes names tensor types, used when a file has no general.file_type.
+var ggmlTypes = map[uint32]string{
+	0: "F32", 1: "F16", 2: "Q4_0", 3: "Q4_1", 6: "Q5_0", 7: "Q5_1", 8: "Q8_0", 9: "Q8_1",
+	10: "Q2_K", 11: "Q3_K", 12: "Q4_K", 13: "Q5_K", 14: "Q6_K", 15: "Q8_K",
+	16: "IQ2_XXS", 17: "IQ2_XS", 18: "IQ3_XXS", 19: "IQ1_S", 20: "IQ4_NL", 21: "IQ3_S",
+	22: "IQ2_S", 23: "IQ4_XS", 24: "I8", 25: "I16", 26: "I32", 27: "I64", 28: "F64",
+	29: "IQ1_M", 30: "BF16", 34: "TQ1_0", 35: "TQ2_0",
+}
+
+// GGUFInfo is the model description stored in a GGUF file header.
+type GGUFInfo struct {
+	// File is relative to the model directory.
+	File            string `json:"file"`
+	Size            int64  `json:"size"`
+	Version         uint32 `json:"version"`
+	Name            string `json:"name,omitempty"`
+	Architecture    string `json:"architecture,omitempty"`
+	ContextLength   uint64 `json:"context_length,omitempty"`
+	EmbeddingLength uint64 `json:"embedding_length,omitempty"`
+	BlockCount      uint64 `json:"block_count,omitempty"`
+	HeadCount       uint64 `json:"head_count,omitempty"`
+	HeadCountKV     uint64 `json:"head_count_kv,omitempty"`
+	KeyLength       uint64 `json:"key_length,omitempty"`
+	ValueLength     uint64 `json:"value_length,omitempty"`
+	// Quantization is the file type, e.g. "Q4_K_M".
+	Quantization   string `json:"quantization,omitempty"`
+	ParameterCount uint64 `json:"parameter_count"`
+	TensorCount    uint64 `json:"tensor_count"`
+	// SplitCount is set for models split across several files; only the
+	// first split carries the full metadata.
+	SplitCount uint64 `json:"split_count,omitempty"`
+}
+
+// ParameterBillions returns the parameter count in billions.
+func (info GGUFInfo) ParameterBillions() float64 {
+	return float64(info.ParameterCount) / 1e9
+}
+
+// ReadGGUFInfo parses the header of a GGUF file: its metadata key/values
+// and tensor descriptions. The tensor data is not read.
+func ReadGGUFInfo(path string) (*GGUFInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := parseGGUF(bufio.NewReaderSize(file, 1<<16))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GGUF header of %s: %w", filepath.Base(path), err)
+	}
+	info.File = filepath.Base(path)
+	info.Size = stat.Size()
+	return info, nil
+}
+
+type ggufReader struct {
+	r       io.Reader
+	version uint32
+}
+
+func parseGGUF(r io.Reader) (*GGUFInfo, error) {
+	g := &ggufReader{r: r}
+	magic, err := g.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if magic != ggufMagic {
+		return nil, errors.New("not a GGUF file")
+	}
+	if g.version, err = g.uint32(); err != nil {
+		return nil, err
+	}
+	if g.version < 1 || g.version > 3 {
+		return nil, fmt.Errorf("unsupported GGUF version %d", g.version)
+	}
+	tensorCount, err := g.count()
+	if err != nil {
+		return nil, err
+	}
+	kvCount, err := g.count()
+	if err != nil {
+		return nil, err
+	}
+	if tensorCount > maxGGUFTensors {
+		return nil, fmt.Errorf("implausible tensor count %d", tensorCount)
+	}
+
+	values := map[string]any{}
+	for i := uint64(0); i < kvCount; i++ {
+		key, err := g.string()
+		if err != nil {
+			return nil, err
+		}
+		valueType, err := g.uint32()
+		if err != nil {
+			return nil, err
+		}
+		value, err := g.value(valueType, 0)
+		if err != nil {
+			return nil, fmt.Errorf("metadata %s: %w", key, err)
+		}
+		if value != nil {
+			values[key] = value
+		}
+	}
+
+	info := &GGUFInfo{Version: g.version, TensorCount: tensorCount}
+	info.Name, _ = values["general.name"].(string)
+	info.Architecture, _ = values["general.architecture"].(string)
+	arch := info.Architecture
+	info.ContextLength = ggufUint(values[arch+".context_length"])
+	info.EmbeddingLength = ggufUint(values[arch+".embedding_length"])
+	info.BlockCount = ggufUint(values[arch+".block_count"])
+	info.HeadCount = ggufUint(values[arch+".attention.head_count"])
+	info.HeadCountKV = ggufUint(values[arch+".attention.head_count_kv"])
+	info.KeyLength = ggufUint(values[arch+".attention.key_length"])
+	info.ValueLength = ggufUint(values[arch+".attention.value_length"])
+	info.SplitCount = ggufUint(values["split.count"])
+	if fileType, ok := values["general.file_type"]; ok {
+		info.Quantization = ggufFileTypes[ggufUint(fileType)]
+	}
+
+	// Tensor infos give the parameter count and, without a file type, the
+	// dominant tensor type.
+	elementsByType := map[uint32]uint64{}
+	for i := uint64(0); i < tensorCount; i++ {
+		if _, err := g.string(); err != nil {
+			return nil, err
+		}
+		dims, err := g.uint32()
+		if err != nil {
+			return nil, err
+		}
+		if dims > maxGGUFDims {
+			return nil, fmt.Errorf("tensor %d has %d dimensions", i, dims)
+		}
+		elements := uint64(1)
+		for d := uint32(0); d < dims; d++ {
+			size, err := g.count()
+			if err != nil {
+				return nil, err
+			}
+			elements *= size
+		}
+		tensorType, err := g.uint32()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := g.uint64(); err != nil { // data offset
+			return nil, err
+		}
+		info.ParameterCount += elements
+		if dims >= 2 {
+			elementsByType[tensorType] += elements
+		}
+	}
+	if info.Quantization == "" {
+		var dominant uint32
+		var most uint64
+		for tensorType, elements := range elementsByType {
+			if elements > most || (elements == most && tensorType < dominant) {
+				dominant, most = tensorType, elements
+			}
+		}
+		if most > 0 {
+			info.Quantization = ggmlTypes[dominant]
+		}
+	}
+	return info, nil
+}
+
+func (g *ggufReader) uint32() (uint32, error) {
+	var value uint32
+	err := binary.Read(g.r, binary.LittleEndian, &value)
+	return value, err
+}
+
+func (g *ggufReader) uint64() (uint64, error) {
+	var value uint64
+	err := binary.Read(g.r, binary.LittleEndian, &value)
+	return value, err
+}
+
+// count reads a length or count, 32-bit in GGUF version 1.
+func (g *ggufReader) count() (uint64, error) {
+	if g.version == 1 {
+		value, err := g.uint32()
+		return uint64(value), err
+	}
+	return g.uint64()
+}
+
+func (g *ggufReader) string() (string, error) {
+	length, err := g.count()
+	if err != nil {
+		return "", err
+	}
+	if length > maxGGUFStringLength {
+		return "", fmt.Errorf("string of %d bytes is too long", length)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(g.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// value reads a metadata value. Scalars and strings are returned; numeric
+// arrays up to maxGGUFNumericArray elements are returned as []any and
+// other arrays are skipped and returned as nil. Arrays nested deeper than
+// maxGGUFArrayDepth are rejected.
+func (g *ggufReader) value(valueType uint32, depth int) (any, error) {
+	switch valueType {
+	case ggufTypeUint8, ggufTypeInt8, ggufTypeBool:
+		var value uint8
+		err := binary.Read(g.r, binary.LittleEndian, &value)
+		if valueType == ggufTypeInt8 {
+			return int64(int8(value)), err
+		}
+		if valueType == ggufTypeBool {
+			return value != 0, err
+		}
+		return uint64(value), err
+	case ggufTypeUint16, ggufTypeInt16:
+		var value uint16
+		err := binary.Read(g.r, binary.LittleEndian, &value)
+		if valueType == ggufTypeInt16 {
+			return int64(int16(value)), err
+		}
+		return uint64(value), err
+	case ggufTypeUint32, ggufTypeInt32:
+		value, err := g.uint32()
+		if valueType == ggufTypeInt32 {
+			return int64(int32(value)), err
+		}
+		return uint64(value), err
+	case ggufTypeUint64, ggufTypeInt64:
+		value, err := g.uint64()
+		if valueType == ggufTypeInt64 {
+			return int64(value), err
+		}
+		return value, err
+	case ggufTypeFloat32:
+		value, err := g.uint32()
+		return float64(math.Float32frombits(value)), err
+	case ggufTypeFloat64:
+		value, err := g.uint64()
+		return math.Float64frombits(value), err
+	case ggufTypeString:
+		return g.string()
+	case ggufTypeArray:
+		if depth >= maxGGUFArrayDepth {
+			return nil, fmt.Errorf("arrays nested more than %d deep", maxGGUFArrayDepth)
+		}
+		elementType, err := g.uint32()
+		if err != nil {
+			return nil, err
+		}
+		length, err := g.count()
+		if err != nil {
+			return nil, err
+		}
+		keep := elementType != ggufTypeString && elementType != ggufTypeArray && length <= maxGGUFNumericArray
+		var values []any
+		for i := uint64(0); i < length; i++ {
+			value, err := g.value(elementType, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if keep {
+				values = append(values, value)
+			}
+		}
+		if !keep {
+			return nil, nil
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("unknown value type %d", valueType)
+	}
+}
+
+// ggufUint converts an integer metadata value to uint64. Per-layer arrays,
+// as some architectures use for head counts, yield their maximum.
+func ggufUint(value any) uint64 {
+	switch v := value.(type) {
+	case uint64:
+		return v
+	case int64:
+		if v > 0 {
+			return uint64(v)
+		}
+	case []any:
+		var largest uint64
+		for _, element := range v {
+			largest = max(largest, ggufUint(element))
+		}
+		return largest
+	}
+	return 0
+}
+
+var ggufSplitPattern = regexp.MustCompile(`^(.*)-(\d{5})-of-(\d{5})\.gguf$`)
+
+// ReadGGUFModel reads the model a GGUF file belongs to. For models split
+// into "-0000N-of-0000M.gguf" files the metadata comes from the given file
+// and the size and parameter count cover all splits.
+func ReadGGUFModel(path string) (*GGUFInfo, error) {
+	info, err := ReadGGUFInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	match := ggufSplitPattern.FindStringSubmatch(filepath.Base(path))
+	if match == nil {
+		return info, nil
+	}
+	siblings, err := filepath.Glob(filepath.Join(filepath.Dir(path), match[1]+"-*-of-"+match[3]+".gguf"))
+	if err != nil {
+		return info, nil
+	}
+	for _, sibling := range siblings {
+		if sibling == path {
+			continue
+		}
+		split, err := ReadGGUFInfo(sibling)
+		if err != nil {
+			return nil, err
+		}
+		info.ParameterCount += split.ParameterCount
+		info.TensorCount += split.TensorCount
+		info.Size += split.Size
+	}
+	return info, nil
+}
+
+// ModelGGUFInfo returns the GGUF headers of the model files in modelDir,
+// using the copies stored in metadata.json when they match the files.
+func ModelGGUFInfo(modelDir string) ([]GGUFInfo, error) {
+	files, err := FindGGUFFiles(modelDir)
+	if err != nil {
+		return nil, err
+	}
+	cached := map[string]GGUFInfo{}
+	if raw, err := os.ReadFile(filepath.Join(modelDir, "metadata.json")); err == nil {
+		var metadata struct {
+			GGUF []GGUFInfo `json:"gguf"`
+		}
+		if json.Unmarshal(raw, &metadata) == nil {
+			for _, info := range metadata.GGUF {
+				cached[info.File] = info
+			}
+		}
+	}
+
+	infos := make([]GGUFInfo, 0, len(files))
+	for _, path := range files {
+		rel, err := filepath.Rel(modelDir, path)
+		if err != nil {
+			rel = filepath.Base(path)
+		}
+		if stat, err := os.Stat(path); err == nil {
+			if info, ok := cached[rel]; ok && info.Size == stat.Size() {
+				infos = append(infos, info)
+				continue
+			}
+		}
+		info, err := ReadGGUFInfo(path)
+		if err != nil {
+			return nil, err
+		}
+		info.File = rel
+		infos = append(infos, *info)
+	}
+	return infos, nil
+}
+
+// recordGGUFInfo stores the GGUF headers of a downloaded model in its
+// metadata.json, keeping the fields the provider wrote.
+func recordGGUFInfo(modelDir string) error {
+	infos, err := ModelGGUFInfo(modelDir)
+	if err != nil {
+		return err
+	}
+	if len(infos) == 0 {
+		return nil
+	}
+	return updateMetadata(modelDir, func(metadata map[string]any) {
+		metadata["gguf"] = infos
+		if format, _ := metadata["format"].(string); format == "" || format == string(FormatUnknown) {
+			metadata["format"] = string(FormatGGUF)
+		}
+	})
+}
@@ -0,0 +1,296 @@
+package modelmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type ggufKV struct {
+	key   string
+	value any
+}
+
+// ggufNestedArray is a uint32 wrapped in arrays nested this many levels deep.
+type ggufNestedArray int
+
+type ggufTensor struct {
+	name       string
+	dims       []uint64
+	tensorType uint32
+}
+
+// writeGGUF writes a version 3 GGUF header with the given metadata and
+// tensor infos, followed by a few bytes standing in for tensor data.
+func writeGGUF(t *testing.T, path string, kvs []ggufKV, tensors []ggufTensor) {
+	t.Helper()
+	var buf bytes.Buffer
+	put := func(value any) {
+		if err := binary.Write(&buf, binary.LittleEndian, value); err != nil {
+			t.Fatalf("binary.Write: %v", err)
+		}
+	}
+	putString := func(value string) {
+		put(uint64(len(value)))
+		buf.WriteString(value)
+	}
+
+	put(uint32(ggufMagic))
+	put(uint32(3))
+	put(uint64(len(tensors)))
+	put(uint64(len(kvs)))
+	for _, kv := range kvs {
+		putString(kv.key)
+		switch value := kv.value.(type) {
+		case string:
+			put(ggufTypeString)
+			putString(value)
+		case uint32:
+			put(ggufTypeUint32)
+			put(value)
+		case uint64:
+			put(ggufTypeUint64)
+			put(value)
+		case float32:
+			put(ggufTypeFloat32)
+			put(math.Float32bits(value))
+		case bool:
+			put(ggufTypeBool)
+			if value {
+				put(uint8(1))
+			} else {
+				put(uint8(0))
+			}
+		case []string:
+			put(ggufTypeArray)
+			put(ggufTypeString)
+			put(uint64(len(value)))
+			for _, element := range value {
+				putString(element)
+			}
+		case []uint32:
+			put(ggufTypeArray)
+			put(ggufTypeUint32)
+			put(uint64(len(value)))
+			for _, element := range value {
+				put(element)
+			}
+		case ggufNestedArray:
+			put(ggufTypeArray)
+			for level := 1; level <= int(value); level++ {
+				if level < int(value) {
+					put(ggufTypeArray)
+				} else {
+					put(ggufTypeUint32)
+				}
+				put(uint64(1))
+			}
+			put(uint32(7))
+		default:
+			t.Fatalf("unsupported test value %T", value)
+		}
+	}
+	var offset uint64
+	for _, tensor := range tensors {
+		putString(tensor.name)
+		put(uint32(len(tensor.dims)))
+		for _, dim := range tensor.dims {
+			put(dim)
+		}
+		put(tensor.tensorType)
+		put(offset)
+		offset += 32
+	}
+	buf.Write(make([]byte, 32))
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func llamaKVs(fileType uint32) []ggufKV {
+	kvs := []ggufKV{
+		{"general.architecture", "llama"},
+		{"general.name", "Tiny Llama"},
+		{"general.alignment", uint32(32)},
+		{"llama.context_length", uint32(8192)},
+		{"llama.embedding_length", uint32(64)},
+		{"llama.block_count", uint32(2)},
+		{"llama.attention.head_count", uint32(8)},
+		{"llama.attention.head_count_kv", uint32(2)},
+		{"llama.rope.freq_base", float32(10000)},
+		{"tokenizer.ggml.add_bos_token", true},
+		{"tokenizer.ggml.tokens", []string{"<s>", "</s>", "hello", "world"}},
+	}
+	if fileType > 0 {
+		kvs = append(kvs, ggufKV{"general.file_type", fileType})
+	}
+	return kvs
+}
+
+var llamaTensors = []ggufTensor{
+	{name: "token_embd.weight", dims: []uint64{64, 100}, tensorType: 12},
+	{name: "blk.0.attn_q.weight", dims: []uint64{64, 64}, tensorType: 12},
+	{name: "blk.0.attn_norm.weight", dims: []uint64{64}, tensorType: 0},
+	{name: "output.weight", dims: []uint64{64, 100}, tensorType: 14},
+}
+
+func TestReadGGUFInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tiny-llama.Q4_K_M.gguf")
+	writeGGUF(t, path, llamaKVs(15), llamaTensors)
+
+	info, err := ReadGGUFInfo(path)
+	if err != nil {
+		t.Fatalf("ReadGGUFInfo returned error: %v", err)
+	}
+	want := GGUFInfo{
+		File:            "tiny-llama.Q4_K_M.gguf",
+		Size:            info.Size,
+		Version:         3,
+		Name:            "Tiny Llama",
+		Architecture:    "llama",
+		ContextLength:   8192,
+		EmbeddingLength: 64,
+		BlockCount:      2,
+		HeadCount:       8,
+		HeadCountKV:     2,
+		Quantization:    "Q4_K_M",
+		ParameterCount:  64*100 + 64*64 + 64 + 64*100,
+		TensorCount:     4,
+	}
+	if *info != want {
+		t.Fatalf("ReadGGUFInfo = %+v, want %+v", *info, want)
+	}
+}
+
+func TestReadGGUFInfo_QuantizationFromTensorsAndHeadArrays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	kvs := llamaKVs(0)
+	kvs[6] = ggufKV{"llama.attention.head_count", []uint32{4, 8, 6}}
+	writeGGUF(t, path, kvs, llamaTensors)
+
+	info, err := ReadGGUFInfo(path)
+	if err != nil {
+		t.Fatalf("ReadGGUFInfo returned error: %v", err)
+	}
+	if info.Quantization != "Q4_K" {
+		t.Fatalf("expected the dominant tensor type Q4_K, got %q", info.Quantization)
+	}
+	if info.HeadCount != 8 {
+		t.Fatalf("expected the largest per-layer head count, got %d", info.HeadCount)
+	}
+}
+
+func TestReadGGUFInfo_RejectsInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	notGGUF := filepath.Join(dir, "model.bin")
+	if err := os.WriteFile(notGGUF, []byte("PK\x03\x04 not a gguf file"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := ReadGGUFInfo(notGGUF); err == nil {
+		t.Fatalf("expected an error for a non-GGUF file")
+	}
+
+	valid := filepath.Join(dir, "valid.gguf")
+	writeGGUF(t, valid, llamaKVs(15), llamaTensors)
+	raw, err := os.ReadFile(valid)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	truncated := filepath.Join(dir, "truncated.gguf")
+	if err := os.WriteFile(truncated, raw[:len(raw)/2], 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := ReadGGUFInfo(truncated); err == nil {
+		t.Fatalf("expected an error for a truncated header")
+	}
+}
+
+func TestReadGGUFInfo_LimitsArrayNesting(t *testing.T) {
+	dir := t.TempDir()
+	for depth, ok := range map[int]bool{2: true, 3: false, 100000: false} {
+		path := filepath.Join(dir, fmt.Sprintf("nested-%d.gguf", depth))
+		writeGGUF(t, path, append(llamaKVs(15), ggufKV{"general.nested", ggufNestedArray(depth)}), llamaTensors)
+		_, err := ReadGGUFInfo(path)
+		if ok && err != nil {
+			t.Fatalf("arrays nested %d deep: unexpected error %v", depth, err)
+		}
+		if !ok && err == nil {
+			t.Fatalf("arrays nested %d deep: expected an error", depth)
+		}
+	}
+}
+
+func TestReadGGUFModel_SumsSplits(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "big-00001-of-00002.gguf")
+	writeGGUF(t, first, append(llamaKVs(15), ggufKV{"split.count", uint32(2)}), llamaTensors[:2])
+	writeGGUF(t, filepath.Join(dir, "big-00002-of-00002.gguf"), []ggufKV{{"split.count", uint32(2)}}, llamaTensors[2:])
+
+	info, err := ReadGGUFModel(first)
+	if err != nil {
+		t.Fatalf("ReadGGUFModel returned error: %v", err)
+	}
+	if info.SplitCount != 2 || info.TensorCount != 4 || info.ParameterCount != 64*100+64*64+64+64*100 {
+		t.Fatalf("unexpected split model info %+v", info)
+	}
+}
+
+type ggufTestProvider struct {
+	t *testing.T
+}
+
+func (p ggufTestProvider) Name() ModelSource { return SourceHuggingFace }
+
+func (p ggufTestProvider) Search(context.Context, string, int) ([]ModelInfo, error) {
+	return nil, nil
+}
+
+func (p ggufTestProvider) Download(_ context.Context, modelID string, destPath string, _ func(downloaded, total int64), _ DownloadOptions) error {
+	modelDir := filepath.Join(destPath, localDirName(SourceHuggingFace, modelID))
+	if err := os.MkdirAll(modelDir, 0o755); err != nil {
+		return err
+	}
+	writeGGUF(p.t, filepath.Join(modelDir, "tiny.Q4_K_M.gguf"), llamaKVs(15), llamaTensors)
+	metadata, err := json.Marshal(map[string]any{"id": modelID, "source": "huggingface", "downloaded_at": 1})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(modelDir, "metadata.json"), metadata, 0o644)
+}
+
+func (p ggufTestProvider) Delete(context.Context, string) error { return nil }
+
+func (p ggufTestProvider) GetModelInfo(context.Context, string) (*ModelInfo, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func TestDownloadModel_RecordsGGUFMetadata(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	if err := mgr.RegisterProvider(ggufTestProvider{t: t}); err != nil {
+		t.Fatalf("RegisterProvider: %v", err)
+	}
+	if err := mgr.DownloadModel(SourceHuggingFace, "org/tiny-GGUF", nil, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadModel returned error: %v", err)
+	}
+
+	models, err := mgr.ListDownloadedModels()
+	if err != nil {
+		t.Fatalf("ListDownloadedModels returned error: %v", err)
+	}
+	if len(models) != 1 || len(models[0].GGUF) != 1 {
+		t.Fatalf("expected one model with GGUF metadata, got %+v", models)
+	}
+	model := models[0]
+	if model.Format != FormatGGUF || model.GGUF[0].Architecture != "llama" || model.GGUF[0].Quantization != "Q4_K_M" {
+		t.Fatalf("unexpected recorded metadata %+v", model)
+	}
+	if model.ID != "org/tiny-GGUF" {
+		t.Fatalf("provider metadata was not kept: %+v", model)
+	}
+}
@@ -0,0 +1,170 @@
+package modelmanager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// HTTPProvider downloads models from plain HTTP(S) URLs, either a single
+// file given as the model ID or the files an index entry lists. A direct
+// URL may carry its digest as a "#sha256=<hex>" fragment.
+type HTTPProvider struct {
+	client *artifactClient
+}
+
+func NewHTTPProvider(opts ArtifactOptions) *HTTPProvider {
+	return &HTTPProvider{client: newArtifactClient(opts)}
+}
+
+func (p *HTTPProvider) Name() ModelSource {
+	return SourceURL
+}
+
+func (p *HTTPProvider) Search(ctx context.Context, query string, limit int) ([]ModelInfo, error) {
+	return p.client.search(ctx, SourceURL, query, limit, func(entry artifactEntry) bool {
+		return len(entry.Files) > 0
+	})
+}
+
+// resolve returns the files of a model: a direct URL or an index entry.
+func (p *HTTPProvider) resolve(ctx context.Context, modelID string) (*artifactEntry, error) {
+	if isHTTPURL(modelID) {
+		u, err := url.Parse(modelID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid model URL: %w", err)
+		}
+		file := artifactFile{Path: path.Base(u.Path)}
+		if digest, ok := strings.CutPrefix(u.Fragment, "sha256="); ok {
+			file.SHA256 = digest
+		}
+		u.Fragment = ""
+		file.URL = u.String()
+		return &artifactEntry{ID: modelID, Files: []artifactFile{file}}, nil
+	}
+	entry, err := p.client.lookup(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entry.Files) == 0 {
+		return nil, fmt.Errorf("model %s lists no files; try oci:%s", modelID, modelID)
+	}
+	return entry, nil
+}
+
+func (p *HTTPProvider) Download(ctx context.Context, modelID string, destPath string, progress func(downloaded, total int64), opts DownloadOptions) error {
+	entry, err := p.resolve(ctx, modelID)
+	if err != nil {
+		return err
+	}
+	var files []artifactFile
+	for _, file := range entry.Files {
+		if matchesFileHint(file.Path, opts.FileHint) {
+			files = append(files, file)
+		}
+	}
+	if opts.FileHint != "" && detectFilesFormat(artifactPaths(files)) == FormatUnknown {
+		return fmt.Errorf("no files matching %q found for %s", opts.FileHint, modelID)
+	}
+
+	modelDir := filepath.Join(destPath, localDirName(SourceURL, modelID))
+	for _, file := range files {
+		destFile, err := artifactFilePath(modelDir, file.Path)
+		if err != nil {
+			return err
+		}
+		err = fetchFile(ctx, destFile, file.Size, file.SHA256, progress, func(header http.Header) (*http.Response, error) {
+			return p.client.send(ctx, p.client.download, file.URL, header)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to download file %s: %w", file.Path, err)
+		}
+	}
+
+	extra := map[string]string{}
+	if isHTTPURL(modelID) {
+		extra["url"] = entry.Files[0].URL
+	} else {
+		extra["index"] = p.client.opts.IndexURL
+	}
+	format := entry.Format
+	if format == "" {
+		format = detectFilesFormat(artifactPaths(files))
+	}
+	return writeArtifactMetadata(modelDir, modelID, SourceURL, format, extra)
+}
+
+// DownloadSize sums the sizes the index lists, asking the server for
+// files without one.
+func (p *HTTPProvider) DownloadSize(ctx context.Context, modelID string, opts DownloadOptions) (int64, error) {
+	entry, err := p.resolve(ctx, modelID)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, file := range entry.Files {
+		if !matchesFileHint(file.Path, opts.FileHint) {
+			continue
+		}
+		if file.Size > 0 {
+			total += file.Size
+			continue
+		}
+		size, err := p.contentLength(ctx, file.URL)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+func (p *HTTPProvider) contentLength(ctx context.Context, rawURL string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	p.client.authorize(req)
+	resp, err := p.client.api.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+		return 0, fmt.Errorf("size of %s is unknown", redactURL(rawURL))
+	}
+	return resp.ContentLength, nil
+}
+
+func (p *HTTPProvider) Delete(ctx context.Context, modelID string) error {
+	return nil
+}
+
+func (p *HTTPProvider) GetModelInfo(ctx context.Context, modelID string) (*ModelInfo, error) {
+	entry, err := p.resolve(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	info := entry.info(SourceURL)
+	if size, err := p.DownloadSize(ctx, modelID, DownloadOptions{}); err == nil {
+		info.Size = size
+	}
+	return &info, nil
+}
+
+func isHTTPURL(input string) bool {
+	lower := strings.ToLower(input)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+func artifactPaths(files []artifactFile) []string {
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	return paths
+}
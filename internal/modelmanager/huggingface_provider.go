@@ -104,6 +104,19 @@ type HFModelFile struct {
 	Type string `json:"type"`
 	Path string `json:"path"`
 	Size int64  `json:"size"`
+	// LFS is set for files stored in Git LFS; its oid is the sha256 digest
+	// of the file.
+	LFS *struct {
+		OID string `json:"oid"`
+	} `json:"lfs,omitempty"`
+}
+
+// digest returns the sha256 digest of an LFS file, or "".
+func (f HFModelFile) digest() string {
+	if f.LFS == nil || !isBlobDigest(f.LFS.OID) {
+		return ""
+	}
+	return f.LFS.OID
 }
 
 type HFSibling struct {
@@ -215,7 +228,7 @@ func (p *HuggingFaceProvider) Download(ctx context.Context, modelID string, dest
 			return fmt.Errorf("failed to create destination directory for %s: %w", file.Path, err)
 		}
 
-		if err := p.downloadFile(ctx, fileURL, destFile, file.Size, progress); err != nil {
+		if err := p.downloadFile(ctx, fileURL, destFile, file.Size, file.digest(), progress); err != nil {
 			return fmt.Errorf("failed to download file %s: %w", file.Path, err)
 		}
 	}
@@ -242,6 +255,23 @@ func (p *HuggingFaceProvider) Download(ctx context.Context, modelID string, dest
 	return nil
 }
 
+// DownloadSize returns the bytes Download would fetch for modelID.
+func (p *HuggingFaceProvider) DownloadSize(ctx context.Context, modelID string, opts DownloadOptions) (int64, error) {
+	files, err := p.listModelFiles(ctx, modelID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list model files: %w", err)
+	}
+	candidates, err := filterDownloadFiles(files, opts.FileHint)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, file := range candidates {
+		size += file.Size
+	}
+	return size, nil
+}
+
 func filterDownloadFiles(files []HFModelFile, hint string) ([]HFModelFile, error) {
 	allowed := make([]HFModelFile, 0, len(files))
 	for _, file := range files {
@@ -498,7 +528,7 @@ func (p *HuggingFaceProvider) DownloadSupportFiles(ctx context.Context, modelID
 			continue
 		}
 		fileURL := fmt.Sprintf("%s/%s/resolve/main/%s", p.modelURL, modelID, remoteFile.Path)
-		if err := p.downloadFile(ctx, fileURL, destFile, remoteFile.Size, nil); err != nil {
+		if err := p.downloadFile(ctx, fileURL, destFile, remoteFile.Size, remoteFile.digest(), nil); err != nil {
 			return fmt.Errorf("failed to download file %s: %w", remoteFile.Path, err)
 		}
 	}
@@ -506,84 +536,52 @@ func (p *HuggingFaceProvider) DownloadSupportFiles(ctx context.Context, modelID
 	return nil
 }
 
-func (p *HuggingFaceProvider) downloadFile(ctx context.Context, url, destPath string, totalSize int64, progress func(downloaded, total int64)) error {
+// downloadFile fetches a file, retrying transient failures. Each attempt
+// resumes the partial file left by the previous one.
+func (p *HuggingFaceProvider) downloadFile(ctx context.Context, url, destPath string, totalSize int64, digest string, progress func(downloaded, total int64)) error {
 	downloadClient := &http.Client{
 		Timeout: hfDownloadTimeout,
 	}
 	if p.client != nil {
 		downloadClient.Transport = p.client.Transport
 	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return err
-	}
-
-	if p.token != "" {
-		req.Header.Set("Authorization", "Bearer "+p.token)
-	}
-
-	var lastErr error
-	for attempt := 1; attempt <= 3; attempt++ {
-		file, err := os.Create(destPath)
+	get := func(header http.Header) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
-			return err
+			return nil, err
 		}
-
-		resp, err := downloadClient.Do(req)
-		if err != nil {
-			_ = file.Close()
-			lastErr = err
-			if attempt < 3 && shouldRetryHuggingFace(err, 0) {
-				time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
-				continue
-			}
-			return err
+		if p.token != "" {
+			req.Header.Set("Authorization", "Bearer "+p.token)
+		}
+		for name, values := range header {
+			req.Header[name] = values
 		}
+		return downloadClient.Do(req)
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			_ = resp.Body.Close()
-			_ = file.Close()
-			lastErr = fmt.Errorf("HTTP %d", resp.StatusCode)
-			if attempt < 3 && shouldRetryHuggingFace(nil, resp.StatusCode) {
-				time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
-				continue
-			}
-			return lastErr
-		}
-
-		var downloaded int64
-		buf := make([]byte, chunkSize)
-		readOK := true
-		for {
-			n, readErr := resp.Body.Read(buf)
-			if n > 0 {
-				if _, werr := file.Write(buf[:n]); werr != nil {
-					_ = resp.Body.Close()
-					_ = file.Close()
-					return werr
-				}
-				downloaded += int64(n)
-				if progress != nil {
-					progress(downloaded, totalSize)
-				}
-			}
-			if readErr == io.EOF {
-				break
-			}
-			if readErr != nil {
-				lastErr = readErr
-				readOK = false
-				break
-			}
+	// A retry continues the same file, so it only reports progress beyond
+	// what earlier attempts reported.
+	var reported int64
+	report := func(downloaded, total int64) {
+		if progress == nil || downloaded < reported {
+			return
 		}
-		_ = resp.Body.Close()
-		_ = file.Close()
+		reported = downloaded
+		progress(downloaded, total)
+	}
 
-		if readOK {
+	var lastErr error
+	for attempt := 1; attempt <= 3; attempt++ {
+		lastErr = fetchFile(ctx, destPath, totalSize, digest, report, get)
+		if lastErr == nil {
 			return nil
 		}
-		if attempt < 3 && shouldRetryHuggingFace(lastErr, 0) {
+		statusCode := 0
+		var statusErr *httpStatusError
+		if errors.As(lastErr, &statusErr) {
+			statusCode = statusErr.StatusCode
+		}
+		if attempt < 3 && ctx.Err() == nil && shouldRetryHuggingFace(lastErr, statusCode) {
 			time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
 			continue
 		}
@@ -0,0 +1,364 @@
+package modelmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ImportMode selects how ImportModel places a model's files in the model
+// directory.
+type ImportMode string
+
+const (
+	// ImportCopy copies the files, leaving the originals untouched.
+	ImportCopy ImportMode = "copy"
+	// ImportLink symlinks the files; the originals must stay in place.
+	ImportLink ImportMode = "link"
+	// ImportMove moves the files into the model directory.
+	ImportMove ImportMode = "move"
+)
+
+type ImportOptions struct {
+	// Mode defaults to ImportCopy.
+	Mode ImportMode
+	// IgnoreQuota imports even when the model quota would be exceeded.
+	IgnoreQuota bool
+	// Source registers the model under another source than SourceLocal,
+	// as for a downloaded model restored from an offline bundle.
+	Source ModelSource
+	// Origin is recorded as where the model was imported from; it
+	// defaults to path.
+	Origin string
+}
+
+// ImportModel registers the model at path, a GGUF file or a directory of
+// GGUF or safetensors files, as the local model modelID. Its headers and
+// config.json are checked before anything is placed, and metadata.json is
+// written with source "local" unless opts.Source says otherwise.
+func (m *Manager) ImportModel(path, modelID string, opts ImportOptions) (*DownloadedModel, error) {
+	modelID = strings.TrimSpace(modelID)
+	source := opts.Source
+	if source == "" {
+		source = SourceLocal
+	}
+	if err := validateImportID(source, modelID); err != nil {
+		return nil, err
+	}
+	mode := opts.Mode
+	if mode == "" {
+		mode = ImportCopy
+	}
+	switch mode {
+	case ImportCopy, ImportLink, ImportMove:
+	default:
+		return nil, fmt.Errorf("unknown import mode: %s", mode)
+	}
+
+	srcPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	files, size, err := importFiles(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	srcDir := srcPath
+	if info, err := os.Stat(srcPath); err == nil && !info.IsDir() {
+		srcDir = filepath.Dir(srcPath)
+	}
+	format, name, err := inspectImport(srcPath, srcDir, files)
+	if err != nil {
+		return nil, err
+	}
+
+	destDir := filepath.Join(m.modelDir, localDirName(source, modelID))
+	if _, err := os.Lstat(destDir); err == nil {
+		return nil, fmt.Errorf("model %s already exists at %s", modelID, destDir)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if mode != ImportLink && !opts.IgnoreQuota && !m.quota.IsZero() {
+		limit, err := m.quota.Limit(m.modelDir)
+		if err != nil {
+			return nil, err
+		}
+		used, err := m.Usage()
+		if err != nil {
+			return nil, err
+		}
+		if used+size > limit {
+			return nil, fmt.Errorf("%w: %s needs %s but %s of the %s quota is used", ErrQuotaExceeded, modelID,
+				FormatBytes(size), FormatBytes(used), FormatBytes(limit))
+		}
+	}
+
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := placeImportFiles(srcDir, destDir, files, mode); err != nil {
+		_ = os.RemoveAll(destDir)
+		return nil, err
+	}
+	if mode == ImportMove && srcDir == srcPath {
+		removeEmptyDirs(srcDir)
+	}
+
+	if name == "" {
+		name = filepath.Base(modelID)
+	}
+	origin := opts.Origin
+	if origin == "" {
+		origin = srcPath
+	}
+	model := DownloadedModel{
+		ModelInfo: ModelInfo{
+			ID:     modelID,
+			Name:   name,
+			Source: source,
+			Format: format,
+			Size:   size,
+			Metadata: map[string]string{
+				"imported_from": origin,
+				"import_mode":   string(mode),
+			},
+		},
+		LocalPath:    destDir,
+		DownloadedAt: time.Now().Unix(),
+	}
+	encoded, err := json.MarshalIndent(map[string]any{
+		"id":            model.ID,
+		"name":          model.Name,
+		"source":        model.Source,
+		"format":        model.Format,
+		"size":          model.Size,
+		"metadata":      model.Metadata,
+		"downloaded_at": model.DownloadedAt,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(destDir, "metadata.json"), encoded, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write metadata: %w", err)
+	}
+	if mode != ImportLink {
+		// As for downloads, files that could not be deduplicated stay in place.
+		_ = m.storeBlobs(destDir)
+	}
+	_ = recordGGUFInfo(destDir)
+	if infos, err := ModelGGUFInfo(destDir); err == nil {
+		model.GGUF = infos
+	}
+	return &model, nil
+}
+
+// validateImportID rejects IDs of source that would not map to a single
+// directory under the model directory.
+func validateImportID(source ModelSource, modelID string) error {
+	switch source {
+	case SourceLocal, SourceHuggingFace, SourceModelScope:
+		return validateLocalModelID(modelID)
+	case SourceURL, SourceOCI:
+		name := localDirName(source, modelID)
+		if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `\`) {
+			return fmt.Errorf("invalid model id: %s", modelID)
+		}
+		return nil
+	}
+	return fmt.Errorf("models from %s cannot be imported", source)
+}
+
+// validateLocalModelID rejects IDs that would not map to a single
+// directory under the model directory.
+func validateLocalModelID(modelID string) error {
+	if modelID == "" {
+		return fmt.Errorf("model id is required")
+	}
+	for _, part := range strings.Split(modelID, "/") {
+		if part == "" || part == "." || part == ".." || strings.HasPrefix(part, ".") {
+			return fmt.Errorf("invalid model id: %s", modelID)
+		}
+	}
+	if strings.ContainsAny(modelID, `\:`) {
+		return fmt.Errorf("invalid model id: %s", modelID)
+	}
+	return nil
+}
+
+// importFiles lists the files to import from path, relative to the
+// directory holding them, and their total size. A metadata.json left by
+// another model directory is skipped.
+func importFiles(path string) ([]string, int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !info.IsDir() {
+		return []string{filepath.Base(path)}, info.Size(), nil
+	}
+	var files []string
+	var size int64
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if file != path && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+		if rel == "metadata.json" {
+			return nil
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		files = append(files, rel)
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Strings(files)
+	return files, size, nil
+}
+
+// inspectImport detects the format of the files to import and reads their
+// headers, so broken models are refused before any file is placed. It
+// returns the model name found in a GGUF header, if any.
+func inspectImport(srcPath, srcDir string, files []string) (ModelFormat, string, error) {
+	formats := map[ModelFormat]bool{}
+	for _, file := range files {
+		formats[DetectFormat(strings.ToLower(file))] = true
+	}
+	switch {
+	case formats[FormatSafetensors]:
+		if srcDir != srcPath {
+			return "", "", fmt.Errorf("import safetensors models as a directory with their config.json")
+		}
+		if _, err := ReadSafetensorsModel(srcDir); err != nil {
+			return "", "", fmt.Errorf("failed to read safetensors model: %w", err)
+		}
+		return FormatSafetensors, "", nil
+	case formats[FormatGGUF]:
+		name := ""
+		found := false
+		for _, file := range files {
+			if !strings.EqualFold(filepath.Ext(file), ".gguf") {
+				continue
+			}
+			info, err := ReadGGUFInfo(filepath.Join(srcDir, file))
+			if err != nil {
+				return "", "", fmt.Errorf("failed to read %s: %w", file, err)
+			}
+			if name == "" {
+				name = info.Name
+			}
+			found = true
+		}
+		if !found {
+			return "", "", fmt.Errorf("no .gguf files found in %s", srcPath)
+		}
+		return FormatGGUF, name, nil
+	default:
+		return "", "", fmt.Errorf("no GGUF or safetensors model files found in %s", srcPath)
+	}
+}
+
+// placeImportFiles links, copies or moves files from srcDir to destDir.
+// Files moved before a failure are moved back.
+func placeImportFiles(srcDir, destDir string, files []string, mode ImportMode) error {
+	var moved []string
+	for _, rel := range files {
+		src := filepath.Join(srcDir, rel)
+		dest := filepath.Join(destDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return err
+		}
+		var err error
+		switch mode {
+		case ImportLink:
+			err = os.Symlink(src, dest)
+		case ImportCopy:
+			err = copyFile(src, dest)
+		case ImportMove:
+			if err = moveFile(src, dest); err == nil {
+				moved = append(moved, rel)
+			}
+		}
+		if err != nil {
+			for _, rel := range moved {
+				_ = moveFile(filepath.Join(destDir, rel), filepath.Join(srcDir, rel))
+			}
+			return fmt.Errorf("failed to %s %s: %w", mode, rel, err)
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// moveFile renames src to dest, copying across file systems.
+func moveFile(src, dest string) error {
+	err := os.Rename(src, dest)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyFile(src, dest); err != nil {
+		_ = os.Remove(dest)
+		return err
+	}
+	return os.Remove(src)
+}
+
+// removeEmptyDirs removes dir and its subdirectories once a move emptied
+// them; anything left behind keeps its directory.
+func removeEmptyDirs(dir string) {
+	var dirs []string
+	_ = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i])
+	}
+}
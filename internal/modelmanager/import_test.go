@@ -0,0 +1,103 @@
+package modelmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportModel_GGUFFile(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	src := filepath.Join(t.TempDir(), "finetune.Q4_K_M.gguf")
+	writeGGUF(t, src, llamaKVs(15), llamaTensors)
+
+	model, err := mgr.ImportModel(src, "myorg/my-model", ImportOptions{Mode: ImportLink})
+	if err != nil {
+		t.Fatalf("ImportModel returned error: %v", err)
+	}
+	if model.Source != SourceLocal || model.Format != FormatGGUF || model.Name != "Tiny Llama" || len(model.GGUF) != 1 {
+		t.Fatalf("unexpected imported model %+v", model)
+	}
+	if target, err := os.Readlink(filepath.Join(model.LocalPath, "finetune.Q4_K_M.gguf")); err != nil || target != src {
+		t.Fatalf("expected a symlink to %s, got %q (%v)", src, target, err)
+	}
+
+	// Unprefixed and local: IDs both resolve to the imported model.
+	for _, ref := range []string{"myorg/my-model", "local:myorg/my-model"} {
+		source, modelID, err := ParseModelID(ref)
+		if err != nil {
+			t.Fatalf("ParseModelID(%q): %v", ref, err)
+		}
+		if dir, err := mgr.ResolveLocalModelDir(source, modelID); err != nil || dir != model.LocalPath {
+			t.Fatalf("%s resolved to %q (%v)", ref, dir, err)
+		}
+	}
+	models, err := mgr.ListDownloadedModels()
+	if err != nil || len(models) != 1 || models[0].ID != "myorg/my-model" || models[0].Source != SourceLocal {
+		t.Fatalf("expected the imported model to be listed, got %+v (%v)", models, err)
+	}
+
+	if _, err := mgr.ImportModel(src, "myorg/my-model", ImportOptions{}); err == nil {
+		t.Fatalf("expected an error when the model already exists")
+	}
+}
+
+func TestImportModel_MoveDirectory(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	if err := mgr.RegisterProvider(NewLocalProvider(mgr.GetModelDir())); err != nil {
+		t.Fatalf("RegisterProvider: %v", err)
+	}
+	src := filepath.Join(t.TempDir(), "checkpoint")
+	if err := os.MkdirAll(src, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeJSONFile(t, filepath.Join(src, "config.json"), map[string]any{
+		"num_hidden_layers": 2, "hidden_size": 64, "num_attention_heads": 4, "torch_dtype": "bfloat16",
+	})
+	writeSafetensors(t, filepath.Join(src, "model.safetensors"), map[string][]uint64{"embed.weight": {100, 64}}, "BF16")
+
+	model, err := mgr.ImportModel(src, "trained", ImportOptions{Mode: ImportMove})
+	if err != nil {
+		t.Fatalf("ImportModel returned error: %v", err)
+	}
+	if model.Format != FormatSafetensors {
+		t.Fatalf("expected a safetensors model, got %+v", model)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Fatalf("expected the emptied source directory to be removed, got %v", err)
+	}
+	if _, err := ReadSafetensorsModel(model.LocalPath); err != nil {
+		t.Fatalf("moved model is not readable: %v", err)
+	}
+
+	if err := mgr.RemoveModel(SourceLocal, "trained"); err != nil {
+		t.Fatalf("RemoveModel returned error: %v", err)
+	}
+	if mgr.HasLocalModel(SourceLocal, "trained", "") {
+		t.Fatalf("the imported model was not removed")
+	}
+}
+
+func TestImportModel_RejectsInvalidInput(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "broken.gguf"), []byte("not a gguf file"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if _, err := mgr.ImportModel(dir, "org/broken", ImportOptions{}); err == nil {
+		t.Fatalf("expected an error for an invalid GGUF file")
+	}
+	if _, err := mgr.ImportModel(filepath.Join(dir, "notes.txt"), "org/notes", ImportOptions{}); err == nil {
+		t.Fatalf("expected an error without model files")
+	}
+	if _, err := mgr.ImportModel(dir, "../escape", ImportOptions{}); err == nil {
+		t.Fatalf("expected an error for an invalid model ID")
+	}
+	if entries, _ := os.ReadDir(mgr.GetModelDir()); len(entries) != 0 {
+		t.Fatalf("failed imports left files behind: %v", entries)
+	}
+}
@@ -9,14 +9,25 @@ import (
 	"strings"
 )
 
-func (m *Manager) ResolveLocalModelDir(source ModelSource, modelID string) (string, error) {
-	modelDir := modelID
+// localDirName returns the directory name used for a model under the model
+// directory; repository IDs are flattened. Imported models share the
+// layout, so an unprefixed ID finds them too.
+func localDirName(source ModelSource, modelID string) string {
 	switch source {
-	case SourceHuggingFace, SourceModelScope:
-		modelDir = strings.ReplaceAll(modelID, "/", "_")
+	case SourceHuggingFace, SourceModelScope, SourceLocal:
+		return strings.ReplaceAll(modelID, "/", "_")
+	case SourceURL, SourceOCI:
+		// URLs and registry references also carry a scheme, port, tag or
+		// digest.
+		name := strings.TrimPrefix(strings.TrimPrefix(modelID, "https://"), "http://")
+		name, _, _ = strings.Cut(name, "#")
+		return strings.NewReplacer("/", "_", ":", "_", "@", "_", "?", "_").Replace(name)
 	}
+	return modelID
+}
 
-	modelPath := filepath.Join(m.modelDir, modelDir)
+func (m *Manager) ResolveLocalModelDir(source ModelSource, modelID string) (string, error) {
+	modelPath := filepath.Join(m.modelDir, localDirName(source, modelID))
 	if _, err := os.Stat(modelPath); err != nil {
 		if os.IsNotExist(err) {
 			return "", fmt.Errorf("model %s not found locally", modelID)
@@ -27,6 +38,30 @@ func (m *Manager) ResolveLocalModelDir(source ModelSource, modelID string) (stri
 	return modelPath, nil
 }
 
+// HasLocalModel reports whether a model is downloaded and, when fileHint is
+// set, whether a file whose name contains the hint is present.
+func (m *Manager) HasLocalModel(source ModelSource, modelID, fileHint string) bool {
+	modelDir, err := m.ResolveLocalModelDir(source, modelID)
+	if err != nil {
+		return false
+	}
+	hint := strings.ToLower(strings.TrimSpace(fileHint))
+	if hint == "" {
+		return true
+	}
+	found := false
+	_ = filepath.WalkDir(modelDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || found {
+			return nil
+		}
+		if !entry.IsDir() && !IsPartialFile(entry.Name()) && strings.Contains(strings.ToLower(entry.Name()), hint) {
+			found = true
+		}
+		return nil
+	})
+	return found
+}
+
 func FindGGUFFiles(modelPath string) ([]string, error) {
 	var files []string
 	err := filepath.WalkDir(modelPath, func(path string, d fs.DirEntry, err error) error {
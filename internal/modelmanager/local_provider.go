@@ -0,0 +1,61 @@
+package modelmanager
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// LocalProvider serves the models registered with ImportModel. They live
+// in the model directory like downloaded models but have nothing to fetch.
+type LocalProvider struct {
+	modelDir string
+}
+
+func NewLocalProvider(modelDir string) *LocalProvider {
+	return &LocalProvider{modelDir: modelDir}
+}
+
+func (p *LocalProvider) Name() ModelSource {
+	return SourceLocal
+}
+
+// Search matches the IDs and names of the imported models.
+func (p *LocalProvider) Search(ctx context.Context, query string, limit int) ([]ModelInfo, error) {
+	models, err := NewManager(p.modelDir).ListDownloadedModels()
+	if err != nil {
+		return nil, err
+	}
+	query = strings.ToLower(strings.TrimSpace(query))
+	var results []ModelInfo
+	for _, model := range models {
+		if model.Source != SourceLocal {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(model.ID), query) && !strings.Contains(strings.ToLower(model.Name), query) {
+			continue
+		}
+		results = append(results, model.ModelInfo)
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+func (p *LocalProvider) Download(ctx context.Context, modelID string, destPath string, progress func(downloaded, total int64), opts DownloadOptions) error {
+	return fmt.Errorf("local model %s cannot be downloaded; add it with `las model import`", modelID)
+}
+
+func (p *LocalProvider) Delete(ctx context.Context, modelID string) error {
+	return nil
+}
+
+func (p *LocalProvider) GetModelInfo(ctx context.Context, modelID string) (*ModelInfo, error) {
+	info, err := readLocalMetadata(filepath.Join(p.modelDir, localDirName(SourceLocal, modelID)))
+	if err != nil {
+		return nil, fmt.Errorf("local model %s not found: %w", modelID, err)
+	}
+	return &info, nil
+}
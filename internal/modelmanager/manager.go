@@ -3,6 +3,7 @@ package modelmanager
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -60,11 +61,18 @@ func (m *Manager) RemoveModel(source ModelSource, modelID string) error {
 		return fmt.Errorf("failed to delete model from %s: %w", source, err)
 	}
 
-	modelPath := filepath.Join(m.modelDir, modelID)
+	modelPath := filepath.Join(m.modelDir, localDirName(source, modelID))
 	if _, err := os.Stat(modelPath); !os.IsNotExist(err) {
+		var digests []string
+		for _, digest := range modelBlobs(modelPath) {
+			digests = append(digests, digest)
+		}
 		if err := os.RemoveAll(modelPath); err != nil {
 			return fmt.Errorf("failed to remove local metadata for %s: %w", modelID, err)
 		}
+		if err := m.releaseBlobs(digests); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -96,6 +104,11 @@ func (m *Manager) SearchAll(query string, limit int) (map[ModelSource][]ModelInf
 }
 
 func (m *Manager) DownloadModel(source ModelSource, modelID string, progress func(downloaded, total int64), opts DownloadOptions) error {
+	return m.DownloadModelWithContext(context.Background(), source, modelID, progress, opts)
+}
+
+// DownloadModelWithContext is DownloadModel with cancellation through ctx.
+func (m *Manager) DownloadModelWithContext(ctx context.Context, source ModelSource, modelID string, progress func(downloaded, total int64), opts DownloadOptions) error {
 	provider, err := m.GetProvider(source)
 	if err != nil {
 		return err
@@ -105,7 +118,107 @@ func (m *Manager) DownloadModel(source ModelSource, modelID string, progress fun
 		return err
 	}
 
-	return provider.Download(context.Background(), modelID, m.modelDir, progress, opts)
+	if !opts.IgnoreQuota {
+		// A download that cannot be sized is not held back.
+		if status, err := m.CheckQuota(ctx, source, modelID, opts); err == nil && status.Exceeded() {
+			return fmt.Errorf("%w: %s needs %s but %s of the %s quota is used", ErrQuotaExceeded, modelID,
+				FormatBytes(status.Required), FormatBytes(status.Used), FormatBytes(status.Limit))
+		}
+	}
+
+	if err := provider.Download(ctx, modelID, m.modelDir, progress, opts); err != nil {
+		return err
+	}
+	if modelDir, err := m.ResolveLocalModelDir(source, modelID); err == nil {
+		// Files that could not be deduplicated stay in place and are
+		// retried on the next download.
+		_ = m.storeBlobs(modelDir)
+		// The header metadata is a cache; models without it are read on demand.
+		_ = recordGGUFInfo(modelDir)
+	}
+	return nil
+}
+
+// ErrRepairNotRequired is returned by RepairModel for sources whose models
+// carry no separate tokenizer or config files.
+var ErrRepairNotRequired = errors.New("model does not require tokenizer/config repair")
+
+// RepairModel downloads missing tokenizer and config files for a local
+// model. Unless explicitSource is set, the source and ID recorded in the
+// model's metadata.json take precedence.
+func (m *Manager) RepairModel(ctx context.Context, source ModelSource, modelID string, explicitSource bool) error {
+	modelDir, err := m.ResolveLocalModelDir(source, modelID)
+	if err != nil {
+		return fmt.Errorf("local model not found: %w", err)
+	}
+
+	if !explicitSource {
+		if meta, err := readLocalMetadata(modelDir); err == nil && meta.ID != "" {
+			if meta.Source != "" {
+				source = meta.Source
+			}
+			modelID = meta.ID
+		}
+	}
+
+	switch source {
+	case SourceOllama:
+		return ErrRepairNotRequired
+	case SourceHuggingFace:
+		provider, err := m.GetProvider(source)
+		if err != nil {
+			return err
+		}
+		hf, ok := provider.(*HuggingFaceProvider)
+		if !ok {
+			return fmt.Errorf("huggingface provider not available")
+		}
+		return hf.DownloadSupportFiles(ctx, modelID, m.modelDir)
+	case SourceModelScope:
+		provider, err := m.GetProvider(source)
+		if err != nil {
+			return err
+		}
+		ms, ok := provider.(*ModelScopeProvider)
+		if !ok {
+			return fmt.Errorf("modelscope provider not available")
+		}
+		return ms.DownloadSupportFiles(ctx, modelID, m.modelDir)
+	default:
+		return fmt.Errorf("unsupported model source: %s", source)
+	}
+}
+
+func readLocalMetadata(modelDir string) (ModelInfo, error) {
+	raw, err := os.ReadFile(filepath.Join(modelDir, "metadata.json"))
+	if err != nil {
+		return ModelInfo{}, err
+	}
+	var info ModelInfo
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return ModelInfo{}, err
+	}
+	return info, nil
+}
+
+// updateMetadata rewrites a model's metadata.json through update, keeping
+// the fields it does not touch.
+func updateMetadata(modelDir string, update func(metadata map[string]any)) error {
+	path := filepath.Join(modelDir, "metadata.json")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	metadata := map[string]any{}
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return err
+	}
+	update(metadata)
+	encoded, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, encoded, 0o644)
 }
 
 func (m *Manager) GetModelInfo(source ModelSource, modelID string) (*ModelInfo, error) {
@@ -118,13 +231,22 @@ func (m *Manager) GetModelInfo(source ModelSource, modelID string) (*ModelInfo,
 }
 
 func (m *Manager) GetModelSize(modelID string) (int64, error) {
-	modelPath := filepath.Join(m.modelDir, modelID)
+	return PathSize(filepath.Join(m.modelDir, modelID))
+}
 
+// PathSize returns the total size of the regular files under path.
+func PathSize(modelPath string) (int64, error) {
 	var totalSize int64
 	err := filepath.Walk(modelPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			// Files linked from the blob store count at their full size.
+			if target, err := os.Stat(path); err == nil {
+				info = target
+			}
+		}
 		if !info.IsDir() {
 			totalSize += info.Size()
 		}
@@ -138,6 +260,19 @@ func (m *Manager) GetModelSize(modelID string) (int64, error) {
 	return totalSize, nil
 }
 
+// FormatParameters formats a parameter count the way model names do, e.g.
+// "7.24B" or "135.0M".
+func FormatParameters(count uint64) string {
+	switch {
+	case count >= 1e9:
+		return fmt.Sprintf("%.2fB", float64(count)/1e9)
+	case count >= 1e6:
+		return fmt.Sprintf("%.1fM", float64(count)/1e6)
+	default:
+		return fmt.Sprintf("%d", count)
+	}
+}
+
 func FormatBytes(bytes int64) string {
 	const (
 		KB = 1024
@@ -160,6 +295,40 @@ func FormatBytes(bytes int64) string {
 	}
 }
 
+// ParseSource maps a source name, including the "hf" alias, to a
+// ModelSource.
+func ParseSource(name string) (ModelSource, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "ollama":
+		return SourceOllama, nil
+	case "huggingface", "hf":
+		return SourceHuggingFace, nil
+	case "modelscope":
+		return SourceModelScope, nil
+	case "local":
+		return SourceLocal, nil
+	case "url":
+		return SourceURL, nil
+	case "oci":
+		return SourceOCI, nil
+	default:
+		return "", fmt.Errorf("unknown source: %s", name)
+	}
+}
+
+// HasSourcePrefix reports whether input names its source explicitly, as in
+// "hf:org/model".
+func HasSourcePrefix(input string) bool {
+	inputLower := strings.ToLower(strings.TrimSpace(input))
+	return strings.HasPrefix(inputLower, "ollama:") ||
+		strings.HasPrefix(inputLower, "huggingface:") ||
+		strings.HasPrefix(inputLower, "hf:") ||
+		strings.HasPrefix(inputLower, "modelscope:") ||
+		strings.HasPrefix(inputLower, "local:") ||
+		strings.HasPrefix(inputLower, "url:") ||
+		strings.HasPrefix(inputLower, "oci:")
+}
+
 func ParseModelID(input string) (ModelSource, string, error) {
 	inputLower := strings.ToLower(input)
 
@@ -175,6 +344,15 @@ func ParseModelID(input string) (ModelSource, string, error) {
 	if strings.HasPrefix(inputLower, "modelscope:") {
 		return SourceModelScope, input[11:], nil
 	}
+	if strings.HasPrefix(inputLower, "local:") {
+		return SourceLocal, input[6:], nil
+	}
+	if strings.HasPrefix(inputLower, "url:") {
+		return SourceURL, input[4:], nil
+	}
+	if strings.HasPrefix(inputLower, "oci:") {
+		return SourceOCI, input[4:], nil
+	}
 
 	if strings.Contains(input, ":") && !strings.Contains(input, "/") {
 		return SourceOllama, input, nil
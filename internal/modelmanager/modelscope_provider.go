@@ -188,6 +188,23 @@ func (p *ModelScopeProvider) Download(ctx context.Context, modelID string, destP
 	return nil
 }
 
+// DownloadSize returns the bytes Download would fetch for modelID.
+func (p *ModelScopeProvider) DownloadSize(ctx context.Context, modelID string, opts DownloadOptions) (int64, error) {
+	files, err := p.listModelFiles(ctx, modelID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list model files: %w", err)
+	}
+	candidates, err := filterModelScopeFiles(files, opts.FileHint)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, file := range candidates {
+		size += file.Size
+	}
+	return size, nil
+}
+
 func filterModelScopeFiles(files []ModelScopeFile, hint string) ([]ModelScopeFile, error) {
 	allowed := make([]ModelScopeFile, 0, len(files))
 	required := make([]ModelScopeFile, 0)
@@ -332,55 +349,20 @@ func (p *ModelScopeProvider) DownloadSupportFiles(ctx context.Context, modelID s
 }
 
 func (p *ModelScopeProvider) downloadFile(ctx context.Context, url, destPath string, totalSize int64, progress func(downloaded, total int64)) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("User-Agent", "LocalAIStack/1.0")
-	if p.token != "" {
-		req.Header.Set("Authorization", "Bearer "+p.token)
-	}
-
-	resp, err := p.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d", resp.StatusCode)
-	}
-
-	file, err := os.Create(destPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	var downloaded int64
-	buf := make([]byte, chunkSize)
-
-	for {
-		n, err := resp.Body.Read(buf)
-		if n > 0 {
-			if _, werr := file.Write(buf[:n]); werr != nil {
-				return werr
-			}
-			downloaded += int64(n)
-			if progress != nil {
-				progress(downloaded, totalSize)
-			}
+	return fetchFile(ctx, destPath, totalSize, "", progress, func(header http.Header) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
 		}
-		if err == io.EOF {
-			break
+		req.Header.Set("User-Agent", "LocalAIStack/1.0")
+		if p.token != "" {
+			req.Header.Set("Authorization", "Bearer "+p.token)
 		}
-		if err != nil {
-			return err
+		for name, values := range header {
+			req.Header[name] = values
 		}
-	}
-
-	return nil
+		return p.client.Do(req)
+	})
 }
 
 func (p *ModelScopeProvider) GetModelInfo(ctx context.Context, modelID string) (*ModelInfo, error) {
@@ -438,6 +420,8 @@ func (p *ModelScopeProvider) GetModelInfo(ctx context.Context, modelID string) (
 	}, nil
 }
 
+// Delete is a no-op: ModelScope models only exist as local files, which the
+// Manager removes.
 func (p *ModelScopeProvider) Delete(ctx context.Context, modelID string) error {
-	return fmt.Errorf("ModelScope models cannot be deleted via API")
+	return nil
 }
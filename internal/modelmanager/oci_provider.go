@@ -0,0 +1,417 @@
+package modelmanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	ociManifestMediaType        = "application/vnd.oci.image.manifest.v1+json"
+	ociIndexMediaType           = "application/vnd.oci.image.index.v1+json"
+	dockerManifestMediaType     = "application/vnd.docker.distribution.manifest.v2+json"
+	dockerManifestListMediaType = "application/vnd.docker.distribution.manifest.list.v2+json"
+	ociTitleAnnotation          = "org.opencontainers.image.title"
+	ociDescriptionAnnotation    = "org.opencontainers.image.description"
+	ociMaxManifestSize          = 4 << 20
+	ociManifestAcceptMediaType  = ociManifestMediaType + ", " + ociIndexMediaType + ", " + dockerManifestMediaType + ", " + dockerManifestListMediaType
+)
+
+// OCIProvider downloads models stored as ORAS-style artifacts in an OCI
+// registry: each layer is a file named by its title annotation. Model IDs
+// are references such as registry.example.com/models/llama:3.1-8b, or IDs
+// whose index entry names one.
+type OCIProvider struct {
+	client *artifactClient
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+func NewOCIProvider(opts ArtifactOptions) *OCIProvider {
+	return &OCIProvider{client: newArtifactClient(opts), tokens: map[string]string{}}
+}
+
+func (p *OCIProvider) Name() ModelSource {
+	return SourceOCI
+}
+
+func (p *OCIProvider) Search(ctx context.Context, query string, limit int) ([]ModelInfo, error) {
+	return p.client.search(ctx, SourceOCI, query, limit, func(entry artifactEntry) bool {
+		return entry.OCI != ""
+	})
+}
+
+// ociReference is a parsed registry/repository[:tag|@digest] reference.
+type ociReference struct {
+	Registry   string
+	Repository string
+	// Reference is a tag or a digest.
+	Reference string
+}
+
+func (r ociReference) String() string {
+	if strings.HasPrefix(r.Reference, "sha256:") {
+		return r.Registry + "/" + r.Repository + "@" + r.Reference
+	}
+	return r.Registry + "/" + r.Repository + ":" + r.Reference
+}
+
+// parseOCIReference parses a reference that starts with a registry host;
+// the tag defaults to "latest".
+func parseOCIReference(input string) (ociReference, error) {
+	registry, rest, ok := strings.Cut(input, "/")
+	if !ok || rest == "" || !isRegistryHost(registry) {
+		return ociReference{}, fmt.Errorf("OCI reference %s must start with a registry host", input)
+	}
+	ref := ociReference{Registry: registry, Reference: "latest"}
+	if repository, digest, ok := strings.Cut(rest, "@"); ok {
+		ref.Repository, ref.Reference = repository, digest
+		if _, hexDigest, ok := strings.Cut(digest, ":"); !ok || !strings.HasPrefix(digest, "sha256:") || !isBlobDigest(hexDigest) {
+			return ociReference{}, fmt.Errorf("unsupported digest in OCI reference %s", input)
+		}
+	} else if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		ref.Repository, ref.Reference = rest[:i], rest[i+1:]
+	} else {
+		ref.Repository = rest
+	}
+	if ref.Repository == "" || ref.Reference == "" || ref.Repository != strings.ToLower(ref.Repository) {
+		return ociReference{}, fmt.Errorf("invalid OCI reference %s", input)
+	}
+	return ref, nil
+}
+
+// isRegistryHost reports whether the first element of a reference is a
+// registry host rather than a repository namespace.
+func isRegistryHost(name string) bool {
+	return strings.ContainsAny(name, ".:") || name == "localhost"
+}
+
+// ociDescriptor and ociManifest hold the parts of an image manifest or
+// index the provider uses.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	MediaType   string            `json:"mediaType"`
+	Layers      []ociDescriptor   `json:"layers"`
+	Manifests   []ociDescriptor   `json:"manifests"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Digest is the digest of the manifest itself.
+	Digest string `json:"-"`
+}
+
+// files returns the layers that carry a file name, skipping config and
+// other unnamed layers as ORAS does.
+func (m *ociManifest) files() []ociDescriptor {
+	var files []ociDescriptor
+	for _, layer := range m.Layers {
+		if layer.Annotations[ociTitleAnnotation] != "" {
+			files = append(files, layer)
+		}
+	}
+	return files
+}
+
+// resolve returns the reference a model ID names.
+func (p *OCIProvider) resolve(ctx context.Context, modelID string) (ociReference, error) {
+	if host, _, _ := strings.Cut(modelID, "/"); isRegistryHost(host) {
+		return parseOCIReference(modelID)
+	}
+	entry, err := p.client.lookup(ctx, modelID)
+	if err != nil {
+		return ociReference{}, err
+	}
+	if entry.OCI == "" {
+		return ociReference{}, fmt.Errorf("model %s has no OCI reference; try url:%s", modelID, modelID)
+	}
+	return parseOCIReference(entry.OCI)
+}
+
+// manifest fetches the manifest for ref. For an image index, the first
+// manifest is used.
+func (p *OCIProvider) manifest(ctx context.Context, ref ociReference) (*ociManifest, error) {
+	for depth := 0; depth < 2; depth++ {
+		resp, err := p.registryGet(ctx, ref, "manifests/"+ref.Reference, http.Header{"Accept": {ociManifestAcceptMediaType}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch manifest for %s: %w", ref, err)
+		}
+		raw, err := io.ReadAll(io.LimitReader(resp.Body, ociMaxManifestSize))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(raw)
+		digest := "sha256:" + hex.EncodeToString(sum[:])
+		if strings.HasPrefix(ref.Reference, "sha256:") && ref.Reference != digest {
+			return nil, fmt.Errorf("manifest digest mismatch for %s: got %s", ref, digest)
+		}
+		var manifest ociManifest
+		if err := json.Unmarshal(raw, &manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest for %s: %w", ref, err)
+		}
+		if manifest.MediaType == "" {
+			manifest.MediaType = resp.Header.Get("Content-Type")
+		}
+		if len(manifest.Manifests) == 0 {
+			manifest.Digest = digest
+			return &manifest, nil
+		}
+		ref.Reference = manifest.Manifests[0].Digest
+	}
+	return nil, fmt.Errorf("nested image index in %s is not supported", ref)
+}
+
+func (p *OCIProvider) Download(ctx context.Context, modelID string, destPath string, progress func(downloaded, total int64), opts DownloadOptions) error {
+	ref, err := p.resolve(ctx, modelID)
+	if err != nil {
+		return err
+	}
+	manifest, err := p.manifest(ctx, ref)
+	if err != nil {
+		return err
+	}
+	layers := ociFiles(manifest, opts.FileHint)
+	if len(layers) == 0 {
+		return fmt.Errorf("no files found in %s", ref)
+	}
+	if opts.FileHint != "" && detectFilesFormat(ociLayerNames(layers)) == FormatUnknown {
+		return fmt.Errorf("no files matching %q found in %s", opts.FileHint, ref)
+	}
+
+	modelDir := filepath.Join(destPath, localDirName(SourceOCI, modelID))
+	for _, layer := range layers {
+		name := layer.Annotations[ociTitleAnnotation]
+		destFile, err := artifactFilePath(modelDir, name)
+		if err != nil {
+			return err
+		}
+		digest, ok := strings.CutPrefix(layer.Digest, "sha256:")
+		if !ok || !isBlobDigest(digest) {
+			return fmt.Errorf("unsupported digest %s for %s", layer.Digest, name)
+		}
+		err = fetchFile(ctx, destFile, layer.Size, digest, progress, func(header http.Header) (*http.Response, error) {
+			return p.registryGet(ctx, ref, "blobs/"+layer.Digest, header)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to download file %s: %w", name, err)
+		}
+	}
+	return writeArtifactMetadata(modelDir, modelID, SourceOCI, detectFilesFormat(ociLayerNames(layers)), map[string]string{
+		"oci":             ref.String(),
+		"manifest_digest": manifest.Digest,
+	})
+}
+
+// ociFiles returns the named layers kept for a download with hint.
+func ociFiles(manifest *ociManifest, hint string) []ociDescriptor {
+	var layers []ociDescriptor
+	for _, layer := range manifest.files() {
+		if matchesFileHint(layer.Annotations[ociTitleAnnotation], hint) {
+			layers = append(layers, layer)
+		}
+	}
+	return layers
+}
+
+func ociLayerNames(layers []ociDescriptor) []string {
+	names := make([]string, 0, len(layers))
+	for _, layer := range layers {
+		names = append(names, layer.Annotations[ociTitleAnnotation])
+	}
+	return names
+}
+
+// DownloadSize sums the sizes of the layers a download fetches.
+func (p *OCIProvider) DownloadSize(ctx context.Context, modelID string, opts DownloadOptions) (int64, error) {
+	ref, err := p.resolve(ctx, modelID)
+	if err != nil {
+		return 0, err
+	}
+	manifest, err := p.manifest(ctx, ref)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, layer := range ociFiles(manifest, opts.FileHint) {
+		total += layer.Size
+	}
+	return total, nil
+}
+
+func (p *OCIProvider) Delete(ctx context.Context, modelID string) error {
+	return nil
+}
+
+func (p *OCIProvider) GetModelInfo(ctx context.Context, modelID string) (*ModelInfo, error) {
+	ref, err := p.resolve(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := p.manifest(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	info := &ModelInfo{
+		ID:          modelID,
+		Name:        ref.Repository,
+		Description: manifest.Annotations[ociDescriptionAnnotation],
+		Source:      SourceOCI,
+		Metadata:    map[string]string{"oci": ref.String(), "manifest_digest": manifest.Digest},
+	}
+	for _, layer := range manifest.files() {
+		info.Size += layer.Size
+	}
+	info.Format = detectFilesFormat(ociLayerNames(manifest.files()))
+	return info, nil
+}
+
+// registryGet sends a GET with the extra headers to the registry API for
+// ref's repository. On a bearer challenge it obtains a token, with the
+// configured credentials if any, and retries once. Responses to a Range
+// header are returned as they are.
+func (p *OCIProvider) registryGet(ctx context.Context, ref ociReference, path string, header http.Header) (*http.Response, error) {
+	auth := p.client.auth(ref.Registry)
+	scheme := "https"
+	if auth.PlainHTTP {
+		scheme = "http"
+	}
+	endpoint := fmt.Sprintf("%s://%s/v2/%s/%s", scheme, ref.Registry, ref.Repository, path)
+	client := p.client.api
+	if strings.HasPrefix(path, "blobs/") {
+		client = p.client.download
+	}
+	tokenKey := ref.Registry + "/" + ref.Repository
+
+	for attempt := 0; attempt < 2; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		p.client.authorize(req)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		p.mu.Lock()
+		token := p.tokens[tokenKey]
+		p.mu.Unlock()
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK || (header.Get("Range") != "" && (resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable)) {
+			return resp, nil
+		}
+		resp.Body.Close()
+		challenge := resp.Header.Get("WWW-Authenticate")
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 || !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+			return nil, fmt.Errorf("GET %s: HTTP %d", endpoint, resp.StatusCode)
+		}
+		token, err = p.fetchToken(ctx, challenge, ref, auth)
+		if err != nil {
+			return nil, err
+		}
+		p.mu.Lock()
+		p.tokens[tokenKey] = token
+		p.mu.Unlock()
+	}
+	return nil, fmt.Errorf("GET %s: unauthorized", endpoint)
+}
+
+// fetchToken answers a registry bearer challenge.
+func (p *OCIProvider) fetchToken(ctx context.Context, challenge string, ref ociReference, auth ArtifactAuth) (string, error) {
+	params := parseAuthChallenge(challenge[len("bearer "):])
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry %s sent a challenge without a realm", ref.Registry)
+	}
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm %s: %w", realm, err)
+	}
+	query := tokenURL.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + ref.Repository + ":pull"
+	}
+	query.Set("scope", scope)
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case auth.Username != "":
+		req.SetBasicAuth(auth.Username, auth.Password)
+	case auth.Token != "":
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	}
+	resp, err := p.client.api.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch registry token: HTTP %d", resp.StatusCode)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse registry token: %w", err)
+	}
+	if body.Token == "" {
+		body.Token = body.AccessToken
+	}
+	if body.Token == "" {
+		return "", fmt.Errorf("registry %s returned an empty token", ref.Registry)
+	}
+	return body.Token, nil
+}
+
+// parseAuthChallenge parses the key="value" pairs of a WWW-Authenticate
+// challenge.
+func parseAuthChallenge(params string) map[string]string {
+	values := map[string]string{}
+	for params != "" {
+		key, rest, ok := strings.Cut(params, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(strings.TrimLeft(key, ", ")))
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				values[key] = rest[1:]
+				break
+			}
+			value, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		values[key] = value
+		params = strings.TrimLeft(rest, ", ")
+	}
+	return values
+}
@@ -0,0 +1,565 @@
+package modelmanager
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	queueFileName        = ".queue.json"
+	defaultMaxConcurrent = 3
+	defaultPerHost       = 2
+)
+
+// QueueState is the state of a queued download.
+type QueueState string
+
+const (
+	QueueQueued   QueueState = "queued"
+	QueueRunning  QueueState = "running"
+	QueuePaused   QueueState = "paused"
+	QueueDone     QueueState = "done"
+	QueueFailed   QueueState = "failed"
+	QueueCanceled QueueState = "canceled"
+)
+
+var (
+	ErrQueueItemNotFound = errors.New("queued download not found")
+	ErrQueueItemFinished = errors.New("queued download already finished")
+)
+
+// QueueItem is a model download in the queue.
+type QueueItem struct {
+	ID          string      `json:"id"`
+	Source      ModelSource `json:"source"`
+	ModelID     string      `json:"model_id"`
+	FileHint    string      `json:"file_hint,omitempty"`
+	IgnoreQuota bool        `json:"ignore_quota,omitempty"`
+	// Priority orders queued downloads, highest first; downloads of equal
+	// priority start in the order they were added.
+	Priority int        `json:"priority"`
+	State    QueueState `json:"state"`
+	// Host groups downloads for the per-host concurrency limit.
+	Host       string    `json:"host"`
+	Downloaded int64     `json:"downloaded"`
+	Total      int64     `json:"total"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Done reports whether the download reached a final state.
+func (i QueueItem) Done() bool {
+	return i.State == QueueDone || i.State == QueueFailed || i.State == QueueCanceled
+}
+
+// Ref returns the model reference, e.g. "huggingface:Qwen/Qwen2.5-7B".
+func (i QueueItem) Ref() string {
+	return string(i.Source) + ":" + i.ModelID
+}
+
+type QueueOptions struct {
+	// MaxConcurrent caps the downloads running at once; it defaults to 3.
+	MaxConcurrent int
+	// PerHost caps the downloads running at once from one host; it
+	// defaults to 2.
+	PerHost int
+	// BandwidthLimit caps the combined transfer rate in bytes per second;
+	// zero means unlimited.
+	BandwidthLimit int64
+}
+
+// DownloadQueue runs model downloads in the background in priority order,
+// within per-host and global concurrency limits and a shared bandwidth
+// cap. The queue is saved in the model directory, so it survives restarts;
+// downloads interrupted by a restart resume their partial files.
+type DownloadQueue struct {
+	mgr     *Manager
+	path    string
+	opts    QueueOptions
+	limiter *rateLimiter
+
+	mu    sync.Mutex
+	items []*QueueItem
+	// running holds the cancel function of each running download, and
+	// intents the state a canceled download moves to.
+	running map[string]context.CancelFunc
+	intents map[string]QueueState
+	// changed is closed and replaced on every change.
+	changed chan struct{}
+	wake    chan struct{}
+}
+
+// NewDownloadQueue loads the queue saved in the manager's model directory.
+func NewDownloadQueue(mgr *Manager, opts QueueOptions) (*DownloadQueue, error) {
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = defaultMaxConcurrent
+	}
+	if opts.PerHost <= 0 {
+		opts.PerHost = defaultPerHost
+	}
+	q := &DownloadQueue{
+		mgr:     mgr,
+		path:    filepath.Join(mgr.modelDir, queueFileName),
+		opts:    opts,
+		limiter: newRateLimiter(opts.BandwidthLimit),
+		running: map[string]context.CancelFunc{},
+		intents: map[string]QueueState{},
+		changed: make(chan struct{}),
+		wake:    make(chan struct{}, 1),
+	}
+	data, err := os.ReadFile(q.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &q.items); err != nil {
+			return nil, fmt.Errorf("failed to parse download queue %s: %w", q.path, err)
+		}
+	}
+	for _, item := range q.items {
+		if item.State == QueueRunning {
+			item.State = QueueQueued
+		}
+	}
+	return q, nil
+}
+
+// Add queues a download and returns it. A model with an unfinished
+// download in the queue is not queued twice.
+func (q *DownloadQueue) Add(source ModelSource, modelID string, opts DownloadOptions, priority int) (QueueItem, error) {
+	provider, err := q.mgr.GetProvider(source)
+	if err != nil {
+		return QueueItem{}, err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, item := range q.items {
+		if item.Source == source && item.ModelID == modelID && item.FileHint == opts.FileHint && !item.Done() {
+			return QueueItem{}, fmt.Errorf("%s is already queued as %s", item.Ref(), item.ID)
+		}
+	}
+	now := time.Now().UTC()
+	item := &QueueItem{
+		ID:          newQueueID(),
+		Source:      source,
+		ModelID:     modelID,
+		FileHint:    opts.FileHint,
+		IgnoreQuota: opts.IgnoreQuota,
+		Priority:    priority,
+		State:       QueueQueued,
+		Host:        downloadHost(provider, source, modelID),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	q.items = append(q.items, item)
+	if err := q.changedLocked(true); err != nil {
+		q.items = q.items[:len(q.items)-1]
+		return QueueItem{}, err
+	}
+	return *item, nil
+}
+
+// List returns the downloads in schedule order: unfinished downloads by
+// priority, then finished ones, most recent first.
+func (q *DownloadQueue) List() []QueueItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items := make([]QueueItem, 0, len(q.items))
+	for _, item := range q.sortedLocked() {
+		items = append(items, *item)
+	}
+	return items
+}
+
+// Get returns the download with the given ID.
+func (q *DownloadQueue) Get(id string) (QueueItem, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item := q.findLocked(id)
+	if item == nil {
+		return QueueItem{}, ErrQueueItemNotFound
+	}
+	return *item, nil
+}
+
+// Pause stops a queued or running download until it is resumed. A resumed
+// download continues from the partial files it left.
+func (q *DownloadQueue) Pause(id string) (QueueItem, error) {
+	return q.stop(id, QueuePaused)
+}
+
+// Cancel stops a download for good.
+func (q *DownloadQueue) Cancel(id string) (QueueItem, error) {
+	return q.stop(id, QueueCanceled)
+}
+
+func (q *DownloadQueue) stop(id string, state QueueState) (QueueItem, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item := q.findLocked(id)
+	if item == nil {
+		return QueueItem{}, ErrQueueItemNotFound
+	}
+	if item.Done() {
+		return *item, ErrQueueItemFinished
+	}
+	if cancel, ok := q.running[id]; ok {
+		// The download's goroutine records the state once it returns; the
+		// caller sees the state it is moving to.
+		q.intents[id] = state
+		cancel()
+		stopped := *item
+		stopped.State = state
+		return stopped, nil
+	}
+	item.State = state
+	item.UpdatedAt = time.Now().UTC()
+	return *item, q.changedLocked(true)
+}
+
+// Resume queues a paused, failed or canceled download again.
+func (q *DownloadQueue) Resume(id string) (QueueItem, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item := q.findLocked(id)
+	if item == nil {
+		return QueueItem{}, ErrQueueItemNotFound
+	}
+	switch item.State {
+	case QueueQueued, QueueRunning:
+		return *item, nil
+	case QueueDone:
+		return *item, ErrQueueItemFinished
+	}
+	item.State = QueueQueued
+	item.Error = ""
+	item.UpdatedAt = time.Now().UTC()
+	return *item, q.changedLocked(true)
+}
+
+// SetPriority changes the priority of a download; it takes effect the
+// next time a download slot frees up.
+func (q *DownloadQueue) SetPriority(id string, priority int) (QueueItem, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item := q.findLocked(id)
+	if item == nil {
+		return QueueItem{}, ErrQueueItemNotFound
+	}
+	item.Priority = priority
+	item.UpdatedAt = time.Now().UTC()
+	return *item, q.changedLocked(true)
+}
+
+// Clear removes finished downloads from the queue and returns how many
+// were removed.
+func (q *DownloadQueue) Clear() (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	kept := q.items[:0]
+	for _, item := range q.items {
+		if !item.Done() {
+			kept = append(kept, item)
+		}
+	}
+	removed := len(q.items) - len(kept)
+	q.items = kept
+	return removed, q.changedLocked(true)
+}
+
+// Wait blocks until the download finishes or ctx is done, calling fn with
+// a snapshot whenever the download changes.
+func (q *DownloadQueue) Wait(ctx context.Context, id string, fn func(QueueItem)) (QueueItem, error) {
+	for {
+		q.mu.Lock()
+		item := q.findLocked(id)
+		changed := q.changed
+		var snapshot QueueItem
+		if item != nil {
+			snapshot = *item
+		}
+		q.mu.Unlock()
+		if item == nil {
+			return QueueItem{}, ErrQueueItemNotFound
+		}
+		if fn != nil {
+			fn(snapshot)
+		}
+		if snapshot.Done() {
+			return snapshot, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return snapshot, ctx.Err()
+		}
+	}
+}
+
+// Run starts queued downloads as slots free up until ctx is done. Running
+// downloads are then stopped and stay queued for the next run.
+func (q *DownloadQueue) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for {
+		q.mu.Lock()
+		for _, item := range q.nextLocked() {
+			downloadCtx, cancel := context.WithCancel(ctx)
+			q.running[item.ID] = cancel
+			item.State = QueueRunning
+			item.Error = ""
+			item.UpdatedAt = time.Now().UTC()
+			_ = q.changedLocked(true)
+			wg.Add(1)
+			go func(item QueueItem) {
+				defer wg.Done()
+				err := q.download(downloadCtx, item)
+				q.finish(ctx, item.ID, err)
+			}(*item)
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case <-q.wake:
+		}
+	}
+}
+
+// nextLocked picks the queued downloads to start, in schedule order,
+// within the concurrency limits.
+func (q *DownloadQueue) nextLocked() []*QueueItem {
+	total := len(q.running)
+	perHost := map[string]int{}
+	for _, item := range q.items {
+		if item.State == QueueRunning {
+			perHost[item.Host]++
+		}
+	}
+	var next []*QueueItem
+	for _, item := range q.sortedLocked() {
+		if total >= q.opts.MaxConcurrent {
+			break
+		}
+		if item.State != QueueQueued || perHost[item.Host] >= q.opts.PerHost {
+			continue
+		}
+		next = append(next, item)
+		total++
+		perHost[item.Host]++
+	}
+	return next
+}
+
+func (q *DownloadQueue) download(ctx context.Context, item QueueItem) error {
+	opts := DownloadOptions{FileHint: item.FileHint, IgnoreQuota: item.IgnoreQuota}
+	if provider, err := q.mgr.GetProvider(item.Source); err == nil {
+		if sizer, ok := provider.(downloadSizer); ok {
+			if size, err := sizer.DownloadSize(ctx, item.ModelID, opts); err == nil {
+				q.update(item.ID, func(current *QueueItem) { current.Total = size })
+			}
+		}
+	}
+
+	// Providers report the bytes of each file as they arrive, including
+	// those resumed from a partial file, and start every file at 0. The
+	// bandwidth limit is applied to the transfers themselves through ctx.
+	var last, transferred int64
+	progress := func(downloaded, total int64) {
+		if downloaded < last {
+			// A new file started.
+			last = 0
+		}
+		transferred += downloaded - last
+		last = downloaded
+		q.update(item.ID, func(current *QueueItem) {
+			current.Downloaded = transferred
+			if current.Total < transferred {
+				current.Total = max(total, transferred)
+			}
+		})
+	}
+	return q.mgr.DownloadModelWithContext(withRateLimit(ctx, q.limiter), item.Source, item.ModelID, progress, opts)
+}
+
+// finish records the outcome of a download.
+func (q *DownloadQueue) finish(runCtx context.Context, id string, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.running[id]()
+	delete(q.running, id)
+	intent, stopped := q.intents[id]
+	delete(q.intents, id)
+
+	item := q.findLocked(id)
+	if item == nil {
+		return
+	}
+	switch {
+	case err == nil:
+		item.State = QueueDone
+	case stopped:
+		item.State = intent
+	case runCtx.Err() != nil:
+		item.State = QueueQueued
+	default:
+		item.State = QueueFailed
+		item.Error = err.Error()
+	}
+	item.UpdatedAt = time.Now().UTC()
+	_ = q.changedLocked(true)
+}
+
+// update applies fn to a download's progress without saving the queue.
+func (q *DownloadQueue) update(id string, fn func(item *QueueItem)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if item := q.findLocked(id); item != nil {
+		fn(item)
+		_ = q.changedLocked(false)
+	}
+}
+
+// changedLocked wakes waiters and the scheduler, saving the queue when
+// save is set.
+func (q *DownloadQueue) changedLocked(save bool) error {
+	close(q.changed)
+	q.changed = make(chan struct{})
+	if !save {
+		return nil
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return q.saveLocked()
+}
+
+func (q *DownloadQueue) saveLocked() error {
+	data, err := json.MarshalIndent(q.items, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(q.path), 0o755); err != nil {
+		return err
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path)
+}
+
+func (q *DownloadQueue) findLocked(id string) *QueueItem {
+	for _, item := range q.items {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
+
+func (q *DownloadQueue) sortedLocked() []*QueueItem {
+	items := append([]*QueueItem(nil), q.items...)
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Done() != b.Done() {
+			return !a.Done()
+		}
+		if a.Done() {
+			return a.UpdatedAt.After(b.UpdatedAt)
+		}
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	return items
+}
+
+// downloadHost returns the host a download is fetched from: the server or
+// registry of url: and oci: models, and the source for the others.
+func downloadHost(provider Provider, source ModelSource, modelID string) string {
+	switch source {
+	case SourceURL:
+		rawURL := modelID
+		if p, ok := provider.(*HTTPProvider); ok && !isHTTPURL(modelID) {
+			rawURL = p.client.opts.IndexURL
+		}
+		if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+			return u.Host
+		}
+	case SourceOCI:
+		if ref, err := parseOCIReference(modelID); err == nil {
+			return ref.Registry
+		}
+	}
+	return string(source)
+}
+
+func newQueueID() string {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// rateLimiter is a token bucket holding up to one second of traffic. A
+// nil limiter does not limit.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	rate := float64(bytesPerSecond)
+	return &rateLimiter{rate: rate, tokens: rate, last: time.Now()}
+}
+
+// wait takes n bytes from the bucket, sleeping until they are covered.
+// Tokens are reserved before sleeping, so concurrent callers share the
+// rate fairly.
+func (l *rateLimiter) wait(ctx context.Context, n int64) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.rate, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
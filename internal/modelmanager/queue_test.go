@@ -0,0 +1,262 @@
+package modelmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// queueTestProvider blocks each download until the test releases it.
+type queueTestProvider struct {
+	source ModelSource
+	mu     sync.Mutex
+	gates  map[string]chan error
+}
+
+func (p *queueTestProvider) gate(modelID string) chan error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.gates[modelID] == nil {
+		p.gates[modelID] = make(chan error, 1)
+	}
+	return p.gates[modelID]
+}
+
+func (p *queueTestProvider) Name() ModelSource { return p.source }
+
+func (p *queueTestProvider) Search(context.Context, string, int) ([]ModelInfo, error) {
+	return nil, nil
+}
+
+func (p *queueTestProvider) Download(ctx context.Context, modelID string, destPath string, progress func(downloaded, total int64), _ DownloadOptions) error {
+	progress(10, 20)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-p.gate(modelID):
+		if err != nil {
+			return err
+		}
+	}
+	progress(20, 20)
+	modelDir := filepath.Join(destPath, localDirName(p.source, modelID))
+	if err := os.MkdirAll(modelDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(modelDir, "metadata.json"), []byte(`{"id":"`+modelID+`"}`), 0o644)
+}
+
+func (p *queueTestProvider) Delete(context.Context, string) error { return nil }
+
+func (p *queueTestProvider) GetModelInfo(context.Context, string) (*ModelInfo, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func newQueueTestManager(t *testing.T) (*Manager, map[ModelSource]*queueTestProvider) {
+	t.Helper()
+	mgr := NewManager(t.TempDir())
+	providers := map[ModelSource]*queueTestProvider{}
+	for _, source := range []ModelSource{SourceHuggingFace, SourceModelScope} {
+		providers[source] = &queueTestProvider{source: source, gates: map[string]chan error{}}
+		if err := mgr.RegisterProvider(providers[source]); err != nil {
+			t.Fatalf("RegisterProvider: %v", err)
+		}
+	}
+	return mgr, providers
+}
+
+// waitState waits until the download reaches state.
+func waitState(t *testing.T, q *DownloadQueue, id string, state QueueState) QueueItem {
+	t.Helper()
+	return waitFor(t, q, id, string(state), func(item QueueItem) bool { return item.State == state })
+}
+
+func waitFor(t *testing.T, q *DownloadQueue, id, want string, match func(QueueItem) bool) QueueItem {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		item, err := q.Get(id)
+		if err != nil {
+			t.Fatalf("Get(%s): %v", id, err)
+		}
+		if match(item) {
+			return item
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("download %s is %+v, want %s", id, item, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDownloadQueue_SchedulesByPriorityWithinHostLimit(t *testing.T) {
+	mgr, providers := newQueueTestManager(t)
+	q, err := NewDownloadQueue(mgr, QueueOptions{MaxConcurrent: 2, PerHost: 1})
+	if err != nil {
+		t.Fatalf("NewDownloadQueue: %v", err)
+	}
+	low, _ := q.Add(SourceHuggingFace, "org/low", DownloadOptions{}, 0)
+	high, _ := q.Add(SourceHuggingFace, "org/high", DownloadOptions{}, 5)
+	other, _ := q.Add(SourceModelScope, "org/other", DownloadOptions{}, 0)
+	if _, err := q.Add(SourceHuggingFace, "org/low", DownloadOptions{}, 0); err == nil {
+		t.Fatalf("expected an error when queueing a model twice")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- q.Run(ctx) }()
+
+	running := waitFor(t, q, high.ID, "running with progress", func(item QueueItem) bool {
+		return item.State == QueueRunning && item.Downloaded == 10
+	})
+	waitState(t, q, other.ID, QueueRunning)
+	if item, _ := q.Get(low.ID); item.State != QueueQueued {
+		t.Fatalf("the per-host limit should hold back %s, got %s", low.ModelID, item.State)
+	}
+	if running.Host != string(SourceHuggingFace) || running.Total != 20 {
+		t.Fatalf("unexpected running download %+v", running)
+	}
+
+	providers[SourceHuggingFace].gate("org/high") <- nil
+	if item := waitState(t, q, high.ID, QueueDone); item.Downloaded != 20 {
+		t.Fatalf("unexpected finished download %+v", item)
+	}
+	if !mgr.HasLocalModel(SourceHuggingFace, "org/high", "") {
+		t.Fatalf("the finished download is missing")
+	}
+	waitState(t, q, low.ID, QueueRunning)
+	providers[SourceHuggingFace].gate("org/low") <- fmt.Errorf("connection reset")
+	if item := waitState(t, q, low.ID, QueueFailed); item.Error != "connection reset" {
+		t.Fatalf("unexpected failed download %+v", item)
+	}
+
+	// Stopping the queue leaves the running download queued for the next run.
+	cancel()
+	<-done
+	reloaded, err := NewDownloadQueue(mgr, QueueOptions{})
+	if err != nil {
+		t.Fatalf("NewDownloadQueue: %v", err)
+	}
+	items := reloaded.List()
+	if len(items) != 3 || items[0].ID != other.ID || items[0].State != QueueQueued {
+		t.Fatalf("unexpected reloaded queue %+v", items)
+	}
+}
+
+func TestDownloadQueue_PauseResumeCancel(t *testing.T) {
+	mgr, providers := newQueueTestManager(t)
+	q, err := NewDownloadQueue(mgr, QueueOptions{})
+	if err != nil {
+		t.Fatalf("NewDownloadQueue: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+
+	item, _ := q.Add(SourceHuggingFace, "org/model", DownloadOptions{}, 0)
+	waitState(t, q, item.ID, QueueRunning)
+	if _, err := q.Pause(item.ID); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	waitState(t, q, item.ID, QueuePaused)
+	if _, err := q.Resume(item.ID); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	waitState(t, q, item.ID, QueueRunning)
+	if _, err := q.Cancel(item.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	waitState(t, q, item.ID, QueueCanceled)
+	if _, err := q.Cancel(item.ID); err != ErrQueueItemFinished {
+		t.Fatalf("expected ErrQueueItemFinished, got %v", err)
+	}
+
+	queued, _ := q.Add(SourceHuggingFace, "org/next", DownloadOptions{}, 0)
+	var release sync.Once
+	final, err := q.Wait(ctx, queued.ID, func(QueueItem) {
+		release.Do(func() { providers[SourceHuggingFace].gate("org/next") <- nil })
+	})
+	if err != nil || final.State != QueueDone {
+		t.Fatalf("Wait = %+v, %v", final, err)
+	}
+	if removed, err := q.Clear(); err != nil || removed != 2 || len(q.List()) != 0 {
+		t.Fatalf("Clear = %d, %v", removed, err)
+	}
+}
+
+func TestDownloadQueue_CountsEveryFile(t *testing.T) {
+	files := map[string][]byte{
+		"config.json": []byte(`{"a":1}`),
+		"tiny.gguf":   []byte(strings.Repeat("w", 300)),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.json" {
+			json.NewEncoder(w).Encode(map[string]any{"models": []map[string]any{
+				{"id": "approved/tiny", "files": []map[string]any{{"url": "config.json"}, {"url": "tiny.gguf"}}},
+			}})
+			return
+		}
+		content, ok := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+	mgr := NewManager(t.TempDir())
+	if err := mgr.RegisterProvider(NewHTTPProvider(ArtifactOptions{IndexURL: server.URL + "/index.json"})); err != nil {
+		t.Fatalf("RegisterProvider: %v", err)
+	}
+	q, err := NewDownloadQueue(mgr, QueueOptions{})
+	if err != nil {
+		t.Fatalf("NewDownloadQueue: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+
+	item, err := q.Add(SourceURL, "approved/tiny", DownloadOptions{}, 0)
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	done := waitFor(t, q, item.ID, "finished", func(item QueueItem) bool { return item.Done() })
+	want := int64(len(files["config.json"]) + len(files["tiny.gguf"]))
+	if done.State != QueueDone || done.Downloaded != want || done.Total != want {
+		t.Fatalf("expected %d bytes over both files, got %+v", want, done)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(1000)
+	start := time.Now()
+	if err := limiter.wait(context.Background(), 1000); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("the first second of traffic should not wait, took %s", elapsed)
+	}
+	if err := limiter.wait(context.Background(), 200); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("expected to wait about 200ms, took %s", elapsed)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.wait(ctx, 5000); err == nil {
+		t.Fatalf("expected a canceled wait to fail")
+	}
+	var unlimited *rateLimiter
+	if err := unlimited.wait(context.Background(), 1<<30); err != nil {
+		t.Fatalf("a nil limiter should not limit: %v", err)
+	}
+}
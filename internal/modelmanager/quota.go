@@ -0,0 +1,380 @@
+package modelmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// lastUsedResolution bounds how often name-based use rewrites metadata.json.
+const lastUsedResolution = time.Minute
+
+// ErrQuotaExceeded is returned when a download would exceed the model quota.
+var ErrQuotaExceeded = errors.New("model quota exceeded")
+
+// Quota limits the disk space of the model directory, either in bytes or
+// as a percentage of its file system. The zero Quota is unlimited.
+type Quota struct {
+	Bytes   int64
+	Percent float64
+}
+
+// downloadSizer is implemented by providers that can tell how many bytes a
+// download writes before starting it.
+type downloadSizer interface {
+	DownloadSize(ctx context.Context, modelID string, opts DownloadOptions) (int64, error)
+}
+
+// QuotaStatus compares a download with the model quota.
+type QuotaStatus struct {
+	Limit    int64
+	Used     int64
+	Required int64
+	// SizeKnown is false when the provider could not size the download.
+	SizeKnown bool
+}
+
+// Exceeded reports whether the download would go over the quota.
+func (s QuotaStatus) Exceeded() bool {
+	return s.Limit > 0 && s.SizeKnown && s.Used+s.Required > s.Limit
+}
+
+// PruneOptions select the models Prune removes: everything but the
+// KeepRecent most recently used models, least recently used first, until
+// the model directory is within MaxSize (when set).
+type PruneOptions struct {
+	KeepRecent int
+	MaxSize    int64
+	DryRun     bool
+}
+
+// PruneResult lists the models Prune removed, or would remove.
+type PruneResult struct {
+	Removed    []DownloadedModel `json:"removed"`
+	FreedBytes int64             `json:"freed_bytes"`
+	UsedBytes  int64             `json:"used_bytes"`
+}
+
+// ParseQuota parses a quota such as "200GB" or "80%". An empty value,
+// "0" or "unlimited" means no quota.
+func ParseQuota(raw string) (Quota, error) {
+	trimmed := strings.TrimSpace(raw)
+	switch strings.ToLower(trimmed) {
+	case "", "0", "unlimited":
+		return Quota{}, nil
+	}
+	if number, ok := strings.CutSuffix(trimmed, "%"); ok {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return Quota{}, fmt.Errorf("invalid quota percentage: %s", raw)
+		}
+		return Quota{Percent: percent}, nil
+	}
+	bytes, err := ParseSize(trimmed)
+	if err != nil {
+		return Quota{}, fmt.Errorf("invalid quota: %w", err)
+	}
+	return Quota{Bytes: bytes}, nil
+}
+
+// ParseSize parses a byte size such as "500GB", "1.5T" or "1024". Units
+// are binary.
+func ParseSize(raw string) (int64, error) {
+	trimmed := strings.TrimSpace(strings.ToUpper(raw))
+	if trimmed == "" {
+		return 0, fmt.Errorf("empty size")
+	}
+	multipliers := []struct {
+		suffix string
+		value  float64
+	}{
+		{"TIB", 1 << 40}, {"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	}
+	multiplier := 1.0
+	for _, unit := range multipliers {
+		if number, ok := strings.CutSuffix(trimmed, unit.suffix); ok {
+			trimmed, multiplier = strings.TrimSpace(number), unit.value
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size: %s", raw)
+	}
+	return int64(value * multiplier), nil
+}
+
+// IsZero reports whether the quota is unlimited.
+func (q Quota) IsZero() bool {
+	return q.Bytes <= 0 && q.Percent <= 0
+}
+
+func (q Quota) String() string {
+	switch {
+	case q.Percent > 0:
+		return strconv.FormatFloat(q.Percent, 'f', -1, 64) + "%"
+	case q.Bytes > 0:
+		return FormatBytes(q.Bytes)
+	default:
+		return "unlimited"
+	}
+}
+
+// Limit returns the quota in bytes for a directory; percentages are of the
+// file system holding it.
+func (q Quota) Limit(dir string) (int64, error) {
+	if q.Percent <= 0 {
+		return max(q.Bytes, 0), nil
+	}
+	// The model directory may not exist before the first download.
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	total := float64(uint64(stat.Blocks) * uint64(stat.Bsize))
+	return int64(total * q.Percent / 100), nil
+}
+
+// SetQuota sets the quota downloads are checked against.
+func (m *Manager) SetQuota(quota Quota) {
+	m.quota = quota
+}
+
+// Quota returns the configured model quota.
+func (m *Manager) Quota() Quota {
+	return m.quota
+}
+
+// Usage returns the disk space of the model directory, counting each blob
+// once however many models link to it.
+func (m *Manager) Usage() (int64, error) {
+	entries, err := os.ReadDir(m.modelDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var total int64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if entry.Name() == blobDirName {
+			size, err := PathSize(filepath.Join(m.modelDir, entry.Name()))
+			if err != nil {
+				return 0, err
+			}
+			total += size
+			continue
+		}
+		size, _, err := m.modelDiskUsage(filepath.Join(m.modelDir, entry.Name()))
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// modelDiskUsage returns the size of a model's own files, those not linked
+// from the blob store, and the blobs it references.
+func (m *Manager) modelDiskUsage(modelDir string) (int64, map[string]string, error) {
+	blobs := modelBlobs(modelDir)
+	var size int64
+	err := filepath.WalkDir(modelDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(modelDir, path)
+		if err != nil {
+			return err
+		}
+		if digest, ok := blobs[rel]; ok && m.isBlobLink(path, digest) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, blobs, err
+}
+
+// CheckQuota sizes a download and compares it with the quota. Without a
+// quota the returned status has a zero Limit and nothing is sized.
+func (m *Manager) CheckQuota(ctx context.Context, source ModelSource, modelID string, opts DownloadOptions) (QuotaStatus, error) {
+	var status QuotaStatus
+	if m.quota.IsZero() {
+		return status, nil
+	}
+	limit, err := m.quota.Limit(m.modelDir)
+	if err != nil {
+		return status, err
+	}
+	used, err := m.Usage()
+	if err != nil {
+		return status, err
+	}
+	status.Limit, status.Used = limit, used
+
+	provider, err := m.GetProvider(source)
+	if err != nil {
+		return status, err
+	}
+	if sizer, ok := provider.(downloadSizer); ok {
+		size, err := sizer.DownloadSize(ctx, modelID, opts)
+		if err != nil {
+			return status, err
+		}
+		status.Required, status.SizeKnown = size, true
+	} else if info, err := provider.GetModelInfo(ctx, modelID); err == nil && info.Size > 0 {
+		status.Required, status.SizeKnown = info.Size, true
+	}
+	return status, nil
+}
+
+// TouchModel records that a model was used, for Prune.
+func (m *Manager) TouchModel(source ModelSource, modelID string) error {
+	modelDir, err := m.ResolveLocalModelDir(source, modelID)
+	if err != nil {
+		return err
+	}
+	return updateMetadata(modelDir, func(metadata map[string]any) {
+		metadata["last_used"] = time.Now().Unix()
+	})
+}
+
+// TouchModelByName records the use of the downloaded model a model server
+// reports as name: its ID, its directory or a file inside it, or the name
+// of one of its GGUF files. It reports whether a model matched.
+func (m *Manager) TouchModelByName(name string) bool {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return false
+	}
+	models, err := m.ListDownloadedModels()
+	if err != nil {
+		return false
+	}
+	for _, model := range models {
+		if !modelMatchesName(model, name) {
+			continue
+		}
+		if time.Since(time.Unix(model.LastUsed, 0)) >= lastUsedResolution {
+			_ = updateMetadata(model.LocalPath, func(metadata map[string]any) {
+				metadata["last_used"] = time.Now().Unix()
+			})
+		}
+		return true
+	}
+	return false
+}
+
+func modelMatchesName(model DownloadedModel, name string) bool {
+	if strings.EqualFold(model.ID, name) {
+		return true
+	}
+	if name == model.LocalPath || strings.HasPrefix(name, model.LocalPath+string(filepath.Separator)) {
+		return true
+	}
+	for _, info := range model.GGUF {
+		if strings.EqualFold(filepath.Base(info.File), filepath.Base(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastActivity is when a model was last used, or downloaded if never used.
+func (d DownloadedModel) lastActivity() int64 {
+	return max(d.LastUsed, d.DownloadedAt)
+}
+
+// Prune removes the least recently used models as selected by opts.
+func (m *Manager) Prune(opts PruneOptions) (PruneResult, error) {
+	result := PruneResult{Removed: []DownloadedModel{}}
+	if opts.KeepRecent <= 0 && opts.MaxSize <= 0 {
+		return result, fmt.Errorf("set the number of models to keep or a maximum size")
+	}
+	models, err := m.ListDownloadedModels()
+	if err != nil {
+		return result, err
+	}
+	sort.SliceStable(models, func(i, j int) bool {
+		return models[i].lastActivity() > models[j].lastActivity()
+	})
+	used, err := m.Usage()
+	if err != nil {
+		return result, err
+	}
+	refs, err := m.blobReferences()
+	if err != nil {
+		return result, err
+	}
+
+	for i := len(models) - 1; i >= max(opts.KeepRecent, 0); i-- {
+		if opts.MaxSize > 0 && used <= opts.MaxSize {
+			break
+		}
+		model := models[i]
+		freed, blobs, err := m.modelDiskUsage(model.LocalPath)
+		if err != nil {
+			return result, err
+		}
+		for _, digest := range blobs {
+			refs[digest]--
+			if refs[digest] == 0 {
+				if info, err := os.Stat(m.BlobPath(digest)); err == nil {
+					freed += info.Size()
+				}
+			}
+		}
+		if !opts.DryRun {
+			if err := m.removeDownloadedModel(model); err != nil {
+				return result, err
+			}
+		}
+		used -= freed
+		result.Removed = append(result.Removed, model)
+		result.FreedBytes += freed
+	}
+	result.UsedBytes = used
+	return result, nil
+}
+
+// removeDownloadedModel removes a model found in the model directory,
+// through its provider when the metadata names one.
+func (m *Manager) removeDownloadedModel(model DownloadedModel) error {
+	if model.ID != "" {
+		if _, err := m.GetProvider(model.Source); err == nil {
+			return m.RemoveModel(model.Source, model.ID)
+		}
+	}
+	var digests []string
+	for _, digest := range modelBlobs(model.LocalPath) {
+		digests = append(digests, digest)
+	}
+	if err := os.RemoveAll(model.LocalPath); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", model.LocalPath, err)
+	}
+	return m.releaseBlobs(digests)
+}
@@ -0,0 +1,168 @@
+package modelmanager
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseQuota(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want Quota
+	}{
+		{"", Quota{}},
+		{"unlimited", Quota{}},
+		{"500GB", Quota{Bytes: 500 << 30}},
+		{"1.5T", Quota{Bytes: 3 << 39}},
+		{"2048", Quota{Bytes: 2048}},
+		{"80%", Quota{Percent: 80}},
+	}
+	for _, tc := range cases {
+		got, err := ParseQuota(tc.raw)
+		if err != nil {
+			t.Fatalf("ParseQuota(%q) returned error: %v", tc.raw, err)
+		}
+		if got != tc.want {
+			t.Fatalf("ParseQuota(%q) = %+v, want %+v", tc.raw, got, tc.want)
+		}
+	}
+	for _, raw := range []string{"lots", "120%", "-5GB"} {
+		if _, err := ParseQuota(raw); err == nil {
+			t.Fatalf("expected an error for %q", raw)
+		}
+	}
+
+	limit, err := Quota{Percent: 50}.Limit(filepath.Join(t.TempDir(), "missing", "models"))
+	if err != nil || limit <= 0 {
+		t.Fatalf("expected a percentage of the file system, got %d (%v)", limit, err)
+	}
+}
+
+func TestDownloadModel_EnforcesQuota(t *testing.T) {
+	mgr := newBlobTestManager(t)
+	if err := mgr.DownloadModel(SourceHuggingFace, "org/model", nil, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadModel returned error: %v", err)
+	}
+	used, err := mgr.Usage()
+	if err != nil {
+		t.Fatalf("Usage returned error: %v", err)
+	}
+
+	mgr.SetQuota(Quota{Bytes: used + blobMinSize/2})
+	err = mgr.DownloadModel(SourceModelScope, "mirror/model", nil, DownloadOptions{})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if mgr.HasLocalModel(SourceModelScope, "mirror/model", "") {
+		t.Fatalf("the refused download should not have started")
+	}
+
+	if err := mgr.DownloadModel(SourceModelScope, "mirror/model", nil, DownloadOptions{IgnoreQuota: true}); err != nil {
+		t.Fatalf("DownloadModel with IgnoreQuota returned error: %v", err)
+	}
+	after, err := mgr.Usage()
+	if err != nil {
+		t.Fatalf("Usage returned error: %v", err)
+	}
+	if after-used >= blobMinSize {
+		t.Fatalf("expected the shared blob to be counted once, usage grew from %d to %d", used, after)
+	}
+}
+
+func setLastUsed(t *testing.T, mgr *Manager, source ModelSource, modelID string, lastUsed int64) {
+	t.Helper()
+	modelDir, err := mgr.ResolveLocalModelDir(source, modelID)
+	if err != nil {
+		t.Fatalf("ResolveLocalModelDir: %v", err)
+	}
+	if err := updateMetadata(modelDir, func(metadata map[string]any) { metadata["last_used"] = lastUsed }); err != nil {
+		t.Fatalf("updateMetadata: %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	mgr := newBlobTestManager(t)
+	for _, id := range []string{"org/old", "org/middle", "org/new"} {
+		if err := mgr.DownloadModel(SourceHuggingFace, id, nil, DownloadOptions{}); err != nil {
+			t.Fatalf("DownloadModel returned error: %v", err)
+		}
+	}
+	// A unique file makes each model's removal free space of its own.
+	for i, id := range []string{"org/old", "org/middle", "org/new"} {
+		modelDir, _ := mgr.ResolveLocalModelDir(SourceHuggingFace, id)
+		if err := os.WriteFile(filepath.Join(modelDir, "notes.txt"), make([]byte, 1000*(i+1)), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	setLastUsed(t, mgr, SourceHuggingFace, "org/old", 100)
+	setLastUsed(t, mgr, SourceHuggingFace, "org/middle", 200)
+	if err := mgr.TouchModel(SourceHuggingFace, "org/new"); err != nil {
+		t.Fatalf("TouchModel returned error: %v", err)
+	}
+
+	if _, err := mgr.Prune(PruneOptions{}); err == nil {
+		t.Fatalf("expected an error without keep-recent or max-size")
+	}
+
+	plan, err := mgr.Prune(PruneOptions{KeepRecent: 1, DryRun: true})
+	if err != nil {
+		t.Fatalf("Prune returned error: %v", err)
+	}
+	if len(plan.Removed) != 2 || plan.Removed[0].ID != "org/old" || plan.Removed[1].ID != "org/middle" {
+		t.Fatalf("expected the two least recently used models, got %+v", plan.Removed)
+	}
+	if count := blobCount(t, mgr); count != 1 {
+		t.Fatalf("dry run changed the blob store")
+	}
+
+	used, _ := mgr.Usage()
+	result, err := mgr.Prune(PruneOptions{MaxSize: used - 1000})
+	if err != nil {
+		t.Fatalf("Prune returned error: %v", err)
+	}
+	if len(result.Removed) != 1 || result.Removed[0].ID != "org/old" {
+		t.Fatalf("expected only the oldest model to go, got %+v", result.Removed)
+	}
+	if mgr.HasLocalModel(SourceHuggingFace, "org/old", "") || !mgr.HasLocalModel(SourceHuggingFace, "org/middle", "") {
+		t.Fatalf("prune removed the wrong models")
+	}
+
+	result, err = mgr.Prune(PruneOptions{MaxSize: 1})
+	if err != nil {
+		t.Fatalf("Prune returned error: %v", err)
+	}
+	if len(result.Removed) != 2 || result.UsedBytes != 0 || blobCount(t, mgr) != 0 {
+		t.Fatalf("expected every model and blob to be removed, got %+v", result)
+	}
+}
+
+func TestTouchModelByName(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	if err := mgr.RegisterProvider(ggufTestProvider{t: t}); err != nil {
+		t.Fatalf("RegisterProvider: %v", err)
+	}
+	if err := mgr.DownloadModel(SourceHuggingFace, "org/tiny-GGUF", nil, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadModel returned error: %v", err)
+	}
+	modelDir, _ := mgr.ResolveLocalModelDir(SourceHuggingFace, "org/tiny-GGUF")
+
+	if mgr.TouchModelByName("other-model") {
+		t.Fatalf("an unknown name should not match")
+	}
+	if !mgr.TouchModelByName(filepath.Join(modelDir, "tiny.Q4_K_M.gguf")) {
+		t.Fatalf("expected the llama-server model path to match")
+	}
+	models, err := mgr.ListDownloadedModels()
+	if err != nil || len(models) != 1 {
+		t.Fatalf("ListDownloadedModels: %v", err)
+	}
+	if time.Since(time.Unix(models[0].LastUsed, 0)) > time.Minute {
+		t.Fatalf("last use was not recorded: %d", models[0].LastUsed)
+	}
+	if !mgr.TouchModelByName("tiny.q4_k_m.gguf") || !mgr.TouchModelByName("ORG/tiny-gguf") {
+		t.Fatalf("expected the GGUF file name and model ID to match")
+	}
+}
@@ -0,0 +1,163 @@
+package modelmanager
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSafetensorsHeader bounds the JSON header of a safetensors file.
+const maxSafetensorsHeader = 100 << 20
+
+// safetensorsDtypeBytes is the element size of safetensors dtypes.
+var safetensorsDtypeBytes = map[string]uint64{
+	"F64": 8, "I64": 8, "U64": 8,
+	"F32": 4, "I32": 4, "U32": 4,
+	"F16": 2, "BF16": 2, "I16": 2, "U16": 2,
+	"F8_E4M3": 1, "F8_E5M2": 1, "I8": 1, "U8": 1, "BOOL": 1,
+}
+
+// QuantizationConfig is the quantization_config section of config.json.
+type QuantizationConfig struct {
+	QuantMethod string `json:"quant_method"`
+	Bits        int    `json:"bits,omitempty"`
+}
+
+// HFConfig holds the config.json fields that size a transformer model.
+type HFConfig struct {
+	Architectures         []string            `json:"architectures"`
+	ModelType             string              `json:"model_type"`
+	NumHiddenLayers       uint64              `json:"num_hidden_layers"`
+	HiddenSize            uint64              `json:"hidden_size"`
+	NumAttentionHeads     uint64              `json:"num_attention_heads"`
+	NumKeyValueHeads      uint64              `json:"num_key_value_heads"`
+	HeadDim               uint64              `json:"head_dim"`
+	MaxPositionEmbeddings uint64              `json:"max_position_embeddings"`
+	TorchDtype            string              `json:"torch_dtype"`
+	QuantizationConfig    *QuantizationConfig `json:"quantization_config"`
+	// TextConfig carries the language model of multimodal configs.
+	TextConfig *HFConfig `json:"text_config"`
+}
+
+// SafetensorsModel describes a safetensors model directory.
+type SafetensorsModel struct {
+	Config         HFConfig
+	WeightBytes    uint64
+	ParameterCount uint64
+	FileCount      int
+}
+
+// ReadHFConfig parses config.json. For multimodal models the text model's
+// sizes are used, keeping the top-level quantization and dtype.
+func ReadHFConfig(path string) (*HFConfig, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config HFConfig
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if config.NumHiddenLayers == 0 && config.TextConfig != nil {
+		text := *config.TextConfig
+		if text.TorchDtype == "" {
+			text.TorchDtype = config.TorchDtype
+		}
+		if text.QuantizationConfig == nil {
+			text.QuantizationConfig = config.QuantizationConfig
+		}
+		text.Architectures = config.Architectures
+		config = text
+	}
+	config.TextConfig = nil
+	return &config, nil
+}
+
+// ReadSafetensorsHeader returns the tensor data bytes and parameter count
+// of a safetensors file from its JSON header.
+func ReadSafetensorsHeader(path string) (bytes, parameters uint64, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	var length uint64
+	if err := binary.Read(file, binary.LittleEndian, &length); err != nil {
+		return 0, 0, fmt.Errorf("%s: not a safetensors file: %w", path, err)
+	}
+	if length == 0 || length > maxSafetensorsHeader {
+		return 0, 0, fmt.Errorf("%s: invalid safetensors header length %d", path, length)
+	}
+	raw := make([]byte, length)
+	if _, err := io.ReadFull(file, raw); err != nil {
+		return 0, 0, fmt.Errorf("%s: truncated safetensors header: %w", path, err)
+	}
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return 0, 0, fmt.Errorf("%s: invalid safetensors header: %w", path, err)
+	}
+	for name, value := range header {
+		if name == "__metadata__" {
+			continue
+		}
+		var tensor struct {
+			Dtype       string   `json:"dtype"`
+			Shape       []uint64 `json:"shape"`
+			DataOffsets []uint64 `json:"data_offsets"`
+		}
+		if err := json.Unmarshal(value, &tensor); err != nil {
+			return 0, 0, fmt.Errorf("%s: invalid tensor %s: %w", path, name, err)
+		}
+		elements := uint64(1)
+		for _, dim := range tensor.Shape {
+			elements *= dim
+		}
+		parameters += elements
+		if len(tensor.DataOffsets) == 2 && tensor.DataOffsets[1] >= tensor.DataOffsets[0] {
+			bytes += tensor.DataOffsets[1] - tensor.DataOffsets[0]
+		} else {
+			bytes += elements * safetensorsDtypeBytes[tensor.Dtype]
+		}
+	}
+	return bytes, parameters, nil
+}
+
+// ReadSafetensorsModel reads config.json and the headers of every
+// safetensors file in modelDir.
+func ReadSafetensorsModel(modelDir string) (*SafetensorsModel, error) {
+	config, err := ReadHFConfig(filepath.Join(modelDir, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+	files, err := FindSafetensorsFiles(modelDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no safetensors files found in %s", modelDir)
+	}
+	model := &SafetensorsModel{Config: *config, FileCount: len(files)}
+	for _, path := range files {
+		bytes, parameters, err := ReadSafetensorsHeader(path)
+		if err != nil {
+			return nil, err
+		}
+		model.WeightBytes += bytes
+		model.ParameterCount += parameters
+	}
+	return model, nil
+}
+
+// Quantization returns the quantization method, or "" for full-precision
+// weights.
+func (m SafetensorsModel) Quantization() string {
+	if m.Config.QuantizationConfig == nil {
+		return ""
+	}
+	return strings.ToLower(m.Config.QuantizationConfig.QuantMethod)
+}
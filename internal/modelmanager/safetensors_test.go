@@ -0,0 +1,149 @@
+package modelmanager
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSafetensors(t *testing.T, path string, tensors map[string][]uint64, dtype string) {
+	t.Helper()
+	header := map[string]any{"__metadata__": map[string]string{"format": "pt"}}
+	var offset uint64
+	for name, shape := range tensors {
+		size := safetensorsDtypeBytes[dtype]
+		for _, dim := range shape {
+			size *= dim
+		}
+		header[name] = map[string]any{"dtype": dtype, "shape": shape, "data_offsets": []uint64{offset, offset + size}}
+		offset += size
+	}
+	raw, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("marshal header: %v", err)
+	}
+	data := binary.LittleEndian.AppendUint64(nil, uint64(len(raw)))
+	data = append(data, raw...)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func writeJSONFile(t *testing.T, path string, value any) {
+	t.Helper()
+	raw, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestReadSafetensorsModel(t *testing.T) {
+	dir := t.TempDir()
+	writeJSONFile(t, filepath.Join(dir, "config.json"), map[string]any{
+		"architectures": []string{"Gemma3ForConditionalGeneration"},
+		"torch_dtype":   "bfloat16",
+		"text_config": map[string]any{
+			"num_hidden_layers":       2,
+			"hidden_size":             64,
+			"num_attention_heads":     4,
+			"num_key_value_heads":     2,
+			"max_position_embeddings": 8192,
+		},
+	})
+	writeSafetensors(t, filepath.Join(dir, "model-00001-of-00002.safetensors"), map[string][]uint64{
+		"embed.weight": {100, 64},
+		"norm.weight":  {64},
+	}, "BF16")
+	writeSafetensors(t, filepath.Join(dir, "model-00002-of-00002.safetensors"), map[string][]uint64{
+		"lm_head.weight": {100, 64},
+	}, "F32")
+
+	model, err := ReadSafetensorsModel(dir)
+	if err != nil {
+		t.Fatalf("ReadSafetensorsModel returned error: %v", err)
+	}
+	if model.FileCount != 2 || model.ParameterCount != 100*64*2+64 {
+		t.Fatalf("unexpected model totals %+v", model)
+	}
+	if model.WeightBytes != (100*64+64)*2+100*64*4 {
+		t.Fatalf("WeightBytes = %d", model.WeightBytes)
+	}
+	config := model.Config
+	if config.NumHiddenLayers != 2 || config.TorchDtype != "bfloat16" || len(config.Architectures) != 1 || config.MaxPositionEmbeddings != 8192 {
+		t.Fatalf("text config was not merged: %+v", config)
+	}
+}
+
+func TestReadSafetensorsHeader_RejectsInvalidFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.safetensors")
+	data := binary.LittleEndian.AppendUint64(nil, 1<<40)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, _, err := ReadSafetensorsHeader(path); err == nil {
+		t.Fatalf("expected an error for an oversized header")
+	}
+}
+
+// qwen7B resembles a Qwen2.5 7B bf16 checkpoint.
+func qwen7B() *SafetensorsModel {
+	return &SafetensorsModel{
+		Config: HFConfig{
+			NumHiddenLayers:       28,
+			HiddenSize:            3584,
+			NumAttentionHeads:     28,
+			NumKeyValueHeads:      4,
+			MaxPositionEmbeddings: 32768,
+			TorchDtype:            "bfloat16",
+		},
+		WeightBytes:    15_200_000_000,
+		ParameterCount: 7_600_000_000,
+	}
+}
+
+func TestEstimateVLLMFit(t *testing.T) {
+	fit, err := EstimateVLLMFit(qwen7B(), FitOptions{GPUs: []GPUMemory{{FreeBytes: 80 * gib}}}, 0)
+	if err != nil {
+		t.Fatalf("EstimateVLLMFit returned error: %v", err)
+	}
+	if fit.TensorParallelSize != 1 || fit.MaxModelLen != 32768 || fit.Dtype != "bfloat16" || fit.GPUMemoryUtilization != vllmDefaultGPUMemoryUtilization {
+		t.Fatalf("unexpected fit %+v", fit)
+	}
+	if want := uint64(28 * 4 * 128 * 2 * 2); fit.KVBytesPerToken != want {
+		t.Fatalf("KV cache per token = %d, want %d", fit.KVBytesPerToken, want)
+	}
+
+	// Two 12 GiB GPUs only hold the weights together.
+	fit, err = EstimateVLLMFit(qwen7B(), FitOptions{GPUs: []GPUMemory{{FreeBytes: 12 * gib}, {FreeBytes: 12 * gib}}}, 0.9)
+	if err != nil {
+		t.Fatalf("EstimateVLLMFit returned error: %v", err)
+	}
+	if fit.TensorParallelSize != 2 || fit.MaxModelLen < fitDefaultMinContext {
+		t.Fatalf("expected tensor parallelism across both GPUs, got %+v", fit)
+	}
+
+	if _, err := EstimateVLLMFit(qwen7B(), FitOptions{GPUs: []GPUMemory{{FreeBytes: 12 * gib}}}, 0.9); err == nil {
+		t.Fatalf("expected an error when the weights do not fit")
+	}
+}
+
+func TestEstimateVLLMFit_QuantizedAndFixedLength(t *testing.T) {
+	model := qwen7B()
+	model.WeightBytes = 5_500_000_000
+	model.Config.QuantizationConfig = &QuantizationConfig{QuantMethod: "AWQ", Bits: 4}
+	fit, err := EstimateVLLMFit(model, FitOptions{GPUs: []GPUMemory{{FreeBytes: 12 * gib}}, ContextSize: 16384}, 0.9)
+	if err != nil {
+		t.Fatalf("EstimateVLLMFit returned error: %v", err)
+	}
+	if fit.Quantization != "awq" || fit.WeightBytes != model.WeightBytes || fit.MaxModelLen != 16384 {
+		t.Fatalf("unexpected quantized fit %+v", fit)
+	}
+	if _, err := EstimateVLLMFit(model, FitOptions{GPUs: []GPUMemory{{FreeBytes: 8 * gib}}, ContextSize: 1 << 20}, 0.9); err == nil {
+		t.Fatalf("expected an error when the requested length does not fit")
+	}
+}
@@ -22,6 +22,12 @@ const (
 	SourceOllama      ModelSource = "ollama"
 	SourceHuggingFace ModelSource = "huggingface"
 	SourceModelScope  ModelSource = "modelscope"
+	// SourceLocal marks models registered with ImportModel.
+	SourceLocal ModelSource = "local"
+	// SourceURL and SourceOCI download from HTTP(S) URLs and OCI
+	// registries, e.g. an internal artifact server.
+	SourceURL ModelSource = "url"
+	SourceOCI ModelSource = "oci"
 )
 
 type ModelInfo struct {
@@ -39,11 +45,20 @@ type DownloadedModel struct {
 	ModelInfo
 	LocalPath    string `json:"local_path"`
 	DownloadedAt int64  `json:"downloaded_at"`
+	// LastUsed is when the model was last run or served through the
+	// gateway, in Unix seconds.
+	LastUsed int64 `json:"last_used,omitempty"`
+	// GGUF holds the headers of the model's GGUF files, recorded at
+	// download time.
+	GGUF []GGUFInfo `json:"gguf,omitempty"`
 }
 
 type Provider interface {
 	Name() ModelSource
 	Search(ctx context.Context, query string, limit int) ([]ModelInfo, error)
+	// Download fetches a model below destPath. progress receives the bytes
+	// of the file being downloaded so far and its size; it is called with 0
+	// when each file starts, so callers can add up the files of a model.
 	Download(ctx context.Context, modelID string, destPath string, progress func(downloaded, total int64), opts DownloadOptions) error
 	Delete(ctx context.Context, modelID string) error
 	GetModelInfo(ctx context.Context, modelID string) (*ModelInfo, error)
@@ -52,10 +67,13 @@ type Provider interface {
 type Manager struct {
 	providers map[ModelSource]Provider
 	modelDir  string
+	quota     Quota
 }
 
 type DownloadOptions struct {
 	FileHint string
+	// IgnoreQuota downloads even when the model quota would be exceeded.
+	IgnoreQuota bool
 }
 
 func NewManager(modelDir string) *Manager {
@@ -69,6 +87,17 @@ func NewManager(modelDir string) *Manager {
 	}
 }
 
+// NewDefaultManager returns a Manager for the default model directory with
+// the Ollama, Hugging Face, ModelScope and local providers registered.
+func NewDefaultManager() *Manager {
+	mgr := NewManager("")
+	mgr.RegisterProvider(NewOllamaProvider())
+	mgr.RegisterProvider(NewHuggingFaceProvider(""))
+	mgr.RegisterProvider(NewModelScopeProvider(""))
+	mgr.RegisterProvider(NewLocalProvider(mgr.modelDir))
+	return mgr
+}
+
 func (m *Manager) RegisterProvider(provider Provider) error {
 	if provider == nil {
 		return fmt.Errorf("provider cannot be nil")
@@ -0,0 +1,291 @@
+package module
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"gopkg.in/yaml.v3"
+)
+
+// Artifact is a file that a module's install steps download and declare as
+// cacheable. Once it is in the artifact cache, the download step reads it
+// from there, so the module installs without network access.
+type Artifact struct {
+	Module string `json:"module"`
+	Step   string `json:"step"`
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// ModuleDir returns the directory of an available module.
+func ModuleDir(name string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if normalized == "" {
+		return "", i18n.Errorf("module name is required")
+	}
+	return resolveModuleDir(normalized)
+}
+
+// ArtifactCacheDir returns the directory of the artifact cache, next to the
+// module data directory.
+func ArtifactCacheDir() (string, error) {
+	dataDir, err := ModuleDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(dataDir), "artifacts"), nil
+}
+
+// artifactCachePath returns where the artifact downloaded from rawURL is
+// cached. Artifacts are keyed by URL; their checksum is verified on use.
+func artifactCachePath(rawURL string) (string, error) {
+	dir, err := ArtifactCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])), nil
+}
+
+// NetworkStep is an install step that needs network access even when the
+// artifact cache holds the module's cacheable downloads.
+type NetworkStep struct {
+	Module string `json:"module"`
+	Mode   string `json:"mode"`
+	// DefaultMode is set when Mode is the module's default install mode.
+	DefaultMode bool   `json:"default_mode,omitempty"`
+	Step        string `json:"step"`
+	Reason      string `json:"reason"`
+}
+
+// networkCommand matches shell commands that fetch from the network.
+var networkCommand = regexp.MustCompile("(?m)(?:^|[\\s;&|(`$])(curl|wget|git\\s+(?:clone|fetch|pull)|pip3?\\s+install|npm\\s+(?:install|i|ci)|npx|(?:apt-get|apt|dnf|yum)\\s+(?:-y\\s+)?install|docker\\s+pull|podman\\s+pull)\\b")
+
+// moduleScriptRef matches script paths named in a shell command or script.
+var moduleScriptRef = regexp.MustCompile(`[\w./-]+\.(?:sh|bash|py)\b`)
+
+// CacheableArtifacts lists the cacheable downloads of a module's install
+// plan across all install modes, with their URLs and checksums rendered
+// from the module configuration.
+func CacheableArtifacts(name string) ([]Artifact, error) {
+	normalized, spec, _, vars, err := loadModuleSpecForBundle(name)
+	if err != nil || spec == nil {
+		return nil, err
+	}
+
+	var artifacts []Artifact
+	seen := map[string]bool{}
+	for _, mode := range sortedInstallModes(spec) {
+		for _, step := range spec.Install[mode] {
+			if step.Tool != "download" || !step.Download.Cacheable {
+				continue
+			}
+			rawURL := renderStepValue(step.Download.URL, vars)
+			if rawURL == "" || seen[rawURL] {
+				continue
+			}
+			seen[rawURL] = true
+			artifacts = append(artifacts, Artifact{
+				Module: normalized,
+				Step:   step.ID,
+				URL:    rawURL,
+				SHA256: normalizeChecksum(renderStepValue(step.Download.SHA256, vars)),
+			})
+		}
+	}
+	return artifacts, nil
+}
+
+// NetworkSteps lists the install steps of a module, across all install
+// modes, that fetch from the network outside the artifact cache: downloads
+// that are not cacheable, virtualenvs with requirements, and shell steps
+// whose command, or a module script it runs, calls a network client.
+func NetworkSteps(name string) ([]NetworkStep, error) {
+	normalized, spec, moduleDir, vars, err := loadModuleSpecForBundle(name)
+	if err != nil || spec == nil {
+		return nil, err
+	}
+
+	defaultMode := selectInstallMode(*spec)
+	var steps []NetworkStep
+	for _, mode := range sortedInstallModes(spec) {
+		for _, step := range spec.Install[mode] {
+			var reason string
+			switch step.Tool {
+			case "shell":
+				reason = shellNetworkReason(moduleDir, renderStepValue(step.Command, vars))
+			case "download":
+				rawURL := renderStepValue(step.Download.URL, vars)
+				if !step.Download.Cacheable && !strings.HasPrefix(rawURL, "file://") {
+					reason = i18n.T("downloads %s without cacheable", rawURL)
+				}
+			case "python_venv":
+				if len(step.PythonVenv.Requirements) > 0 || step.PythonVenv.RequirementsFile != "" {
+					reason = i18n.T("installs Python requirements with pip")
+				}
+			}
+			if reason != "" {
+				steps = append(steps, NetworkStep{Module: normalized, Mode: mode, DefaultMode: mode == defaultMode, Step: step.ID, Reason: reason})
+			}
+		}
+	}
+	return steps, nil
+}
+
+// shellNetworkReason reports the network client a shell command runs,
+// directly or through the module scripts it names.
+func shellNetworkReason(moduleDir, command string) string {
+	call, script := findNetworkCall(moduleDir, moduleDir, command, map[string]bool{})
+	switch {
+	case call == "":
+		return ""
+	case script == "":
+		return i18n.T("runs %s", call)
+	default:
+		return i18n.T("runs %s (in %s)", call, script)
+	}
+}
+
+// findNetworkCall returns the network client command runs and the module
+// script, relative to moduleDir, that runs it. Script paths resolve against
+// dir, the directory of the naming script, and the module directory.
+func findNetworkCall(moduleDir, dir, command string, visited map[string]bool) (string, string) {
+	if match := networkCommand.FindStringSubmatch(command); match != nil {
+		return strings.Join(strings.Fields(match[1]), " "), ""
+	}
+	for _, ref := range moduleScriptRef.FindAllString(command, -1) {
+		for _, base := range []string{dir, moduleDir} {
+			rel, err := filepath.Rel(moduleDir, filepath.Join(base, ref))
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			script := filepath.Join(moduleDir, rel)
+			if visited[script] {
+				continue
+			}
+			content, err := os.ReadFile(script)
+			if err != nil {
+				continue
+			}
+			visited[script] = true
+			if call, inner := findNetworkCall(moduleDir, filepath.Dir(script), string(content), visited); call != "" {
+				if inner == "" {
+					inner = filepath.ToSlash(rel)
+				}
+				return call, inner
+			}
+		}
+	}
+	return "", ""
+}
+
+// loadModuleSpecForBundle reads a module's install plan and configuration
+// variables. The spec is nil for a module without an install plan.
+func loadModuleSpecForBundle(name string) (string, *moduleInstallSpec, string, map[string]string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	moduleDir, err := ModuleDir(normalized)
+	if err != nil {
+		return normalized, nil, "", nil, err
+	}
+	raw, err := os.ReadFile(filepath.Join(moduleDir, "INSTALL.yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return normalized, nil, moduleDir, nil, nil
+		}
+		return normalized, nil, "", nil, i18n.Errorf("failed to read install plan for module %q: %w", normalized, err)
+	}
+	var spec moduleInstallSpec
+	if err := yaml.Unmarshal(raw, &spec); err != nil {
+		return normalized, nil, "", nil, i18n.Errorf("failed to parse install plan for module %q: %w", normalized, err)
+	}
+	vars, err := moduleConfigVars(normalized, spec.Configuration)
+	if err != nil {
+		return normalized, nil, "", nil, err
+	}
+	return normalized, &spec, moduleDir, vars, nil
+}
+
+func sortedInstallModes(spec *moduleInstallSpec) []string {
+	modes := make([]string, 0, len(spec.Install))
+	for mode := range spec.Install {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	return modes
+}
+
+// FetchArtifact saves an artifact to dest, from the artifact cache when it
+// holds it and from its URL otherwise, and verifies its checksum.
+func FetchArtifact(ctx context.Context, artifact Artifact, dest string) error {
+	reader, _, err := openArtifact(ctx, artifact.URL)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return saveArtifact(reader, artifact, dest)
+}
+
+// CacheArtifact adds the file at path to the artifact cache as artifact,
+// after verifying its checksum.
+func CacheArtifact(artifact Artifact, path string) error {
+	dest, err := artifactCachePath(artifact.URL)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return saveArtifact(file, artifact, dest)
+}
+
+// openArtifact opens the cached copy of rawURL, falling back to
+// downloading it.
+func openArtifact(ctx context.Context, rawURL string) (io.ReadCloser, int64, error) {
+	if cached, err := artifactCachePath(rawURL); err == nil {
+		if file, err := os.Open(cached); err == nil {
+			size := int64(-1)
+			if info, err := file.Stat(); err == nil {
+				size = info.Size()
+			}
+			return file, size, nil
+		}
+	}
+	return openDownload(ctx, rawURL)
+}
+
+// saveArtifact writes reader to dest through a temporary file, failing
+// when the content does not match the artifact's checksum.
+func saveArtifact(reader io.Reader, artifact Artifact, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".part-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hasher), reader); err != nil {
+		tmp.Close()
+		return i18n.Errorf("failed to download %s: %w", artifact.URL, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	if expected := normalizeChecksum(artifact.SHA256); expected != "" && !strings.EqualFold(actual, expected) {
+		return i18n.Errorf("checksum mismatch for %s: expected %s got %s", artifact.URL, expected, actual)
+	}
+	return os.Rename(tmp.Name(), dest)
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
 )
 
+const checkTimeout = 5 * time.Second
+
 func Check(name string) error {
 	normalized := strings.ToLower(name)
 	moduleDir, err := resolveModuleDir(normalized)
@@ -20,6 +22,25 @@ func Check(name string) error {
 	return runModuleCheck(name, moduleDir)
 }
 
+// FindModuleDir returns the absolute directory of the named module.
+func FindModuleDir(name string) (string, error) {
+	return resolveModuleDir(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// AvailableVersion returns the manifest version of the named module, which
+// is the version Install provides.
+func AvailableVersion(name string) (string, error) {
+	moduleDir, err := FindModuleDir(name)
+	if err != nil {
+		return "", err
+	}
+	record, err := LoadModuleRecord(filepath.Join(moduleDir, "manifest.yaml"))
+	if err != nil {
+		return "", i18n.Errorf("load module manifest for %q: %w", name, err)
+	}
+	return record.Manifest.Version, nil
+}
+
 func resolveModuleDir(name string) (string, error) {
 	roots := []string{"."}
 	if exePath, err := os.Executable(); err == nil {
@@ -43,8 +64,12 @@ func resolveModuleDir(name string) (string, error) {
 }
 
 func runModuleCheck(name, moduleDir string) error {
-	script_path := filepath.Join(moduleDir, "scripts")
-	verifyScript := filepath.Join(script_path, "verify.sh")
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	return runVerifyScript(ctx, name, filepath.Join(moduleDir, "scripts", "verify.sh"))
+}
+
+func runVerifyScript(ctx context.Context, name, verifyScript string) error {
 	if _, err := os.Stat(verifyScript); err != nil {
 		if os.IsNotExist(err) {
 			return i18n.Errorf("module %q does not provide a check script", name)
@@ -52,9 +77,6 @@ func runModuleCheck(name, moduleDir string) error {
 		return i18n.Errorf("failed to read module check script for %q: %w", name, err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
 	cmd := exec.CommandContext(ctx, "bash", verifyScript)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
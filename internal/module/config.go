@@ -0,0 +1,406 @@
+package module
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/config"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"gopkg.in/yaml.v3"
+)
+
+const moduleConfigFileName = "config.yaml"
+
+// configSchemaEntry refines the value of a configuration.defaults key. The
+// type is inferred from the default value when it is not declared. Apply is
+// an optional shell command run from the module directory after the value
+// changes; it may reference configuration variables.
+type configSchemaEntry struct {
+	Type        string   `yaml:"type"`
+	Enum        []string `yaml:"enum"`
+	Pattern     string   `yaml:"pattern"`
+	Description string   `yaml:"description"`
+	Apply       string   `yaml:"apply"`
+}
+
+var validConfigTypes = map[string]struct{}{"string": {}, "int": {}, "bool": {}, "float": {}}
+
+// ConfigValue is the effective value of a module configuration key.
+type ConfigValue struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Default     string `json:"default"`
+	Type        string `json:"type"`
+	Overridden  bool   `json:"overridden"`
+	Description string `json:"description,omitempty"`
+}
+
+// ConfigApplyOptions controls what happens after a configuration change.
+type ConfigApplyOptions struct {
+	Restart bool
+}
+
+// ConfigApplyResult reports the side effects of a configuration change.
+type ConfigApplyResult struct {
+	Applied   []string `json:"applied,omitempty"`
+	Rendered  []string `json:"rendered,omitempty"`
+	Restarted string   `json:"restarted,omitempty"`
+	Installed bool     `json:"installed"`
+}
+
+// ModuleDataDir returns the writable directory that holds per-module state.
+// It prefers control.data_dir and falls back to ~/.localaistack.
+func ModuleDataDir() (string, error) {
+	var candidates []string
+	if cfg, err := config.LoadConfig(); err == nil && strings.TrimSpace(cfg.Control.DataDir) != "" {
+		candidates = append(candidates, cfg.Control.DataDir)
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		candidates = append(candidates, filepath.Join(home, config.DefaultConfigDirName))
+	}
+	for _, candidate := range candidates {
+		dir := filepath.Join(candidate, "modules")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			continue
+		}
+		if !needsSudo(dir) {
+			return dir, nil
+		}
+	}
+	return "", i18n.Errorf("module data directory not available")
+}
+
+// GetConfig returns every configuration key of a module with its effective
+// value, sorted by key.
+func GetConfig(name string) ([]ConfigValue, error) {
+	normalized, _, spec, err := loadModuleConfigSpec(name)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := loadStoredConfig(normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := flattenDefaults(spec.Configuration.Defaults)
+	values := make([]ConfigValue, 0, len(spec.Configuration.Defaults))
+	for key := range spec.Configuration.Defaults {
+		value := ConfigValue{
+			Key:         key,
+			Default:     defaults[key],
+			Value:       defaults[key],
+			Type:        configKeyType(spec.Configuration, key),
+			Description: spec.Configuration.Schema[key].Description,
+		}
+		if override, ok := stored[key]; ok {
+			value.Value = override
+			value.Overridden = true
+		}
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Key < values[j].Key })
+	return values, nil
+}
+
+// SetConfig validates and persists configuration overrides, then applies them
+// to an installed module.
+func SetConfig(name string, values map[string]string, opts ConfigApplyOptions) (ConfigApplyResult, error) {
+	normalized, moduleDir, spec, err := loadModuleConfigSpec(name)
+	if err != nil {
+		return ConfigApplyResult{}, err
+	}
+	if len(values) == 0 {
+		return ConfigApplyResult{}, i18n.Errorf("at least one key=value is required")
+	}
+	for key, value := range values {
+		if err := validateConfigValue(spec.Configuration, key, value); err != nil {
+			return ConfigApplyResult{}, err
+		}
+	}
+
+	stored, err := loadStoredConfig(normalized)
+	if err != nil {
+		return ConfigApplyResult{}, err
+	}
+	previous := copyStoredConfig(stored)
+	changed := make([]string, 0, len(values))
+	for key, value := range values {
+		if current, ok := stored[key]; ok && current == value {
+			continue
+		}
+		stored[key] = value
+		changed = append(changed, key)
+	}
+	return saveAndApplyConfig(normalized, moduleDir, spec, previous, stored, changed, opts)
+}
+
+// UnsetConfig removes configuration overrides so the keys fall back to their
+// defaults, then applies the result to an installed module.
+func UnsetConfig(name string, keys []string, opts ConfigApplyOptions) (ConfigApplyResult, error) {
+	normalized, moduleDir, spec, err := loadModuleConfigSpec(name)
+	if err != nil {
+		return ConfigApplyResult{}, err
+	}
+	if len(keys) == 0 {
+		return ConfigApplyResult{}, i18n.Errorf("at least one key is required")
+	}
+	stored, err := loadStoredConfig(normalized)
+	if err != nil {
+		return ConfigApplyResult{}, err
+	}
+	previous := copyStoredConfig(stored)
+	changed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := spec.Configuration.Defaults[key]; !ok {
+			return ConfigApplyResult{}, i18n.Errorf("unknown configuration key %q for module %q", key, normalized)
+		}
+		if _, ok := stored[key]; ok {
+			delete(stored, key)
+			changed = append(changed, key)
+		}
+	}
+	return saveAndApplyConfig(normalized, moduleDir, spec, previous, stored, changed, opts)
+}
+
+// saveAndApplyConfig persists stored and applies the changed keys. The apply
+// hooks read the persisted overrides, so they are saved first and restored to
+// previous when applying fails; otherwise a retry would find nothing changed.
+func saveAndApplyConfig(moduleName, moduleDir string, spec moduleInstallSpec, previous, stored map[string]string, changed []string, opts ConfigApplyOptions) (ConfigApplyResult, error) {
+	if err := saveStoredConfig(moduleName, stored); err != nil {
+		return ConfigApplyResult{}, err
+	}
+	result, err := applyModuleConfig(moduleName, moduleDir, spec, changed, opts)
+	if err != nil {
+		if restoreErr := saveStoredConfig(moduleName, previous); restoreErr != nil {
+			return result, i18n.Errorf("%w (restoring the previous configuration failed: %v)", err, restoreErr)
+		}
+		return result, err
+	}
+	return result, nil
+}
+
+func copyStoredConfig(stored map[string]string) map[string]string {
+	copied := make(map[string]string, len(stored))
+	for key, value := range stored {
+		copied[key] = value
+	}
+	return copied
+}
+
+func loadModuleConfigSpec(name string) (string, string, moduleInstallSpec, error) {
+	normalized, moduleDir, raw, err := readModulePlan(name)
+	if err != nil {
+		return "", "", moduleInstallSpec{}, err
+	}
+	var spec moduleInstallSpec
+	if err := yaml.Unmarshal(raw, &spec); err != nil {
+		return "", "", moduleInstallSpec{}, i18n.Errorf("failed to parse install plan for module %q: %w", normalized, err)
+	}
+	return normalized, moduleDir, spec, nil
+}
+
+// builtinConfigVars are variables that step values and templates may use
+// without declaring them in configuration.defaults: arch is the Go name of
+// the host architecture, e.g. amd64 or arm64.
+var builtinConfigVars = map[string]string{"arch": runtime.GOARCH}
+
+// moduleConfigVars returns the built-in variables and configuration.defaults
+// overlaid with the values persisted by `las module config set`.
+func moduleConfigVars(moduleName string, cfg installConfiguration) (map[string]string, error) {
+	vars := flattenDefaults(cfg.Defaults)
+	for key, value := range builtinConfigVars {
+		if _, ok := vars[key]; !ok {
+			vars[key] = value
+		}
+	}
+	if _, err := ModuleDataDir(); err != nil {
+		// Without a data directory there can be no overrides.
+		return vars, nil
+	}
+	stored, err := loadStoredConfig(moduleName)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range stored {
+		if _, ok := cfg.Defaults[key]; ok {
+			vars[key] = value
+		}
+	}
+	return vars, nil
+}
+
+func storedConfigPath(moduleName string) (string, error) {
+	dataDir, err := ModuleDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, moduleName, moduleConfigFileName), nil
+}
+
+func loadStoredConfig(moduleName string) (map[string]string, error) {
+	path, err := storedConfigPath(moduleName)
+	if err != nil {
+		return nil, err
+	}
+	stored := map[string]string{}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stored, nil
+		}
+		return nil, i18n.Errorf("failed to read module config %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(raw, &stored); err != nil {
+		return nil, i18n.Errorf("failed to parse module config %s: %w", path, err)
+	}
+	if stored == nil {
+		stored = map[string]string{}
+	}
+	return stored, nil
+}
+
+func saveStoredConfig(moduleName string, stored map[string]string) error {
+	path, err := storedConfigPath(moduleName)
+	if err != nil {
+		return err
+	}
+	if len(stored) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	raw, err := yaml.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func configKeyType(cfg installConfiguration, key string) string {
+	if declared := strings.ToLower(strings.TrimSpace(cfg.Schema[key].Type)); declared != "" {
+		return declared
+	}
+	switch cfg.Defaults[key].(type) {
+	case int, int64, uint64:
+		return "int"
+	case bool:
+		return "bool"
+	case float64:
+		return "float"
+	default:
+		return "string"
+	}
+}
+
+func validateConfigValue(cfg installConfiguration, key, value string) error {
+	if _, ok := cfg.Defaults[key]; !ok {
+		return i18n.Errorf("unknown configuration key %q", key)
+	}
+	switch configKeyType(cfg, key) {
+	case "int":
+		if _, err := strconv.Atoi(value); err != nil {
+			return i18n.Errorf("configuration key %q expects an integer, got %q", key, value)
+		}
+	case "bool":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return i18n.Errorf("configuration key %q expects a boolean, got %q", key, value)
+		}
+	case "float":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return i18n.Errorf("configuration key %q expects a number, got %q", key, value)
+		}
+	}
+	schema := cfg.Schema[key]
+	if len(schema.Enum) > 0 && !containsString(schema.Enum, value) {
+		return i18n.Errorf("configuration key %q must be one of %s, got %q", key, strings.Join(schema.Enum, ", "), value)
+	}
+	if schema.Pattern != "" {
+		pattern, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			return i18n.Errorf("configuration key %q has an invalid pattern: %w", key, err)
+		}
+		if !pattern.MatchString(value) {
+			return i18n.Errorf("configuration key %q does not match %s, got %q", key, schema.Pattern, value)
+		}
+	}
+	return nil
+}
+
+// applyModuleConfig runs the apply hooks of changed keys and, when the module
+// is installed, re-runs the template and service steps of its install mode so
+// rendered files pick up the new values.
+func applyModuleConfig(moduleName, moduleDir string, spec moduleInstallSpec, changed []string, opts ConfigApplyOptions) (ConfigApplyResult, error) {
+	result := ConfigApplyResult{}
+	if len(changed) == 0 && !opts.Restart {
+		return result, nil
+	}
+	vars, err := moduleConfigVars(moduleName, spec.Configuration)
+	if err != nil {
+		return result, err
+	}
+
+	sort.Strings(changed)
+	for _, key := range changed {
+		apply := strings.TrimSpace(spec.Configuration.Schema[key].Apply)
+		if apply == "" || strings.TrimSpace(vars[key]) == "" {
+			continue
+		}
+		command, _ := renderTemplate(apply, vars)
+		if _, _, err := runShellCommand(command, moduleDir, false); err != nil {
+			return result, i18n.Errorf("failed to apply configuration key %q: %w", key, err)
+		}
+		result.Applied = append(result.Applied, key)
+	}
+
+	if err := runModuleCheck(moduleName, moduleDir); err != nil {
+		return result, nil
+	}
+	result.Installed = true
+
+	mode, _ := selectInstallModeForSystem(moduleName, spec)
+	serviceName := ""
+	for _, step := range spec.Install[mode] {
+		tool := strings.TrimSpace(step.Tool)
+		if tool != "template" && !isServiceStep(step) {
+			continue
+		}
+		if tool == "systemd_unit" {
+			// Restarts are driven by opts.Restart below, not by unit changes.
+			noStart := false
+			step.SystemdUnit.Start = &noStart
+			serviceName = systemdUnitName(moduleName, step.SystemdUnit)
+		} else if strings.TrimSpace(step.Expected.Service) != "" && serviceName == "" {
+			serviceName = moduleName
+		}
+		step.Expected.Service = ""
+		if err := runInstallStep(context.Background(), moduleName, moduleDir, step, vars, nil); err != nil {
+			return result, err
+		}
+		result.Rendered = append(result.Rendered, step.ID)
+	}
+
+	if opts.Restart {
+		if serviceName == "" {
+			return result, i18n.Errorf("module %q does not manage a service", moduleName)
+		}
+		if err := runSystemctl(context.Background(), moduleDir, nil, needsSudo(defaultSystemdUnitDir), "restart", serviceName); err != nil {
+			return result, i18n.Errorf("failed to restart %s: %w", serviceName, err)
+		}
+		result.Restarted = serviceName
+	}
+	return result, nil
+}
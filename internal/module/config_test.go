@@ -0,0 +1,220 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const configTestInstallPlan = `apiVersion: las.installspec/v0.1.2
+kind: InstallPlan
+id: demo
+category: tool
+install_modes:
+  - native
+decision_matrix:
+  default: native
+install:
+  native:
+    - id: S10
+      intent: Install
+      tool: shell
+      command: bash scripts/install.sh
+      expected:
+        exit_code: 0
+      idempotent: true
+    - id: S20
+      intent: Render config
+      tool: template
+      edit:
+        template: templates/demo.conf.tmpl
+        destination: rendered/demo.conf
+      expected:
+        unit: rendered/demo.conf
+      idempotent: true
+configuration:
+  defaults:
+    bind: 127.0.0.1:8080
+    workers: 2
+    model: ""
+  schema:
+    bind:
+      pattern: ^[^:]+:[0-9]+$
+    model:
+      enum: [small, large]
+      apply: echo "{{ model }}" > applied.txt
+`
+
+func setupConfigModule(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	moduleDir := filepath.Join(root, "modules", "demo")
+	for _, dir := range []string{"scripts", "templates"} {
+		if err := os.MkdirAll(filepath.Join(moduleDir, dir), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	files := map[string]string{
+		"manifest.yaml":            lintTestManifest,
+		"INSTALL.yaml":             configTestInstallPlan,
+		"scripts/install.sh":       "#!/usr/bin/env bash\n",
+		"scripts/verify.sh":        "#!/usr/bin/env bash\ntest -f \"$(dirname \"$0\")/../installed\"\n",
+		"templates/demo.conf.tmpl": "listen={{ bind }} workers={{ workers }}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(moduleDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	t.Chdir(root)
+	t.Setenv("HOME", root)
+	t.Setenv("LOCALAISTACK_CONTROL_DATA_DIR", filepath.Join(root, "data"))
+	return moduleDir
+}
+
+func TestSetConfig_PersistsAndValidates(t *testing.T) {
+	setupConfigModule(t)
+
+	for _, values := range []map[string]string{
+		{"unknown": "x"},
+		{"workers": "many"},
+		{"bind": "8080"},
+		{"model": "medium"},
+	} {
+		if _, err := SetConfig("demo", values, ConfigApplyOptions{}); err == nil {
+			t.Fatalf("expected validation error for %v", values)
+		}
+	}
+
+	result, err := SetConfig("demo", map[string]string{"bind": "0.0.0.0:9000", "workers": "4"}, ConfigApplyOptions{})
+	if err != nil {
+		t.Fatalf("SetConfig returned error: %v", err)
+	}
+	if result.Installed {
+		t.Fatalf("module should not be reported as installed")
+	}
+
+	values, err := GetConfig("demo")
+	if err != nil {
+		t.Fatalf("GetConfig returned error: %v", err)
+	}
+	got := map[string]ConfigValue{}
+	for _, value := range values {
+		got[value.Key] = value
+	}
+	if got["bind"].Value != "0.0.0.0:9000" || !got["bind"].Overridden || got["workers"].Type != "int" {
+		t.Fatalf("unexpected config values %+v", values)
+	}
+	if got["model"].Overridden || got["model"].Value != "" {
+		t.Fatalf("unexpected model value %+v", got["model"])
+	}
+
+	if _, err := UnsetConfig("demo", []string{"bind"}, ConfigApplyOptions{}); err != nil {
+		t.Fatalf("UnsetConfig returned error: %v", err)
+	}
+	plan, err := PlanInstall("demo")
+	if err != nil {
+		t.Fatalf("PlanInstall returned error: %v", err)
+	}
+	if plan.Variables["bind"] != "127.0.0.1:8080" || plan.Variables["workers"] != "4" {
+		t.Fatalf("unexpected install variables %+v", plan.Variables)
+	}
+}
+
+func TestSetConfig_RerendersInstalledModuleAndRunsApplyHook(t *testing.T) {
+	moduleDir := setupConfigModule(t)
+	if err := os.WriteFile(filepath.Join(moduleDir, "installed"), nil, 0o644); err != nil {
+		t.Fatalf("write marker: %v", err)
+	}
+
+	result, err := SetConfig("demo", map[string]string{"bind": "0.0.0.0:9000", "model": "large"}, ConfigApplyOptions{})
+	if err != nil {
+		t.Fatalf("SetConfig returned error: %v", err)
+	}
+	if !result.Installed || strings.Join(result.Rendered, ",") != "S20" || strings.Join(result.Applied, ",") != "model" {
+		t.Fatalf("unexpected apply result %+v", result)
+	}
+	rendered, err := os.ReadFile(filepath.Join(moduleDir, "rendered", "demo.conf"))
+	if err != nil || string(rendered) != "listen=0.0.0.0:9000 workers=2\n" {
+		t.Fatalf("unexpected rendered config %q: %v", rendered, err)
+	}
+	applied, err := os.ReadFile(filepath.Join(moduleDir, "applied.txt"))
+	if err != nil || strings.TrimSpace(string(applied)) != "large" {
+		t.Fatalf("unexpected apply hook output %q: %v", applied, err)
+	}
+
+	if _, err := SetConfig("demo", map[string]string{"bind": "0.0.0.0:9001"}, ConfigApplyOptions{Restart: true}); err == nil {
+		t.Fatalf("expected restart to fail for a module without a service")
+	}
+}
+
+func TestSetConfig_RetriesAfterFailedApply(t *testing.T) {
+	moduleDir := setupConfigModule(t)
+	// The hook's redirect fails while applied.txt is a directory.
+	blocker := filepath.Join(moduleDir, "applied.txt")
+	if err := os.Mkdir(blocker, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if _, err := SetConfig("demo", map[string]string{"model": "large"}, ConfigApplyOptions{}); err == nil {
+		t.Fatalf("expected the apply hook to fail")
+	}
+	values, err := GetConfig("demo")
+	if err != nil {
+		t.Fatalf("GetConfig returned error: %v", err)
+	}
+	for _, value := range values {
+		if value.Key == "model" && value.Overridden {
+			t.Fatalf("a failed apply left the override stored: %+v", value)
+		}
+	}
+
+	if err := os.Remove(blocker); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	result, err := SetConfig("demo", map[string]string{"model": "large"}, ConfigApplyOptions{})
+	if err != nil {
+		t.Fatalf("SetConfig returned error: %v", err)
+	}
+	if strings.Join(result.Applied, ",") != "model" {
+		t.Fatalf("expected the retry to run the apply hook, got %+v", result)
+	}
+	applied, err := os.ReadFile(blocker)
+	if err != nil || strings.TrimSpace(string(applied)) != "large" {
+		t.Fatalf("unexpected apply hook output %q: %v", applied, err)
+	}
+}
+
+func TestSetting_UsesTypedConfigForApplyHook(t *testing.T) {
+	moduleDir := setupConfigModule(t)
+
+	if err := Setting("demo", []string{"small"}); err != nil {
+		t.Fatalf("Setting returned error: %v", err)
+	}
+	applied, err := os.ReadFile(filepath.Join(moduleDir, "applied.txt"))
+	if err != nil || strings.TrimSpace(string(applied)) != "small" {
+		t.Fatalf("unexpected apply hook output %q: %v", applied, err)
+	}
+	if err := Setting("demo", []string{"huge"}); err == nil {
+		t.Fatalf("expected enum validation error")
+	}
+}
+
+func TestSetting_WithoutArgumentsRunsSettingScript(t *testing.T) {
+	moduleDir := setupConfigModule(t)
+	script := "#!/usr/bin/env bash\necho \"$# args\" > setting.out\n"
+	if err := os.WriteFile(filepath.Join(moduleDir, "scripts", "setting.sh"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write setting.sh: %v", err)
+	}
+
+	if err := Setting("demo", nil); err != nil {
+		t.Fatalf("Setting returned error: %v", err)
+	}
+	out, err := os.ReadFile(filepath.Join(moduleDir, "setting.out"))
+	if err != nil || strings.TrimSpace(string(out)) != "0 args" {
+		t.Fatalf("expected setting.sh to run without arguments, got %q: %v", out, err)
+	}
+	if _, err := os.Stat(filepath.Join(moduleDir, "applied.txt")); !os.IsNotExist(err) {
+		t.Fatalf("a bare setting should not run the apply hook")
+	}
+}
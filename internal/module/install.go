@@ -26,6 +26,7 @@ type moduleInstallSpec struct {
 	Preconditions  []installPrecondition    `yaml:"preconditions"`
 	Install        map[string][]installStep `yaml:"install"`
 	Configuration  installConfiguration     `yaml:"configuration"`
+	Verification   installPlanScript        `yaml:"verification"`
 }
 
 type installDecisionMatrix struct {
@@ -33,7 +34,8 @@ type installDecisionMatrix struct {
 }
 
 type installConfiguration struct {
-	Defaults map[string]any `yaml:"defaults"`
+	Defaults map[string]any               `yaml:"defaults"`
+	Schema   map[string]configSchemaEntry `yaml:"schema"`
 }
 
 type installPrecondition struct {
@@ -45,13 +47,19 @@ type installPrecondition struct {
 }
 
 type installStep struct {
-	ID         string        `yaml:"id"`
-	Intent     string        `yaml:"intent"`
-	Tool       string        `yaml:"tool"`
-	Command    string        `yaml:"command"`
-	Edit       installEdit   `yaml:"edit"`
-	Expected   installExpect `yaml:"expected"`
-	Idempotent bool          `yaml:"idempotent"`
+	ID          string             `yaml:"id"`
+	Intent      string             `yaml:"intent"`
+	Tool        string             `yaml:"tool"`
+	Command     string             `yaml:"command"`
+	Edit        installEdit        `yaml:"edit"`
+	Download    installDownload    `yaml:"download"`
+	Extract     installExtract     `yaml:"extract"`
+	PythonVenv  installPythonVenv  `yaml:"python_venv"`
+	SystemdUnit installSystemdUnit `yaml:"systemd_unit"`
+	Expected    installExpect      `yaml:"expected"`
+	Idempotent  bool               `yaml:"idempotent"`
+	OnFail      string             `yaml:"on_fail"`
+	Timeout     string             `yaml:"timeout"`
 }
 
 type installEdit struct {
@@ -65,6 +73,7 @@ type installExpect struct {
 	Bin      string `yaml:"bin"`
 	Unit     string `yaml:"unit"`
 	Service  string `yaml:"service"`
+	Path     string `yaml:"path"`
 }
 
 type llmInstallPlan struct {
@@ -73,6 +82,14 @@ type llmInstallPlan struct {
 }
 
 func Install(name string) error {
+	return InstallWithContext(context.Background(), name, RunOptions{})
+}
+
+// InstallWithContext installs a module, reporting each precondition and step
+// through opts. Cancelling ctx stops the running step.
+func InstallWithContext(ctx context.Context, name string, opts RunOptions) error {
+	ctx = withRunOutput(ctx, opts.Output)
+	ctx = withDownloadProgress(ctx, opts.Download)
 	normalized := strings.ToLower(strings.TrimSpace(name))
 	if normalized == "" {
 		return i18n.Errorf("module name is required")
@@ -96,8 +113,14 @@ func Install(name string) error {
 		return i18n.Errorf("failed to parse install plan for module %q: %w", normalized, err)
 	}
 
-	if err := runPreconditions(spec.Preconditions, moduleDir); err != nil {
-		return err
+	reporter := stepReporter{opts: opts, module: normalized, action: PlanActionInstall, total: len(spec.Preconditions)}
+	for i, pre := range spec.Preconditions {
+		if err := reporter.run(i, pre.ID, pre.Intent, func() error {
+			_, err := checkPrecondition(pre, moduleDir)
+			return err
+		}); err != nil {
+			return err
+		}
 	}
 
 	mode, env := selectInstallModeForSystem(normalized, spec)
@@ -109,7 +132,7 @@ func Install(name string) error {
 	planMode := mode
 	planSteps := steps
 	if cfg, cfgErr := config.LoadConfig(); cfgErr == nil {
-		if llmPlan, err := interpretInstallPlanWithLLM(cfg.LLM, normalized, string(raw), mode, steps); err == nil {
+		if llmPlan, err := interpretInstallPlanWithLLM(ctx, cfg.LLM, normalized, string(raw), mode, steps); err == nil {
 			if strings.TrimSpace(llmPlan.Mode) != "" {
 				planMode = strings.TrimSpace(llmPlan.Mode)
 			}
@@ -129,9 +152,19 @@ func Install(name string) error {
 	}
 	planSteps = ensureServiceSteps(planSteps, steps)
 
-	vars := flattenDefaults(spec.Configuration.Defaults)
-	for _, step := range planSteps {
-		if err := runInstallStep(normalized, moduleDir, step, vars, env); err != nil {
+	vars, err := moduleConfigVars(normalized, spec.Configuration)
+	if err != nil {
+		return err
+	}
+	offset := len(spec.Preconditions)
+	reporter.total = offset + len(planSteps)
+	for i, step := range planSteps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := reporter.run(offset+i, step.ID, step.Intent, func() error {
+			return runInstallStep(ctx, normalized, moduleDir, step, vars, env)
+		}); err != nil {
 			return err
 		}
 	}
@@ -140,29 +173,36 @@ func Install(name string) error {
 
 func runPreconditions(preconditions []installPrecondition, moduleDir string) error {
 	for _, pre := range preconditions {
-		tool := strings.TrimSpace(pre.Tool)
-		if tool == "" {
-			continue
+		if _, err := checkPrecondition(pre, moduleDir); err != nil {
+			return err
 		}
-		switch tool {
-		case "shell":
-			output, exitCode, err := runShellCommand(pre.Command, moduleDir, false)
-			if err != nil {
-				return i18n.Errorf("precondition %s failed: %w", pre.ID, err)
-			}
-			if pre.Expected.ExitCode != nil && exitCode != *pre.Expected.ExitCode {
-				return i18n.Errorf("precondition %s failed: expected exit code %d but got %d", pre.ID, *pre.Expected.ExitCode, exitCode)
-			}
-			if pre.Expected.Equals != "" {
-				if normalizedOutput(output) != normalizedOutput(pre.Expected.Equals) {
-					return i18n.Errorf("precondition %s failed: expected %q but got %q", pre.ID, normalizedOutput(pre.Expected.Equals), normalizedOutput(output))
-				}
+	}
+	return nil
+}
+
+func checkPrecondition(pre installPrecondition, moduleDir string) (string, error) {
+	tool := strings.TrimSpace(pre.Tool)
+	if tool == "" {
+		return "", nil
+	}
+	switch tool {
+	case "shell":
+		output, exitCode, err := runShellCommand(pre.Command, moduleDir, false)
+		if err != nil {
+			return output, i18n.Errorf("precondition %s failed: %w", pre.ID, err)
+		}
+		if pre.Expected.ExitCode != nil && exitCode != *pre.Expected.ExitCode {
+			return output, i18n.Errorf("precondition %s failed: expected exit code %d but got %d", pre.ID, *pre.Expected.ExitCode, exitCode)
+		}
+		if pre.Expected.Equals != "" {
+			if normalizedOutput(output) != normalizedOutput(pre.Expected.Equals) {
+				return output, i18n.Errorf("precondition %s failed: expected %q but got %q", pre.ID, normalizedOutput(pre.Expected.Equals), normalizedOutput(output))
 			}
-		default:
-			return i18n.Errorf("precondition %s uses unsupported tool %q", pre.ID, tool)
 		}
+		return output, nil
+	default:
+		return "", i18n.Errorf("precondition %s uses unsupported tool %q", pre.ID, tool)
 	}
-	return nil
 }
 
 func selectInstallMode(spec moduleInstallSpec) string {
@@ -203,7 +243,7 @@ func selectInstallModeForSystem(moduleName string, spec moduleInstallSpec) (stri
 	return mode, env
 }
 
-func interpretInstallPlanWithLLM(cfg config.LLMConfig, moduleName, installYAML, mode string, steps []installStep) (llmInstallPlan, error) {
+func interpretInstallPlanWithLLM(ctx context.Context, cfg config.LLMConfig, moduleName, installYAML, mode string, steps []installStep) (llmInstallPlan, error) {
 	registry, err := llm.NewRegistryFromConfig(cfg)
 	if err != nil {
 		return llmInstallPlan{}, err
@@ -226,7 +266,7 @@ Available step IDs: %s
 INSTALL.yaml:
 %s`, moduleName, mode, strings.Join(stepIDs, ", "), installYAML)
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.TimeoutSeconds)*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.TimeoutSeconds)*time.Second)
 	defer cancel()
 
 	resp, err := provider.Generate(ctx, llm.Request{Prompt: prompt, Model: cfg.Model, Timeout: cfg.TimeoutSeconds})
@@ -292,13 +332,30 @@ func ensureServiceSteps(selected, all []installStep) []installStep {
 }
 
 func isServiceStep(step installStep) bool {
-	return strings.TrimSpace(step.Expected.Unit) != "" || strings.TrimSpace(step.Expected.Service) != ""
+	return strings.TrimSpace(step.Tool) == "systemd_unit" || strings.TrimSpace(step.Expected.Unit) != "" || strings.TrimSpace(step.Expected.Service) != ""
 }
 
-func runInstallStep(moduleName, moduleDir string, step installStep, vars map[string]string, env map[string]string) error {
+var installStepTools = map[string]struct{}{
+	"shell":        {},
+	"template":     {},
+	"download":     {},
+	"extract":      {},
+	"python_venv":  {},
+	"systemd_unit": {},
+}
+
+// IsKnownInstallTool reports whether tool can be used by an install step.
+func IsKnownInstallTool(tool string) bool {
+	_, ok := installStepTools[strings.TrimSpace(tool)]
+	return ok
+}
+
+func runInstallStep(ctx context.Context, moduleName, moduleDir string, step installStep, vars map[string]string, env map[string]string) error {
+	serviceName := moduleName
 	switch strings.TrimSpace(step.Tool) {
 	case "shell":
-		output, exitCode, err := runShellCommandWithEnv(step.Command, moduleDir, true, env)
+		command, _ := renderTemplate(step.Command, vars)
+		output, exitCode, err := runShellCommandWithEnv(ctx, command, moduleDir, true, env)
 		if err != nil {
 			return i18n.Errorf("install step %s failed: %w", step.ID, err)
 		}
@@ -314,27 +371,46 @@ func runInstallStep(moduleName, moduleDir string, step installStep, vars map[str
 		if err := runTemplateStep(moduleDir, step.Edit, vars); err != nil {
 			return i18n.Errorf("install step %s failed: %w", step.ID, err)
 		}
+	case "download":
+		if err := runDownloadStep(ctx, moduleDir, step.Download, vars); err != nil {
+			return i18n.Errorf("install step %s failed: %w", step.ID, err)
+		}
+	case "extract":
+		if err := runExtractStep(moduleDir, step.Extract, vars); err != nil {
+			return i18n.Errorf("install step %s failed: %w", step.ID, err)
+		}
+	case "python_venv":
+		if err := runPythonVenvStep(ctx, moduleDir, step.PythonVenv, vars, env); err != nil {
+			return i18n.Errorf("install step %s failed: %w", step.ID, err)
+		}
+	case "systemd_unit":
+		if err := runSystemdUnitStep(ctx, moduleName, moduleDir, step.SystemdUnit, vars, env); err != nil {
+			return i18n.Errorf("install step %s failed: %w", step.ID, err)
+		}
+		serviceName = strings.TrimSuffix(systemdUnitName(moduleName, step.SystemdUnit), ".service")
 	default:
 		return i18n.Errorf("install step %s uses unsupported tool %q", step.ID, step.Tool)
 	}
 
-	if err := validateExpected(moduleName, moduleDir, step.Expected); err != nil {
+	if err := validateExpected(serviceName, moduleDir, step.Expected, vars); err != nil {
 		return i18n.Errorf("install step %s failed: %w", step.ID, err)
 	}
 	return nil
 }
 
 func runShellCommand(command, moduleDir string, stream bool) (string, int, error) {
-	return runShellCommandWithEnv(command, moduleDir, stream, nil)
+	return runShellCommandWithEnv(context.Background(), command, moduleDir, stream, nil)
 }
 
-func runShellCommandWithEnv(command, moduleDir string, stream bool, env map[string]string) (string, int, error) {
-	cmd := exec.Command("bash", "-c", command)
+func runShellCommandWithEnv(ctx context.Context, command, moduleDir string, stream bool, env map[string]string) (string, int, error) {
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
 	cmd.Dir = moduleDir
 	cmd.Env = commandEnv(env)
+	// Background children may keep the output pipes open after a cancel.
+	cmd.WaitDelay = 5 * time.Second
 	if stream {
 		var buffer bytes.Buffer
-		writer := io.MultiWriter(&buffer, os.Stdout)
+		writer := io.MultiWriter(&buffer, runOutput(ctx))
 		cmd.Stdout = writer
 		cmd.Stderr = writer
 		err := cmd.Run()
@@ -470,7 +546,7 @@ func runTemplateStep(moduleDir string, edit installEdit, vars map[string]string)
 	if err != nil {
 		return err
 	}
-	destPath := strings.TrimSpace(edit.Destination)
+	destPath, _ := renderTemplate(strings.TrimSpace(edit.Destination), vars)
 	if destPath == "" {
 		return i18n.Errorf("template destination is required")
 	}
@@ -512,6 +588,23 @@ func renderTemplate(content string, vars map[string]string) (string, error) {
 	return result, nil
 }
 
+// undefinedTemplateVars lists the variables of {{ name }} placeholders
+// without a default that vars does not define. renderTemplate renders them
+// empty.
+func undefinedTemplateVars(content string, vars map[string]string) []string {
+	pattern := regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*\}\}`)
+	seen := make(map[string]bool)
+	var undefined []string
+	for _, match := range pattern.FindAllStringSubmatch(content, -1) {
+		if _, ok := vars[match[1]]; ok || seen[match[1]] {
+			continue
+		}
+		seen[match[1]] = true
+		undefined = append(undefined, match[1])
+	}
+	return undefined
+}
+
 func flattenDefaults(defaults map[string]any) map[string]string {
 	vars := make(map[string]string)
 	for key, value := range defaults {
@@ -523,21 +616,10 @@ func flattenDefaults(defaults map[string]any) map[string]string {
 	return vars
 }
 
-func validateExpected(moduleName, moduleDir string, expect installExpect) error {
+func validateExpected(moduleName, moduleDir string, expect installExpect, vars map[string]string) error {
 	if expect.Bin != "" {
-		bin := strings.TrimSpace(expect.Bin)
-		if filepath.IsAbs(bin) {
-			if _, err := os.Stat(bin); err != nil {
-				// Some installers place binaries in a different PATH location (e.g. /usr/bin).
-				// Fall back to PATH lookup for the same command name.
-				if _, lookupErr := exec.LookPath(filepath.Base(bin)); lookupErr != nil {
-					return err
-				}
-			}
-		} else {
-			if _, err := exec.LookPath(bin); err != nil {
-				return err
-			}
+		if err := checkExpectedBin(expect.Bin); err != nil {
+			return err
 		}
 	}
 	if expect.Unit != "" {
@@ -549,6 +631,11 @@ func validateExpected(moduleName, moduleDir string, expect installExpect) error
 			return err
 		}
 	}
+	if expect.Path != "" {
+		if _, err := os.Stat(resolveStepPath(moduleDir, renderStepValue(expect.Path, vars))); err != nil {
+			return err
+		}
+	}
 	if strings.TrimSpace(expect.Service) != "" {
 		statusCmd := exec.Command("systemctl", "is-active", moduleName)
 		output, err := statusCmd.CombinedOutput()
@@ -561,3 +648,21 @@ func validateExpected(moduleName, moduleDir string, expect installExpect) error
 	}
 	return nil
 }
+
+// checkExpectedBin reports whether an expected.bin entry is present, either
+// at its absolute path or as a command on PATH.
+func checkExpectedBin(bin string) error {
+	bin = strings.TrimSpace(bin)
+	if filepath.IsAbs(bin) {
+		if _, err := os.Stat(bin); err != nil {
+			// Some installers place binaries in a different PATH location (e.g. /usr/bin).
+			// Fall back to PATH lookup for the same command name.
+			if _, lookupErr := exec.LookPath(filepath.Base(bin)); lookupErr != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	_, err := exec.LookPath(bin)
+	return err
+}
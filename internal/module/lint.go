@@ -0,0 +1,659 @@
+package module
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	InstallSpecAPIVersion = "las.installspec/v0.1.2"
+	InstallSpecKind       = "InstallPlan"
+)
+
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+)
+
+type LintIssue struct {
+	Severity LintSeverity `json:"severity"`
+	File     string       `json:"file"`
+	Field    string       `json:"field,omitempty"`
+	Message  string       `json:"message"`
+}
+
+type LintReport struct {
+	Module string      `json:"module"`
+	Dir    string      `json:"dir"`
+	Issues []LintIssue `json:"issues"`
+}
+
+func (r LintReport) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *LintReport) addError(file, field, message string) {
+	r.Issues = append(r.Issues, LintIssue{Severity: LintError, File: file, Field: field, Message: message})
+}
+
+func (r *LintReport) addWarning(file, field, message string) {
+	r.Issues = append(r.Issues, LintIssue{Severity: LintWarning, File: file, Field: field, Message: message})
+}
+
+// installPlanDocument mirrors the full InstallSpec top-level structure so the
+// linter can reject unknown keys. The installer itself only decodes the
+// sections it executes.
+type installPlanDocument struct {
+	APIVersion         string                    `yaml:"apiVersion"`
+	Kind               string                    `yaml:"kind"`
+	ID                 string                    `yaml:"id"`
+	Category           Category                  `yaml:"category"`
+	SupportedPlatforms []string                  `yaml:"supported_platforms"`
+	InstallModes       []string                  `yaml:"install_modes"`
+	RebuildModes       []string                  `yaml:"rebuild_modes"`
+	ToolsRequired      []string                  `yaml:"tools_required"`
+	Description        installPlanDescription    `yaml:"description"`
+	Dependencies       installPlanDependencies   `yaml:"dependencies"`
+	Preconditions      []installPrecondition     `yaml:"preconditions"`
+	DecisionMatrix     installPlanDecisionMatrix `yaml:"decision_matrix"`
+	EnvironmentRebuild installPlanRebuild        `yaml:"environment_rebuild"`
+	Install            map[string][]installStep  `yaml:"install"`
+	Configuration      installPlanConfiguration  `yaml:"configuration"`
+	Verification       installPlanScript         `yaml:"verification"`
+	Rollback           installPlanScript         `yaml:"rollback"`
+	Uninstall          uninstallSpec             `yaml:"uninstall"`
+	Purge              installPlanPurge          `yaml:"purge"`
+	Security           installPlanSecurity       `yaml:"security"`
+}
+
+type installPlanDescription struct {
+	Purpose  string   `yaml:"purpose"`
+	Scope    []string `yaml:"scope"`
+	NonGoals []string `yaml:"non_goals"`
+}
+
+type installPlanDependencies struct {
+	System       []string       `yaml:"system"`
+	Modules      []string       `yaml:"modules"`
+	Capabilities []string       `yaml:"capabilities"`
+	Optional     map[string]any `yaml:"optional"`
+}
+
+type installPlanDecisionMatrix struct {
+	Default string           `yaml:"default"`
+	Rules   []map[string]any `yaml:"rules"`
+}
+
+type installPlanRebuild struct {
+	Detect      []installPrecondition `yaml:"detect"`
+	SoftCleanup []installPrecondition `yaml:"soft_cleanup"`
+	FullCleanup []installPrecondition `yaml:"full_cleanup"`
+}
+
+type installPlanConfiguration struct {
+	Required  bool                         `yaml:"required"`
+	Defaults  map[string]any               `yaml:"defaults"`
+	Schema    map[string]configSchemaEntry `yaml:"schema"`
+	Templates []string                     `yaml:"templates"`
+}
+
+type installPlanScript struct {
+	Script string `yaml:"script"`
+}
+
+type installPlanPurge struct {
+	Script      string `yaml:"script"`
+	Destructive bool   `yaml:"destructive"`
+}
+
+type installPlanSecurity struct {
+	Network struct {
+		Bind string `yaml:"bind"`
+		Auth string `yaml:"auth"`
+	} `yaml:"network"`
+	Privileges struct {
+		RequiresSudo bool `yaml:"requires_sudo"`
+	} `yaml:"privileges"`
+}
+
+var (
+	validPlatformOS     = map[string]struct{}{"linux": {}, "darwin": {}}
+	validPlatformArch   = map[string]struct{}{"amd64": {}, "arm64": {}}
+	validRebuildModes   = map[string]struct{}{"none": {}, "soft": {}, "full": {}}
+	validServiceState   = map[string]struct{}{"active": {}, "inactive": {}, "failed": {}, "activating": {}}
+	precondTools        = map[string]struct{}{"shell": {}}
+	validArchiveFormats = map[string]struct{}{"tar": {}, "tar.gz": {}, "tgz": {}, "tar.bz2": {}, "tbz2": {}, "zip": {}}
+)
+
+var (
+	scriptReferencePattern  = regexp.MustCompile(`(?:^|[\s"'])((?:scripts|templates)/[A-Za-z0-9._/-]+)`)
+	templateVariablePattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*(\|\s*default\("[^"]*"\)\s*)?\}\}`)
+	yamlUnknownFieldPattern = regexp.MustCompile(`line (\d+): field (\S+) not found in type`)
+)
+
+// LintModuleDir validates manifest.yaml and INSTALL.yaml of a single module
+// directory against the InstallSpec rules and returns every issue found.
+func LintModuleDir(dir string) LintReport {
+	report := LintReport{Module: filepath.Base(dir), Dir: dir, Issues: []LintIssue{}}
+
+	manifest, manifestOK := lintManifest(dir, &report)
+	if manifestOK && strings.TrimSpace(manifest.Name) != "" {
+		report.Module = manifest.Name
+	}
+	lintInstallPlan(dir, manifest, manifestOK, &report)
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		if report.Issues[i].File != report.Issues[j].File {
+			return report.Issues[i].File < report.Issues[j].File
+		}
+		return report.Issues[i].Severity == LintError && report.Issues[j].Severity != LintError
+	})
+	return report
+}
+
+// LintModules lints a single module when dir contains manifest.yaml, or every
+// module directory directly below dir otherwise.
+func LintModules(dir string) ([]LintReport, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, i18n.Errorf("failed to access %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, i18n.Errorf("%s is not a directory", dir)
+	}
+	if isModuleDir(dir) {
+		return []LintReport{LintModuleDir(dir)}, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, i18n.Errorf("failed to read %s: %w", dir, err)
+	}
+	var reports []LintReport
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		moduleDir := filepath.Join(dir, entry.Name())
+		if !isModuleDir(moduleDir) {
+			continue
+		}
+		reports = append(reports, LintModuleDir(moduleDir))
+	}
+	if len(reports) == 0 {
+		return nil, i18n.Errorf("no modules found in %s", dir)
+	}
+	return reports, nil
+}
+
+func isModuleDir(dir string) bool {
+	for _, name := range []string{"manifest.yaml", "INSTALL.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func lintManifest(dir string, report *LintReport) (Manifest, bool) {
+	const file = "manifest.yaml"
+	raw, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		if os.IsNotExist(err) {
+			report.addError(file, "", i18n.T("manifest.yaml is missing"))
+		} else {
+			report.addError(file, "", i18n.T("failed to read manifest: %v", err))
+		}
+		return Manifest{}, false
+	}
+
+	var manifest Manifest
+	if err := decodeStrictYAML(raw, &manifest); err != nil {
+		report.Issues = append(report.Issues, yamlDecodeIssues(file, err)...)
+		if err := yaml.Unmarshal(raw, &manifest); err != nil {
+			return Manifest{}, false
+		}
+	}
+
+	if err := ValidateManifest(manifest); err != nil {
+		for _, item := range splitJoinedErrors(err) {
+			report.addError(file, "", item.Error())
+		}
+	}
+	if manifest.Runtime.Preferred != "" && !containsString(manifest.Runtime.Modes, manifest.Runtime.Preferred) {
+		report.addError(file, "runtime.preferred", i18n.T("preferred mode %q is not listed in runtime.modes", manifest.Runtime.Preferred))
+	}
+	if manifest.Hardware.CPU != nil && manifest.Hardware.CPU.CoresMin < 0 {
+		report.addError(file, "hardware.cpu.cores_min", i18n.T("cores_min must not be negative"))
+	}
+	if manifest.Hardware.Memory != nil && manifest.Hardware.Memory.RAMMin != "" && !sizePattern.MatchString(manifest.Hardware.Memory.RAMMin) {
+		report.addError(file, "hardware.memory.ram_min", i18n.T("invalid size %q", manifest.Hardware.Memory.RAMMin))
+	}
+	if manifest.Hardware.GPU != nil && manifest.Hardware.GPU.VRAMMin != "" && !sizePattern.MatchString(manifest.Hardware.GPU.VRAMMin) {
+		report.addError(file, "hardware.gpu.vram_min", i18n.T("invalid size %q", manifest.Hardware.GPU.VRAMMin))
+	}
+	if base := filepath.Base(dir); manifest.Name != "" && manifest.Name != base {
+		report.addWarning(file, "name", i18n.T("module name %q does not match directory %q", manifest.Name, base))
+	}
+	return manifest, true
+}
+
+var sizePattern = regexp.MustCompile(`(?i)^\s*\d+(\.\d+)?\s*(B|KB|MB|GB|TB)?\s*$`)
+
+func lintInstallPlan(dir string, manifest Manifest, manifestOK bool, report *LintReport) {
+	const file = "INSTALL.yaml"
+	raw, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		if os.IsNotExist(err) {
+			report.addError(file, "", i18n.T("INSTALL.yaml is missing"))
+		} else {
+			report.addError(file, "", i18n.T("failed to read install plan: %v", err))
+		}
+		return
+	}
+
+	var plan installPlanDocument
+	if err := decodeStrictYAML(raw, &plan); err != nil {
+		report.Issues = append(report.Issues, yamlDecodeIssues(file, err)...)
+		if err := yaml.Unmarshal(raw, &plan); err != nil {
+			return
+		}
+	}
+
+	if plan.APIVersion == "" {
+		report.addError(file, "apiVersion", i18n.T("apiVersion is required"))
+	} else if plan.APIVersion != InstallSpecAPIVersion {
+		report.addError(file, "apiVersion", i18n.T("unsupported apiVersion %q (expected %q)", plan.APIVersion, InstallSpecAPIVersion))
+	}
+	if plan.Kind != InstallSpecKind {
+		report.addError(file, "kind", i18n.T("kind must be %q", InstallSpecKind))
+	}
+	if strings.TrimSpace(plan.ID) == "" {
+		report.addError(file, "id", i18n.T("id is required"))
+	} else if manifestOK && manifest.Name != "" && plan.ID != manifest.Name {
+		report.addError(file, "id", i18n.T("id %q does not match manifest name %q", plan.ID, manifest.Name))
+	}
+	if _, ok := validCategories[plan.Category]; !ok {
+		report.addError(file, "category", i18n.T("invalid category %q", plan.Category))
+	} else if manifestOK && manifest.Category != "" && plan.Category != manifest.Category {
+		report.addWarning(file, "category", i18n.T("category %q does not match manifest category %q", plan.Category, manifest.Category))
+	}
+
+	if len(plan.SupportedPlatforms) == 0 {
+		report.addError(file, "supported_platforms", i18n.T("supported_platforms must include at least one entry"))
+	}
+	for i, platform := range plan.SupportedPlatforms {
+		if err := validatePlatform(platform); err != nil {
+			report.addError(file, fmt.Sprintf("supported_platforms[%d]", i), err.Error())
+		}
+	}
+
+	lintInstallModes(dir, plan, report)
+	lintRebuild(dir, plan, report)
+
+	lintActions(dir, file, "preconditions", plan.Preconditions, precondTools, report)
+
+	for i, template := range plan.Configuration.Templates {
+		lintModuleFile(dir, file, fmt.Sprintf("configuration.templates[%d]", i), template, report)
+	}
+	lintConfigurationSchema(dir, file, plan.Configuration, report)
+
+	lintScriptSection(dir, file, "verification.script", plan.Verification.Script, report)
+	lintScriptSection(dir, file, "rollback.script", plan.Rollback.Script, report)
+	lintScriptSection(dir, file, "uninstall.script", plan.Uninstall.Script, report)
+	lintScriptSection(dir, file, "purge.script", plan.Purge.Script, report)
+	if plan.Purge.Script != "" && !plan.Purge.Destructive {
+		report.addWarning(file, "purge.destructive", i18n.T("purge must be marked destructive: true"))
+	}
+
+	for _, dep := range plan.Dependencies.Modules {
+		if _, _, err := ParseModuleDependency(dep); err != nil {
+			report.addError(file, "dependencies.modules", i18n.T("invalid module dependency %q: %v", dep, err))
+		}
+	}
+}
+
+func lintInstallModes(dir string, plan installPlanDocument, report *LintReport) {
+	const file = "INSTALL.yaml"
+	if len(plan.InstallModes) == 0 {
+		report.addError(file, "install_modes", i18n.T("install_modes must include at least one entry"))
+	}
+	if len(plan.Install) == 0 {
+		report.addError(file, "install", i18n.T("install must define steps for at least one mode"))
+	}
+	for _, mode := range plan.InstallModes {
+		if steps, ok := plan.Install[mode]; !ok || len(steps) == 0 {
+			report.addError(file, "install."+mode, i18n.T("install mode %q has no steps", mode))
+		}
+	}
+	modes := make([]string, 0, len(plan.Install))
+	for mode := range plan.Install {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	for _, mode := range modes {
+		if len(plan.InstallModes) > 0 && !containsString(plan.InstallModes, mode) {
+			report.addWarning(file, "install."+mode, i18n.T("install mode %q is not listed in install_modes", mode))
+		}
+		lintSteps(dir, file, "install."+mode, plan.Install[mode], plan.Configuration.Defaults, report)
+	}
+	if def := strings.TrimSpace(plan.DecisionMatrix.Default); def != "" {
+		if _, ok := plan.Install[def]; !ok {
+			report.addError(file, "decision_matrix.default", i18n.T("default mode %q has no install steps", def))
+		}
+	} else {
+		report.addWarning(file, "decision_matrix.default", i18n.T("decision_matrix.default is not set"))
+	}
+}
+
+func lintRebuild(dir string, plan installPlanDocument, report *LintReport) {
+	const file = "INSTALL.yaml"
+	for i, mode := range plan.RebuildModes {
+		if _, ok := validRebuildModes[mode]; !ok {
+			report.addError(file, fmt.Sprintf("rebuild_modes[%d]", i), i18n.T("unknown rebuild mode %q", mode))
+		}
+	}
+	if containsString(plan.RebuildModes, "soft") && len(plan.EnvironmentRebuild.SoftCleanup) == 0 {
+		report.addError(file, "environment_rebuild.soft_cleanup", i18n.T("rebuild mode %q requires %s", "soft", "environment_rebuild.soft_cleanup"))
+	}
+	if containsString(plan.RebuildModes, "full") && len(plan.EnvironmentRebuild.FullCleanup) == 0 {
+		report.addError(file, "environment_rebuild.full_cleanup", i18n.T("rebuild mode %q requires %s", "full", "environment_rebuild.full_cleanup"))
+	}
+	lintActions(dir, file, "environment_rebuild.detect", plan.EnvironmentRebuild.Detect, precondTools, report)
+	lintActions(dir, file, "environment_rebuild.soft_cleanup", plan.EnvironmentRebuild.SoftCleanup, precondTools, report)
+	lintActions(dir, file, "environment_rebuild.full_cleanup", plan.EnvironmentRebuild.FullCleanup, precondTools, report)
+}
+
+func lintActions(dir, file, section string, actions []installPrecondition, tools map[string]struct{}, report *LintReport) {
+	seen := map[string]bool{}
+	for i, action := range actions {
+		field := fmt.Sprintf("%s[%d]", section, i)
+		lintActionID(file, field, action.ID, seen, report)
+		if _, ok := tools[strings.TrimSpace(action.Tool)]; !ok {
+			report.addError(file, field+".tool", i18n.T("unknown tool %q", action.Tool))
+		}
+		if strings.TrimSpace(action.Command) == "" {
+			report.addError(file, field+".command", i18n.T("command is required"))
+		}
+		lintCommandReferences(dir, file, field+".command", action.Command, report)
+		lintExpected(file, field+".expected", action.Tool, action.Expected, report)
+	}
+}
+
+func lintSteps(dir, file, section string, steps []installStep, defaults map[string]any, report *LintReport) {
+	seen := map[string]bool{}
+	for i, step := range steps {
+		field := fmt.Sprintf("%s[%d]", section, i)
+		lintActionID(file, field, step.ID, seen, report)
+		if strings.TrimSpace(step.Intent) == "" {
+			report.addWarning(file, field+".intent", i18n.T("intent is required"))
+		}
+		tool := strings.TrimSpace(step.Tool)
+		if !IsKnownInstallTool(tool) {
+			report.addError(file, field+".tool", i18n.T("unknown tool %q", step.Tool))
+		}
+		switch tool {
+		case "shell":
+			if strings.TrimSpace(step.Command) == "" {
+				report.addError(file, field+".command", i18n.T("command is required"))
+			}
+			lintCommandReferences(dir, file, field+".command", step.Command, report)
+		case "template":
+			if strings.TrimSpace(step.Edit.Template) == "" {
+				report.addError(file, field+".edit.template", i18n.T("template path is required"))
+			} else if lintModuleFile(dir, file, field+".edit.template", step.Edit.Template, report) {
+				lintTemplateVariables(dir, file, field+".edit.template", step.Edit.Template, defaults, report)
+			}
+			if strings.TrimSpace(step.Edit.Destination) == "" {
+				report.addError(file, field+".edit.destination", i18n.T("template destination is required"))
+			}
+		case "download":
+			if strings.TrimSpace(step.Download.URL) == "" {
+				report.addError(file, field+".download.url", i18n.T("download url is required"))
+			}
+			if strings.TrimSpace(step.Download.Dest) == "" {
+				report.addError(file, field+".download.dest", i18n.T("download dest is required"))
+			}
+			if checksum := normalizeChecksum(step.Download.SHA256); checksum == "" && step.Download.Cacheable {
+				report.addError(file, field+".download.sha256", i18n.T("sha256 is required for cacheable downloads"))
+			} else if checksum == "" {
+				report.addWarning(file, field+".download.sha256", i18n.T("sha256 is recommended for downloads"))
+			} else if !templateVariablePattern.MatchString(checksum) {
+				if _, err := hex.DecodeString(checksum); err != nil || len(checksum) != 64 {
+					report.addError(file, field+".download.sha256", i18n.T("invalid sha256 value"))
+				}
+			}
+		case "extract":
+			if strings.TrimSpace(step.Extract.Archive) == "" {
+				report.addError(file, field+".extract.archive", i18n.T("extract archive is required"))
+			}
+			if strings.TrimSpace(step.Extract.Dest) == "" {
+				report.addError(file, field+".extract.dest", i18n.T("extract dest is required"))
+			}
+			if format := strings.TrimSpace(step.Extract.Format); format != "" {
+				if _, ok := validArchiveFormats[strings.ToLower(format)]; !ok {
+					report.addError(file, field+".extract.format", i18n.T("unsupported archive format %q", format))
+				}
+			}
+			if step.Extract.StripComponents < 0 {
+				report.addError(file, field+".extract.strip_components", i18n.T("strip_components must not be negative"))
+			}
+		case "python_venv":
+			if strings.TrimSpace(step.PythonVenv.Path) == "" {
+				report.addError(file, field+".python_venv.path", i18n.T("python_venv path is required"))
+			}
+			if requirementsFile := strings.TrimSpace(step.PythonVenv.RequirementsFile); requirementsFile != "" && !filepath.IsAbs(requirementsFile) && !strings.HasPrefix(requirementsFile, "~/") {
+				lintModuleFile(dir, file, field+".python_venv.requirements_file", requirementsFile, report)
+			}
+		case "systemd_unit":
+			if strings.TrimSpace(step.SystemdUnit.Template) == "" {
+				report.addError(file, field+".systemd_unit.template", i18n.T("systemd_unit template is required"))
+			} else if lintModuleFile(dir, file, field+".systemd_unit.template", step.SystemdUnit.Template, report) {
+				lintTemplateVariables(dir, file, field+".systemd_unit.template", step.SystemdUnit.Template, defaults, report)
+			}
+		}
+		lintExpected(file, field+".expected", tool, step.Expected, report)
+	}
+}
+
+func lintConfigurationSchema(dir, file string, cfg installPlanConfiguration, report *LintReport) {
+	keys := make([]string, 0, len(cfg.Schema))
+	for key := range cfg.Schema {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	typed := installConfiguration{Defaults: cfg.Defaults, Schema: cfg.Schema}
+	for _, key := range keys {
+		entry := cfg.Schema[key]
+		field := "configuration.schema." + key
+		if _, ok := cfg.Defaults[key]; !ok {
+			report.addError(file, field, i18n.T("schema key %q has no entry in configuration.defaults", key))
+			continue
+		}
+		if entry.Type != "" {
+			if _, ok := validConfigTypes[strings.ToLower(strings.TrimSpace(entry.Type))]; !ok {
+				report.addError(file, field+".type", i18n.T("unknown configuration type %q", entry.Type))
+				continue
+			}
+		}
+		if entry.Pattern != "" {
+			if _, err := regexp.Compile(entry.Pattern); err != nil {
+				report.addError(file, field+".pattern", i18n.T("invalid pattern: %v", err))
+				continue
+			}
+		}
+		// An empty default means "unset" and is exempt from validation.
+		if value := flattenDefaults(map[string]any{key: cfg.Defaults[key]})[key]; value != "" {
+			if err := validateConfigValue(typed, key, value); err != nil {
+				report.addError(file, "configuration.defaults."+key, err.Error())
+			}
+		}
+		lintCommandReferences(dir, file, field+".apply", entry.Apply, report)
+	}
+}
+
+func lintActionID(file, field, id string, seen map[string]bool, report *LintReport) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		report.addError(file, field+".id", i18n.T("id is required"))
+		return
+	}
+	if seen[id] {
+		report.addError(file, field+".id", i18n.T("duplicate id %q", id))
+	}
+	seen[id] = true
+}
+
+func lintExpected(file, field, tool string, expect installExpect, report *LintReport) {
+	if expect == (installExpect{}) {
+		report.addError(file, field, i18n.T("expected block is required"))
+		return
+	}
+	if expect.ExitCode != nil && (*expect.ExitCode < 0 || *expect.ExitCode > 255) {
+		report.addError(file, field+".exit_code", i18n.T("exit_code must be between 0 and 255"))
+	}
+	if expect.Service != "" {
+		if _, ok := validServiceState[strings.TrimSpace(expect.Service)]; !ok {
+			report.addError(file, field+".service", i18n.T("unknown service state %q", expect.Service))
+		}
+	}
+	if expect.Equals != "" && tool != "shell" {
+		report.addWarning(file, field+".equals", i18n.T("equals is only checked for shell actions"))
+	}
+}
+
+func lintScriptSection(dir, file, field, script string, report *LintReport) {
+	if strings.TrimSpace(script) == "" {
+		report.addWarning(file, field, i18n.T("%s is not defined", field))
+		return
+	}
+	lintModuleFile(dir, file, field, script, report)
+}
+
+func lintCommandReferences(dir, file, field, command string, report *LintReport) {
+	for _, match := range scriptReferencePattern.FindAllStringSubmatch(command, -1) {
+		lintModuleFile(dir, file, field, match[1], report)
+	}
+}
+
+func lintModuleFile(dir, file, field, path string, report *LintReport) bool {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return false
+	}
+	resolved := path
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(dir, resolved)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		report.addError(file, field, i18n.T("referenced file %s does not exist", path))
+		return false
+	}
+	if info.IsDir() {
+		report.addError(file, field, i18n.T("referenced path %s is a directory", path))
+		return false
+	}
+	return true
+}
+
+func lintTemplateVariables(dir, file, field, template string, defaults map[string]any, report *LintReport) {
+	path := template
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, match := range templateVariablePattern.FindAllStringSubmatch(string(contents), -1) {
+		if match[2] != "" {
+			continue
+		}
+		if _, builtin := builtinConfigVars[match[1]]; builtin {
+			continue
+		}
+		if _, ok := defaults[match[1]]; !ok {
+			report.addWarning(file, field, i18n.T("template variable %q has no default in configuration.defaults", match[1]))
+		}
+	}
+}
+
+func validatePlatform(platform string) error {
+	parts := strings.Split(strings.TrimSpace(platform), "/")
+	if len(parts) != 2 {
+		return i18n.Errorf("platform %q must be in os/arch form", platform)
+	}
+	if _, ok := validPlatformOS[parts[0]]; !ok {
+		return i18n.Errorf("unsupported platform os %q", parts[0])
+	}
+	if _, ok := validPlatformArch[parts[1]]; !ok {
+		return i18n.Errorf("unsupported platform arch %q", parts[1])
+	}
+	return nil
+}
+
+func decodeStrictYAML(raw []byte, out any) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func yamlDecodeIssues(file string, err error) []LintIssue {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return []LintIssue{{Severity: LintError, File: file, Message: i18n.T("invalid YAML: %v", err)}}
+	}
+	issues := make([]LintIssue, 0, len(typeErr.Errors))
+	for _, message := range typeErr.Errors {
+		if match := yamlUnknownFieldPattern.FindStringSubmatch(message); match != nil {
+			issues = append(issues, LintIssue{
+				Severity: LintError,
+				File:     file,
+				Field:    match[2],
+				Message:  i18n.T("unknown field %s (line %s)", match[2], match[1]),
+			})
+			continue
+		}
+		issues = append(issues, LintIssue{Severity: LintError, File: file, Message: message})
+	}
+	return issues
+}
+
+func splitJoinedErrors(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const lintTestManifest = `name: demo
+category: tool
+version: 0.1.0
+description: Demo module
+runtime:
+  modes:
+    - native
+  preferred: native
+`
+
+const lintTestInstallPlan = `apiVersion: las.installspec/v0.1.2
+kind: InstallPlan
+id: demo
+category: tool
+supported_platforms:
+  - linux/amd64
+install_modes:
+  - native
+preconditions:
+  - id: P10
+    intent: Must be Linux
+    tool: shell
+    command: uname -s
+    expected:
+      equals: Linux
+decision_matrix:
+  default: native
+install:
+  native:
+    - id: S10
+      intent: Install
+      tool: shell
+      command: bash scripts/install.sh
+      expected:
+        exit_code: 0
+      idempotent: true
+verification:
+  script: scripts/verify.sh
+rollback:
+  script: scripts/uninstall.sh
+uninstall:
+  script: scripts/uninstall.sh
+purge:
+  script: scripts/uninstall.sh
+  destructive: true
+`
+
+func writeLintModule(t *testing.T, manifest, plan string, scripts ...string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "demo")
+	if err := os.MkdirAll(filepath.Join(dir, "scripts"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(manifest), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "INSTALL.yaml"), []byte(plan), 0o644); err != nil {
+		t.Fatalf("write plan: %v", err)
+	}
+	for _, script := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, "scripts", script), []byte("#!/usr/bin/env bash\n"), 0o755); err != nil {
+			t.Fatalf("write script: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestLintModuleDir_ValidModule(t *testing.T) {
+	dir := writeLintModule(t, lintTestManifest, lintTestInstallPlan, "install.sh", "verify.sh", "uninstall.sh")
+
+	report := LintModuleDir(dir)
+	if len(report.Issues) != 0 {
+		t.Fatalf("expected no issues, got %+v", report.Issues)
+	}
+}
+
+func TestLintModuleDir_ReportsBrokenPlan(t *testing.T) {
+	plan := strings.NewReplacer(
+		"apiVersion: las.installspec/v0.1.2", "apiVersion: las.installspec/v9",
+		"linux/amd64", "plan9/amd64",
+		"      tool: shell\n      command: bash scripts/install.sh", "      tool: rsync\n      command: bash scripts/install.sh",
+		"decision_matrix:", "decison_matrix:",
+		"verification:", `    - id: S10
+      intent: Duplicate
+      tool: shell
+      command: bash scripts/missing.sh
+      expected:
+        exit_code: 300
+      idempotent: true
+verification:`,
+	).Replace(lintTestInstallPlan)
+	dir := writeLintModule(t, lintTestManifest, plan, "install.sh", "verify.sh", "uninstall.sh")
+
+	report := LintModuleDir(dir)
+	if !report.HasErrors() {
+		t.Fatalf("expected lint errors")
+	}
+
+	want := map[string]bool{
+		"apiVersion":                           false,
+		"decison_matrix":                       false,
+		"supported_platforms[0]":               false,
+		"install.native[0].tool":               false,
+		"install.native[1].id":                 false,
+		"install.native[1].command":            false,
+		"install.native[1].expected.exit_code": false,
+	}
+	for _, issue := range report.Issues {
+		if _, ok := want[issue.Field]; ok && issue.Severity == LintError {
+			want[issue.Field] = true
+		}
+	}
+	for field, found := range want {
+		if !found {
+			t.Errorf("expected error for %s, got %+v", field, report.Issues)
+		}
+	}
+}
+
+func TestLintModuleDir_ChecksStepToolFields(t *testing.T) {
+	plan := strings.Replace(lintTestInstallPlan, "verification:", `    - id: S20
+      intent: Fetch release
+      tool: download
+      download:
+        url: https://example.com/demo.tar.gz
+        sha256: not-a-checksum
+        dest: /tmp/demo.tar.gz
+      expected:
+        path: /tmp/demo.tar.gz
+      idempotent: true
+    - id: S30
+      intent: Unpack release
+      tool: extract
+      extract:
+        archive: /tmp/demo.tar.gz
+        format: rar
+      expected:
+        path: /opt/demo
+      idempotent: true
+verification:`, 1)
+	dir := writeLintModule(t, lintTestManifest, plan, "install.sh", "verify.sh", "uninstall.sh")
+
+	report := LintModuleDir(dir)
+	want := map[string]bool{
+		"install.native[1].download.sha256": false,
+		"install.native[2].extract.dest":    false,
+		"install.native[2].extract.format":  false,
+	}
+	for _, issue := range report.Issues {
+		if _, ok := want[issue.Field]; ok && issue.Severity == LintError {
+			want[issue.Field] = true
+		}
+	}
+	for field, found := range want {
+		if !found {
+			t.Errorf("expected error for %s, got %+v", field, report.Issues)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"gopkg.in/yaml.v3"
+)
+
+// Plan actions reported by PlanInstall, PlanUninstall and PlanPurge.
+const (
+	PlanActionInstall   = "install"
+	PlanActionUninstall = "uninstall"
+	PlanActionPurge     = "purge"
+)
+
+// Plan describes what a module lifecycle action would execute without
+// changing the system.
+type Plan struct {
+	Module        string             `json:"module"`
+	Action        string             `json:"action"`
+	Dir           string             `json:"dir"`
+	Mode          string             `json:"mode,omitempty"`
+	Env           map[string]string  `json:"env,omitempty"`
+	Variables     map[string]string  `json:"variables,omitempty"`
+	Preconditions []PlanPrecondition `json:"preconditions,omitempty"`
+	Steps         []PlanStep         `json:"steps"`
+	Preserves     []string           `json:"preserves,omitempty"`
+	Removes       []string           `json:"removes,omitempty"`
+}
+
+// PlanPrecondition is the result of evaluating a precondition during a dry
+// run. Preconditions are read-only checks, so they are actually executed.
+type PlanPrecondition struct {
+	ID      string `json:"id"`
+	Intent  string `json:"intent,omitempty"`
+	Command string `json:"command"`
+	Passed  bool   `json:"passed"`
+	Output  string `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PlanStep is a single action with its variables substituted.
+type PlanStep struct {
+	ID          string            `json:"id"`
+	Intent      string            `json:"intent,omitempty"`
+	Tool        string            `json:"tool"`
+	Command     string            `json:"command,omitempty"`
+	Template    string            `json:"template,omitempty"`
+	Destination string            `json:"destination,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Expected    map[string]string `json:"expected,omitempty"`
+	Idempotent  bool              `json:"idempotent"`
+	// Undefined lists variables the step references that are neither
+	// configured nor given a default; they render as empty strings.
+	Undefined []string `json:"undefined,omitempty"`
+}
+
+// PlanInstall resolves the install mode and steps Install would run. The
+// optional LLM step selection is not consulted, so the plan lists every step
+// of the resolved mode.
+func PlanInstall(name string) (Plan, error) {
+	normalized, moduleDir, raw, err := readModulePlan(name)
+	if err != nil {
+		return Plan{}, err
+	}
+	var spec moduleInstallSpec
+	if err := yaml.Unmarshal(raw, &spec); err != nil {
+		return Plan{}, i18n.Errorf("failed to parse install plan for module %q: %w", normalized, err)
+	}
+
+	mode, env := selectInstallModeForSystem(normalized, spec)
+	steps, ok := spec.Install[mode]
+	if !ok || len(steps) == 0 {
+		return Plan{}, i18n.Errorf("install plan for module %q has no steps for mode %q", normalized, mode)
+	}
+
+	vars, err := moduleConfigVars(normalized, spec.Configuration)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan := Plan{
+		Module:        normalized,
+		Action:        PlanActionInstall,
+		Dir:           moduleDir,
+		Mode:          mode,
+		Env:           env,
+		Variables:     vars,
+		Preconditions: evaluatePreconditions(spec.Preconditions, moduleDir),
+		Steps:         make([]PlanStep, 0, len(steps)),
+	}
+	for _, step := range steps {
+		plan.Steps = append(plan.Steps, planStep(normalized, moduleDir, step, vars))
+	}
+	return plan, nil
+}
+
+// PlanUninstall reports the uninstall script and the paths it removes.
+func PlanUninstall(name string) (Plan, error) {
+	normalized, moduleDir, raw, err := readModulePlan(name)
+	if err != nil {
+		return Plan{}, err
+	}
+	var spec installSpec
+	if err := yaml.Unmarshal(raw, &spec); err != nil {
+		return Plan{}, i18n.Errorf("failed to parse install plan for module %q: %w", normalized, err)
+	}
+	script := strings.TrimSpace(spec.Uninstall.Script)
+	if script == "" {
+		return Plan{}, i18n.Errorf("module %q does not define an uninstall script", normalized)
+	}
+	plan, err := planScript(normalized, moduleDir, PlanActionUninstall, script)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan.Preserves = spec.Uninstall.Preserves
+	return plan, nil
+}
+
+// PlanPurge reports the purge script and the files and directories it deletes.
+func PlanPurge(name string) (Plan, error) {
+	normalized, moduleDir, raw, err := readModulePlan(name)
+	if err != nil {
+		return Plan{}, err
+	}
+	var spec purgeSpec
+	if err := yaml.Unmarshal(raw, &spec); err != nil {
+		return Plan{}, i18n.Errorf("failed to parse install plan for module %q: %w", normalized, err)
+	}
+	script := strings.TrimSpace(spec.Purge.Script)
+	if script == "" {
+		return Plan{}, i18n.Errorf("module %q does not define a purge script", normalized)
+	}
+	return planScript(normalized, moduleDir, PlanActionPurge, script)
+}
+
+func readModulePlan(name string) (string, string, []byte, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if normalized == "" {
+		return "", "", nil, i18n.Errorf("module name is required")
+	}
+	moduleDir, err := resolveModuleDir(normalized)
+	if err != nil {
+		return "", "", nil, err
+	}
+	raw, err := os.ReadFile(filepath.Join(moduleDir, "INSTALL.yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", nil, i18n.Errorf("install plan not found for module %q", normalized)
+		}
+		return "", "", nil, i18n.Errorf("failed to read install plan for module %q: %w", normalized, err)
+	}
+	return normalized, moduleDir, raw, nil
+}
+
+func evaluatePreconditions(preconditions []installPrecondition, moduleDir string) []PlanPrecondition {
+	results := make([]PlanPrecondition, 0, len(preconditions))
+	for _, pre := range preconditions {
+		if strings.TrimSpace(pre.Tool) == "" {
+			continue
+		}
+		result := PlanPrecondition{ID: pre.ID, Intent: pre.Intent, Command: pre.Command}
+		output, err := checkPrecondition(pre, moduleDir)
+		result.Output = normalizedOutput(output)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Passed = true
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func planStep(moduleName, moduleDir string, step installStep, vars map[string]string) PlanStep {
+	planned := PlanStep{
+		ID:         step.ID,
+		Intent:     step.Intent,
+		Tool:       strings.TrimSpace(step.Tool),
+		Idempotent: step.Idempotent,
+		Expected:   describeExpected(step.Expected, vars),
+		Details:    stepDetails(moduleName, moduleDir, step, vars),
+		Undefined:  undefinedStepVars(moduleDir, step, vars),
+	}
+	if command := strings.TrimSpace(step.Command); command != "" {
+		planned.Command, _ = renderTemplate(command, vars)
+	}
+	if template := strings.TrimSpace(step.Edit.Template); template != "" {
+		if !filepath.IsAbs(template) {
+			template = filepath.Join(moduleDir, template)
+		}
+		planned.Template = template
+	}
+	if destination := strings.TrimSpace(step.Edit.Destination); destination != "" {
+		destination, _ = renderTemplate(destination, vars)
+		if !filepath.IsAbs(destination) {
+			destination = filepath.Join(moduleDir, destination)
+		}
+		planned.Destination = destination
+	}
+	return planned
+}
+
+// undefinedStepVars collects the undefined variables of every templated
+// field of a step, including the contents of the files it renders.
+func undefinedStepVars(moduleDir string, step installStep, vars map[string]string) []string {
+	fields := []string{
+		step.Command,
+		step.Edit.Destination,
+		step.Download.URL,
+		step.Download.SHA256,
+		step.Download.Dest,
+		step.Extract.Archive,
+		step.Extract.Dest,
+		step.Extract.Creates,
+		step.PythonVenv.Path,
+		step.PythonVenv.Python,
+		step.PythonVenv.RequirementsFile,
+		step.Expected.Path,
+	}
+	fields = append(fields, step.PythonVenv.Requirements...)
+	for _, template := range []string{step.Edit.Template, step.SystemdUnit.Template} {
+		if path := resolveStepPath(moduleDir, strings.TrimSpace(template)); path != "" {
+			if contents, err := os.ReadFile(path); err == nil {
+				fields = append(fields, string(contents))
+			}
+		}
+	}
+	undefined := undefinedTemplateVars(strings.Join(fields, "\n"), vars)
+	sort.Strings(undefined)
+	return undefined
+}
+
+func describeExpected(expect installExpect, vars map[string]string) map[string]string {
+	described := make(map[string]string)
+	if expect.Equals != "" {
+		described["equals"] = expect.Equals
+	}
+	if expect.ExitCode != nil {
+		described["exit_code"] = strconv.Itoa(*expect.ExitCode)
+	}
+	if expect.Bin != "" {
+		described["bin"] = expect.Bin
+	}
+	if expect.Unit != "" {
+		described["unit"] = expect.Unit
+	}
+	if expect.Service != "" {
+		described["service"] = expect.Service
+	}
+	if expect.Path != "" {
+		described["path"] = renderStepValue(expect.Path, vars)
+	}
+	if len(described) == 0 {
+		return nil
+	}
+	return described
+}
+
+func planScript(moduleName, moduleDir, action, script string) (Plan, error) {
+	scriptPath := script
+	if !filepath.IsAbs(scriptPath) {
+		scriptPath = filepath.Join(moduleDir, scriptPath)
+	}
+	if _, err := os.Stat(scriptPath); err != nil {
+		if os.IsNotExist(err) {
+			return Plan{}, i18n.Errorf("%s script not found for module %q", action, moduleName)
+		}
+		return Plan{}, i18n.Errorf("failed to read %s script for module %q: %w", action, moduleName, err)
+	}
+	return Plan{
+		Module: moduleName,
+		Action: action,
+		Dir:    moduleDir,
+		Steps: []PlanStep{{
+			ID:      action,
+			Tool:    "shell",
+			Command: "bash " + scriptPath,
+		}},
+		Removes: scriptRemovals(scriptPath, moduleDir),
+	}, nil
+}
+
+var (
+	removeCommandPattern = regexp.MustCompile(`(?:^|[\s;&|])rm\s+(.+)$`)
+	nestedScriptPattern  = regexp.MustCompile(`bash\s+"?(?:\$\(dirname "\$0"\)/|\$\{?SCRIPT_DIR\}?/)?((?:scripts/)?[A-Za-z0-9._-]+\.sh)"?`)
+)
+
+// scriptRemovals statically scans a cleanup script, and the module scripts it
+// invokes, for rm targets. The result is a best-effort preview: paths built
+// at runtime are reported with their shell variables unexpanded.
+func scriptRemovals(scriptPath, moduleDir string) []string {
+	seen := make(map[string]struct{})
+	visited := make(map[string]struct{})
+	var removals []string
+
+	var scan func(path string)
+	scan = func(path string) {
+		if _, ok := visited[path]; ok {
+			return
+		}
+		visited[path] = struct{}{}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
+		for _, line := range strings.Split(string(raw), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if match := nestedScriptPattern.FindStringSubmatch(line); match != nil {
+				nested := match[1]
+				if strings.HasPrefix(nested, "scripts/") {
+					nested = filepath.Join(moduleDir, nested)
+				} else {
+					nested = filepath.Join(filepath.Dir(path), nested)
+				}
+				scan(nested)
+			}
+			for _, target := range removeTargets(line) {
+				if _, ok := seen[target]; ok {
+					continue
+				}
+				seen[target] = struct{}{}
+				removals = append(removals, target)
+			}
+		}
+	}
+	scan(scriptPath)
+	sort.Strings(removals)
+	return removals
+}
+
+func removeTargets(line string) []string {
+	match := removeCommandPattern.FindStringSubmatch(line)
+	if match == nil {
+		return nil
+	}
+	args := match[1]
+	for _, sep := range []string{"||", "&&", ";", "#", "|", ">"} {
+		if idx := strings.Index(args, sep); idx >= 0 {
+			args = args[:idx]
+		}
+	}
+	var targets []string
+	for _, field := range strings.Fields(args) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		field = strings.Trim(field, `"'`)
+		if field == "" {
+			continue
+		}
+		targets = append(targets, expandKnownEnv(field))
+	}
+	return targets
+}
+
+func expandKnownEnv(value string) string {
+	return os.Expand(value, func(key string) string {
+		switch key {
+		case "HOME", "USER":
+			if resolved := os.Getenv(key); resolved != "" {
+				return resolved
+			}
+		}
+		return "${" + key + "}"
+	})
+}
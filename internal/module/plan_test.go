@@ -0,0 +1,125 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPlanInstall_ResolvesStepsWithoutRunningThem(t *testing.T) {
+	root := t.TempDir()
+	if _, err := Scaffold(ScaffoldOptions{Name: "demo", Category: CategoryRuntime, Kind: ScaffoldKindService, Dir: filepath.Join(root, "modules")}); err != nil {
+		t.Fatalf("Scaffold returned error: %v", err)
+	}
+	t.Chdir(root)
+	t.Setenv("LOCALAISTACK_CONTROL_DATA_DIR", filepath.Join(root, "data"))
+
+	plan, err := PlanInstall("demo")
+	if err != nil {
+		t.Fatalf("PlanInstall returned error: %v", err)
+	}
+	if plan.Mode != "native" {
+		t.Fatalf("unexpected mode %q", plan.Mode)
+	}
+	if len(plan.Preconditions) != 2 || plan.Preconditions[0].ID != "P10" {
+		t.Fatalf("unexpected preconditions %+v", plan.Preconditions)
+	}
+	if len(plan.Steps) != 3 {
+		t.Fatalf("expected 3 steps, got %+v", plan.Steps)
+	}
+	template := plan.Steps[1]
+	if template.Destination != "/tmp/demo.service" || filepath.Base(template.Template) != "demo.service.tmpl" {
+		t.Fatalf("unexpected template step %+v", template)
+	}
+	if plan.Steps[2].Expected["service"] != "active" {
+		t.Fatalf("unexpected expectations %+v", plan.Steps[2].Expected)
+	}
+	if plan.Variables["bind"] != "127.0.0.1:8080" {
+		t.Fatalf("unexpected variables %+v", plan.Variables)
+	}
+}
+
+func TestPlanPurge_ListsRemovedPaths(t *testing.T) {
+	root := t.TempDir()
+	if _, err := Scaffold(ScaffoldOptions{Name: "demo", Category: CategoryRuntime, Kind: ScaffoldKindService, Dir: filepath.Join(root, "modules")}); err != nil {
+		t.Fatalf("Scaffold returned error: %v", err)
+	}
+	t.Chdir(root)
+	t.Setenv("LOCALAISTACK_CONTROL_DATA_DIR", filepath.Join(root, "data"))
+
+	plan, err := PlanPurge("demo")
+	if err != nil {
+		t.Fatalf("PlanPurge returned error: %v", err)
+	}
+	want := map[string]bool{"/etc/systemd/system/demo.service": false, "/usr/local/bin/demo": false}
+	for _, path := range plan.Removes {
+		if _, ok := want[path]; ok {
+			want[path] = true
+		}
+	}
+	for path, found := range want {
+		if !found {
+			t.Fatalf("expected %s in removals, got %v", path, plan.Removes)
+		}
+	}
+}
+
+func TestRemoveTargets(t *testing.T) {
+	t.Setenv("HOME", "/home/demo")
+	got := removeTargets(`$SUDO rm -rf "${HOME}/.cache/demo" /opt/demo || true`)
+	if len(got) != 2 || got[0] != "/home/demo/.cache/demo" || got[1] != "/opt/demo" {
+		t.Fatalf("unexpected targets %v", got)
+	}
+	if got := removeTargets("echo removing"); got != nil {
+		t.Fatalf("unexpected targets %v", got)
+	}
+}
+
+func TestPlanInstall_ReportsUndefinedVariables(t *testing.T) {
+	root := t.TempDir()
+	moduleDir := filepath.Join(root, "modules", "demo")
+	if err := os.MkdirAll(filepath.Join(moduleDir, "templates"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	plan := `install_modes: [native]
+configuration:
+  defaults:
+    prefix: /opt/demo
+install:
+  native:
+    - id: S10
+      tool: shell
+      command: mkdir -p {{ prefix }}/{{ prefx }} {{ mode | default("cpu") }}
+    - id: S20
+      tool: template
+      edit:
+        template: templates/demo.conf.tmpl
+        destination: "{{ prefix }}/demo.conf"
+`
+	if err := os.WriteFile(filepath.Join(moduleDir, "INSTALL.yaml"), []byte(plan), 0o644); err != nil {
+		t.Fatalf("write plan: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(moduleDir, "manifest.yaml"), []byte("name: demo\n"), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(moduleDir, "templates", "demo.conf.tmpl"), []byte("listen={{ bind }}\nroot={{ prefix }}\n"), 0o644); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+	t.Chdir(root)
+	t.Setenv("LOCALAISTACK_CONTROL_DATA_DIR", filepath.Join(root, "data"))
+
+	result, err := PlanInstall("demo")
+	if err != nil {
+		t.Fatalf("PlanInstall returned error: %v", err)
+	}
+	if len(result.Steps) != 2 {
+		t.Fatalf("expected 2 steps, got %+v", result.Steps)
+	}
+	if got := result.Steps[0]; got.Command != "mkdir -p /opt/demo/ cpu" || !reflect.DeepEqual(got.Undefined, []string{"prefx"}) {
+		t.Fatalf("unexpected shell step %+v", got)
+	}
+	if got := result.Steps[1].Undefined; !reflect.DeepEqual(got, []string{"bind"}) {
+		t.Fatalf("expected the template's undefined variable, got %v", got)
+	}
+}
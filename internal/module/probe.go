@@ -0,0 +1,88 @@
+package module
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Observation is what probing the host found out about a module,
+// independent of what the state file records.
+type Observation struct {
+	Module string `json:"module"`
+	// Verified is nil when the module has no verification script.
+	Verified    *bool  `json:"verified,omitempty"`
+	VerifyError string `json:"verify_error,omitempty"`
+	// Binaries are the expected.bin entries of the selected install mode.
+	Binaries        []string `json:"binaries,omitempty"`
+	MissingBinaries []string `json:"missing_binaries,omitempty"`
+	Unit            string   `json:"unit,omitempty"`
+	// UnitState is the unit's systemd ActiveState, or "" when the module has
+	// no unit or systemd could not be queried.
+	UnitState string `json:"unit_state,omitempty"`
+}
+
+// Conclusive reports whether the module has any probe that can tell if it
+// is installed.
+func (o Observation) Conclusive() bool {
+	return o.Verified != nil || len(o.Binaries) > 0
+}
+
+// Installed reports whether the probes found the module on the host.
+func (o Observation) Installed() bool {
+	if !o.Conclusive() {
+		return false
+	}
+	return (o.Verified == nil || *o.Verified) && len(o.MissingBinaries) == 0
+}
+
+// Probe checks the host for a module: it runs the install plan's
+// verification script, looks for the expected binaries and queries the
+// module's systemd unit.
+func Probe(ctx context.Context, name string) (Observation, error) {
+	normalized, moduleDir, spec, err := loadModuleConfigSpec(name)
+	if err != nil {
+		return Observation{}, err
+	}
+	observation := Observation{Module: normalized}
+
+	script := strings.TrimSpace(spec.Verification.Script)
+	if script == "" {
+		script = filepath.Join("scripts", "verify.sh")
+	}
+	scriptPath := resolveStepPath(moduleDir, script)
+	if _, err := os.Stat(scriptPath); err == nil {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		err := runVerifyScript(checkCtx, normalized, scriptPath)
+		cancel()
+		verified := err == nil
+		observation.Verified = &verified
+		if err != nil {
+			observation.VerifyError = err.Error()
+		}
+	}
+
+	mode, _ := selectInstallModeForSystem(normalized, spec)
+	steps := spec.Install[mode]
+	seen := map[string]bool{}
+	for _, step := range steps {
+		bin := strings.TrimSpace(step.Expected.Bin)
+		if bin == "" || seen[bin] {
+			continue
+		}
+		seen[bin] = true
+		observation.Binaries = append(observation.Binaries, bin)
+		if checkExpectedBin(bin) != nil {
+			observation.MissingBinaries = append(observation.MissingBinaries, bin)
+		}
+	}
+
+	if unit := moduleServiceUnit(normalized, steps); unit != "" {
+		observation.Unit = unit
+		if status, err := queryServiceUnit(ctx, normalized, unit); err == nil && status.LoadState != "not-found" {
+			observation.UnitState = status.ActiveState
+		}
+	}
+	return observation, nil
+}
@@ -0,0 +1,47 @@
+package module
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProbe_ReportsVerificationAndBinaries(t *testing.T) {
+	root := t.TempDir()
+	const name = "las-probe-demo"
+	if _, err := Scaffold(ScaffoldOptions{Name: name, Category: CategoryTool, Kind: ScaffoldKindBinary, Dir: filepath.Join(root, "modules")}); err != nil {
+		t.Fatalf("Scaffold returned error: %v", err)
+	}
+	t.Chdir(root)
+	binDir := filepath.Join(root, "bin")
+	if err := os.MkdirAll(binDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	observation, err := Probe(context.Background(), name)
+	if err != nil {
+		t.Fatalf("Probe returned error: %v", err)
+	}
+	if !observation.Conclusive() || observation.Installed() {
+		t.Fatalf("expected a conclusive not-installed observation, got %+v", observation)
+	}
+	if len(observation.MissingBinaries) != 1 || observation.VerifyError == "" {
+		t.Fatalf("expected a missing binary and a verification error, got %+v", observation)
+	}
+
+	if err := os.WriteFile(filepath.Join(binDir, name), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("write binary: %v", err)
+	}
+	observation, err = Probe(context.Background(), name)
+	if err != nil {
+		t.Fatalf("Probe returned error: %v", err)
+	}
+	if !observation.Installed() {
+		t.Fatalf("expected the module to be installed, got %+v", observation)
+	}
+	if observation.Unit != "" {
+		t.Fatalf("binary modules have no unit, got %q", observation.Unit)
+	}
+}
@@ -0,0 +1,105 @@
+package module
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// Step statuses reported through RunOptions.Progress.
+const (
+	StepRunning   = "running"
+	StepSucceeded = "succeeded"
+	StepFailed    = "failed"
+)
+
+// StepEvent describes a state change of a single lifecycle step.
+type StepEvent struct {
+	Module string `json:"module"`
+	Action string `json:"action"`
+	StepID string `json:"step_id"`
+	Intent string `json:"intent,omitempty"`
+	Index  int    `json:"index"`
+	Total  int    `json:"total"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RunOptions customizes a lifecycle action started with a context. Progress
+// receives step events; Output receives the streamed command output and
+// defaults to os.Stdout. Download receives byte counts while a download step
+// runs; total is -1 when the size is unknown.
+type RunOptions struct {
+	Progress func(StepEvent)
+	Output   io.Writer
+	Download func(downloaded, total int64)
+}
+
+type outputContextKey struct{}
+
+type downloadContextKey struct{}
+
+func withRunOutput(ctx context.Context, output io.Writer) context.Context {
+	if output == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, outputContextKey{}, output)
+}
+
+func withDownloadProgress(ctx context.Context, progress func(downloaded, total int64)) context.Context {
+	if progress == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, downloadContextKey{}, progress)
+}
+
+func downloadProgress(ctx context.Context) func(downloaded, total int64) {
+	progress, _ := ctx.Value(downloadContextKey{}).(func(downloaded, total int64))
+	return progress
+}
+
+func runOutput(ctx context.Context) io.Writer {
+	if output, ok := ctx.Value(outputContextKey{}).(io.Writer); ok {
+		return output
+	}
+	return os.Stdout
+}
+
+// stepReporter emits StepEvents for one action; it is a no-op without a
+// Progress callback.
+type stepReporter struct {
+	opts   RunOptions
+	module string
+	action string
+	total  int
+}
+
+func (r stepReporter) report(index int, id, intent, status string, err error) {
+	if r.opts.Progress == nil {
+		return
+	}
+	event := StepEvent{
+		Module: r.module,
+		Action: r.action,
+		StepID: id,
+		Intent: intent,
+		Index:  index,
+		Total:  r.total,
+		Status: status,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	r.opts.Progress(event)
+}
+
+// run reports the step as running, executes fn and reports its outcome.
+func (r stepReporter) run(index int, id, intent string, fn func() error) error {
+	r.report(index, id, intent, StepRunning, nil)
+	if err := fn(); err != nil {
+		r.report(index, id, intent, StepFailed, err)
+		return err
+	}
+	r.report(index, id, intent, StepSucceeded, nil)
+	return nil
+}
@@ -1,8 +1,8 @@
 package module
 
 import (
+	"context"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -16,6 +16,12 @@ type purgeSpec struct {
 
 // Purge runs the destructive cleanup script defined in INSTALL.yaml.
 func Purge(name string) error {
+	return PurgeWithContext(context.Background(), name, RunOptions{})
+}
+
+// PurgeWithContext runs the purge script as a single reported step.
+// Cancelling ctx stops the script.
+func PurgeWithContext(ctx context.Context, name string, opts RunOptions) error {
 	normalized := strings.ToLower(strings.TrimSpace(name))
 	if normalized == "" {
 		return i18n.Errorf("module name is required")
@@ -54,15 +60,5 @@ func Purge(name string) error {
 		return i18n.Errorf("failed to read purge script for module %q: %w", normalized, err)
 	}
 
-	cmd := exec.Command("bash", scriptPath)
-	cmd.Dir = moduleDir
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		message := strings.TrimSpace(string(output))
-		if message == "" {
-			return i18n.Errorf("module %q purge failed: %w", normalized, err)
-		}
-		return i18n.Errorf("module %q purge failed: %s", normalized, message)
-	}
-	return nil
+	return runModuleScript(ctx, normalized, moduleDir, PlanActionPurge, scriptPath, opts)
 }
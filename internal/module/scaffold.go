@@ -0,0 +1,174 @@
+package module
+
+import (
+	"bytes"
+	"embed"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+)
+
+// Scaffold kinds supported by `las module new`.
+const (
+	ScaffoldKindService = "service"
+	ScaffoldKindBinary  = "binary"
+	ScaffoldKindPip     = "pip"
+)
+
+// Scaffold templates use [% %] delimiters so they do not collide with the
+// InstallSpec {{ }} placeholders or bash [[ ]] tests they generate.
+//
+//go:embed scaffold
+var scaffoldFS embed.FS
+
+var moduleNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// ScaffoldOptions describes the module skeleton to generate.
+type ScaffoldOptions struct {
+	Name        string
+	Category    Category
+	Kind        string
+	Description string
+	// Dir is the modules root; the module is created in Dir/Name.
+	Dir string
+}
+
+type scaffoldFile struct {
+	source string
+	target string
+	kinds  []string
+}
+
+var scaffoldFiles = []scaffoldFile{
+	{source: "manifest.yaml.tmpl", target: "manifest.yaml"},
+	{source: "INSTALL.yaml.tmpl", target: "INSTALL.yaml"},
+	{source: "scripts/install.sh.tmpl", target: "scripts/install.sh"},
+	{source: "scripts/verify.sh.tmpl", target: "scripts/verify.sh"},
+	{source: "scripts/uninstall.sh.tmpl", target: "scripts/uninstall.sh"},
+	{source: "scripts/purge.sh.tmpl", target: "scripts/purge.sh"},
+	{source: "scripts/rollback.sh.tmpl", target: "scripts/rollback.sh"},
+	{source: "scripts/install_service.sh.tmpl", target: "scripts/install_service.sh", kinds: []string{ScaffoldKindService}},
+	{source: "templates/service.tmpl.tmpl", target: "templates/{name}.service.tmpl", kinds: []string{ScaffoldKindService}},
+}
+
+type scaffoldData struct {
+	APIVersion  string
+	Name        string
+	Category    Category
+	Kind        string
+	Description string
+	Mode        string
+	Provides    string
+	Tools       []string
+	SystemDeps  []string
+}
+
+// IsScaffoldKind reports whether kind is accepted by Scaffold.
+func IsScaffoldKind(kind string) bool {
+	switch kind {
+	case ScaffoldKindService, ScaffoldKindBinary, ScaffoldKindPip:
+		return true
+	}
+	return false
+}
+
+// Scaffold generates a lint-clean module skeleton from the embedded templates
+// and returns the directory it created.
+func Scaffold(opts ScaffoldOptions) (string, error) {
+	name := strings.TrimSpace(opts.Name)
+	if !moduleNamePattern.MatchString(name) {
+		return "", i18n.Errorf("invalid module name %q", opts.Name)
+	}
+	if _, ok := validCategories[opts.Category]; !ok {
+		return "", i18n.Errorf("invalid category %q", opts.Category)
+	}
+	if !IsScaffoldKind(opts.Kind) {
+		return "", i18n.Errorf("invalid module kind %q (expected service, binary or pip)", opts.Kind)
+	}
+	if strings.TrimSpace(opts.Dir) == "" {
+		return "", i18n.Errorf("modules directory is required")
+	}
+
+	moduleDir := filepath.Join(opts.Dir, name)
+	if _, err := os.Stat(moduleDir); err == nil {
+		return "", i18n.Errorf("module directory %s already exists", moduleDir)
+	} else if !os.IsNotExist(err) {
+		return "", i18n.Errorf("failed to access %s: %w", moduleDir, err)
+	}
+
+	data := newScaffoldData(name, opts)
+	rendered := make(map[string][]byte, len(scaffoldFiles))
+	for _, file := range scaffoldFiles {
+		if len(file.kinds) > 0 && !containsString(file.kinds, opts.Kind) {
+			continue
+		}
+		content, err := renderScaffoldFile(file.source, data)
+		if err != nil {
+			return "", err
+		}
+		rendered[strings.ReplaceAll(file.target, "{name}", name)] = content
+	}
+
+	for target, content := range rendered {
+		path := filepath.Join(moduleDir, filepath.FromSlash(target))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", i18n.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		mode := os.FileMode(0o644)
+		if strings.HasSuffix(target, ".sh") {
+			mode = 0o755
+		}
+		if err := os.WriteFile(path, content, mode); err != nil {
+			return "", i18n.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return moduleDir, nil
+}
+
+func newScaffoldData(name string, opts ScaffoldOptions) scaffoldData {
+	data := scaffoldData{
+		APIVersion:  InstallSpecAPIVersion,
+		Name:        name,
+		Category:    opts.Category,
+		Kind:        opts.Kind,
+		Description: strings.TrimSpace(opts.Description),
+		Mode:        "native",
+		Provides:    strings.ReplaceAll(name, "-", "_"),
+	}
+	switch opts.Kind {
+	case ScaffoldKindService:
+		data.Tools = []string{"bash", "systemctl"}
+		data.SystemDeps = []string{"ca-certificates"}
+	case ScaffoldKindBinary:
+		data.Tools = []string{"bash"}
+		data.SystemDeps = []string{"ca-certificates"}
+	case ScaffoldKindPip:
+		data.Mode = "python"
+		data.Tools = []string{"bash", "python3"}
+		data.SystemDeps = []string{"python3", "python3-pip"}
+	}
+	if data.Description == "" {
+		data.Description = name + " module"
+	}
+	return data
+}
+
+func renderScaffoldFile(source string, data scaffoldData) ([]byte, error) {
+	raw, err := scaffoldFS.ReadFile("scaffold/" + source)
+	if err != nil {
+		return nil, i18n.Errorf("failed to read scaffold template %s: %w", source, err)
+	}
+	tmpl, err := template.New(source).Delims("[%", "%]").Option("missingkey=error").Parse(string(raw))
+	if err != nil {
+		return nil, i18n.Errorf("failed to parse scaffold template %s: %w", source, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, i18n.Errorf("failed to render scaffold template %s: %w", source, err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,67 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScaffold_GeneratesLintCleanModules(t *testing.T) {
+	for _, kind := range []string{ScaffoldKindService, ScaffoldKindBinary, ScaffoldKindPip} {
+		t.Run(kind, func(t *testing.T) {
+			root := t.TempDir()
+			dir, err := Scaffold(ScaffoldOptions{
+				Name:     "demo-" + kind,
+				Category: CategoryRuntime,
+				Kind:     kind,
+				Dir:      root,
+			})
+			if err != nil {
+				t.Fatalf("Scaffold returned error: %v", err)
+			}
+
+			for _, script := range []string{"install.sh", "verify.sh", "uninstall.sh", "purge.sh", "rollback.sh"} {
+				info, err := os.Stat(filepath.Join(dir, "scripts", script))
+				if err != nil {
+					t.Fatalf("missing script %s: %v", script, err)
+				}
+				if info.Mode().Perm()&0o100 == 0 {
+					t.Fatalf("script %s is not executable", script)
+				}
+			}
+			_, err = os.Stat(filepath.Join(dir, "templates", "demo-"+kind+".service.tmpl"))
+			if kind == ScaffoldKindService && err != nil {
+				t.Fatalf("missing systemd template: %v", err)
+			}
+			if kind != ScaffoldKindService && err == nil {
+				t.Fatalf("unexpected systemd template for kind %s", kind)
+			}
+
+			report := LintModuleDir(dir)
+			if len(report.Issues) != 0 {
+				t.Fatalf("expected lint-clean module, got %+v", report.Issues)
+			}
+		})
+	}
+}
+
+func TestScaffold_RejectsInvalidInput(t *testing.T) {
+	root := t.TempDir()
+	cases := []ScaffoldOptions{
+		{Name: "Bad Name", Category: CategoryRuntime, Kind: ScaffoldKindBinary, Dir: root},
+		{Name: "demo", Category: "unknown", Kind: ScaffoldKindBinary, Dir: root},
+		{Name: "demo", Category: CategoryRuntime, Kind: "docker", Dir: root},
+	}
+	for _, opts := range cases {
+		if _, err := Scaffold(opts); err == nil {
+			t.Fatalf("expected error for %+v", opts)
+		}
+	}
+
+	if _, err := Scaffold(ScaffoldOptions{Name: "demo", Category: CategoryTool, Kind: ScaffoldKindBinary, Dir: root}); err != nil {
+		t.Fatalf("Scaffold returned error: %v", err)
+	}
+	if _, err := Scaffold(ScaffoldOptions{Name: "demo", Category: CategoryTool, Kind: ScaffoldKindBinary, Dir: root}); err == nil {
+		t.Fatalf("expected error when module directory already exists")
+	}
+}
@@ -0,0 +1,126 @@
+package module
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+)
+
+// ServiceStatus is the systemd state of the unit a module manages.
+type ServiceStatus struct {
+	Module      string `json:"module"`
+	Unit        string `json:"unit"`
+	LoadState   string `json:"load_state"`
+	ActiveState string `json:"active_state"`
+	SubState    string `json:"sub_state"`
+	Healthy     bool   `json:"healthy"`
+}
+
+// ServiceUnit returns the systemd unit installed by the module's install
+// plan, or "" when the module does not manage a service.
+func ServiceUnit(name string) (string, error) {
+	normalized, _, spec, err := loadModuleConfigSpec(name)
+	if err != nil {
+		return "", err
+	}
+	mode, _ := selectInstallModeForSystem(normalized, spec)
+	return moduleServiceUnit(normalized, spec.Install[mode]), nil
+}
+
+func moduleServiceUnit(moduleName string, steps []installStep) string {
+	for _, step := range steps {
+		if strings.TrimSpace(step.Tool) == "systemd_unit" {
+			return systemdUnitName(moduleName, step.SystemdUnit)
+		}
+	}
+	for _, step := range steps {
+		if strings.TrimSpace(step.Expected.Service) != "" {
+			return moduleName + ".service"
+		}
+	}
+	return ""
+}
+
+// GetServiceStatus queries systemd for the module's service unit.
+func GetServiceStatus(ctx context.Context, name string) (ServiceStatus, error) {
+	unit, err := requireServiceUnit(name)
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+	return queryServiceUnit(ctx, strings.ToLower(strings.TrimSpace(name)), unit)
+}
+
+func queryServiceUnit(ctx context.Context, moduleName, unit string) (ServiceStatus, error) {
+	status := ServiceStatus{Module: moduleName, Unit: unit}
+	output, err := exec.CommandContext(ctx, "systemctl", "show", "--property=LoadState,ActiveState,SubState", unit).Output()
+	if err != nil {
+		return status, i18n.Errorf("failed to query %s: %w", unit, err)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "LoadState":
+			status.LoadState = value
+		case "ActiveState":
+			status.ActiveState = value
+		case "SubState":
+			status.SubState = value
+		}
+	}
+	status.Healthy = status.ActiveState == "active" && status.SubState == "running"
+	return status, nil
+}
+
+// ControlService starts, stops or restarts the module's service unit.
+func ControlService(ctx context.Context, name, action string) error {
+	switch action {
+	case "start", "stop", "restart":
+	default:
+		return i18n.Errorf("unsupported service action %q", action)
+	}
+	unit, err := requireServiceUnit(name)
+	if err != nil {
+		return err
+	}
+	moduleDir, err := resolveModuleDir(strings.ToLower(strings.TrimSpace(name)))
+	if err != nil {
+		return err
+	}
+	if err := runSystemctl(ctx, moduleDir, nil, needsSudo(defaultSystemdUnitDir), action, unit); err != nil {
+		return i18n.Errorf("failed to %s %s: %w", action, unit, err)
+	}
+	return nil
+}
+
+// ServiceLogs returns the last lines of the module service's journal.
+func ServiceLogs(ctx context.Context, name string, lines int) (string, error) {
+	unit, err := requireServiceUnit(name)
+	if err != nil {
+		return "", err
+	}
+	if lines <= 0 {
+		lines = 200
+	}
+	output, err := exec.CommandContext(ctx, "journalctl", "--unit", unit, "--lines", strconv.Itoa(lines), "--no-pager", "--output", "short-iso").CombinedOutput()
+	if err != nil {
+		return "", i18n.Errorf("failed to read logs for %s: %s", unit, normalizedOutput(string(output)))
+	}
+	return string(output), nil
+}
+
+func requireServiceUnit(name string) (string, error) {
+	unit, err := ServiceUnit(name)
+	if err != nil {
+		return "", err
+	}
+	if unit == "" {
+		return "", i18n.Errorf("module %q does not manage a service", name)
+	}
+	return unit, nil
+}
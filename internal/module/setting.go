@@ -9,21 +9,27 @@ import (
 	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
 )
 
-// Setting runs module-specific post-install settings script with arguments.
+// Setting applies a module setting. Arguments of the form key=value, or a
+// single value for a module with exactly one configuration key that declares
+// an apply hook, are stored through SetConfig. Other arguments, or none,
+// fall back to the module's scripts/setting.sh.
 func Setting(name string, args []string) error {
 	normalized := strings.ToLower(strings.TrimSpace(name))
 	if normalized == "" {
 		return i18n.Errorf("module name is required")
 	}
-	if len(args) == 0 {
-		return i18n.Errorf("setting arguments are required")
-	}
-
 	moduleDir, err := resolveModuleDir(normalized)
 	if err != nil {
 		return err
 	}
 
+	if _, _, spec, err := loadModuleConfigSpec(normalized); err == nil {
+		if values, ok := settingValues(spec.Configuration, args); ok {
+			_, err := SetConfig(normalized, values, ConfigApplyOptions{})
+			return err
+		}
+	}
+
 	scriptPath := filepath.Join(moduleDir, "scripts", "setting.sh")
 	if _, err := os.Stat(scriptPath); err != nil {
 		if os.IsNotExist(err) {
@@ -46,3 +52,37 @@ func Setting(name string, args []string) error {
 	}
 	return nil
 }
+
+func settingValues(cfg installConfiguration, args []string) (map[string]string, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+	values := make(map[string]string, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			break
+		}
+		if _, known := cfg.Defaults[key]; !known {
+			return nil, false
+		}
+		values[key] = value
+	}
+	if len(values) == len(args) {
+		return values, true
+	}
+	if len(args) != 1 {
+		return nil, false
+	}
+
+	var hooked []string
+	for key, entry := range cfg.Schema {
+		if strings.TrimSpace(entry.Apply) != "" {
+			hooked = append(hooked, key)
+		}
+	}
+	if len(hooked) != 1 {
+		return nil, false
+	}
+	return map[string]string{hooked[0]: args[0]}, true
+}
@@ -0,0 +1,695 @@
+package module
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+)
+
+type installDownload struct {
+	URL    string `yaml:"url"`
+	SHA256 string `yaml:"sha256"`
+	Dest   string `yaml:"dest"`
+	Mode   string `yaml:"mode"`
+	// Cacheable downloads are read from the artifact cache when it holds
+	// them, and are packaged into offline bundles.
+	Cacheable bool `yaml:"cacheable"`
+}
+
+type installExtract struct {
+	Archive         string `yaml:"archive"`
+	Dest            string `yaml:"dest"`
+	Format          string `yaml:"format"`
+	StripComponents int    `yaml:"strip_components"`
+	Creates         string `yaml:"creates"`
+}
+
+type installPythonVenv struct {
+	Path             string   `yaml:"path"`
+	Python           string   `yaml:"python"`
+	Requirements     []string `yaml:"requirements"`
+	RequirementsFile string   `yaml:"requirements_file"`
+}
+
+type installSystemdUnit struct {
+	Name     string `yaml:"name"`
+	Template string `yaml:"template"`
+	Dir      string `yaml:"dir"`
+	Enable   *bool  `yaml:"enable"`
+	Start    *bool  `yaml:"start"`
+}
+
+const (
+	defaultSystemdUnitDir = "/etc/systemd/system"
+	venvRequirementsStamp = ".las-requirements.sha256"
+)
+
+// runDownloadStep fetches a URL (http, https or file) into dest. An existing
+// file whose checksum matches is left untouched, so the step is idempotent.
+// Cacheable downloads come from the artifact cache when it holds them.
+func runDownloadStep(ctx context.Context, moduleDir string, spec installDownload, vars map[string]string) error {
+	rawURL := renderStepValue(spec.URL, vars)
+	if rawURL == "" {
+		return i18n.Errorf("download url is required")
+	}
+	dest := resolveStepPath(moduleDir, renderStepValue(spec.Dest, vars))
+	if dest == "" {
+		return i18n.Errorf("download dest is required")
+	}
+	expected := normalizeChecksum(renderStepValue(spec.SHA256, vars))
+
+	if _, err := os.Stat(dest); err == nil {
+		if expected == "" {
+			return nil
+		}
+		if actual, err := fileSHA256(dest); err == nil && strings.EqualFold(actual, expected) {
+			return nil
+		}
+	}
+
+	open := openDownload
+	if spec.Cacheable {
+		open = openArtifact
+	}
+	reader, size, err := open(ctx, rawURL)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".part-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	var sink io.Writer = io.MultiWriter(tmp, hasher)
+	if progress := downloadProgress(ctx); progress != nil {
+		sink = &progressWriter{w: sink, total: size, progress: progress}
+	}
+	if _, err := io.Copy(sink, reader); err != nil {
+		tmp.Close()
+		return i18n.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	if expected != "" && !strings.EqualFold(actual, expected) {
+		return i18n.Errorf("checksum mismatch for %s: expected %s got %s", rawURL, expected, actual)
+	}
+	mode := os.FileMode(0o644)
+	if parsed, ok := parseFileMode(spec.Mode); ok {
+		mode = parsed
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+// openDownload opens rawURL and returns its body with the expected size, or
+// -1 when unknown.
+func openDownload(ctx context.Context, rawURL string) (io.ReadCloser, int64, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, -1, i18n.Errorf("invalid download url %q: %w", rawURL, err)
+	}
+	switch parsed.Scheme {
+	case "file":
+		file, err := os.Open(parsed.Path)
+		if err != nil {
+			return nil, -1, err
+		}
+		size := int64(-1)
+		if info, err := file.Stat(); err == nil {
+			size = info.Size()
+		}
+		return file, size, nil
+	case "http", "https":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, -1, i18n.Errorf("invalid download url %q: %w", rawURL, err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, -1, i18n.Errorf("failed to download %s: %w", rawURL, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, -1, i18n.Errorf("failed to download %s: unexpected status %s", rawURL, resp.Status)
+		}
+		return resp.Body, resp.ContentLength, nil
+	default:
+		return nil, -1, i18n.Errorf("unsupported download scheme %q", parsed.Scheme)
+	}
+}
+
+// progressWriter forwards writes and reports the running byte count.
+type progressWriter struct {
+	w          io.Writer
+	downloaded int64
+	total      int64
+	progress   func(downloaded, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.downloaded += int64(n)
+	p.progress(p.downloaded, p.total)
+	return n, err
+}
+
+// runExtractStep unpacks a tar (optionally gzip or bzip2 compressed) or zip
+// archive. When creates is set and already exists the step is skipped.
+func runExtractStep(moduleDir string, spec installExtract, vars map[string]string) error {
+	archive := resolveStepPath(moduleDir, renderStepValue(spec.Archive, vars))
+	if archive == "" {
+		return i18n.Errorf("extract archive is required")
+	}
+	dest := resolveStepPath(moduleDir, renderStepValue(spec.Dest, vars))
+	if dest == "" {
+		return i18n.Errorf("extract dest is required")
+	}
+	if creates := resolveStepPath(moduleDir, renderStepValue(spec.Creates, vars)); creates != "" {
+		if _, err := os.Stat(creates); err == nil {
+			return nil
+		}
+	}
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+
+	format := strings.ToLower(strings.TrimSpace(spec.Format))
+	if format == "" {
+		format = archiveFormat(archive)
+	}
+	switch format {
+	case "zip":
+		return extractZip(archive, dest, spec.StripComponents)
+	case "tar", "tar.gz", "tgz", "tar.bz2", "tbz2":
+		return extractTar(archive, dest, format, spec.StripComponents)
+	default:
+		return i18n.Errorf("unsupported archive format %q", format)
+	}
+}
+
+func archiveFormat(path string) string {
+	lower := strings.ToLower(path)
+	for _, suffix := range []string{"tar.gz", "tgz", "tar.bz2", "tbz2", "tar", "zip"} {
+		if strings.HasSuffix(lower, "."+suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+func extractTar(archive, dest, format string, strip int) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	switch format {
+	case "tar.gz", "tgz":
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return i18n.Errorf("failed to read %s: %w", archive, err)
+		}
+		defer gz.Close()
+		reader = gz
+	case "tar.bz2", "tbz2":
+		reader = bzip2.NewReader(file)
+	}
+
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return i18n.Errorf("failed to read %s: %w", archive, err)
+		}
+		target, ok, err := archiveTarget(dest, header.Name, strip)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(target, tr, os.FileMode(header.Mode).Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// The link is checked from where its parent really is, which
+			// differs from the entry path when the parent is a symlink.
+			parent, err := resolvedArchiveDir(dest, filepath.Dir(target))
+			if err != nil {
+				return err
+			}
+			root, err := filepath.EvalSymlinks(dest)
+			if err != nil {
+				return err
+			}
+			if filepath.IsAbs(header.Linkname) || !withinDir(root, filepath.Join(parent, header.Linkname)) {
+				return i18n.Errorf("archive entry %s links outside destination", header.Name)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			// Hard link names are archive paths of entries extracted
+			// earlier; the file they resolve to must be inside dest.
+			source, ok, err := archiveTarget(dest, header.Linkname, strip)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return i18n.Errorf("archive entry %s links to %s, which is not extracted", header.Name, header.Linkname)
+			}
+			source, err = filepath.EvalSymlinks(source)
+			if err != nil {
+				return i18n.Errorf("archive entry %s links to %s: %w", header.Name, header.Linkname, err)
+			}
+			root, err := filepath.EvalSymlinks(dest)
+			if err != nil {
+				return err
+			}
+			if info, err := os.Stat(source); err != nil || !info.Mode().IsRegular() || !withinDir(root, source) {
+				return i18n.Errorf("archive entry %s links outside destination", header.Name)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err := os.Link(source, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractZip(archive, dest string, strip int) error {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return i18n.Errorf("failed to read %s: %w", archive, err)
+	}
+	defer reader.Close()
+
+	for _, entry := range reader.File {
+		target, ok, err := archiveTarget(dest, entry.Name, strip)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if entry.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		src, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		err = writeArchiveFile(target, src, entry.Mode().Perm())
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveTarget maps an archive entry to a path below dest, dropping strip
+// leading components and rejecting entries that escape dest, also through
+// symlinks extracted earlier.
+func archiveTarget(dest, name string, strip int) (string, bool, error) {
+	parts := strings.Split(strings.Trim(filepath.ToSlash(name), "/"), "/")
+	if len(parts) <= strip {
+		return "", false, nil
+	}
+	relative := filepath.FromSlash(strings.Join(parts[strip:], "/"))
+	target := filepath.Join(dest, relative)
+	if !withinDir(dest, target) {
+		return "", false, i18n.Errorf("archive entry %s escapes destination", name)
+	}
+	if _, err := resolvedArchiveDir(dest, filepath.Dir(target)); err != nil {
+		return "", false, i18n.Errorf("archive entry %s escapes destination: %w", name, err)
+	}
+	return target, true, nil
+}
+
+// resolvedArchiveDir resolves the symlinks of dir, which may not exist yet,
+// and checks that it stays inside dest. Extracted symlinks must not lead
+// writes out of dest, so a dangling symlink on the way is refused too.
+func resolvedArchiveDir(dest, dir string) (string, error) {
+	root, err := filepath.EvalSymlinks(dest)
+	if err != nil {
+		return "", err
+	}
+	existing, rest := dir, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			resolved = filepath.Join(resolved, rest)
+			if !withinDir(root, resolved) {
+				return "", i18n.Errorf("%s resolves outside it", dir)
+			}
+			return resolved, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		if _, lerr := os.Lstat(existing); lerr == nil {
+			return "", i18n.Errorf("%s is a dangling symlink", existing)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return "", err
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+}
+
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func writeArchiveFile(target string, src io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	// Replace a symlink rather than write through it.
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+	}
+	if mode == 0 {
+		mode = 0o644
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// runPythonVenvStep creates a virtual environment and installs requirements
+// into it. A stamp file records the installed requirement set so re-running
+// the step with unchanged requirements does not invoke pip again.
+func runPythonVenvStep(ctx context.Context, moduleDir string, spec installPythonVenv, vars map[string]string, env map[string]string) error {
+	venv := resolveStepPath(moduleDir, renderStepValue(spec.Path, vars))
+	if venv == "" {
+		return i18n.Errorf("python_venv path is required")
+	}
+	python := renderStepValue(spec.Python, vars)
+	if python == "" {
+		python = "python3"
+	}
+	venvPython := filepath.Join(venv, "bin", "python")
+	if _, err := os.Stat(venvPython); err != nil {
+		if err := runStepCommand(ctx, moduleDir, env, python, "-m", "venv", venv); err != nil {
+			return i18n.Errorf("failed to create virtualenv %s: %w", venv, err)
+		}
+	}
+
+	args := []string{"-m", "pip", "install"}
+	for _, requirement := range spec.Requirements {
+		if requirement = renderStepValue(requirement, vars); requirement != "" {
+			args = append(args, requirement)
+		}
+	}
+	stamp := strings.Join(args[3:], "\n")
+	if file := resolveStepPath(moduleDir, renderStepValue(spec.RequirementsFile, vars)); file != "" {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		args = append(args, "-r", file)
+		stamp += "\n" + string(contents)
+	}
+	if len(args) == 3 {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(stamp))
+	digest := hex.EncodeToString(sum[:])
+	stampPath := filepath.Join(venv, venvRequirementsStamp)
+	if existing, err := os.ReadFile(stampPath); err == nil && strings.TrimSpace(string(existing)) == digest {
+		return nil
+	}
+	if err := runStepCommand(ctx, moduleDir, env, venvPython, args...); err != nil {
+		return i18n.Errorf("failed to install requirements into %s: %w", venv, err)
+	}
+	return os.WriteFile(stampPath, []byte(digest+"\n"), 0o644)
+}
+
+// runSystemdUnitStep renders a unit template into the systemd directory,
+// reloads systemd when the unit changed, then enables and starts it. Commands
+// run through sudo when the unit directory is not writable.
+func runSystemdUnitStep(ctx context.Context, moduleName, moduleDir string, spec installSystemdUnit, vars map[string]string, env map[string]string) error {
+	unitName := systemdUnitName(moduleName, spec)
+	templatePath := resolveStepPath(moduleDir, strings.TrimSpace(spec.Template))
+	if templatePath == "" {
+		return i18n.Errorf("systemd_unit template is required")
+	}
+	contents, err := os.ReadFile(templatePath)
+	if err != nil {
+		return err
+	}
+	rendered, err := renderTemplate(string(contents), vars)
+	if err != nil {
+		return err
+	}
+
+	unitDir := strings.TrimSpace(spec.Dir)
+	if unitDir == "" {
+		unitDir = defaultSystemdUnitDir
+	}
+	if err := os.MkdirAll(unitDir, 0o755); err != nil && !os.IsPermission(err) {
+		return err
+	}
+	useSudo := needsSudo(unitDir)
+	unitPath := filepath.Join(unitDir, unitName)
+
+	changed := true
+	if existing, err := os.ReadFile(unitPath); err == nil && bytes.Equal(existing, []byte(rendered)) {
+		changed = false
+	}
+	if changed {
+		if err := writeUnitFile(ctx, moduleDir, unitPath, rendered, useSudo, env); err != nil {
+			return err
+		}
+		if err := runSystemctl(ctx, moduleDir, env, useSudo, "daemon-reload"); err != nil {
+			return err
+		}
+	}
+	if spec.Enable == nil || *spec.Enable {
+		if err := runSystemctl(ctx, moduleDir, env, useSudo, "enable", unitName); err != nil {
+			return err
+		}
+	}
+	if spec.Start == nil || *spec.Start {
+		action := "start"
+		if changed {
+			action = "restart"
+		}
+		if err := runSystemctl(ctx, moduleDir, env, useSudo, action, unitName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func systemdUnitName(moduleName string, spec installSystemdUnit) string {
+	name := strings.TrimSpace(spec.Name)
+	if name == "" {
+		name = moduleName
+	}
+	if !strings.Contains(name, ".") {
+		name += ".service"
+	}
+	return name
+}
+
+func writeUnitFile(ctx context.Context, moduleDir, unitPath, contents string, useSudo bool, env map[string]string) error {
+	if !useSudo {
+		return os.WriteFile(unitPath, []byte(contents), 0o644)
+	}
+	tmp, err := os.CreateTemp("", "las-unit-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(contents); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return runStepCommand(ctx, moduleDir, env, "sudo", "install", "-m", "0644", tmp.Name(), unitPath)
+}
+
+func runSystemctl(ctx context.Context, moduleDir string, env map[string]string, useSudo bool, args ...string) error {
+	if useSudo {
+		return runStepCommand(ctx, moduleDir, env, "sudo", append([]string{"systemctl"}, args...)...)
+	}
+	return runStepCommand(ctx, moduleDir, env, "systemctl", args...)
+}
+
+func needsSudo(dir string) bool {
+	if os.Geteuid() == 0 {
+		return false
+	}
+	probe, err := os.CreateTemp(dir, ".las-write-check-*")
+	if err != nil {
+		return true
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return false
+}
+
+func runStepCommand(ctx context.Context, moduleDir string, env map[string]string, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = moduleDir
+	cmd.Env = commandEnv(env)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		message := normalizedOutput(string(output))
+		if message == "" {
+			return err
+		}
+		return i18n.Errorf("%s", message)
+	}
+	return nil
+}
+
+func renderStepValue(value string, vars map[string]string) string {
+	rendered, _ := renderTemplate(strings.TrimSpace(value), vars)
+	return strings.TrimSpace(rendered)
+}
+
+func resolveStepPath(moduleDir, path string) string {
+	if path == "" {
+		return ""
+	}
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(moduleDir, path)
+	}
+	return path
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func parseFileMode(value string) (os.FileMode, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	var mode uint32
+	for _, ch := range value {
+		if ch < '0' || ch > '7' {
+			return 0, false
+		}
+		mode = mode*8 + uint32(ch-'0')
+	}
+	return os.FileMode(mode), true
+}
+
+// stepDetails summarizes the tool-specific block of a step for dry-run output.
+func stepDetails(moduleName, moduleDir string, step installStep, vars map[string]string) map[string]string {
+	details := map[string]string{}
+	set := func(key, value string) {
+		if value != "" {
+			details[key] = value
+		}
+	}
+	switch strings.TrimSpace(step.Tool) {
+	case "download":
+		set("url", renderStepValue(step.Download.URL, vars))
+		set("sha256", renderStepValue(step.Download.SHA256, vars))
+		set("dest", resolveStepPath(moduleDir, renderStepValue(step.Download.Dest, vars)))
+	case "extract":
+		set("archive", resolveStepPath(moduleDir, renderStepValue(step.Extract.Archive, vars)))
+		set("dest", resolveStepPath(moduleDir, renderStepValue(step.Extract.Dest, vars)))
+		set("creates", resolveStepPath(moduleDir, renderStepValue(step.Extract.Creates, vars)))
+	case "python_venv":
+		set("path", resolveStepPath(moduleDir, renderStepValue(step.PythonVenv.Path, vars)))
+		requirements := make([]string, 0, len(step.PythonVenv.Requirements))
+		for _, requirement := range step.PythonVenv.Requirements {
+			requirements = append(requirements, renderStepValue(requirement, vars))
+		}
+		set("requirements", strings.Join(requirements, " "))
+		set("requirements_file", resolveStepPath(moduleDir, renderStepValue(step.PythonVenv.RequirementsFile, vars)))
+	case "systemd_unit":
+		unitDir := strings.TrimSpace(step.SystemdUnit.Dir)
+		if unitDir == "" {
+			unitDir = defaultSystemdUnitDir
+		}
+		set("unit", filepath.Join(unitDir, systemdUnitName(moduleName, step.SystemdUnit)))
+		set("template", resolveStepPath(moduleDir, strings.TrimSpace(step.SystemdUnit.Template)))
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
@@ -0,0 +1,445 @@
+package module
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFakeCommand(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/usr/bin/env bash\n"+script), 0o755); err != nil {
+		t.Fatalf("failed to write fake %s: %v", name, err)
+	}
+}
+
+func TestRunDownloadStep_VerifiesChecksumAndIsIdempotent(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "payload.bin")
+	if err := os.WriteFile(source, []byte("payload"), 0o644); err != nil {
+		t.Fatalf("failed to write source: %v", err)
+	}
+	sum := sha256.Sum256([]byte("payload"))
+	step := installStep{
+		ID:   "S10",
+		Tool: "download",
+		Download: installDownload{
+			URL:    "file://" + source,
+			SHA256: "sha256:" + hex.EncodeToString(sum[:]),
+			Dest:   "{{ dest_dir }}/payload.bin",
+			Mode:   "0755",
+		},
+		Expected: installExpect{Path: filepath.Join(dir, "out", "payload.bin")},
+	}
+	vars := map[string]string{"dest_dir": filepath.Join(dir, "out")}
+
+	var downloaded, total int64
+	ctx := withDownloadProgress(context.Background(), func(d, t int64) { downloaded, total = d, t })
+	if err := runInstallStep(ctx, "demo", dir, step, vars, nil); err != nil {
+		t.Fatalf("download step returned error: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "out", "payload.bin"))
+	if err != nil || info.Mode().Perm() != 0o755 {
+		t.Fatalf("unexpected downloaded file: %v %v", info, err)
+	}
+	if downloaded != 7 || total != 7 {
+		t.Fatalf("expected progress 7/7, got %d/%d", downloaded, total)
+	}
+
+	// A matching file is kept, so the step succeeds even when the source is gone.
+	if err := os.Remove(source); err != nil {
+		t.Fatalf("failed to remove source: %v", err)
+	}
+	if err := runInstallStep(context.Background(), "demo", dir, step, vars, nil); err != nil {
+		t.Fatalf("second download returned error: %v", err)
+	}
+
+	step.Download.SHA256 = strings.Repeat("0", 64)
+	if err := os.WriteFile(source, []byte("payload"), 0o644); err != nil {
+		t.Fatalf("failed to write source: %v", err)
+	}
+	if err := runInstallStep(context.Background(), "demo", dir, step, vars, nil); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestRunDownloadStep_ReadsCacheableFromArtifactCache(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("LOCALAISTACK_CONTROL_DATA_DIR", filepath.Join(dir, "data"))
+	sum := sha256.Sum256([]byte("cached"))
+	artifact := Artifact{Module: "demo", Step: "S10", URL: "https://example.invalid/release.tar", SHA256: hex.EncodeToString(sum[:])}
+	source := filepath.Join(dir, "release.tar")
+	if err := os.WriteFile(source, []byte("cached"), 0o644); err != nil {
+		t.Fatalf("failed to write source: %v", err)
+	}
+	if err := CacheArtifact(artifact, source); err != nil {
+		t.Fatalf("CacheArtifact returned error: %v", err)
+	}
+	if err := CacheArtifact(Artifact{URL: "https://example.invalid/other.tar", SHA256: strings.Repeat("0", 64)}, source); err == nil {
+		t.Fatalf("expected a checksum mismatch when caching")
+	}
+
+	spec := installDownload{URL: artifact.URL, SHA256: artifact.SHA256, Dest: filepath.Join(dir, "out", "release.tar"), Cacheable: true}
+	if err := runDownloadStep(context.Background(), dir, spec, nil); err != nil {
+		t.Fatalf("download step returned error: %v", err)
+	}
+	if data, err := os.ReadFile(spec.Dest); err != nil || string(data) != "cached" {
+		t.Fatalf("unexpected downloaded file %q (%v)", data, err)
+	}
+
+	// Downloads that are not cacheable go to the network.
+	spec.Cacheable = false
+	spec.Dest = filepath.Join(dir, "out", "uncached.tar")
+	if err := runDownloadStep(context.Background(), dir, spec, nil); err == nil {
+		t.Fatalf("expected the uncached download to fail")
+	}
+}
+
+func TestShellNetworkReason_FollowsModuleScripts(t *testing.T) {
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"scripts/install.sh": "#!/bin/bash\nbash \"$(dirname \"$0\")/fetch.sh\"\n",
+		"scripts/fetch.sh":   "#!/bin/bash\nset -e\nsudo apt-get -y install build-essential\n",
+		"scripts/local.sh":   "#!/bin/bash\nsystemctl daemon-reload\n",
+	}
+	for name, content := range scripts {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	for command, want := range map[string]string{
+		"curl -fsSL https://example.com/install.sh | bash": "runs curl",
+		"python3 -m pip install --user demo":               "runs pip install",
+		"bash scripts/install.sh":                          "runs apt-get -y install (in scripts/fetch.sh)",
+		"bash scripts/local.sh /tmp/demo.service":          "",
+		"bash ../outside.sh":                               "",
+	} {
+		if got := shellNetworkReason(dir, command); got != want {
+			t.Errorf("shellNetworkReason(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
+
+func TestRunExtractStep_TarGzAndZip(t *testing.T) {
+	dir := t.TempDir()
+
+	tarPath := filepath.Join(dir, "app.tar.gz")
+	tarFile, err := os.Create(tarPath)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	gz := gzip.NewWriter(tarFile)
+	tw := tar.NewWriter(gz)
+	for name, body := range map[string]string{"app-1.0/bin/app": "binary", "app-1.0/README": "readme"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write body: %v", err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+	tarFile.Close()
+
+	spec := installExtract{Archive: "app.tar.gz", Dest: "out", StripComponents: 1, Creates: "out/bin/app"}
+	if err := runExtractStep(dir, spec, nil); err != nil {
+		t.Fatalf("extract returned error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "out", "bin", "app"))
+	if err != nil || string(data) != "binary" {
+		t.Fatalf("unexpected extracted file: %q %v", data, err)
+	}
+	// creates exists now, so a missing archive no longer matters.
+	if err := os.Remove(tarPath); err != nil {
+		t.Fatalf("failed to remove archive: %v", err)
+	}
+	if err := runExtractStep(dir, spec, nil); err != nil {
+		t.Fatalf("second extract returned error: %v", err)
+	}
+
+	zipPath := filepath.Join(dir, "evil.zip")
+	zipFile, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatalf("failed to create zip: %v", err)
+	}
+	zw := zip.NewWriter(zipFile)
+	w, _ := zw.Create("ok.txt")
+	_, _ = w.Write([]byte("ok"))
+	w, _ = zw.Create("../escape.txt")
+	_, _ = w.Write([]byte("escape"))
+	zw.Close()
+	zipFile.Close()
+
+	err = runExtractStep(dir, installExtract{Archive: zipPath, Dest: filepath.Join(dir, "zip")}, nil)
+	if err == nil || !strings.Contains(err.Error(), "escapes destination") {
+		t.Fatalf("expected path traversal error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.txt")); !os.IsNotExist(err) {
+		t.Fatalf("archive entry escaped destination")
+	}
+}
+
+func TestExtractTar_RejectsSymlinkChains(t *testing.T) {
+	writeTar := func(t *testing.T, path string, entries []tar.Header) {
+		t.Helper()
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("failed to create archive: %v", err)
+		}
+		defer file.Close()
+		tw := tar.NewWriter(file)
+		for _, header := range entries {
+			body := ""
+			if header.Typeflag == tar.TypeReg {
+				body = "escaped"
+				header.Size = int64(len(body))
+				header.Mode = 0o644
+			}
+			if err := tw.WriteHeader(&header); err != nil {
+				t.Fatalf("failed to write header: %v", err)
+			}
+			tw.Write([]byte(body))
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatalf("failed to close archive: %v", err)
+		}
+	}
+
+	cases := map[string][]tar.Header{
+		// a resolves to the destination, so a/l really points at ../x.
+		"chained": {
+			{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "a/l", Typeflag: tar.TypeSymlink, Linkname: "../x"},
+			{Name: "a/l/evil", Typeflag: tar.TypeReg},
+		},
+		// c looks like it stays inside but resolves through a to ../missing.
+		"dangling": {
+			{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "c", Typeflag: tar.TypeSymlink, Linkname: "a/../missing"},
+			{Name: "c/evil", Typeflag: tar.TypeReg},
+		},
+	}
+	for name, entries := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			dest := filepath.Join(dir, "root", "dest")
+			if err := os.MkdirAll(dest, 0o755); err != nil {
+				t.Fatalf("mkdir: %v", err)
+			}
+			archive := filepath.Join(dir, "evil.tar")
+			writeTar(t, archive, entries)
+			if err := extractTar(archive, dest, "tar", 0); err == nil {
+				t.Fatalf("expected the archive to be rejected")
+			}
+			for _, outside := range []string{filepath.Join(dir, "root", "x", "evil"), filepath.Join(dir, "root", "missing", "evil")} {
+				if _, err := os.Stat(outside); !os.IsNotExist(err) {
+					t.Fatalf("archive entry escaped destination to %s", outside)
+				}
+			}
+		})
+	}
+
+	// A regular entry replaces a symlink instead of writing through it.
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "replace.tar")
+	writeTar(t, archive, []tar.Header{
+		{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."},
+		{Name: "c", Typeflag: tar.TypeSymlink, Linkname: "a/../outside"},
+		{Name: "c", Typeflag: tar.TypeReg},
+	})
+	dest := filepath.Join(dir, "dest")
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := extractTar(archive, dest, "tar", 0); err != nil {
+		t.Fatalf("extract returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outside")); !os.IsNotExist(err) {
+		t.Fatalf("a regular entry was written through a symlink")
+	}
+}
+
+func TestExtractTar_HardLinks(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secret, []byte("secret"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+	writeTar := func(t *testing.T, name string, entries []tar.Header) string {
+		t.Helper()
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, header := range entries {
+			body := ""
+			if header.Typeflag == tar.TypeReg {
+				body = "#!/bin/sh\n"
+				header.Size = int64(len(body))
+				header.Mode = 0o755
+			}
+			if err := tw.WriteHeader(&header); err != nil {
+				t.Fatalf("failed to write header: %v", err)
+			}
+			tw.Write([]byte(body))
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatalf("failed to close archive: %v", err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatalf("write archive: %v", err)
+		}
+		return path
+	}
+
+	archive := writeTar(t, "release.tar", []tar.Header{
+		{Name: "release/bin/tool", Typeflag: tar.TypeReg},
+		{Name: "release/bin/tool-alias", Typeflag: tar.TypeLink, Linkname: "release/bin/tool"},
+	})
+	dest := filepath.Join(dir, "dest")
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := extractTar(archive, dest, "tar", 1); err != nil {
+		t.Fatalf("extract returned error: %v", err)
+	}
+	original, err := os.Stat(filepath.Join(dest, "bin", "tool"))
+	if err != nil {
+		t.Fatalf("stat tool: %v", err)
+	}
+	alias, err := os.Stat(filepath.Join(dest, "bin", "tool-alias"))
+	if err != nil {
+		t.Fatalf("the hard link was not extracted: %v", err)
+	}
+	if !os.SameFile(original, alias) {
+		t.Fatalf("expected tool-alias to be a hard link to tool")
+	}
+
+	for name, linkname := range map[string]string{
+		"parent":   "../secret",
+		"absolute": secret,
+		"stripped": "release",
+	} {
+		t.Run(name, func(t *testing.T) {
+			archive := writeTar(t, name+".tar", []tar.Header{
+				{Name: "release/evil", Typeflag: tar.TypeLink, Linkname: linkname},
+			})
+			dest := filepath.Join(dir, name)
+			if err := os.MkdirAll(dest, 0o755); err != nil {
+				t.Fatalf("mkdir: %v", err)
+			}
+			if err := extractTar(archive, dest, "tar", 1); err == nil {
+				t.Fatalf("expected a link to %s to be rejected", linkname)
+			}
+			if _, err := os.Lstat(filepath.Join(dest, "evil")); !os.IsNotExist(err) {
+				t.Fatalf("a rejected hard link was created")
+			}
+		})
+	}
+}
+
+func TestRunSystemdUnitStep_UsesSystemctlAndSkipsUnchangedUnits(t *testing.T) {
+	dir := t.TempDir()
+	binDir := filepath.Join(dir, "bin")
+	if err := os.MkdirAll(binDir, 0o755); err != nil {
+		t.Fatalf("failed to create bin dir: %v", err)
+	}
+	logPath := filepath.Join(dir, "systemctl.log")
+	writeFakeCommand(t, binDir, "systemctl", `echo "$*" >> "`+logPath+`"
+if [[ "$1" == "is-active" ]]; then echo active; fi
+`)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if err := os.MkdirAll(filepath.Join(dir, "templates"), 0o755); err != nil {
+		t.Fatalf("failed to create templates dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "templates", "demo.service.tmpl"), []byte("ExecStart=/bin/demo --listen {{ bind }}\n"), 0o644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+	unitDir := filepath.Join(dir, "units")
+	step := installStep{
+		ID:          "S30",
+		Tool:        "systemd_unit",
+		SystemdUnit: installSystemdUnit{Name: "demo-api", Template: "templates/demo.service.tmpl", Dir: unitDir},
+		Expected:    installExpect{Service: "active"},
+	}
+	vars := map[string]string{"bind": "127.0.0.1:9000"}
+
+	for i := 0; i < 2; i++ {
+		if err := runInstallStep(context.Background(), "demo", dir, step, vars, nil); err != nil {
+			t.Fatalf("systemd_unit step returned error: %v", err)
+		}
+	}
+	unit, err := os.ReadFile(filepath.Join(unitDir, "demo-api.service"))
+	if err != nil || string(unit) != "ExecStart=/bin/demo --listen 127.0.0.1:9000\n" {
+		t.Fatalf("unexpected unit file: %q %v", unit, err)
+	}
+	log, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read systemctl log: %v", err)
+	}
+	want := []string{
+		"daemon-reload",
+		"enable demo-api.service",
+		"restart demo-api.service",
+		"is-active demo-api",
+		"enable demo-api.service",
+		"start demo-api.service",
+		"is-active demo-api",
+	}
+	if got := strings.Split(strings.TrimSpace(string(log)), "\n"); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected systemctl calls:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestRunPythonVenvStep_SkipsUnchangedRequirements(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "pip.log")
+	// The fake interpreter creates a venv whose python records pip invocations.
+	writeFakeCommand(t, dir, "fake-python", `venv="$3"
+mkdir -p "$venv/bin"
+cat > "$venv/bin/python" <<'SCRIPT'
+#!/usr/bin/env bash
+echo "$*" >> "`+logPath+`"
+SCRIPT
+chmod +x "$venv/bin/python"
+`)
+	spec := installPythonVenv{
+		Path:         "venv",
+		Python:       filepath.Join(dir, "fake-python"),
+		Requirements: []string{"demo=={{ version }}"},
+	}
+	vars := map[string]string{"version": "1.0"}
+	for i := 0; i < 2; i++ {
+		if err := runPythonVenvStep(context.Background(), dir, spec, vars, nil); err != nil {
+			t.Fatalf("python_venv step returned error: %v", err)
+		}
+	}
+	vars["version"] = "1.1"
+	if err := runPythonVenvStep(context.Background(), dir, spec, vars, nil); err != nil {
+		t.Fatalf("python_venv step returned error: %v", err)
+	}
+
+	log, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read pip log: %v", err)
+	}
+	want := "-m pip install demo==1.0\n-m pip install demo==1.1\n"
+	if string(log) != want {
+		t.Fatalf("unexpected pip calls %q, want %q", log, want)
+	}
+}
@@ -1,6 +1,7 @@
 package module
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -20,6 +21,12 @@ type uninstallSpec struct {
 }
 
 func Uninstall(name string) error {
+	return UninstallWithContext(context.Background(), name, RunOptions{})
+}
+
+// UninstallWithContext runs the module uninstall script as a single reported
+// step. Cancelling ctx stops the script.
+func UninstallWithContext(ctx context.Context, name string, opts RunOptions) error {
 	normalized := strings.ToLower(strings.TrimSpace(name))
 	if normalized == "" {
 		return i18n.Errorf("module name is required")
@@ -58,15 +65,25 @@ func Uninstall(name string) error {
 		return i18n.Errorf("failed to read uninstall script for module %q: %w", normalized, err)
 	}
 
-	cmd := exec.Command("bash", scriptPath)
-	cmd.Dir = moduleDir
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		message := strings.TrimSpace(string(output))
-		if message == "" {
-			return i18n.Errorf("module %q uninstall failed: %w", normalized, err)
+	return runModuleScript(ctx, normalized, moduleDir, PlanActionUninstall, scriptPath, opts)
+}
+
+func runModuleScript(ctx context.Context, moduleName, moduleDir, action, scriptPath string, opts RunOptions) error {
+	reporter := stepReporter{opts: opts, module: moduleName, action: action, total: 1}
+	return reporter.run(0, action, "", func() error {
+		cmd := exec.CommandContext(ctx, "bash", scriptPath)
+		cmd.Dir = moduleDir
+		output, err := cmd.CombinedOutput()
+		if opts.Output != nil {
+			_, _ = opts.Output.Write(output)
 		}
-		return i18n.Errorf("module %q uninstall failed: %s", normalized, message)
-	}
-	return nil
+		if err != nil {
+			message := strings.TrimSpace(string(output))
+			if message == "" {
+				return i18n.Errorf("module %q %s failed: %w", moduleName, action, err)
+			}
+			return i18n.Errorf("module %q %s failed: %s", moduleName, action, message)
+		}
+		return nil
+	})
 }
@@ -23,9 +23,11 @@ type Manager struct {
 	nativeEnabled bool
 	mu            sync.Mutex
 	processes     map[string]*process
+	watchers      []func(Status)
 }
 
 type process struct {
+	spec         ModuleSpec
 	status       Status
 	cmd          *exec.Cmd
 	containerID  string
@@ -35,6 +37,7 @@ type process struct {
 	cancelHealth context.CancelFunc
 	logFile      *os.File
 	healthCheck  HealthCheck
+	exited       chan struct{}
 }
 
 func NewManager(cfg config.RuntimeConfig) *Manager {
@@ -67,10 +70,14 @@ func (m *Manager) Start(ctx context.Context, spec ModuleSpec) (*Status, error) {
 		return nil, err
 	}
 
+	restarts := 0
 	m.mu.Lock()
-	if existing, ok := m.processes[spec.Name]; ok && existing.status.State == StateRunning {
-		m.mu.Unlock()
-		return nil, i18n.Errorf("module %q already running", spec.Name)
+	if existing, ok := m.processes[spec.Name]; ok {
+		if existing.status.State == StateRunning {
+			m.mu.Unlock()
+			return nil, i18n.Errorf("module %q already running", spec.Name)
+		}
+		restarts = existing.status.Restarts + 1
 	}
 	m.mu.Unlock()
 
@@ -86,12 +93,15 @@ func (m *Manager) Start(ctx context.Context, spec ModuleSpec) (*Status, error) {
 		Health:    HealthUnknown,
 		StartedAt: time.Now(),
 		LogPath:   logPath,
+		Restarts:  restarts,
 	}
 
 	proc := &process{
+		spec:        spec,
 		status:      status,
 		logFile:     logFile,
 		healthCheck: spec.HealthCheck,
+		exited:      make(chan struct{}),
 	}
 
 	switch mode {
@@ -112,7 +122,9 @@ func (m *Manager) Start(ctx context.Context, spec ModuleSpec) (*Status, error) {
 
 	m.mu.Lock()
 	m.processes[spec.Name] = proc
+	current := proc.status
 	m.mu.Unlock()
+	m.notify(current)
 
 	m.startHealthMonitor(proc)
 
@@ -139,6 +151,37 @@ func (m *Manager) Stop(ctx context.Context, name string) error {
 	return err
 }
 
+// Restart stops a module if it is running and starts it again with the spec
+// it was last started with.
+func (m *Manager) Restart(ctx context.Context, name string) (*Status, error) {
+	proc, ok := m.getProcess(name)
+	if !ok {
+		return nil, i18n.Errorf("module %q not found", name)
+	}
+	m.mu.Lock()
+	spec := proc.spec
+	active := proc.status.State == StateRunning || proc.status.State == StateStarting
+	m.mu.Unlock()
+
+	if active {
+		if err := m.Stop(ctx, name); err != nil {
+			log.Warn().Err(err).Str("module", name).Msg(i18n.T("Error while stopping module for restart"))
+		}
+		for {
+			status, _ := m.Status(name)
+			if status.State != StateRunning && status.State != StateStarting {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return nil, i18n.Errorf("timeout waiting for module %q to stop: %w", name, ctx.Err())
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+	return m.Start(ctx, spec)
+}
+
 func (m *Manager) Status(name string) (Status, bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -159,6 +202,23 @@ func (m *Manager) List() []Status {
 	return statuses
 }
 
+// Watch registers fn to be called with a snapshot whenever a process changes
+// state or health.
+func (m *Manager) Watch(fn func(Status)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.watchers = append(m.watchers, fn)
+}
+
+func (m *Manager) notify(status Status) {
+	m.mu.Lock()
+	watchers := append([]func(Status){}, m.watchers...)
+	m.mu.Unlock()
+	for _, watch := range watchers {
+		watch(status)
+	}
+}
+
 func (m *Manager) validateMode(mode ExecutionMode) error {
 	switch mode {
 	case ModeContainer:
@@ -216,8 +276,8 @@ func (m *Manager) updateProcess(name string, update func(*process)) {
 
 func (m *Manager) markStopped(proc *process, err error) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	if proc.status.State == StateStopped || proc.status.State == StateFailed {
+		m.mu.Unlock()
 		return
 	}
 	finished := time.Now()
@@ -229,6 +289,9 @@ func (m *Manager) markStopped(proc *process, err error) {
 		proc.status.State = StateStopped
 	}
 	proc.status.Health = HealthUnhealthy
+	current := proc.status
+	m.mu.Unlock()
+	m.notify(current)
 }
 
 func (m *Manager) startHealthMonitor(proc *process) {
@@ -249,9 +312,16 @@ func (m *Manager) startHealthMonitor(proc *process) {
 				return
 			case <-ticker.C:
 				health := m.checkHealth(proc)
+				changed := false
+				var current Status
 				m.updateProcess(proc.status.Name, func(p *process) {
+					changed = p.status.Health != health
 					p.status.Health = health
+					current = p.status
 				})
+				if changed {
+					m.notify(current)
+				}
 			}
 		}
 	}()
@@ -342,6 +412,7 @@ func (m *Manager) waitForExit(proc *process, waitFunc func() error) {
 	m.stopLogStream(proc)
 	m.stopHealthMonitor(proc)
 	m.closeLogFile(proc)
+	close(proc.exited)
 }
 
 func buildEnv(env map[string]string) []string {
@@ -35,24 +35,22 @@ func (m *Manager) startNative(_ context.Context, spec ModuleSpec, proc *process)
 }
 
 func (m *Manager) stopNative(ctx context.Context, proc *process) error {
-	if proc.cancelRun != nil {
-		proc.cancelRun()
-	}
 	if proc.cmd == nil {
+		if proc.cancelRun != nil {
+			proc.cancelRun()
+		}
 		return nil
 	}
+	// Record the stop as intended before the kill makes Wait report it as
+	// a failure.
+	m.markStopped(proc, nil)
+	if proc.cancelRun != nil {
+		proc.cancelRun()
+	}
 
-	done := make(chan error, 1)
-	go func() {
-		done <- proc.cmd.Wait()
-	}()
-
+	// waitForExit owns cmd.Wait; calling it again would block.
 	select {
-	case err := <-done:
-		if err != nil {
-			return i18n.Errorf("native process exit: %w", err)
-		}
-		m.markStopped(proc, nil)
+	case <-proc.exited:
 	case <-ctx.Done():
 		if proc.cmd.Process != nil {
 			if killErr := proc.cmd.Process.Kill(); killErr != nil {
@@ -46,14 +46,15 @@ type ModuleSpec struct {
 }
 
 type Status struct {
-	Name        string
-	Mode        ExecutionMode
-	PID         int
-	ContainerID string
-	State       ProcessState
-	Health      HealthState
-	StartedAt   time.Time
-	FinishedAt  *time.Time
-	LogPath     string
-	LastError   string
+	Name        string        `json:"name"`
+	Mode        ExecutionMode `json:"mode"`
+	PID         int           `json:"pid,omitempty"`
+	ContainerID string        `json:"container_id,omitempty"`
+	State       ProcessState  `json:"state"`
+	Health      HealthState   `json:"health"`
+	StartedAt   time.Time     `json:"started_at"`
+	FinishedAt  *time.Time    `json:"finished_at,omitempty"`
+	LogPath     string        `json:"log_path,omitempty"`
+	LastError   string        `json:"last_error,omitempty"`
+	Restarts    int           `json:"restarts"`
 }
@@ -0,0 +1,173 @@
+package stack
+
+import (
+	"strings"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/control"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/modelmanager"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+)
+
+type Kind string
+
+const (
+	KindModule     Kind = "module"
+	KindModel      Kind = "model"
+	KindDeployment Kind = "deployment"
+)
+
+type Action string
+
+const (
+	// ActionNone marks an entry the host already satisfies.
+	ActionNone      Action = "none"
+	ActionInstall   Action = "install"
+	ActionReinstall Action = "reinstall"
+	ActionDownload  Action = "download"
+	ActionStart     Action = "start"
+)
+
+// Item is one entry of a stack and what applying it takes.
+type Item struct {
+	Kind   Kind   `json:"kind"`
+	Name   string `json:"name"`
+	Action Action `json:"action"`
+	Detail string `json:"detail,omitempty"`
+
+	// Model entries and deployments carry the parsed model reference.
+	Source   modelmanager.ModelSource `json:"source,omitempty"`
+	ModelID  string                   `json:"model_id,omitempty"`
+	FileHint string                   `json:"file,omitempty"`
+	// Deployment is set for deployments.
+	Deployment *Deployment `json:"deployment,omitempty"`
+}
+
+// Plan lists every stack entry in the order applying it runs: modules in
+// dependency order, then models, then deployments.
+type Plan struct {
+	Items []Item `json:"items"`
+}
+
+// Changes returns the items that need an action.
+func (p Plan) Changes() []Item {
+	var changes []Item
+	for _, item := range p.Items {
+		if item.Action != ActionNone {
+			changes = append(changes, item)
+		}
+	}
+	return changes
+}
+
+// Environment is what a stack is compared against.
+type Environment struct {
+	// Registry holds the modules available for installation.
+	Registry *module.Registry
+	// Modules is the recorded module state.
+	Modules map[string]control.ModuleState
+	// Models finds downloaded models.
+	Models *modelmanager.Manager
+	// Running holds the names of running runtime services.
+	Running map[string]bool
+}
+
+// Diff plans the changes that make env match the stack. Module
+// dependencies are resolved with module.Resolver and included in the plan,
+// and models that deployments serve are downloaded even when the stack
+// does not list them.
+func Diff(s *Stack, env Environment) (Plan, error) {
+	var plan Plan
+
+	resolved, err := module.NewResolver(env.Registry).ResolveInstallPlan(s.Modules)
+	if err != nil {
+		return Plan{}, err
+	}
+	constraints := map[string]*module.VersionConstraint{}
+	for _, entry := range s.Modules {
+		name, constraint, _ := module.ParseModuleDependency(entry)
+		constraints[name] = constraint
+	}
+	for _, name := range resolved.Order {
+		plan.Items = append(plan.Items, diffModule(name, resolved.Modules[name], constraints[name], env.Modules))
+	}
+
+	// planned maps each model to the file hints already planned for it; an
+	// empty hint is satisfied by any of them.
+	planned := map[string]map[string]bool{}
+	addModel := func(ref, fileHint string) error {
+		source, modelID, err := modelmanager.ParseModelID(ref)
+		if err != nil {
+			return err
+		}
+		key := string(source) + ":" + modelID
+		if hints, ok := planned[key]; ok && (fileHint == "" || hints[fileHint]) {
+			return nil
+		}
+		if planned[key] == nil {
+			planned[key] = map[string]bool{}
+		}
+		planned[key][fileHint] = true
+		item := Item{Kind: KindModel, Name: ref, Action: ActionNone, Source: source, ModelID: modelID, FileHint: fileHint}
+		if env.Models == nil || !env.Models.HasLocalModel(source, modelID, fileHint) {
+			item.Action = ActionDownload
+			item.Detail = i18n.T("from %s", source)
+		}
+		if fileHint != "" {
+			item.Detail = strings.TrimSpace(item.Detail + " " + i18n.T("(file %s)", fileHint))
+		}
+		plan.Items = append(plan.Items, item)
+		return nil
+	}
+	for _, model := range s.Models {
+		if err := addModel(model.ID, model.File); err != nil {
+			return Plan{}, err
+		}
+	}
+	for _, deployment := range s.Deployments {
+		if err := addModel(deployment.Model, deployment.File); err != nil {
+			return Plan{}, err
+		}
+	}
+
+	for i := range s.Deployments {
+		deployment := s.Deployments[i]
+		source, modelID, _ := modelmanager.ParseModelID(deployment.Model)
+		item := Item{
+			Kind:       KindDeployment,
+			Name:       deployment.Name,
+			Action:     ActionNone,
+			Detail:     i18n.T("%s on port %d", deployment.Model, deployment.Port),
+			Source:     source,
+			ModelID:    modelID,
+			FileHint:   deployment.File,
+			Deployment: &deployment,
+		}
+		if !env.Running[deployment.Name] {
+			item.Action = ActionStart
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	return plan, nil
+}
+
+func diffModule(name string, record module.ModuleRecord, constraint *module.VersionConstraint, recorded map[string]control.ModuleState) Item {
+	item := Item{Kind: KindModule, Name: name, Action: ActionNone}
+	available := record.Version.String()
+	state, ok := recorded[name]
+	if !ok || !state.Present() {
+		item.Action = ActionInstall
+		item.Detail = available
+		return item
+	}
+	item.Detail = state.Version
+	if constraint == nil {
+		return item
+	}
+	installed, err := module.ParseVersion(state.Version)
+	if err != nil || !constraint.Match(installed) {
+		item.Action = ActionReinstall
+		item.Detail = i18n.T("%s -> %s", state.Version, available)
+	}
+	return item
+}
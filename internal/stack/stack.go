@@ -0,0 +1,138 @@
+// Package stack reads stack files, which declare the modules, models and
+// model deployments a host should have, and plans the changes that make a
+// host match one.
+package stack
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/modelmanager"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+	"gopkg.in/yaml.v3"
+)
+
+// Stack is the content of a stack file.
+type Stack struct {
+	// Modules use the dependency syntax of module manifests, e.g.
+	// "llama.cpp" or "vllm@>=0.8".
+	Modules     []string     `yaml:"modules"`
+	Models      []Model      `yaml:"models"`
+	Deployments []Deployment `yaml:"deployments"`
+}
+
+// Model is a model to download.
+type Model struct {
+	// ID is a model reference as accepted by `las model download`, e.g.
+	// "hf:Qwen/Qwen2.5-7B-Instruct-GGUF".
+	ID string `yaml:"id"`
+	// File selects one file of the repository, e.g. "Q4_K_M".
+	File string `yaml:"file"`
+}
+
+// Deployment is a downloaded model served in the background by the
+// server's runtime manager, with the settings of `las model deploy`.
+type Deployment struct {
+	// Name is the service name; it defaults to one derived from the model.
+	Name                     string  `yaml:"name"`
+	Model                    string  `yaml:"model"`
+	File                     string  `yaml:"file"`
+	Host                     string  `yaml:"host"`
+	Port                     int     `yaml:"port"`
+	Threads                  int     `yaml:"threads"`
+	CtxSize                  int     `yaml:"ctx_size"`
+	GPULayers                *int    `yaml:"n_gpu_layers"`
+	TensorSplit              string  `yaml:"tensor_split"`
+	VLLMMaxModelLen          int     `yaml:"vllm_max_model_len"`
+	VLLMGPUMemoryUtilization float64 `yaml:"vllm_gpu_memory_utilization"`
+}
+
+const (
+	defaultDeployHost = "0.0.0.0"
+	defaultDeployPort = 8080
+)
+
+var serviceNamePattern = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// ServiceName derives a runtime service name from a model ID.
+func ServiceName(modelID string) string {
+	name := serviceNamePattern.ReplaceAllString(strings.ToLower(modelID), "-")
+	return "model-" + strings.Trim(name, "-")
+}
+
+// Load reads and validates a stack file.
+func Load(path string) (*Stack, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, i18n.Errorf("failed to read stack file: %w", err)
+	}
+	return Parse(raw)
+}
+
+// Parse decodes and validates stack file content. Unknown keys are
+// rejected so typos do not silently drop part of a stack.
+func Parse(raw []byte) (*Stack, error) {
+	var stack Stack
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&stack); err != nil {
+		return nil, i18n.Errorf("failed to parse stack file: %w", err)
+	}
+	if err := stack.normalize(); err != nil {
+		return nil, err
+	}
+	return &stack, nil
+}
+
+func (s *Stack) normalize() error {
+	for i, entry := range s.Modules {
+		entry = strings.TrimSpace(entry)
+		if _, _, err := module.ParseModuleDependency(entry); err != nil {
+			return i18n.Errorf("modules[%d]: %w", i, err)
+		}
+		s.Modules[i] = entry
+	}
+	for i := range s.Models {
+		s.Models[i].ID = strings.TrimSpace(s.Models[i].ID)
+		if s.Models[i].ID == "" {
+			return i18n.Errorf("models[%d]: id is required", i)
+		}
+	}
+	names := map[string]bool{}
+	ports := map[int]string{}
+	for i := range s.Deployments {
+		deployment := &s.Deployments[i]
+		deployment.Model = strings.TrimSpace(deployment.Model)
+		if deployment.Model == "" {
+			return i18n.Errorf("deployments[%d]: model is required", i)
+		}
+		source, modelID, err := modelmanager.ParseModelID(deployment.Model)
+		if err != nil {
+			return i18n.Errorf("deployments[%d]: %w", i, err)
+		}
+		if source == modelmanager.SourceOllama {
+			return i18n.Errorf("deployments[%d]: ollama models are served by the ollama module and cannot be deployed", i)
+		}
+		if strings.TrimSpace(deployment.Name) == "" {
+			deployment.Name = ServiceName(modelID)
+		}
+		if names[deployment.Name] {
+			return i18n.Errorf("deployments[%d]: duplicate deployment name %q", i, deployment.Name)
+		}
+		names[deployment.Name] = true
+		if deployment.Host == "" {
+			deployment.Host = defaultDeployHost
+		}
+		if deployment.Port == 0 {
+			deployment.Port = defaultDeployPort
+		}
+		if other, ok := ports[deployment.Port]; ok {
+			return i18n.Errorf("deployments[%d]: port %d is already used by %q", i, deployment.Port, other)
+		}
+		ports[deployment.Port] = deployment.Name
+	}
+	return nil
+}
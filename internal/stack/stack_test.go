@@ -0,0 +1,124 @@
+package stack
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhuangbiaowei/LocalAIStack/internal/control"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/modelmanager"
+	"github.com/zhuangbiaowei/LocalAIStack/internal/module"
+)
+
+const testStack = `
+modules:
+  - server@>=2.0
+  - tools
+models:
+  - id: hf:org/ready-GGUF
+    file: Q4_K_M
+  - id: hf:org/other-GGUF
+deployments:
+  - model: hf:org/ready-GGUF
+    file: Q4_K_M
+    port: 8081
+    n_gpu_layers: 0
+  - name: chat
+    model: hf:org/fresh-GGUF
+`
+
+func testRegistry(t *testing.T) *module.Registry {
+	t.Helper()
+	registry := module.NewRegistry()
+	records := []module.Manifest{
+		{Name: "runtime", Version: "1.0.0"},
+		{Name: "server", Version: "2.1.0", Dependencies: module.Dependencies{Modules: []string{"runtime"}}},
+		{Name: "tools", Version: "0.3.0"},
+	}
+	for _, manifest := range records {
+		version, err := module.ParseVersion(manifest.Version)
+		if err != nil {
+			t.Fatalf("ParseVersion: %v", err)
+		}
+		if err := registry.Add(module.ModuleRecord{Manifest: manifest, Version: version}); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	return registry
+}
+
+func TestParseValidatesEntries(t *testing.T) {
+	s, err := Parse([]byte(testStack))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if s.Deployments[0].Name != "model-org-ready-gguf" || s.Deployments[0].Host != defaultDeployHost {
+		t.Fatalf("deployment defaults not applied: %+v", s.Deployments[0])
+	}
+	if s.Deployments[1].Port != defaultDeployPort || *s.Deployments[0].GPULayers != 0 {
+		t.Fatalf("unexpected deployments %+v", s.Deployments)
+	}
+
+	for _, invalid := range []string{
+		"modules: [\"bad@>>1\"]",
+		"models:\n  - file: x\n",
+		"deployments:\n  - model: ollama:llama3\n",
+		"deployments:\n  - model: hf:a/b\n  - model: hf:c/d\n",
+		"module: [x]\n",
+	} {
+		if _, err := Parse([]byte(invalid)); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestDiffPlansOnlyMissingEntries(t *testing.T) {
+	s, err := Parse([]byte(testStack))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	modelDir := t.TempDir()
+	readyDir := filepath.Join(modelDir, "org_ready-GGUF")
+	if err := os.MkdirAll(readyDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(readyDir, "ready.Q4_K_M.gguf"), []byte("gguf"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	plan, err := Diff(s, Environment{
+		Registry: testRegistry(t),
+		Modules: map[string]control.ModuleState{
+			"runtime": {Name: "runtime", Version: "1.0.0", State: module.StateInstalled},
+			"server":  {Name: "server", Version: "1.5.0", State: module.StateRunning},
+			"tools":   {Name: "tools", Version: "0.3.0", State: module.StateFailed},
+		},
+		Models:  modelmanager.NewManager(modelDir),
+		Running: map[string]bool{"model-org-ready-gguf": true},
+	})
+	if err != nil {
+		t.Fatalf("Diff returned error: %v", err)
+	}
+
+	var got []string
+	for _, item := range plan.Items {
+		got = append(got, string(item.Kind)+" "+item.Name+" "+string(item.Action))
+	}
+	want := []string{
+		"module runtime none",
+		"module server reinstall",
+		"module tools install",
+		"model hf:org/ready-GGUF none",
+		"model hf:org/other-GGUF download",
+		"model hf:org/fresh-GGUF download",
+		"deployment model-org-ready-gguf none",
+		"deployment chat start",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("plan:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if len(plan.Changes()) != 5 {
+		t.Fatalf("expected 5 changes, got %+v", plan.Changes())
+	}
+}
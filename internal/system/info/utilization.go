@@ -0,0 +1,95 @@
+package info
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Utilization is a point-in-time view of memory and GPU usage. Fields are
+// zero when the platform does not expose them.
+type Utilization struct {
+	MemoryTotalBytes     uint64           `json:"memory_total_bytes"`
+	MemoryAvailableBytes uint64           `json:"memory_available_bytes"`
+	GPUs                 []GPUUtilization `json:"gpus,omitempty"`
+}
+
+type GPUUtilization struct {
+	Index            int     `json:"index"`
+	Name             string  `json:"name"`
+	UtilizationRatio float64 `json:"utilization_ratio"`
+	MemoryUsedBytes  uint64  `json:"memory_used_bytes"`
+	MemoryTotalBytes uint64  `json:"memory_total_bytes"`
+}
+
+func CollectUtilization(ctx context.Context) Utilization {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var usage Utilization
+	if runtime.GOOS == "linux" {
+		if file, err := os.Open("/proc/meminfo"); err == nil {
+			usage.MemoryTotalBytes, usage.MemoryAvailableBytes = parseMeminfo(bufio.NewScanner(file))
+			file.Close()
+		}
+		stdout, _, err := runCommand(ctx, "nvidia-smi", "--query-gpu=index,name,utilization.gpu,memory.used,memory.total", "--format=csv,noheader,nounits")
+		if err == nil {
+			usage.GPUs = parseNvidiaSMIUtilization(stdout)
+		}
+	}
+	return usage
+}
+
+func parseMeminfo(scanner *bufio.Scanner) (total, available uint64) {
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			total = value * 1024
+		case "MemAvailable:":
+			available = value * 1024
+		}
+	}
+	return total, available
+}
+
+// parseNvidiaSMIUtilization parses the csv,noheader,nounits output of
+// nvidia-smi, where memory is reported in MiB and utilization in percent.
+func parseNvidiaSMIUtilization(output string) []GPUUtilization {
+	var gpus []GPUUtilization
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) != 5 {
+			continue
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		index, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		gpu := GPUUtilization{Index: index, Name: fields[1]}
+		if percent, err := strconv.ParseFloat(fields[2], 64); err == nil {
+			gpu.UtilizationRatio = percent / 100
+		}
+		if used, err := strconv.ParseUint(fields[3], 10, 64); err == nil {
+			gpu.MemoryUsedBytes = used << 20
+		}
+		if total, err := strconv.ParseUint(fields[4], 10, 64); err == nil {
+			gpu.MemoryTotalBytes = total << 20
+		}
+		gpus = append(gpus, gpu)
+	}
+	return gpus
+}
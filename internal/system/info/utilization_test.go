@@ -0,0 +1,28 @@
+package info
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestParseNvidiaSMIUtilization(t *testing.T) {
+	output := "0, NVIDIA GeForce RTX 4090, 37, 12288, 24564\n1, NVIDIA A100, [N/A], 0, 81920\nnot,a,row\n"
+	gpus := parseNvidiaSMIUtilization(output)
+	if len(gpus) != 2 {
+		t.Fatalf("expected 2 GPUs, got %+v", gpus)
+	}
+	if gpus[0].Name != "NVIDIA GeForce RTX 4090" || gpus[0].UtilizationRatio != 0.37 || gpus[0].MemoryUsedBytes != 12288<<20 {
+		t.Fatalf("unexpected first GPU %+v", gpus[0])
+	}
+	if gpus[1].Index != 1 || gpus[1].UtilizationRatio != 0 || gpus[1].MemoryTotalBytes != 81920<<20 {
+		t.Fatalf("unexpected second GPU %+v", gpus[1])
+	}
+}
+
+func TestParseMeminfo(t *testing.T) {
+	total, available := parseMeminfo(bufio.NewScanner(strings.NewReader("MemTotal:       65536 kB\nMemFree:         1024 kB\nMemAvailable:   32768 kB\n")))
+	if total != 65536*1024 || available != 32768*1024 {
+		t.Fatalf("unexpected meminfo %d/%d", total, available)
+	}
+}
@@ -17,18 +17,36 @@ if [[ ! -d "$SRC" ]]; then
   exit 1
 fi
 
+BLOB_DIR="$SRC_BASE/.blobs/sha256"
+METADATA="$SRC_BASE/$MODEL_BUNDLE/metadata.json"
+
+# link_model links a split_files entry into ComfyUI. Files kept in the model
+# blob store are linked by their blob path, which stays valid while any
+# downloaded model references the file.
+link_model() {
+  local rel="$1" dest="$2" digest=""
+  if [[ -f "$METADATA" ]]; then
+    digest="$(sed -n "s|^ *\"split_files/$rel\": \"\([0-9a-f]\{64\}\)\".*|\1|p" "$METADATA" | head -n 1)"
+  fi
+  if [[ -n "$digest" && -f "$BLOB_DIR/$digest" ]]; then
+    ln -sfn "$BLOB_DIR/$digest" "$dest"
+  else
+    ln -sfn "$SRC/$rel" "$dest"
+  fi
+}
+
 mkdir -p "$COMFYUI_HOME/models/diffusion_models" "$COMFYUI_HOME/models/text_encoders" "$COMFYUI_HOME/models/vae"
 
 case "$MODEL_BUNDLE" in
   Comfy-Org_z_image_turbo)
-    ln -sfn "$SRC/diffusion_models/z_image_turbo_bf16.safetensors" "$COMFYUI_HOME/models/diffusion_models/z_image_turbo_bf16.safetensors"
-    ln -sfn "$SRC/diffusion_models/z_image_turbo_nvfp4.safetensors" "$COMFYUI_HOME/models/diffusion_models/z_image_turbo_nvfp4.safetensors"
+    link_model "diffusion_models/z_image_turbo_bf16.safetensors" "$COMFYUI_HOME/models/diffusion_models/z_image_turbo_bf16.safetensors"
+    link_model "diffusion_models/z_image_turbo_nvfp4.safetensors" "$COMFYUI_HOME/models/diffusion_models/z_image_turbo_nvfp4.safetensors"
 
-    ln -sfn "$SRC/text_encoders/qwen_3_4b.safetensors" "$COMFYUI_HOME/models/text_encoders/qwen_3_4b.safetensors"
-    ln -sfn "$SRC/text_encoders/qwen_3_4b_fp4_mixed.safetensors" "$COMFYUI_HOME/models/text_encoders/qwen_3_4b_fp4_mixed.safetensors"
-    ln -sfn "$SRC/text_encoders/qwen_3_4b_fp8_mixed.safetensors" "$COMFYUI_HOME/models/text_encoders/qwen_3_4b_fp8_mixed.safetensors"
+    link_model "text_encoders/qwen_3_4b.safetensors" "$COMFYUI_HOME/models/text_encoders/qwen_3_4b.safetensors"
+    link_model "text_encoders/qwen_3_4b_fp4_mixed.safetensors" "$COMFYUI_HOME/models/text_encoders/qwen_3_4b_fp4_mixed.safetensors"
+    link_model "text_encoders/qwen_3_4b_fp8_mixed.safetensors" "$COMFYUI_HOME/models/text_encoders/qwen_3_4b_fp8_mixed.safetensors"
 
-    ln -sfn "$SRC/vae/ae.safetensors" "$COMFYUI_HOME/models/vae/ae.safetensors"
+    link_model "vae/ae.safetensors" "$COMFYUI_HOME/models/vae/ae.safetensors"
     ;;
   *)
     echo "Unsupported model bundle: $MODEL_BUNDLE" >&2
@@ -0,0 +1,34 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+if [[ $# -ne 1 ]]; then
+  echo "Usage: $0 <unpacked-release-dir>"
+  exit 1
+fi
+
+release_dir="$1"
+
+if [[ ! -x "${release_dir}/bin/ollama" ]]; then
+  echo "Ollama binary not found in ${release_dir}/bin"
+  exit 1
+fi
+
+sudo_cmd=""
+if [[ "$(id -u)" -ne 0 ]]; then
+  sudo_cmd="sudo"
+fi
+
+${sudo_cmd} install -m 0755 "${release_dir}/bin/ollama" /usr/local/bin/ollama
+if [[ -d "${release_dir}/lib/ollama" ]]; then
+  ${sudo_cmd} rm -rf /usr/local/lib/ollama
+  ${sudo_cmd} mkdir -p /usr/local/lib
+  ${sudo_cmd} cp -a "${release_dir}/lib/ollama" /usr/local/lib/ollama
+fi
+
+# The service runs as the ollama user, as with the upstream installer.
+if ! id ollama >/dev/null 2>&1; then
+  ${sudo_cmd} useradd -r -s /bin/false -U -m -d /usr/share/ollama ollama
+fi
+
+# The release archive is kept; the unpacked copy is not needed any more.
+rm -rf "${release_dir}"
@@ -7,8 +7,9 @@ systemctl stop ollama 2>/dev/null || true
 rm -f /etc/systemd/system/ollama.service
 rm -f /lib/systemd/system/ollama.service
 
-# Remove binary if installed by the upstream installer
+# Remove the binary and libraries installed by install_binary.sh
 rm -f /usr/local/bin/ollama
+rm -rf /usr/local/lib/ollama
 
 systemctl daemon-reload || true
 echo "Rollback completed (user data preserved)."
@@ -7,6 +7,7 @@ systemctl disable ollama 2>/dev/null || true
 rm -f /etc/systemd/system/ollama.service
 rm -f /lib/systemd/system/ollama.service
 rm -f /usr/local/bin/ollama
+rm -rf /usr/local/lib/ollama
 
 systemctl daemon-reload || true
 echo "Ollama uninstalled (data preserved at ~/.ollama)."
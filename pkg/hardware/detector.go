@@ -3,43 +3,43 @@ package hardware
 import "github.com/zhuangbiaowei/LocalAIStack/internal/i18n"
 
 type CPU struct {
-	Arch      string
-	Cores     int
-	Threads   int
-	ModelName string
-	Vendor    string
+	Arch      string `json:"arch"`
+	Cores     int    `json:"cores"`
+	Threads   int    `json:"threads"`
+	ModelName string `json:"model_name"`
+	Vendor    string `json:"vendor"`
 }
 
 type GPU struct {
-	Index         int
-	Name          string
-	Vendor        string
-	VRAMTotal     uint64
-	VRAMFree      uint64
-	CUDAVersion   string
-	DriverVersion string
-	MultiGPU      bool
-	NVLink        bool
+	Index         int    `json:"index"`
+	Name          string `json:"name"`
+	Vendor        string `json:"vendor"`
+	VRAMTotal     uint64 `json:"vram_total"`
+	VRAMFree      uint64 `json:"vram_free"`
+	CUDAVersion   string `json:"cuda_version"`
+	DriverVersion string `json:"driver_version"`
+	MultiGPU      bool   `json:"multi_gpu"`
+	NVLink        bool   `json:"nvlink"`
 }
 
 type Memory struct {
-	Total     uint64
-	Available uint64
-	Free      uint64
+	Total     uint64 `json:"total"`
+	Available uint64 `json:"available"`
+	Free      uint64 `json:"free"`
 }
 
 type Storage struct {
-	Path  string
-	Total uint64
-	Free  uint64
-	Type  string
+	Path  string `json:"path"`
+	Total uint64 `json:"total"`
+	Free  uint64 `json:"free"`
+	Type  string `json:"type"`
 }
 
 type HardwareProfile struct {
-	CPU     CPU
-	GPUs    []GPU
-	Memory  Memory
-	Storage []Storage
+	CPU     CPU       `json:"cpu"`
+	GPUs    []GPU     `json:"gpus"`
+	Memory  Memory    `json:"memory"`
+	Storage []Storage `json:"storage"`
 }
 
 type Detector interface {
@@ -1,17 +1,17 @@
 package hardware
 
 type NormalizedProfile struct {
-	CPUArch           string
-	CPUCores          int
-	CPUThreads        int
-	GPUCount          int
-	MaxGPUVRAMBytes   uint64
-	TotalGPUVRAMBytes uint64
-	HasNVLink         bool
-	MultiGPU          bool
-	MemoryTotalBytes  uint64
-	StorageTotalBytes uint64
-	StorageFreeBytes  uint64
+	CPUArch           string `json:"cpu_arch"`
+	CPUCores          int    `json:"cpu_cores"`
+	CPUThreads        int    `json:"cpu_threads"`
+	GPUCount          int    `json:"gpu_count"`
+	MaxGPUVRAMBytes   uint64 `json:"max_gpu_vram_bytes"`
+	TotalGPUVRAMBytes uint64 `json:"total_gpu_vram_bytes"`
+	HasNVLink         bool   `json:"has_nvlink"`
+	MultiGPU          bool   `json:"multi_gpu"`
+	MemoryTotalBytes  uint64 `json:"memory_total_bytes"`
+	StorageTotalBytes uint64 `json:"storage_total_bytes"`
+	StorageFreeBytes  uint64 `json:"storage_free_bytes"`
 }
 
 func NormalizeProfile(profile *HardwareProfile) NormalizedProfile {